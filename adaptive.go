@@ -0,0 +1,105 @@
+package lrmr
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ab180/lrmr/partitions"
+	"github.com/pkg/errors"
+)
+
+// AdaptivePartitionOptions chooses the number of partitions from the estimated size of the input.
+// See WithAdaptivePartitions for details.
+type AdaptivePartitionOptions struct {
+	// BytesPerPartition is the target size of the input processed by a partition. Zero disables it.
+	BytesPerPartition int64
+
+	// MinPartitions and MaxPartitions bound the chosen number of partitions. Zero MaxPartitions means unbounded.
+	MinPartitions int
+	MaxPartitions int
+}
+
+// partitionsFor returns the number of partitions for the input of given size.
+func (a AdaptivePartitionOptions) partitionsFor(size int64) int {
+	n := int((size + a.BytesPerPartition - 1) / a.BytesPerPartition)
+	if n < a.MinPartitions {
+		n = a.MinPartitions
+	}
+	if a.MaxPartitions > 0 && n > a.MaxPartitions {
+		n = a.MaxPartitions
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// inputSizeEstimator is an input which can estimate the number of bytes it reads without reading them.
+type inputSizeEstimator interface {
+	EstimateSize() (int64, error)
+}
+
+func (p parallelizedInput) EstimateSize() (int64, error) {
+	var size int64
+	for _, row := range p.data {
+		size += int64(len(row.Key) + len(row.Value))
+	}
+	return size, nil
+}
+
+func (l localInput) EstimateSize() (size int64, err error) {
+	err = filepath.Walk(l.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+func (t textFileInput) EstimateSize() (size int64, err error) {
+	paths, err := filepath.Glob(t.Pattern)
+	if err != nil {
+		return 0, errors.Wrapf(err, "match %s", t.Pattern)
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, errors.Wrapf(err, "stat %s", path)
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+	}
+	return size, nil
+}
+
+// adaptPartitions sets the number of partitions of the stages in the plans from the estimated size
+// of the input, if it is enabled by WithAdaptivePartitions and the input can estimate its size.
+// The stages whose number of partitions is set explicitly (e.g. by Repartition) are left as is.
+// It returns the estimated size, or -1 if it is not estimated.
+func (s *Session) adaptPartitions(ds *Dataset, plans []partitions.Plan) (int64, error) {
+	opts := s.options.AdaptivePartitions
+	if opts.BytesPerPartition <= 0 {
+		return -1, nil
+	}
+	e, ok := ds.input.(inputSizeEstimator)
+	if !ok {
+		return -1, nil
+	}
+	size, err := e.EstimateSize()
+	if err != nil {
+		return -1, errors.Wrap(err, "estimate input size")
+	}
+	n := opts.partitionsFor(size)
+	for i := 1; i < len(plans); i++ {
+		if plans[i].DesiredCount == partitions.Auto {
+			plans[i].DesiredCount = n
+		}
+	}
+	log.Verbose("Chose {} partitions for the input of estimated {} bytes", n, size)
+	return size, nil
+}
@@ -0,0 +1,93 @@
+package lrmr
+
+import (
+	"fmt"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+var _ = RegisterTypes(&fieldKeyMapper{})
+
+// Aggregation aggregates a numeric field of the rows in each group.
+// Row values are expected to be a map of fields.
+type Aggregation struct {
+	// GroupBy is a field used for grouping rows. Rows are grouped by their key if it is empty.
+	GroupBy string
+
+	// Field is a numeric field to be aggregated. It is ignored when the Aggregator is AggregateCount.
+	Field      string
+	Aggregator Aggregator
+}
+
+// Validate checks whether the aggregation is well-formed.
+func (a Aggregation) Validate() error {
+	switch a.Aggregator {
+	case AggregateCount:
+	case AggregateSum, AggregateMin, AggregateMax, AggregateAvg:
+		if a.Field == "" {
+			return errors.Errorf("field is required for %s aggregator", a.Aggregator)
+		}
+	default:
+		return errors.Errorf("unknown aggregator: %s", a.Aggregator)
+	}
+	return nil
+}
+
+type aggregateTransformation struct {
+	Aggregation Aggregation
+}
+
+func (a *aggregateTransformation) PreservesKeys() bool { return true }
+
+// Apply emits a row for each group, whose value is the aggregated value.
+func (a *aggregateTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	if err := a.Aggregation.Validate(); err != nil {
+		return errors.Wrap(err, "invalid aggregation")
+	}
+	states := make(map[string]*aggregationState)
+	var keys []string
+
+	for row := range in {
+		state, ok := states[row.Key]
+		if !ok {
+			state = new(aggregationState)
+			states[row.Key] = state
+			keys = append(keys, row.Key)
+		}
+		if a.Aggregation.Aggregator == AggregateCount {
+			state.add(1)
+			continue
+		}
+		v, ok := row.GetFloat64(a.Aggregation.Field)
+		if !ok {
+			continue
+		}
+		state.add(v)
+	}
+
+	rows := make([]*lrdd.Row, len(keys))
+	for i, key := range keys {
+		v, _ := states[key].result(a.Aggregation.Aggregator)
+		rows[i] = lrdd.KeyValue(key, v)
+	}
+	return out.Write(rows...)
+}
+
+// fieldKeyMapper replaces key of the row with the value of given field.
+type fieldKeyMapper struct {
+	Field string
+}
+
+func (f *fieldKeyMapper) Map(_ Context, row *lrdd.Row) (*lrdd.Row, error) {
+	var fields map[string]interface{}
+	row.UnmarshalValue(&fields)
+
+	v, ok := fields[f.Field]
+	if !ok {
+		return nil, errors.Errorf("field %s does not exist in row %s", f.Field, row.Key)
+	}
+	return &lrdd.Row{Key: fmt.Sprint(v), Value: row.Value}, nil
+}
@@ -0,0 +1,106 @@
+package avro
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ab180/lrmr/lrdd"
+	jsoniter "github.com/json-iterator/go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const eventSchema = `{
+	"type": "record",
+	"name": "Event",
+	"fields": [
+		{"name": "user", "type": "string"},
+		{"name": "amount", "type": "long"}
+	]
+}`
+
+const eventSchemaV2 = `{
+	"type": "record",
+	"name": "Event",
+	"fields": [
+		{"name": "user", "type": "string"},
+		{"name": "country", "type": "string", "default": "KR"}
+	]
+}`
+
+func TestEncoderDecoder(t *testing.T) {
+	Convey("Given a schema registry", t, func() {
+		fetches := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches++
+			if r.URL.Path != "/schemas/ids/1" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = jsoniter.NewEncoder(w).Encode(map[string]string{"schema": eventSchema})
+		}))
+		defer srv.Close()
+
+		Convey("When encoding a row with Encoder", func() {
+			row, err := NewEncoder(srv.URL, 1).Map(nil, lrdd.KeyValue("alice", map[string]interface{}{
+				"user":   "alice",
+				"amount": 10,
+			}))
+			So(err, ShouldBeNil)
+
+			Convey("It should be decoded by Decoder", func() {
+				decoded, err := NewDecoder(srv.URL).Map(nil, row)
+				So(err, ShouldBeNil)
+				So(decoded.Key, ShouldEqual, "alice")
+
+				var record map[string]interface{}
+				decoded.UnmarshalValue(&record)
+				So(record["user"], ShouldEqual, "alice")
+				So(fmt.Sprint(record["amount"]), ShouldEqual, "10")
+
+				Convey("Schemas should be cached by their ID", func() {
+					So(fetches, ShouldEqual, 1)
+				})
+			})
+
+			Convey("It should be resolved to the reader schema", func() {
+				d := NewDecoder(srv.URL)
+				d.ReaderSchema = eventSchemaV2
+
+				decoded, err := d.Map(nil, row)
+				So(err, ShouldBeNil)
+
+				var record map[string]interface{}
+				decoded.UnmarshalValue(&record)
+				So(record, ShouldResemble, map[string]interface{}{"user": "alice", "country": "KR"})
+			})
+		})
+
+		Convey("When encoding a row whose value is not a record", func() {
+			_, err := NewEncoder(srv.URL, 1).Map(nil, lrdd.KeyValue("alice", "not a record"))
+
+			Convey("It should return the error decoding the value", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "decode record of row alice")
+			})
+		})
+
+		Convey("When decoding a row whose value is not a message", func() {
+			_, err := NewDecoder(srv.URL).Map(nil, lrdd.KeyValue("alice", map[string]interface{}{"user": "alice"}))
+
+			Convey("It should return the error decoding the value", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "decode message of row alice")
+			})
+		})
+
+		Convey("When decoding a message with unknown schema", func() {
+			_, err := NewDecoder(srv.URL).Map(nil, lrdd.Value(appendHeader(nil, 2)))
+
+			Convey("It should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,110 @@
+package avro
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+var _ = lrmr.RegisterTypes(&Decoder{})
+
+// Decoder is an input mapper decoding the rows whose value is an Avro message in Confluent wire format.
+// Each message is decoded with its writer schema fetched from the registry, and the value of the decoded row
+// is a map of the record fields. The key of the row is preserved.
+type Decoder struct {
+	RegistryURL string
+
+	// ReaderSchema is an optional record schema that the decoded records are resolved to.
+	// By Avro schema resolution rules, fields missing in the reader schema are ignored and
+	// fields missing in the writer schema are filled with their defaults.
+	// Only the top-level fields of the record are resolved.
+	ReaderSchema string
+
+	readerFields []field
+}
+
+// NewDecoder creates a Decoder fetching schemas from the registry in given URL.
+func NewDecoder(registryURL string) *Decoder {
+	return &Decoder{RegistryURL: registryURL}
+}
+
+type field struct {
+	Name       string      `json:"name"`
+	Default    interface{} `json:"default"`
+	HasDefault bool        `json:"-"`
+}
+
+func (d *Decoder) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	var msg []byte
+	if err := row.DecodeValue(&msg); err != nil {
+		return nil, errors.Wrapf(err, "decode message of row %s", row.Key)
+	}
+
+	schemaID, data, err := readHeader(msg)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := RegistryOf(d.RegistryURL).Codec(schemaID)
+	if err != nil {
+		return nil, err
+	}
+	native, _, err := codec.NativeFromBinary(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode message with schema %d", schemaID)
+	}
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("schema %d is not a record", schemaID)
+	}
+	if d.ReaderSchema != "" {
+		record, err = d.resolve(record)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolve record of schema %d", schemaID)
+		}
+	}
+	return lrdd.KeyValue(row.Key, record), nil
+}
+
+func (d *Decoder) resolve(record map[string]interface{}) (map[string]interface{}, error) {
+	if d.readerFields == nil {
+		fields, err := parseFields(d.ReaderSchema)
+		if err != nil {
+			return nil, err
+		}
+		d.readerFields = fields
+	}
+	resolved := make(map[string]interface{}, len(d.readerFields))
+	for _, f := range d.readerFields {
+		if v, ok := record[f.Name]; ok {
+			resolved[f.Name] = v
+			continue
+		}
+		if !f.HasDefault {
+			return nil, errors.Errorf("field %s is missing in writer schema and has no default", f.Name)
+		}
+		resolved[f.Name] = f.Default
+	}
+	return resolved, nil
+}
+
+func parseFields(schema string) ([]field, error) {
+	var s struct {
+		Type   string                `json:"type"`
+		Fields []jsoniter.RawMessage `json:"fields"`
+	}
+	if err := jsoniter.UnmarshalFromString(schema, &s); err != nil {
+		return nil, errors.Wrap(err, "parse reader schema")
+	}
+	if s.Type != "record" {
+		return nil, errors.Errorf("reader schema must be a record, got %s", s.Type)
+	}
+	fields := make([]field, len(s.Fields))
+	for i, raw := range s.Fields {
+		if err := jsoniter.Unmarshal(raw, &fields[i]); err != nil {
+			return nil, errors.Wrapf(err, "parse field #%d of reader schema", i)
+		}
+		fields[i].HasDefault = jsoniter.Get(raw, "default").ValueType() != jsoniter.InvalidValue
+	}
+	return fields, nil
+}
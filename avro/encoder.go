@@ -0,0 +1,67 @@
+package avro
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/pkg/errors"
+)
+
+var _ = lrmr.RegisterTypes(&Encoder{})
+
+// Encoder is an output mapper encoding the rows whose value is a map of the record fields
+// into Avro messages in Confluent wire format, with the schema of given ID in the registry.
+// The key of the row is preserved, and the value of the encoded row is the message in bytes.
+type Encoder struct {
+	RegistryURL string
+	SchemaID    int
+}
+
+// NewEncoder creates an Encoder with the schema of given ID in the registry.
+func NewEncoder(registryURL string, schemaID int) *Encoder {
+	return &Encoder{RegistryURL: registryURL, SchemaID: schemaID}
+}
+
+func (e *Encoder) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	codec, err := RegistryOf(e.RegistryURL).Codec(e.SchemaID)
+	if err != nil {
+		return nil, err
+	}
+	var record map[string]interface{}
+	if err := row.DecodeValue(&record); err != nil {
+		return nil, errors.Wrapf(err, "decode record of row %s", row.Key)
+	}
+
+	msg, err := codec.BinaryFromNative(appendHeader(nil, e.SchemaID), normalize(record))
+	if err != nil {
+		return nil, errors.Wrapf(err, "encode row %s with schema %d", row.Key, e.SchemaID)
+	}
+	return lrdd.KeyValue(row.Key, msg), nil
+}
+
+// normalize converts integers decoded from msgpack, which can be any width, into int64
+// because Avro codecs only accept int, int32 and int64.
+func normalize(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	case map[string]interface{}:
+		for k, elem := range n {
+			n[k] = normalize(elem)
+		}
+	case []interface{}:
+		for i, elem := range n {
+			n[i] = normalize(elem)
+		}
+	}
+	return v
+}
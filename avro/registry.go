@@ -0,0 +1,61 @@
+package avro
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+)
+
+// registries caches registry clients by URL, so that the codecs are shared
+// by every transformation in the process.
+var registries sync.Map
+
+// Registry is a client of Confluent-compatible schema registry. Schemas are cached by their IDs.
+type Registry struct {
+	url    string
+	client *http.Client
+	codecs sync.Map
+}
+
+// RegistryOf returns a registry client for given URL.
+func RegistryOf(url string) *Registry {
+	r, _ := registries.LoadOrStore(url, &Registry{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	})
+	return r.(*Registry)
+}
+
+// Codec returns a codec of the schema with given ID.
+func (r *Registry) Codec(id int) (*goavro.Codec, error) {
+	if c, ok := r.codecs.Load(id); ok {
+		return c.(*goavro.Codec), nil
+	}
+	resp, err := r.client.Get(r.url + "/schemas/ids/" + strconv.Itoa(id))
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch schema %d", id)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetch schema %d: registry responded %s", id, resp.Status)
+	}
+	var body struct {
+		Schema string `json:"schema"`
+	}
+	if err := jsoniter.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrapf(err, "decode response of schema %d", id)
+	}
+	codec, err := goavro.NewCodec(body.Schema)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse schema %d", id)
+	}
+	r.codecs.Store(id, codec)
+	return codec, nil
+}
@@ -0,0 +1,30 @@
+package avro
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// magicByte is the first byte of the messages in Confluent wire format,
+// which is followed by a 4-byte schema ID in big endian and Avro binary encoded data.
+const magicByte = 0
+
+const headerSize = 5
+
+func readHeader(msg []byte) (schemaID int, data []byte, err error) {
+	if len(msg) < headerSize {
+		return 0, nil, errors.Errorf("message too short: %d bytes", len(msg))
+	}
+	if msg[0] != magicByte {
+		return 0, nil, errors.Errorf("unknown magic byte: %d", msg[0])
+	}
+	return int(binary.BigEndian.Uint32(msg[1:headerSize])), msg[headerSize:], nil
+}
+
+func appendHeader(buf []byte, schemaID int) []byte {
+	var header [headerSize]byte
+	header[0] = magicByte
+	binary.BigEndian.PutUint32(header[1:], uint32(schemaID))
+	return append(buf, header[:]...)
+}
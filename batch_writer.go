@@ -0,0 +1,152 @@
+package lrmr
+
+import (
+	"fmt"
+
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// BatchKey is an idempotency key of a batch written by WriteBatches. It consists of the stage, the partition
+// and the sequence number of the batch in the partition, so the same batch gets the same key on retries
+// as long as the rows of the partition arrive in the same order (see Session.WriteBatches).
+type BatchKey struct {
+	Stage       string
+	PartitionID string
+	Sequence    int
+}
+
+func (k BatchKey) String() string {
+	return fmt.Sprintf("%s/%s/%d", k.Stage, k.PartitionID, k.Sequence)
+}
+
+// BatchWriter writes the batches of rows to an external sink, e.g. a database.
+//
+// Since a failed job is retried from the beginning of its input, batches written by the failed run
+// are written again with the same keys. To deliver the rows exactly once, the writer should write a batch
+// and record its key atomically (e.g. in a single transaction), and skip the batches whose keys are
+// already recorded.
+type BatchWriter interface {
+	WriteBatch(ctx Context, key BatchKey, rows []*lrdd.Row) error
+}
+
+// WriteBatchesOption is an option of WriteBatches.
+type WriteBatchesOption func(w *batchWriterTransformation)
+
+// WithWriteRetries makes the job failed on writing batches run again up to n times. The errors of the
+// BatchWriter are retried unless it implements RetryClassifier. See Dataset.WithRetries.
+func WithWriteRetries(n int) WriteBatchesOption {
+	return func(w *batchWriterTransformation) {
+		w.retries = n
+	}
+}
+
+// WriteBatches runs the job writing the rows of the dataset to w in batches of given size, and waits for the
+// job to complete. See Session.WriteBatches for details.
+func (d *Dataset) WriteBatches(w BatchWriter, size int, opts ...WriteBatchesOption) error {
+	return d.session.WriteBatches(d, w, size, opts...)
+}
+
+// WriteBatches runs the dataset writing the rows in each partition to w in batches of given size,
+// in the tasks of the last stage on the workers. Each batch is passed with its BatchKey.
+//
+// The keys are deterministic as long as the rows of a partition arrive in the same order on every run of
+// the dataset, e.g. when the partitions are read from the input in order. It doesn't hold if the rows are
+// shuffled from multiple tasks, since their order depends on the timing.
+func (s *Session) WriteBatches(ds *Dataset, w BatchWriter, size int, opts ...WriteBatchesOption) error {
+	if size <= 0 {
+		return errors.Errorf("batch size must be positive, got %d", size)
+	}
+	tf := &batchWriterTransformation{
+		stage:  ds.stageName(w),
+		size:   size,
+		writer: w,
+	}
+	for _, o := range opts {
+		o(tf)
+	}
+	ds.addStage(tf.stage, tf)
+	if tf.retries > 0 {
+		ds.WithRetries(tf.retries)
+	}
+	_, err := s.runWithJobRetries(ds, (*RunningJob).Wait)
+	return err
+}
+
+type batchWriterTransformation struct {
+	stage   string
+	size    int
+	retries int
+	writer  BatchWriter
+}
+
+func (b *batchWriterTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, _ output.Output) error {
+	key := BatchKey{Stage: b.stage, PartitionID: ctx.PartitionID()}
+	batch := make([]*lrdd.Row, 0, b.size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := b.writer.WriteBatch(ctx, key, batch); err != nil {
+			return errors.Wrapf(err, "write batch %s", key)
+		}
+		key.Sequence++
+		batch = make([]*lrdd.Row, 0, b.size)
+		return nil
+	}
+	for row := range in {
+		batch = append(batch, row)
+		if len(batch) == b.size {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	// the input also ends when the task is aborted
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// IsRetryable regards the errors of writing batches transient, unless the writer classifies them.
+// They're retried only with WithWriteRetries.
+func (b *batchWriterTransformation) IsRetryable(err error) bool {
+	if c, ok := b.writer.(RetryClassifier); ok {
+		return c.IsRetryable(err)
+	}
+	return true
+}
+
+type batchWriterDesc struct {
+	Stage  string
+	Size   int
+	Writer jsoniter.RawMessage
+}
+
+func (b *batchWriterTransformation) MarshalJSON() ([]byte, error) {
+	writer, err := serialization.SerializeStruct(b.writer)
+	if err != nil {
+		return nil, err
+	}
+	return jsoniter.Marshal(batchWriterDesc{Stage: b.stage, Size: b.size, Writer: writer})
+}
+
+func (b *batchWriterTransformation) UnmarshalJSON(data []byte) error {
+	var desc batchWriterDesc
+	if err := jsoniter.Unmarshal(data, &desc); err != nil {
+		return err
+	}
+	writer, err := serialization.DeserializeStruct(desc.Writer)
+	if err != nil {
+		return err
+	}
+	b.stage = desc.Stage
+	b.size = desc.Size
+	b.writer = writer.(BatchWriter)
+	return nil
+}
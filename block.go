@@ -0,0 +1,241 @@
+package lrmr
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/ab180/lrmr/cluster"
+	"github.com/ab180/lrmr/internal/util"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/ab180/lrmr/worker"
+	"github.com/pkg/errors"
+)
+
+// freeBlockTimeout bounds the time to free a block, which can be called after the session is done.
+const freeBlockTimeout = 10 * time.Second
+
+// blockRowsMetric is the metric of the number of the rows stored by blockWriter.
+const blockRowsMetric = "BlockRows"
+
+// storedBlock is a dataset materialized on the workers (see worker.BlockStore).
+type storedBlock struct {
+	ID string
+
+	// Locations are the hosts of the workers storing the partitions of the block.
+	Locations partitions.Assignments
+
+	// Rows is the number of the rows in the block.
+	Rows int
+}
+
+// storeBlock runs the dataset in a job whose tasks store their partitions on the workers running them.
+// The output of the last stage stays in the same worker unless it's partitioned explicitly.
+func storeBlock(d *Dataset) (*storedBlock, error) {
+	sess := d.session
+	b := &storedBlock{ID: util.GenerateID("B")}
+
+	// put before the job, so that the partitions stored by a failed job are freed by deleting it
+	if err := sess.master.Cluster.States().Put(sess.ctx, worker.BlockKey(b.ID), time.Now()); err != nil {
+		return nil, errors.Wrap(err, "register block")
+	}
+	ds := d.clone()
+	ds.preserveLastPlan()
+	w := &blockWriter{BlockID: b.ID}
+	ds.addStage(ds.stageName(w), w)
+
+	j, err := sess.runWithJobRetries(ds, (*RunningJob).Wait)
+	if err != nil {
+		freeBlock(sess.master.Cluster.States(), b)
+		return nil, err
+	}
+	b.Locations = j.Job.Partitions[len(j.Job.Partitions)-1]
+
+	m, err := j.Metrics()
+	if err != nil {
+		freeBlock(sess.master.Cluster.States(), b)
+		return nil, errors.WithMessage(err, "count rows of block")
+	}
+	b.Rows = m[blockRowsMetric]
+	return b, nil
+}
+
+// freeBlock frees the partitions of the block on the workers.
+func freeBlock(cs cluster.State, b *storedBlock) {
+	ctx, cancel := context.WithTimeout(context.Background(), freeBlockTimeout)
+	defer cancel()
+
+	if _, err := cs.Delete(ctx, worker.BlockKey(b.ID)); err != nil {
+		log.Error("Failed to free block {}: {}", b.ID, err)
+	}
+}
+
+// available returns true if the workers storing the partitions of the block are alive.
+func (b *storedBlock) available(ctx context.Context, c cluster.Cluster) (bool, error) {
+	nodes, err := c.List(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "list nodes")
+	}
+	alive := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		alive[n.Host] = true
+	}
+	for _, l := range b.Locations {
+		if !alive[l.Host] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// blockInput feeds the partitions of the source dataset stored as a block to the blockReader running on
+// the workers storing them.
+type blockInput struct {
+	source *Dataset
+	block  *storedBlock
+
+	// persist makes the block cached in the session, keyed by the hash of the plan of the source.
+	// Otherwise, the block is freed after the job reading it.
+	persist bool
+	key     string
+}
+
+// materialize stores the source dataset as a block unless it's cached. A cached block is stored again
+// if a worker storing its partitions has left.
+func (b *blockInput) materialize() (err error) {
+	if !b.persist {
+		b.block, err = storeBlock(b.source)
+		return err
+	}
+	b.key, err = planHash(b.source)
+	if err != nil {
+		return errors.Wrap(err, "hash plan")
+	}
+	sess := b.source.session
+	for {
+		cached, err := sess.caches.getOrCompute(b.key, func() (*storedBlock, error) {
+			return storeBlock(b.source)
+		})
+		if err != nil {
+			return err
+		}
+		ok, err := cached.available(sess.ctx, sess.master.Cluster)
+		if err != nil {
+			return err
+		}
+		if ok {
+			b.block = cached
+			return nil
+		}
+		log.Warn("Block {} of cache {} is lost with its worker, storing again.", cached.ID, b.key)
+		sess.caches.deleteIf(b.key, cached)
+	}
+}
+
+// ReleaseInput frees the block unless it's cached.
+func (b *blockInput) ReleaseInput() {
+	if b.persist || b.block == nil {
+		return
+	}
+	freeBlock(b.source.session.master.Cluster.States(), b.block)
+}
+
+// PlanNext plans the partitions of the block, each pinned to the worker storing it.
+func (b *blockInput) PlanNext(int) []partitions.Partition {
+	pp := make([]partitions.Partition, len(b.block.Locations))
+	for i, l := range b.block.Locations {
+		pp[i] = partitions.Partition{
+			ID:                 l.PartitionID,
+			IsElastic:          false,
+			AssignmentAffinity: map[string]string{"Host": l.Host},
+		}
+	}
+	return pp
+}
+
+func (b *blockInput) DeterminePartition(_ partitions.Context, r *lrdd.Row, _ int) (string, error) {
+	return r.Key, nil
+}
+
+// FeedInput sends the ID of the block to the reader of each partition.
+func (b *blockInput) FeedInput(out output.Output) error {
+	for _, l := range b.block.Locations {
+		if err := out.Write(lrdd.KeyValue(l.PartitionID, b.block.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockWriter stores the rows of its partition on the worker.
+type blockWriter struct {
+	BlockID string
+}
+
+func (w *blockWriter) Apply(ctx transformation.Context, in chan *lrdd.Row, _ output.Output) error {
+	blocks, err := blocksOf(ctx)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := lrdd.NewRowEncoder(&buf)
+	n := 0
+	for row := range in {
+		if err := enc.Encode(row); err != nil {
+			return errors.Wrap(err, "encode row")
+		}
+		n++
+	}
+	blocks.Put(w.BlockID, ctx.PartitionID(), buf.Bytes())
+	ctx.SetMetric(blockRowsMetric, n)
+	return nil
+}
+
+// blockReader emits the rows of the partitions of the blocks stored on the worker.
+type blockReader struct{}
+
+func (r *blockReader) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	blocks, err := blocksOf(ctx)
+	if err != nil {
+		return err
+	}
+	for row := range in {
+		var id string
+		if err := row.DecodeValue(&id); err != nil {
+			return errors.Wrap(err, "decode block ID")
+		}
+		err := readBlock(blocks, id, ctx.PartitionID(), func(row *lrdd.Row) error {
+			return out.Write(row)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBlock calls fn with each row of the partition of the block stored on the worker.
+func readBlock(blocks *worker.BlockStore, blockID, partitionID string, fn func(*lrdd.Row) error) error {
+	data, ok := blocks.Get(blockID, partitionID)
+	if !ok {
+		return errors.Errorf("partition %s of block %s is not on the worker", partitionID, blockID)
+	}
+	next, decodeErr := lrdd.DecodeRows(bytes.NewReader(data))
+	for row, ok := next(); ok; row, ok = next() {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(decodeErr(), "decode block %s", blockID)
+}
+
+func blocksOf(ctx transformation.Context) (*worker.BlockStore, error) {
+	p, ok := ctx.(worker.BlockProvider)
+	if !ok {
+		return nil, errors.New("blocks are only available on workers")
+	}
+	return p.Blocks(), nil
+}
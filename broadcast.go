@@ -0,0 +1,85 @@
+package lrmr
+
+import (
+	"encoding/base64"
+	"math"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Broadcasts are serialized in JSON and deserialized into JSON types on workers (e.g. numbers into float64,
+// structs into map[string]interface{}). The helpers below convert them into the expected types,
+// returning false if the broadcast doesn't exist or can't be converted.
+
+// BroadcastString returns the broadcast of given key as a string.
+func BroadcastString(ctx Context, key string) (string, bool) {
+	s, ok := ctx.Broadcast(key).(string)
+	return s, ok
+}
+
+// BroadcastBytes returns the broadcast of given key as a byte slice, which is serialized in base64.
+func BroadcastBytes(ctx Context, key string) ([]byte, bool) {
+	switch v := ctx.Broadcast(key).(type) {
+	case []byte:
+		return v, true
+	case string:
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	}
+	return nil, false
+}
+
+// BroadcastBool returns the broadcast of given key as a bool.
+func BroadcastBool(ctx Context, key string) (bool, bool) {
+	b, ok := ctx.Broadcast(key).(bool)
+	return b, ok
+}
+
+// BroadcastInt64 returns the broadcast of given key as an int64. It returns false if the number has a fraction.
+func BroadcastInt64(ctx Context, key string) (int64, bool) {
+	switch n := ctx.Broadcast(key).(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// BroadcastFloat64 returns the broadcast of given key as a float64.
+func BroadcastFloat64(ctx Context, key string) (float64, bool) {
+	switch n := ctx.Broadcast(key).(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// DecodeBroadcast decodes the broadcast of given key into the value pointed by ptr, e.g. a struct.
+func DecodeBroadcast(ctx Context, key string, ptr interface{}) error {
+	v := ctx.Broadcast(key)
+	if v == nil {
+		return errors.Errorf("broadcast %s not found", key)
+	}
+	data, err := jsoniter.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "encode broadcast %s", key)
+	}
+	if err := jsoniter.Unmarshal(data, ptr); err != nil {
+		return errors.Wrapf(err, "decode broadcast %s", key)
+	}
+	return nil
+}
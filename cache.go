@@ -0,0 +1,217 @@
+package lrmr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/stage"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Cache persists the dataset, so that the jobs using the returned Dataset don't recompute it.
+// On the first run, the dataset is run in a separate job whose tasks keep their partitions in the memory of
+// the workers running them (see worker.BlockStore). The jobs using the returned Dataset read the partitions
+// on the same workers, so the rows are neither sent to the master nor over the network. If a worker keeping
+// the partitions has left, the dataset is computed again.
+//
+// The cache is keyed by the hash of the plan of the dataset, and is shared with other datasets with the same
+// plan in the session until it is freed by Unpersist or Session.ClearCache. Transformations added to
+// the returned Dataset are not cached.
+func (d *Dataset) Cache() *Dataset {
+	return d.readBlock(&blockInput{source: d.clone(), persist: true})
+}
+
+// Barrier places a stage boundary after the stages, ensuring that every task of the upstream stages
+// completes before any of the downstream stages starts. It is useful for algorithms requiring the complete
+// result of the upstream (e.g. global statistics).
+//
+// The upstream is run as a separate job whose tasks keep their partitions in the memory of the workers running
+// them, like Cache. The downstream job is created after the upstream job succeeds, and reads the partitions
+// on the same workers, so the rows are not sent over the network at the boundary. Unlike pipelined execution
+// where the downstream processes rows as soon as they are emitted, the downstream waits for the slowest
+// upstream task, and the whole output of the upstream is held in the memory of the workers until
+// the downstream job completes. Unlike Cache, the partitions are freed after the downstream job.
+func (d *Dataset) Barrier() *Dataset {
+	return d.readBlock(&blockInput{source: d.clone()})
+}
+
+// readBlock creates a dataset reading the partitions of given block on the workers storing them.
+func (d *Dataset) readBlock(in *blockInput) *Dataset {
+	bd := newDataset(d.session, in)
+	r := &blockReader{}
+	bd.addStage(bd.stageName(r), r)
+	return bd
+}
+
+// Unpersist frees the cache of the dataset returned by Cache. It does nothing on the other datasets.
+func (d *Dataset) Unpersist() {
+	b, ok := d.input.(*blockInput)
+	if !ok || !b.persist || b.key == "" {
+		return
+	}
+	d.session.caches.delete(b.key)
+}
+
+func (d *Dataset) clone() *Dataset {
+	c := *d
+	c.stages = append([]stage.Stage(nil), d.stages...)
+	c.plans = append([]partitions.Plan(nil), d.plans...)
+	return &c
+}
+
+// materializedInput is an input which needs to run other jobs before feeding rows.
+type materializedInput interface {
+	materialize() error
+}
+
+// planHash returns a hash of the input and the stages of the dataset, which is stable across the
+// datasets built in the same way.
+func planHash(d *Dataset) (string, error) {
+	var in interface{}
+	stages := d.stages
+	switch input := d.input.(type) {
+	case *parallelizedInput:
+		in = input.data
+	case *localInput:
+		in = input.Path
+	case *blockInput:
+		key, err := planHash(input.source)
+		if err != nil {
+			return "", err
+		}
+		in = key
+	case *checkpointInput:
+		key, err := planHash(input.source)
+		if err != nil {
+			return "", err
+		}
+		in = key
+	case *joinedInput:
+		left, err := planHash(input.left)
+		if err != nil {
+			return "", err
+		}
+		right, err := planHash(input.right)
+		if err != nil {
+			return "", err
+		}
+		in = []string{left, right}
+		stages = input.unbind(stages)
+	default:
+		desc, err := serialization.SerializeStruct(input)
+		if err != nil {
+			return "", errors.Wrap(err, "serialize input")
+		}
+		in = jsoniter.RawMessage(desc)
+	}
+	plans := make([]partitions.Plan, len(d.plans))
+	for i, p := range d.plans {
+		plans[i] = p
+		plans[i].Partitioner = partitions.WrapPartitioner(p.Partitioner)
+	}
+	plans[0].Partitioner = nil
+
+	plan, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(struct {
+		Input  interface{}
+		Stages []stage.Stage
+		Plans  []partitions.Plan
+	}{in, stages, plans})
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(plan)
+	return hex.EncodeToString(h[:]), nil
+}
+
+type datasetCache struct {
+	entries map[string]*cacheEntry
+	free    func(b *storedBlock)
+	mu      sync.Mutex
+}
+
+type cacheEntry struct {
+	block *storedBlock
+	err   error
+	done  chan struct{}
+}
+
+func newDatasetCache(free func(b *storedBlock)) *datasetCache {
+	return &datasetCache{
+		entries: make(map[string]*cacheEntry),
+		free:    free,
+	}
+}
+
+// getOrCompute returns the cached block in the key. If it's not cached, it computes and caches the block.
+// Concurrent calls with the same key wait for a single computation.
+func (c *datasetCache) getOrCompute(key string, compute func() (*storedBlock, error)) (*storedBlock, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok {
+		c.mu.Unlock()
+		<-e.done
+		return e.block, e.err
+	}
+	e = &cacheEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	e.block, e.err = compute()
+	if e.err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+	close(e.done)
+	return e.block, e.err
+}
+
+// delete frees the block cached in the key.
+func (c *datasetCache) delete(key string) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	delete(c.entries, key)
+	c.mu.Unlock()
+
+	if ok {
+		c.release(e)
+	}
+}
+
+// deleteIf frees the block cached in the key if it's still given block, so that concurrent callers
+// finding the block lost do not free the block stored again by the others.
+func (c *datasetCache) deleteIf(key string, b *storedBlock) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok || e.block != b {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.entries, key)
+	c.mu.Unlock()
+
+	c.release(e)
+}
+
+func (c *datasetCache) clear() {
+	c.mu.Lock()
+	entries := c.entries
+	c.entries = make(map[string]*cacheEntry)
+	c.mu.Unlock()
+
+	for _, e := range entries {
+		c.release(e)
+	}
+}
+
+// release frees the block of the entry after its computation.
+func (c *datasetCache) release(e *cacheEntry) {
+	<-e.done
+	if e.block != nil {
+		c.free(e.block)
+	}
+}
@@ -0,0 +1,176 @@
+package lrmr
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+const checkpointNs = "checkpoints/"
+
+// Checkpoint persists the dataset to files under given directory, so that a failure in the stages added
+// to the returned Dataset does not lose the work done before the checkpoint.
+//
+// The upstream is run as a separate job writing its partitions to files by WriteFiles, and the checkpoint
+// is recorded in the cluster state under given ID. Running a dataset with the same checkpoint ID again,
+// e.g. after the downstream job failed due to a crashed worker, reads the files of the recorded checkpoint
+// instead of recomputing the upstream, even if the input of the upstream has changed meanwhile. So the ID
+// should identify a run of the pipeline (e.g. the date of the input it processes), rather than the pipeline.
+// The checkpoint is recomputed if the plan of the upstream differs from the recorded one.
+//
+// The checkpoint is deleted after the job reading it succeeds, or after the TTL given by WithCheckpointTTL.
+// The files are left in the directory, and overwritten by the next checkpoint with the same ID.
+//
+// Since the files are read by other workers, the directory should be on a filesystem shared by the nodes.
+func (d *Dataset) Checkpoint(id, dir string, opts ...CheckpointOption) *Dataset {
+	var o CheckpointOptions
+	for _, optFn := range opts {
+		optFn(&o)
+	}
+	cd := newDataset(d.session, &checkpointInput{source: d.clone(), id: id, dir: dir, ttl: o.TTL})
+	reader := &checkpointReader{}
+	cd.addStage(cd.stageName(reader), reader)
+	return cd
+}
+
+type CheckpointOptions struct {
+	// TTL is the time after which the checkpoint is deleted even if the job reading it does not succeed.
+	// The checkpoint is kept until the job succeeds if it is not positive.
+	TTL time.Duration
+}
+
+type CheckpointOption func(o *CheckpointOptions)
+
+// WithCheckpointTTL deletes the checkpoint after given duration, so that a job failing persistently
+// does not keep resuming from an outdated checkpoint.
+func WithCheckpointTTL(ttl time.Duration) CheckpointOption {
+	return func(o *CheckpointOptions) {
+		o.TTL = ttl
+	}
+}
+
+// checkpointRecord is a completed checkpoint in the cluster state.
+type checkpointRecord struct {
+	PlanHash  string    `json:"planHash"`
+	Paths     []string  `json:"paths"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// checkpointInput feeds the paths of the checkpoint files of the source dataset.
+type checkpointInput struct {
+	partitions.ShuffledPartitioner
+	source *Dataset
+	id     string
+	dir    string
+	ttl    time.Duration
+	paths  []string
+}
+
+// materialize runs the source dataset unless its checkpoint is available.
+func (c *checkpointInput) materialize() error {
+	if c.id == "" {
+		return errors.New("checkpoint ID is empty")
+	}
+	hash, err := planHash(c.source)
+	if err != nil {
+		return errors.Wrap(err, "hash plan")
+	}
+	sess := c.source.session
+	cs := sess.master.Cluster.States()
+
+	var rec checkpointRecord
+	err = cs.Get(sess.ctx, c.recordKey(), &rec)
+	if err == nil && rec.PlanHash == hash {
+		log.Info("Resuming from checkpoint {} created at {}", c.id, rec.CreatedAt)
+		c.paths = rec.Paths
+		return nil
+	}
+	if err != nil && err != coordinator.ErrNotFound {
+		return errors.Wrap(err, "read checkpoint")
+	}
+
+	paths, err := c.source.clone().WriteFiles(filepath.Join(c.dir, c.id))
+	if err != nil {
+		return err
+	}
+	var writeOpts []coordinator.WriteOption
+	if c.ttl > 0 {
+		lease, err := cs.GrantLease(sess.ctx, c.ttl)
+		if err != nil {
+			return errors.Wrap(err, "grant lease of checkpoint")
+		}
+		writeOpts = append(writeOpts, coordinator.WithLease(lease))
+	}
+	rec = checkpointRecord{PlanHash: hash, Paths: paths, CreatedAt: time.Now()}
+	if err := cs.Put(sess.ctx, c.recordKey(), rec, writeOpts...); err != nil {
+		return errors.Wrap(err, "write checkpoint")
+	}
+	c.paths = paths
+	return nil
+}
+
+// CommitInput deletes the checkpoint after the job reading it succeeds, so that the next run with the same ID
+// reads the input again.
+func (c *checkpointInput) CommitInput() error {
+	sess := c.source.session
+	if _, err := sess.master.Cluster.States().Delete(sess.ctx, c.recordKey()); err != nil {
+		return errors.Wrapf(err, "delete checkpoint %s", c.id)
+	}
+	return nil
+}
+
+func (c *checkpointInput) recordKey() string {
+	// suffixed, as the deletion by the key deletes the keys prefixed by it
+	return path.Join(checkpointNs, c.id, "record")
+}
+
+func (c *checkpointInput) FeedInput(out output.Output) error {
+	for _, p := range c.paths {
+		if err := out.Write(lrdd.Value(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkpointReader reads the rows in the checkpoint files.
+type checkpointReader struct{}
+
+func (r *checkpointReader) Apply(_ transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	for row := range in {
+		var p string
+		if err := row.DecodeValue(&p); err != nil {
+			return errors.Wrap(err, "decode path")
+		}
+		if err := r.read(p, out); err != nil {
+			return errors.Wrapf(err, "read checkpoint %s", p)
+		}
+	}
+	return nil
+}
+
+func (r *checkpointReader) read(p string, out output.Output) error {
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return errors.Wrap(err, "checkpoint file is missing; the directory should be shared by the workers")
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	next, decodeErr := lrdd.DecodeRows(f)
+	for row, ok := next(); ok; row, ok = next() {
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	return decodeErr()
+}
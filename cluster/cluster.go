@@ -7,7 +7,7 @@ import (
 
 	"github.com/ab180/lrmr/cluster/node"
 	"github.com/ab180/lrmr/coordinator"
-	"github.com/airbloc/logger"
+	"github.com/ab180/lrmr/logging"
 	"github.com/pkg/errors"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"google.golang.org/grpc"
@@ -17,7 +17,7 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-var log = logger.New("lrmr.cluster")
+var log = logging.New("lrmr.cluster")
 
 const nodeNs = "nodes"
 
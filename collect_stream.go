@@ -0,0 +1,99 @@
+package lrmr
+
+import (
+	"context"
+
+	"github.com/ab180/lrmr/internal/util"
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/master"
+)
+
+// CollectStream runs the dataset and streams its result rows to the master as they arrive, so that
+// the caller can process unbounded results without holding all of them in memory like Collect.
+//
+// The row channel is closed after every row is received, or when the stream stops due to an error.
+// The error channel receives at most one error, e.g. a failure of a task, and is closed along with
+// the row channel. Canceling ctx cancels the job, and the channels are closed after the job stops.
+// The rows should be consumed promptly, since the job is blocked while the caller falls behind.
+func (s *Session) CollectStream(ctx context.Context, ds *Dataset) (<-chan *lrdd.Row, <-chan error) {
+	rows := make(chan *lrdd.Row)
+	errs := make(chan error, 1)
+
+	streamID := util.GenerateID("C")
+	batches := master.OpenCollectStream(streamID)
+	ds.addCollectStage(&master.Collector{StreamID: streamID})
+
+	go func() {
+		defer close(errs)
+		defer close(rows)
+		defer master.CloseCollectStream(streamID)
+
+		if err := s.streamCollected(ctx, ds, batches, rows); err != nil {
+			errs <- err
+		}
+	}()
+	return rows, errs
+}
+
+func (s *Session) streamCollected(ctx context.Context, ds *Dataset, batches <-chan []*lrdd.Row, rows chan<- *lrdd.Row) error {
+	started := make(chan *RunningJob, 1)
+	failed := make(chan error, 1)
+	go func() {
+		// run in the background, since feeding the input blocks until the collected rows are consumed
+		j, err := s.Run(ds)
+		if err != nil {
+			failed <- err
+			return
+		}
+		s.master.JobTracker.OnJobCompletion(j.Job, func(_ *job.Job, status *job.Status) {
+			if status.Status == job.Failed && len(status.Errors) > 0 {
+				failed <- status.Errors[0]
+			}
+		})
+		started <- j
+	}()
+
+	var j *RunningJob
+	for {
+		select {
+		case batch, ok := <-batches:
+			if !ok {
+				return nil
+			}
+			for _, row := range batch {
+				select {
+				case rows <- row:
+				case <-ctx.Done():
+					s.cancelStream(j, started, failed, batches)
+					return ctx.Err()
+				}
+			}
+		case j = <-started:
+		case err := <-failed:
+			return err
+		case <-ctx.Done():
+			s.cancelStream(j, started, failed, batches)
+			return ctx.Err()
+		}
+	}
+}
+
+// cancelStream cancels the job of the stream, and waits for the job to stop. The rows collected in between
+// are discarded, not to block the job from starting.
+func (s *Session) cancelStream(j *RunningJob, started <-chan *RunningJob, failed <-chan error, batches <-chan []*lrdd.Row) {
+	for j == nil {
+		select {
+		case j = <-started:
+		case <-failed:
+			return
+		case _, ok := <-batches:
+			if !ok {
+				batches = nil
+			}
+		}
+	}
+	if err := j.Cancel(context.Background()); err != nil {
+		log.Warn("Failed to cancel job {}: {}", j.ID, err)
+	}
+}
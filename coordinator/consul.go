@@ -0,0 +1,464 @@
+package coordinator
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ab180/lrmr/logging"
+	"github.com/hashicorp/consul/api"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/atomic"
+)
+
+const (
+	// consulCounterPrefix prefixes the value of counter keys on Consul, followed by the value of the counter.
+	// Unlike etcd, Consul doesn't have a version of keys which can be used as a counter.
+	consulCounterPrefix = counterMark + ":"
+
+	// Consul limits TTL of sessions between 10s and 24h.
+	minConsulSessionTTL = 10 * time.Second
+	maxConsulSessionTTL = 24 * time.Hour
+
+	// consulWatchRetryInterval is the interval of retrying a failed blocking query on Watch.
+	consulWatchRetryInterval = time.Second
+)
+
+// Consul is a Coordinator backed by KV store of Consul. Leases are implemented with Consul sessions,
+// which delete the keys put with them when they expire.
+type Consul struct {
+	Client *api.Client
+	KV     *api.KV
+
+	ns     string
+	leases *consulLeases
+	log    logging.Logger
+	opts   []WriteOption
+}
+
+// consulLeases maps lease IDs to Consul sessions, as Consul identifies sessions with UUIDs.
+type consulLeases struct {
+	seq      atomic.Int64
+	sessions sync.Map
+}
+
+type consulSession struct {
+	id  string
+	ttl time.Duration
+}
+
+// NewConsul connects to the first reachable Consul agent in the endpoints.
+// Keys are prefixed with nsPrefix, in the same way as NewEtcd.
+func NewConsul(endpoints []string, nsPrefix string) (Coordinator, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no consul endpoints given")
+	}
+	var lastErr error
+	for _, endpoint := range endpoints {
+		cfg := api.DefaultConfig()
+		cfg.Address = endpoint
+
+		cli, err := api.NewClient(cfg)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if _, err := cli.Status().Leader(); err != nil {
+			lastErr = errors.Wrapf(err, "connect %s", endpoint)
+			continue
+		}
+		return &Consul{
+			Client: cli,
+			KV:     cli.KV(),
+			ns:     nsPrefix,
+			leases: &consulLeases{},
+			log:    logging.New("consul"),
+		}, nil
+	}
+	return nil, lastErr
+}
+
+func (c *Consul) Get(ctx context.Context, key string, valuePtr interface{}) error {
+	pair, _, err := c.KV.Get(c.ns+key, c.queryOptions(ctx))
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return ErrNotFound
+	}
+	return jsoniter.Unmarshal(pair.Value, valuePtr)
+}
+
+func (c *Consul) Scan(ctx context.Context, prefix string) (results []RawItem, err error) {
+	pairs, _, err := c.KV.List(c.ns+prefix, c.queryOptions(ctx))
+	if err != nil {
+		return nil, err
+	}
+	// Consul returns the keys in ascending order
+	for _, pair := range pairs {
+		results = append(results, RawItem{
+			Key:   strings.TrimPrefix(pair.Key, c.ns),
+			Value: pair.Value,
+		})
+	}
+	return results, nil
+}
+
+// Watch polls the keys with Consul blocking queries, and emits the difference between the results.
+// Unlike etcd, consecutive updates of a key between the queries are coalesced into the last one.
+func (c *Consul) Watch(ctx context.Context, prefix string) (<-chan WatchEvent, error) {
+	// the events are diffed from the keys at the return
+	pairs, meta, err := c.KV.List(c.ns+prefix, c.queryOptions(ctx))
+	if err != nil {
+		return nil, err
+	}
+	index := meta.LastIndex
+	prev := make(map[string]*api.KVPair, len(pairs))
+	for _, pair := range pairs {
+		prev[pair.Key] = pair
+	}
+
+	watchChan := make(chan WatchEvent)
+	go func() {
+		defer close(watchChan)
+
+		for ctx.Err() == nil {
+			q := &api.QueryOptions{WaitIndex: index}
+			pairs, meta, err := c.KV.List(c.ns+prefix, q.WithContext(ctx))
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				c.log.Error("watch error", err)
+				select {
+				case <-time.After(consulWatchRetryInterval):
+				case <-ctx.Done():
+				}
+				continue
+			}
+			if meta.LastIndex < index {
+				// the index went backwards (e.g. on a snapshot restore). resets the blocking query
+				index = 0
+				continue
+			}
+			index = meta.LastIndex
+
+			cur := make(map[string]*api.KVPair, len(pairs))
+			for _, pair := range pairs {
+				cur[pair.Key] = pair
+			}
+			for _, e := range c.diff(prev, cur) {
+				select {
+				case watchChan <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			prev = cur
+		}
+	}()
+	return watchChan, nil
+}
+
+// diff returns the events which changed the prev keys to the cur keys.
+func (c *Consul) diff(prev, cur map[string]*api.KVPair) (events []WatchEvent) {
+	for key, pair := range cur {
+		if p, ok := prev[key]; ok && p.ModifyIndex == pair.ModifyIndex {
+			continue
+		}
+		item := RawItem{Key: strings.TrimPrefix(key, c.ns)}
+		if count, ok := parseConsulCounter(pair.Value); ok {
+			events = append(events, WatchEvent{Type: CounterEvent, Item: item, Counter: count})
+			continue
+		}
+		item.Value = pair.Value
+		events = append(events, WatchEvent{Type: PutEvent, Item: item})
+	}
+	for key := range prev {
+		if _, ok := cur[key]; !ok {
+			events = append(events, WatchEvent{
+				Type: DeleteEvent,
+				Item: RawItem{Key: strings.TrimPrefix(key, c.ns)},
+			})
+		}
+	}
+	return events
+}
+
+func (c *Consul) Put(ctx context.Context, key string, value interface{}, opts ...WriteOption) error {
+	jsonVal, err := jsoniter.Marshal(value)
+	if err != nil {
+		return err
+	}
+	pair := &api.KVPair{Key: c.ns + key, Value: jsonVal}
+
+	opt := buildWriteOption(append(c.opts, opts...))
+	if opt.Lease == clientv3.NoLease {
+		_, err = c.KV.Put(pair, c.writeOptions(ctx))
+		return err
+	}
+	pair.Session, err = c.leases.sessionOf(opt.Lease)
+	if err != nil {
+		return err
+	}
+	acquired, _, err := c.KV.Acquire(pair, c.writeOptions(ctx))
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return errors.Errorf("key %s is held by another lease", key)
+	}
+	return nil
+}
+
+// Commit applies the transaction with a Consul transaction. Counters are read before the transaction and
+// updated by check-and-set, so the transaction is retried if the counters are updated concurrently.
+// Note that Consul limits the number of operations in a transaction to 64.
+func (c *Consul) Commit(ctx context.Context, txn *Txn, opts ...WriteOption) ([]TxnResult, error) {
+	opt := buildWriteOption(append(c.opts, opts...))
+	var session string
+	if opt.Lease != clientv3.NoLease {
+		s, err := c.leases.sessionOf(opt.Lease)
+		if err != nil {
+			return nil, err
+		}
+		session = s
+	}
+
+	for {
+		results, ok, err := c.tryCommit(ctx, txn, session)
+		if err != nil || ok {
+			return results, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tryCommit returns false if the transaction failed due to concurrent updates of the counters.
+func (c *Consul) tryCommit(ctx context.Context, txn *Txn, session string) ([]TxnResult, bool, error) {
+	type counterState struct {
+		value int64
+		index uint64
+	}
+	var (
+		ops      api.KVTxnOps
+		counters = make(map[string]*counterState)
+		results  = make([]TxnResult, len(txn.Ops))
+	)
+	for i, op := range txn.Ops {
+		results[i].Type = op.Type
+		key := c.ns + op.Key
+
+		switch op.Type {
+		case PutEvent:
+			jsonVal, err := jsoniter.Marshal(op.Value)
+			if err != nil {
+				return nil, false, err
+			}
+			kvOp := &api.KVTxnOp{Verb: api.KVSet, Key: key, Value: jsonVal}
+			if session != "" {
+				kvOp.Verb = api.KVLock
+				kvOp.Session = session
+			}
+			ops = append(ops, kvOp)
+
+		case CounterEvent:
+			cs, ok := counters[key]
+			if !ok {
+				value, index, err := c.readCounter(ctx, key)
+				if err != nil {
+					return nil, false, err
+				}
+				cs = &counterState{value: value, index: index}
+				counters[key] = cs
+			}
+			cs.value++
+			results[i].Counter = cs.value
+
+		case DeleteEvent:
+			keys, _, err := c.KV.Keys(key, "", c.queryOptions(ctx))
+			if err != nil {
+				return nil, false, err
+			}
+			results[i].Deleted = int64(len(keys))
+			ops = append(ops, &api.KVTxnOp{Verb: api.KVDeleteTree, Key: key})
+		}
+	}
+	// a counter updated multiple times in the transaction is set once with its last value
+	for key, cs := range counters {
+		ops = append(ops, &api.KVTxnOp{
+			Verb:  api.KVCAS,
+			Key:   key,
+			Value: formatConsulCounter(cs.value),
+			Index: cs.index,
+		})
+	}
+	if len(ops) == 0 {
+		return results, true, nil
+	}
+	ok, resp, _, err := c.KV.Txn(ops, c.queryOptions(ctx))
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		return results, true, nil
+	}
+	for _, e := range resp.Errors {
+		if ops[e.OpIndex].Verb != api.KVCAS {
+			return nil, false, errors.Errorf("consul transaction failed: %s", e.What)
+		}
+	}
+	return nil, false, nil
+}
+
+func (c *Consul) GrantLease(ctx context.Context, ttl time.Duration) (clientv3.LeaseID, error) {
+	if ttl < minConsulSessionTTL {
+		ttl = minConsulSessionTTL
+	} else if ttl > maxConsulSessionTTL {
+		ttl = maxConsulSessionTTL
+	}
+	id, _, err := c.Client.Session().CreateNoChecks(&api.SessionEntry{
+		Name:      "lrmr",
+		TTL:       ttl.String(),
+		Behavior:  api.SessionBehaviorDelete,
+		LockDelay: time.Millisecond,
+	}, c.writeOptions(ctx))
+	if err != nil {
+		return 0, err
+	}
+	lease := clientv3.LeaseID(c.leases.seq.Inc())
+	c.leases.sessions.Store(lease, consulSession{id: id, ttl: ttl})
+	return lease, nil
+}
+
+func (c *Consul) KeepAlive(ctx context.Context, lease clientv3.LeaseID) error {
+	v, ok := c.leases.sessions.Load(lease)
+	if !ok {
+		return errors.Errorf("unknown lease %d", lease)
+	}
+	s := v.(consulSession)
+	go func() {
+		if err := c.Client.Session().RenewPeriodic(s.ttl.String(), s.id, nil, ctx.Done()); err != nil {
+			c.log.Error("failed to keep session {} alive: {}", s.id, err)
+		}
+	}()
+	return nil
+}
+
+func (c *Consul) IncrementCounter(ctx context.Context, key string) (int64, error) {
+	for {
+		value, index, err := c.readCounter(ctx, c.ns+key)
+		if err != nil {
+			return 0, err
+		}
+		pair := &api.KVPair{
+			Key:         c.ns + key,
+			Value:       formatConsulCounter(value + 1),
+			ModifyIndex: index,
+		}
+		ok, _, err := c.KV.CAS(pair, c.writeOptions(ctx))
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return value + 1, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (c *Consul) ReadCounter(ctx context.Context, key string) (int64, error) {
+	value, _, err := c.readCounter(ctx, c.ns+key)
+	return value, err
+}
+
+// readCounter returns the value of the counter in given namespaced key, and the index for check-and-set.
+func (c *Consul) readCounter(ctx context.Context, key string) (value int64, index uint64, err error) {
+	pair, _, err := c.KV.Get(key, c.queryOptions(ctx))
+	if err != nil {
+		return 0, 0, err
+	}
+	if pair == nil {
+		return 0, 0, nil
+	}
+	value, ok := parseConsulCounter(pair.Value)
+	if !ok {
+		return 0, 0, ErrNotCounter
+	}
+	return value, pair.ModifyIndex, nil
+}
+
+func (c *Consul) Delete(ctx context.Context, prefix string) (deleted int64, err error) {
+	keys, _, err := c.KV.Keys(c.ns+prefix, "", c.queryOptions(ctx))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.KV.DeleteTree(c.ns+prefix, c.writeOptions(ctx)); err != nil {
+		return 0, err
+	}
+	return int64(len(keys)), nil
+}
+
+func (c *Consul) WithOptions(opt ...WriteOption) KV {
+	return &Consul{
+		Client: c.Client,
+		KV:     c.KV,
+		ns:     c.ns,
+		leases: c.leases,
+		log:    c.log,
+		opts:   opt,
+	}
+}
+
+// Close destroys the sessions granted by the coordinator, which deletes the keys put with them.
+func (c *Consul) Close() error {
+	var err error
+	c.leases.sessions.Range(func(lease, v interface{}) bool {
+		if _, err = c.Client.Session().Destroy(v.(consulSession).id, nil); err != nil {
+			return false
+		}
+		c.leases.sessions.Delete(lease)
+		return true
+	})
+	return err
+}
+
+func (c *Consul) queryOptions(ctx context.Context) *api.QueryOptions {
+	return (&api.QueryOptions{}).WithContext(ctx)
+}
+
+func (c *Consul) writeOptions(ctx context.Context) *api.WriteOptions {
+	return (&api.WriteOptions{}).WithContext(ctx)
+}
+
+func (l *consulLeases) sessionOf(lease clientv3.LeaseID) (string, error) {
+	v, ok := l.sessions.Load(lease)
+	if !ok {
+		return "", errors.Errorf("unknown lease %d", lease)
+	}
+	return v.(consulSession).id, nil
+}
+
+func formatConsulCounter(value int64) []byte {
+	return []byte(consulCounterPrefix + strconv.FormatInt(value, 10))
+}
+
+func parseConsulCounter(raw []byte) (int64, bool) {
+	s := string(raw)
+	if !strings.HasPrefix(s, consulCounterPrefix) {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(strings.TrimPrefix(s, consulCounterPrefix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
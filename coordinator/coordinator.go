@@ -37,8 +37,9 @@ type KV interface {
 	// Delete remove all keys starting with given prefix.
 	Delete(ctx context.Context, prefix string) (deleted int64, err error)
 
-	// Watch subscribes modification events of the keys starting with given prefix.
-	Watch(ctx context.Context, prefix string) chan WatchEvent
+	// Watch subscribes modification events of the keys starting with given prefix. The events made after
+	// the return are delivered to the channel, which is closed when the context is done.
+	Watch(ctx context.Context, prefix string) (<-chan WatchEvent, error)
 
 	// IncrementCounter is an atomic operation increasing the counter in given key.
 	// returns a increased value of the counter right after the operation.
@@ -4,7 +4,7 @@ import (
 	"context"
 	"time"
 
-	"github.com/airbloc/logger"
+	"github.com/ab180/lrmr/logging"
 	jsoniter "github.com/json-iterator/go"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -24,11 +24,32 @@ type Etcd struct {
 	Watcher clientv3.Watcher
 	Lease   clientv3.Lease
 
-	log  logger.Logger
+	log  logging.Logger
 	opts []WriteOption
 }
 
-func NewEtcd(endpoints []string, nsPrefix string) (Coordinator, error) {
+// EtcdOption configures the coordinator created by NewEtcd.
+type EtcdOption func(o *etcdOptions)
+
+type etcdOptions struct {
+	retry RetryOptions
+}
+
+// WithRetryOptions overrides the policy of retrying operations failed during a leader election
+// or a brief outage of etcd, which is DefaultRetryOptions by default.
+func WithRetryOptions(opt RetryOptions) EtcdOption {
+	return func(o *etcdOptions) {
+		o.retry = opt
+	}
+}
+
+// NewEtcd connects to the etcd cluster. Keys are prefixed with nsPrefix, and operations failed
+// with transient errors are retried (see WithRetry).
+func NewEtcd(endpoints []string, nsPrefix string, opts ...EtcdOption) (Coordinator, error) {
+	opt := etcdOptions{retry: DefaultRetryOptions()}
+	for _, o := range opts {
+		o(&opt)
+	}
 	cfg := clientv3.Config{
 		Endpoints:   endpoints,
 		DialTimeout: 5 * time.Second,
@@ -38,13 +59,14 @@ func NewEtcd(endpoints []string, nsPrefix string) (Coordinator, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Etcd{
+	etcd := &Etcd{
 		Client:  cli,
 		KV:      namespace.NewKV(cli, nsPrefix),
 		Watcher: namespace.NewWatcher(cli, nsPrefix),
 		Lease:   namespace.NewLease(cli, nsPrefix),
-		log:     logger.New("etcd"),
-	}, nil
+		log:     logging.New("etcd"),
+	}
+	return WithRetry(etcd, opt.retry), nil
 }
 
 func (e *Etcd) Get(ctx context.Context, key string, valuePtr interface{}) error {
@@ -72,7 +94,7 @@ func (e *Etcd) Scan(ctx context.Context, prefix string) (results []RawItem, err
 	return
 }
 
-func (e *Etcd) Watch(ctx context.Context, prefix string) chan WatchEvent {
+func (e *Etcd) Watch(ctx context.Context, prefix string) (<-chan WatchEvent, error) {
 	watchChan := make(chan WatchEvent)
 
 	wc := e.Watcher.Watch(ctx, prefix, clientv3.WithPrefix())
@@ -83,35 +105,44 @@ func (e *Etcd) Watch(ctx context.Context, prefix string) chan WatchEvent {
 				e.log.Error("watch error", err)
 				continue
 			}
-			for _, e := range wr.Events {
-				switch e.Type {
+			for _, ev := range wr.Events {
+				var we WatchEvent
+				switch ev.Type {
 				case mvccpb.PUT:
-					if string(e.Kv.Value) == counterMark {
-						watchChan <- WatchEvent{
+					if string(ev.Kv.Value) == counterMark {
+						we = WatchEvent{
 							Type:    CounterEvent,
-							Item:    RawItem{Key: string(e.Kv.Key)},
-							Counter: e.Kv.Version,
+							Item:    RawItem{Key: string(ev.Kv.Key)},
+							Counter: ev.Kv.Version,
 						}
-						continue
+						break
 					}
-					watchChan <- WatchEvent{
+					we = WatchEvent{
 						Type: PutEvent,
 						Item: RawItem{
-							Key:   string(e.Kv.Key),
-							Value: e.Kv.Value,
+							Key:   string(ev.Kv.Key),
+							Value: ev.Kv.Value,
 						},
 					}
 
 				case mvccpb.DELETE:
-					watchChan <- WatchEvent{
+					we = WatchEvent{
 						Type: DeleteEvent,
-						Item: RawItem{Key: string(e.Kv.Key)},
+						Item: RawItem{Key: string(ev.Kv.Key)},
 					}
+
+				default:
+					continue
+				}
+				select {
+				case watchChan <- we:
+				case <-ctx.Done():
+					return
 				}
 			}
 		}
 	}()
-	return watchChan
+	return watchChan, nil
 }
 
 func (e *Etcd) Put(ctx context.Context, key string, value interface{}, opts ...WriteOption) error {
@@ -250,7 +281,7 @@ func (e *Etcd) WithOptions(opt ...WriteOption) KV {
 		KV:      e.KV,
 		Watcher: e.Watcher,
 		Lease:   e.Lease,
-		log:     logger.New("etcd"),
+		log:     logging.New("etcd"),
 		opts:    opt,
 	}
 }
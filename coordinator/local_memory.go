@@ -2,6 +2,7 @@ package coordinator
 
 import (
 	"context"
+	"io"
 	"math/rand"
 	"strings"
 	"sync"
@@ -16,6 +17,9 @@ type localMemoryCoordinator struct {
 	data   sync.Map
 	leases sync.Map
 
+	// leaseTTLs are the TTLs of the leases, which KeepAlive extends the leases by.
+	leaseTTLs sync.Map
+
 	counter     map[string]int64
 	counterLock sync.RWMutex
 
@@ -35,9 +39,21 @@ type subscription struct {
 	events chan WatchEvent
 }
 
+// LocalMemory is a coordinator keeping the states in the memory of the process.
+type LocalMemory interface {
+	Coordinator
+
+	// Snapshot writes the whole key space, including counters and leases, to w.
+	Snapshot(w io.Writer) error
+
+	// Restore replaces the whole key space with a snapshot written by Snapshot.
+	// Watchers are not notified of the restored keys.
+	Restore(r io.Reader) error
+}
+
 // NewLocalMemory creates local variable based coordinator.
-// Only used for test purpose.
-func NewLocalMemory(opts ...LocalMemoryOption) Coordinator {
+// Only used for test purpose, or for local development with Snapshot and Restore.
+func NewLocalMemory(opts ...LocalMemoryOption) LocalMemory {
 	return &localMemoryCoordinator{
 		counter: map[string]int64{},
 	}
@@ -202,16 +218,23 @@ func (lmc *localMemoryCoordinator) GrantLease(ctx context.Context, ttl time.Dura
 	lease := clientv3.LeaseID(rand.Uint64())
 	deadline := time.Now().Add(ttl)
 	lmc.leases.Store(lease, deadline)
+	lmc.leaseTTLs.Store(lease, ttl)
 	return lease, nil
 }
 
 func (lmc *localMemoryCoordinator) KeepAlive(ctx context.Context, lease clientv3.LeaseID) error {
+	// the leases restored from a snapshot have no TTLs
+	ttl := 7 * time.Second
+	if v, ok := lmc.leaseTTLs.Load(lease); ok {
+		ttl = v.(time.Duration)
+	}
 	go func() {
-		tick := time.NewTicker(5 * time.Second)
+		tick := time.NewTicker(ttl / 3)
+		defer tick.Stop()
 		for {
 			select {
 			case <-tick.C:
-				newDeadline := time.Now().Add(7 * time.Second)
+				newDeadline := time.Now().Add(ttl)
 				lmc.leases.Store(lease, newDeadline)
 
 			case <-ctx.Done():
@@ -236,10 +259,11 @@ func (lmc *localMemoryCoordinator) isAfterDeadline(lease clientv3.LeaseID) (expi
 
 func (lmc *localMemoryCoordinator) expireLease(key interface{}, lease clientv3.LeaseID) {
 	lmc.leases.Delete(lease)
+	lmc.leaseTTLs.Delete(lease)
 	lmc.data.Delete(key)
 }
 
-func (lmc *localMemoryCoordinator) Watch(ctx context.Context, prefix string) chan WatchEvent {
+func (lmc *localMemoryCoordinator) Watch(ctx context.Context, prefix string) (<-chan WatchEvent, error) {
 	lmc.subsLock.Lock()
 	defer lmc.subsLock.Unlock()
 
@@ -249,20 +273,20 @@ func (lmc *localMemoryCoordinator) Watch(ctx context.Context, prefix string) cha
 		events: eventsChan,
 	})
 	go func() {
-		select {
-		case <-ctx.Done():
-			lmc.subsLock.Lock()
-			for i, sub := range lmc.subscriptions {
-				if sub.events == eventsChan {
-					lmc.subscriptions = append(lmc.subscriptions[:i], lmc.subscriptions[i+1:]...)
-					break
-				}
+		<-ctx.Done()
+
+		lmc.subsLock.Lock()
+		defer lmc.subsLock.Unlock()
+		for i, sub := range lmc.subscriptions {
+			// the channel is already closed if the subscription is removed by Close
+			if sub.events == eventsChan {
+				lmc.subscriptions = append(lmc.subscriptions[:i], lmc.subscriptions[i+1:]...)
+				close(eventsChan)
+				break
 			}
-			close(eventsChan)
-			lmc.subsLock.Unlock()
 		}
 	}()
-	return eventsChan
+	return eventsChan, nil
 }
 
 func (lmc *localMemoryCoordinator) notifySubscribers(ev WatchEvent) {
@@ -282,12 +306,13 @@ func (lmc *localMemoryCoordinator) WithOptions(opts ...WriteOption) KV {
 }
 
 func (lmc *localMemoryCoordinator) Close() error {
-	lmc.subsLock.RLock()
-	defer lmc.subsLock.RUnlock()
+	lmc.subsLock.Lock()
+	defer lmc.subsLock.Unlock()
 
 	for _, sub := range lmc.subscriptions {
 		close(sub.events)
 	}
+	lmc.subscriptions = nil
 	return nil
 }
 
@@ -0,0 +1,101 @@
+package coordinator
+
+import (
+	"io"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// localMemorySnapshotVersion is increased on incompatible changes of the snapshot format.
+const localMemorySnapshotVersion = 1
+
+type localMemorySnapshot struct {
+	Version  int                            `json:"version"`
+	Items    []localMemorySnapshotItem      `json:"items"`
+	Counters map[string]int64               `json:"counters"`
+	Leases   map[clientv3.LeaseID]time.Time `json:"leases"`
+}
+
+type localMemorySnapshotItem struct {
+	Key   string              `json:"key"`
+	Value jsoniter.RawMessage `json:"value"`
+	Lease clientv3.LeaseID    `json:"lease,omitempty"`
+}
+
+// Snapshot writes the whole key space in JSON. Keys with expired leases are omitted.
+func (lmc *localMemoryCoordinator) Snapshot(w io.Writer) error {
+	s := localMemorySnapshot{
+		Version:  localMemorySnapshotVersion,
+		Counters: make(map[string]int64),
+		Leases:   make(map[clientv3.LeaseID]time.Time),
+	}
+	lmc.data.Range(func(key, value interface{}) bool {
+		e, ok := value.(entry)
+		if !ok {
+			// counters are written below
+			return true
+		}
+		if lmc.isAfterDeadline(e.lease) {
+			return true
+		}
+		s.Items = append(s.Items, localMemorySnapshotItem{
+			Key:   e.item.Key,
+			Value: e.item.Value,
+			Lease: e.lease,
+		})
+		return true
+	})
+	lmc.counterLock.RLock()
+	for key, count := range lmc.counter {
+		s.Counters[key] = count
+	}
+	lmc.counterLock.RUnlock()
+
+	lmc.leases.Range(func(key, value interface{}) bool {
+		s.Leases[key.(clientv3.LeaseID)] = value.(time.Time)
+		return true
+	})
+	return jsoniter.NewEncoder(w).Encode(s)
+}
+
+// Restore replaces the whole key space with the snapshot. Leases keep their deadlines
+// in the snapshot, so they need to be kept alive again after the restore.
+func (lmc *localMemoryCoordinator) Restore(r io.Reader) error {
+	var s localMemorySnapshot
+	if err := jsoniter.NewDecoder(r).Decode(&s); err != nil {
+		return errors.Wrap(err, "decode snapshot")
+	}
+	if s.Version != localMemorySnapshotVersion {
+		return errors.Errorf("unsupported snapshot version %d", s.Version)
+	}
+
+	lmc.data.Range(func(key, _ interface{}) bool {
+		lmc.data.Delete(key)
+		return true
+	})
+	lmc.leases.Range(func(key, _ interface{}) bool {
+		lmc.leases.Delete(key)
+		return true
+	})
+	for lease, deadline := range s.Leases {
+		lmc.leases.Store(lease, deadline)
+	}
+	for _, item := range s.Items {
+		lmc.data.Store(item.Key, entry{
+			lease: item.Lease,
+			item:  RawItem{Key: item.Key, Value: item.Value},
+		})
+	}
+
+	lmc.counterLock.Lock()
+	defer lmc.counterLock.Unlock()
+	lmc.counter = make(map[string]int64, len(s.Counters))
+	for key, count := range s.Counters {
+		lmc.data.Store(key, counterMark)
+		lmc.counter[key] = count
+	}
+	return nil
+}
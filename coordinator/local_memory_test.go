@@ -1,8 +1,10 @@
 package coordinator
 
 import (
+	"bytes"
 	gocontext "context"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -74,3 +76,106 @@ func TestLocalMemoryCoordinator_GrantLease(t *testing.T) {
 		})
 	})
 }
+
+func TestLocalMemoryCoordinator_Watch(t *testing.T) {
+	Convey("Given LocalMemoryCoordinator", t, func() {
+		crd := NewLocalMemory()
+		ctx, cancel := gocontext.WithCancel(gocontext.Background())
+		defer cancel()
+
+		events, err := crd.Watch(ctx, "watched/")
+		So(err, ShouldBeNil)
+
+		Convey("It should deliver typed events of the keys", func() {
+			So(crd.Put(ctx, "watched/foo", map[string]int{"bar": 1}), ShouldBeNil)
+
+			e := <-events
+			So(e.Type, ShouldEqual, PutEvent)
+			So(e.Key(), ShouldEqual, "watched/foo")
+
+			var val map[string]int
+			So(e.Decode(&val), ShouldBeNil)
+			So(val, ShouldResemble, map[string]int{"bar": 1})
+
+			_, err := crd.Delete(ctx, "watched/foo")
+			So(err, ShouldBeNil)
+
+			e = <-events
+			So(e.Type, ShouldEqual, DeleteEvent)
+			So(e.Decode(&val), ShouldNotBeNil)
+		})
+
+		Convey("It should close the channel when the context is cancelled", func() {
+			cancel()
+			select {
+			case _, ok := <-events:
+				So(ok, ShouldBeFalse)
+			case <-time.After(time.Second):
+				So("channel is not closed", ShouldBeEmpty)
+			}
+		})
+
+		Convey("It should not close the channel twice after Close", func() {
+			So(crd.Close(), ShouldBeNil)
+			cancel()
+			time.Sleep(10 * time.Millisecond)
+
+			_, ok := <-events
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestLocalMemoryCoordinator_Snapshot(t *testing.T) {
+	Convey("Given LocalMemoryCoordinator with states", t, func() {
+		crd := NewLocalMemory()
+		ctx := gocontext.Background()
+
+		So(crd.Put(ctx, "foo", map[string]string{"bar": "baz"}), ShouldBeNil)
+		_, err := crd.IncrementCounter(ctx, "counter")
+		So(err, ShouldBeNil)
+		_, err = crd.IncrementCounter(ctx, "counter")
+		So(err, ShouldBeNil)
+
+		alive, err := crd.GrantLease(ctx, time.Minute)
+		So(err, ShouldBeNil)
+		So(crd.Put(ctx, "alive", "value", WithLease(alive)), ShouldBeNil)
+
+		expired, err := crd.GrantLease(ctx, 0)
+		So(err, ShouldBeNil)
+		So(crd.Put(ctx, "expired", "value", WithLease(expired)), ShouldBeNil)
+
+		Convey("It should restore the states from its snapshot", func() {
+			buf := new(bytes.Buffer)
+			So(crd.Snapshot(buf), ShouldBeNil)
+
+			restored := NewLocalMemory()
+			So(restored.Put(ctx, "overwritten", "value"), ShouldBeNil)
+			So(restored.Restore(buf), ShouldBeNil)
+
+			var val map[string]string
+			So(restored.Get(ctx, "foo", &val), ShouldBeNil)
+			So(val, ShouldResemble, map[string]string{"bar": "baz"})
+
+			count, err := restored.ReadCounter(ctx, "counter")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 2)
+
+			count, err = restored.IncrementCounter(ctx, "counter")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 3)
+
+			var s string
+			So(restored.Get(ctx, "alive", &s), ShouldBeNil)
+			So(restored.Get(ctx, "expired", &s), ShouldEqual, ErrNotFound)
+			So(restored.Get(ctx, "overwritten", &s), ShouldEqual, ErrNotFound)
+		})
+
+		Convey("It should reject an invalid snapshot", func() {
+			So(crd.Restore(strings.NewReader(`{"version": 0}`)), ShouldNotBeNil)
+
+			var val map[string]string
+			So(crd.Get(ctx, "foo", &val), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,405 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ab180/lrmr/logging"
+	"github.com/go-redis/redis/v7"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// redisCounterField is the field of hashes used as counters on Redis.
+	redisCounterField = counterMark
+
+	// redisLeasePrefix prefixes the keys for bookkeeping leases, which is outside of the namespace.
+	redisLeasePrefix = "__lrmr_leases:"
+
+	// redisKeyspaceEvents are the keyspace notifications required for Watch:
+	// keyspace events (K) of string (set), hash (hincrby), generic (del), expired and evicted keys.
+	redisKeyspaceEvents = "K$hgxe"
+
+	redisScanCount = 1000
+)
+
+// Redis is a Coordinator backed by Redis, for small clusters where running etcd is too heavy.
+// It is weaker than etcd in following ways:
+//
+//   - Watch is built on keyspace notifications, which are fire-and-forget. Events are lost while
+//     the connection is broken, and values are read after the notifications, so consecutive updates
+//     of a key can be observed as the last one several times.
+//   - Scan and Delete with a prefix are not atomic, as they iterate the keys with SCAN.
+//   - Keys put with a lease expire individually. KeepAlive extends the keys put with the lease
+//     by the coordinator, which must be the one granted the lease.
+//
+// Keyspace notifications are enabled on connection with CONFIG SET. If the command is disabled
+// (e.g. on managed Redis services), notify-keyspace-events must include "K$hgxe" on the server.
+type Redis struct {
+	Client *redis.Client
+
+	ns     string
+	db     int
+	leases *redisLeases
+	log    logging.Logger
+	opts   []WriteOption
+}
+
+type redisLeases struct {
+	ttls sync.Map
+}
+
+// NewRedis connects to the Redis server in given address. Keys are prefixed with nsPrefix,
+// in the same way as NewEtcd.
+func NewRedis(addr, nsPrefix string) (Coordinator, error) {
+	opt := &redis.Options{Addr: addr}
+	cli := redis.NewClient(opt)
+	if err := cli.Ping().Err(); err != nil {
+		_ = cli.Close()
+		return nil, errors.Wrapf(err, "connect %s", addr)
+	}
+	r := &Redis{
+		Client: cli,
+		ns:     nsPrefix,
+		db:     opt.DB,
+		leases: &redisLeases{},
+		log:    logging.New("redis"),
+	}
+	if err := cli.ConfigSet("notify-keyspace-events", redisKeyspaceEvents).Err(); err != nil {
+		r.log.Warn("Unable to enable keyspace notifications, watch may not work: {}", err)
+	}
+	return r, nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string, valuePtr interface{}) error {
+	val, err := r.Client.WithContext(ctx).Get(r.ns + key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return ErrNotFound
+		}
+		return err
+	}
+	return jsoniter.Unmarshal(val, valuePtr)
+}
+
+func (r *Redis) Scan(ctx context.Context, prefix string) (results []RawItem, err error) {
+	cli := r.Client.WithContext(ctx)
+	keys, err := r.scanKeys(cli, prefix)
+	if err != nil {
+		return nil, err
+	}
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > redisScanCount {
+			batch = batch[:redisScanCount]
+		}
+		keys = keys[len(batch):]
+
+		values, err := cli.MGet(batch...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				// deleted after the scan, or a counter
+				continue
+			}
+			results = append(results, RawItem{
+				Key:   strings.TrimPrefix(batch[i], r.ns),
+				Value: []byte(s),
+			})
+		}
+	}
+	return results, nil
+}
+
+// scanKeys returns the namespaced keys starting with given prefix in ascending order.
+func (r *Redis) scanKeys(cli *redis.Client, prefix string) ([]string, error) {
+	var keys []string
+	iter := cli.Scan(0, escapeRedisPattern(r.ns+prefix)+"*", redisScanCount).Iterator()
+	for iter.Next() {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	// SCAN may return a key multiple times
+	sort.Strings(keys)
+	deduped := keys[:0]
+	for i, k := range keys {
+		if i > 0 && keys[i-1] == k {
+			continue
+		}
+		deduped = append(deduped, k)
+	}
+	return deduped, nil
+}
+
+// Watch subscribes keyspace notifications of the keys starting with given prefix.
+// See Redis for the guarantees weaker than etcd.
+func (r *Redis) Watch(ctx context.Context, prefix string) (<-chan WatchEvent, error) {
+	channelPrefix := fmt.Sprintf("__keyspace@%d__:", r.db)
+
+	pubsub := r.Client.PSubscribe(channelPrefix + escapeRedisPattern(r.ns+prefix) + "*")
+	// ensures that the events after the return are delivered
+	if _, err := pubsub.Receive(); err != nil {
+		_ = pubsub.Close()
+		return nil, errors.Wrap(err, "subscribe keyspace notifications")
+	}
+	watchChan := make(chan WatchEvent)
+	go func() {
+		defer close(watchChan)
+		defer pubsub.Close()
+
+		cli := r.Client.WithContext(ctx)
+		messages := pubsub.Channel()
+		for {
+			var msg *redis.Message
+			select {
+			case m, ok := <-messages:
+				if !ok {
+					return
+				}
+				msg = m
+			case <-ctx.Done():
+				return
+			}
+			key := strings.TrimPrefix(msg.Channel, channelPrefix)
+			item := RawItem{Key: strings.TrimPrefix(key, r.ns)}
+
+			var e WatchEvent
+			switch msg.Payload {
+			case "set":
+				val, err := cli.Get(key).Bytes()
+				if err != nil {
+					if err != redis.Nil {
+						r.log.Error("watch error", err)
+					}
+					continue
+				}
+				item.Value = val
+				e = WatchEvent{Type: PutEvent, Item: item}
+
+			case "hincrby":
+				count, err := cli.HGet(key, redisCounterField).Int64()
+				if err != nil {
+					if err != redis.Nil {
+						r.log.Error("watch error", err)
+					}
+					continue
+				}
+				e = WatchEvent{Type: CounterEvent, Item: item, Counter: count}
+
+			case "del", "expired", "evicted":
+				e = WatchEvent{Type: DeleteEvent, Item: item}
+
+			default:
+				continue
+			}
+			select {
+			case watchChan <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return watchChan, nil
+}
+
+func (r *Redis) Put(ctx context.Context, key string, value interface{}, opts ...WriteOption) error {
+	_, err := r.Commit(ctx, NewTxn().Put(key, value), opts...)
+	return err
+}
+
+// Commit applies the transaction with MULTI and EXEC. Keys to be deleted are scanned before the transaction.
+func (r *Redis) Commit(ctx context.Context, txn *Txn, opts ...WriteOption) ([]TxnResult, error) {
+	cli := r.Client.WithContext(ctx)
+
+	opt := buildWriteOption(append(r.opts, opts...))
+	var ttl time.Duration
+	if opt.Lease != clientv3.NoLease {
+		var err error
+		if ttl, err = r.leases.ttlOf(opt.Lease); err != nil {
+			return nil, err
+		}
+	}
+
+	deletedKeys := make(map[int][]string)
+	for i, op := range txn.Ops {
+		if op.Type != DeleteEvent {
+			continue
+		}
+		keys, err := r.scanKeys(cli, op.Key)
+		if err != nil {
+			return nil, err
+		}
+		deletedKeys[i] = keys
+	}
+
+	cmds := make([]*redis.IntCmd, len(txn.Ops))
+	_, err := cli.TxPipelined(func(pipe redis.Pipeliner) error {
+		for i, op := range txn.Ops {
+			key := r.ns + op.Key
+
+			switch op.Type {
+			case PutEvent:
+				jsonVal, err := jsoniter.Marshal(op.Value)
+				if err != nil {
+					return err
+				}
+				pipe.Set(key, jsonVal, ttl)
+				if ttl > 0 {
+					leaseKeys := redisLeaseKeysKey(opt.Lease)
+					pipe.SAdd(leaseKeys, key)
+					pipe.Expire(leaseKeys, ttl)
+				}
+
+			case CounterEvent:
+				cmds[i] = pipe.HIncrBy(key, redisCounterField, 1)
+
+			case DeleteEvent:
+				if keys := deletedKeys[i]; len(keys) > 0 {
+					cmds[i] = pipe.Del(keys...)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TxnResult, len(txn.Ops))
+	for i, op := range txn.Ops {
+		results[i].Type = op.Type
+		if cmds[i] == nil {
+			continue
+		}
+		switch op.Type {
+		case CounterEvent:
+			results[i].Counter = cmds[i].Val()
+		case DeleteEvent:
+			results[i].Deleted = cmds[i].Val()
+		}
+	}
+	return results, nil
+}
+
+// GrantLease creates a lease. Unlike etcd, the lease is known only to the coordinator granted it.
+func (r *Redis) GrantLease(ctx context.Context, ttl time.Duration) (clientv3.LeaseID, error) {
+	id, err := r.Client.WithContext(ctx).Incr(redisLeasePrefix + "seq").Result()
+	if err != nil {
+		return 0, err
+	}
+	lease := clientv3.LeaseID(id)
+	r.leases.ttls.Store(lease, ttl)
+	return lease, nil
+}
+
+// KeepAlive extends TTL of the keys put with the lease periodically, until the context is done.
+func (r *Redis) KeepAlive(ctx context.Context, lease clientv3.LeaseID) error {
+	ttl, err := r.leases.ttlOf(lease)
+	if err != nil {
+		return err
+	}
+	go func() {
+		cli := r.Client.WithContext(ctx)
+		t := time.NewTicker(ttl / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+			leaseKeys := redisLeaseKeysKey(lease)
+			keys, err := cli.SMembers(leaseKeys).Result()
+			if err != nil {
+				r.log.Error("failed to keep lease {} alive: {}", lease, err)
+				continue
+			}
+			_, err = cli.Pipelined(func(pipe redis.Pipeliner) error {
+				for _, k := range keys {
+					pipe.Expire(k, ttl)
+				}
+				pipe.Expire(leaseKeys, ttl)
+				return nil
+			})
+			if err != nil {
+				r.log.Error("failed to keep lease {} alive: {}", lease, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *Redis) IncrementCounter(ctx context.Context, key string) (count int64, err error) {
+	return r.Client.WithContext(ctx).HIncrBy(r.ns+key, redisCounterField, 1).Result()
+}
+
+func (r *Redis) ReadCounter(ctx context.Context, key string) (count int64, err error) {
+	count, err = r.Client.WithContext(ctx).HGet(r.ns+key, redisCounterField).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	} else if err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		return 0, ErrNotCounter
+	}
+	return count, err
+}
+
+func (r *Redis) Delete(ctx context.Context, prefix string) (deleted int64, err error) {
+	results, err := r.Commit(ctx, NewTxn().Delete(prefix))
+	if err != nil {
+		return 0, err
+	}
+	return results[0].Deleted, nil
+}
+
+func (r *Redis) WithOptions(opt ...WriteOption) KV {
+	return &Redis{
+		Client: r.Client,
+		ns:     r.ns,
+		db:     r.db,
+		leases: r.leases,
+		log:    r.log,
+		opts:   opt,
+	}
+}
+
+func (r *Redis) Close() error {
+	return r.Client.Close()
+}
+
+func (l *redisLeases) ttlOf(lease clientv3.LeaseID) (time.Duration, error) {
+	v, ok := l.ttls.Load(lease)
+	if !ok {
+		return 0, errors.Errorf("unknown lease %d", lease)
+	}
+	return v.(time.Duration), nil
+}
+
+// redisLeaseKeysKey is the key of a set holding the keys put with the lease.
+func redisLeaseKeysKey(lease clientv3.LeaseID) string {
+	return redisLeasePrefix + strconv.FormatInt(int64(lease), 10)
+}
+
+// escapeRedisPattern escapes the special characters of glob-style patterns used by SCAN and PSUBSCRIBE.
+func escapeRedisPattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,198 @@
+package coordinator
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/ab180/lrmr/logging"
+	"github.com/creasty/defaults"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryOptions is a policy of retrying coordinator operations failed with transient errors,
+// such as the ones during a leader election of etcd.
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of attempts of an operation, including the first one.
+	// Operations are not retried if it is less than 2.
+	MaxAttempts int `default:"5"`
+
+	// BaseDelay is the delay before the first retry, which is doubled on each retry up to MaxDelay.
+	BaseDelay time.Duration `default:"100ms"`
+	MaxDelay  time.Duration `default:"3s"`
+
+	// Jitter randomizes the delays by given fraction of them, e.g. 0.2 gives 80%~120% of the delays.
+	Jitter float64 `default:"0.2"`
+}
+
+// DefaultRetryOptions returns the retry policy used by NewEtcd by default.
+func DefaultRetryOptions() (o RetryOptions) {
+	if err := defaults.Set(&o); err != nil {
+		panic(err)
+	}
+	return
+}
+
+// delay returns the delay before the retry after given number of attempts.
+func (o RetryOptions) delay(attempt int) time.Duration {
+	d := o.MaxDelay
+	if attempt < 32 && o.BaseDelay<<(attempt-1) < o.MaxDelay {
+		d = o.BaseDelay << (attempt - 1)
+	}
+	if o.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + o.Jitter*(2*rand.Float64()-1)))
+	}
+	return d
+}
+
+// isNotApplied returns true if the operation failed with given error is surely not applied,
+// so that it is safe to retry any kind of operations.
+func isNotApplied(err error) bool {
+	return err == rpctypes.ErrNoLeader || err == rpctypes.ErrTooManyRequests
+}
+
+// isTransient returns true if given error is temporary, but the operation failed with the error
+// might have been applied. Only idempotent operations are retried on these errors.
+func isTransient(err error) bool {
+	switch err {
+	case rpctypes.ErrLeaderChanged, rpctypes.ErrTimeout,
+		rpctypes.ErrTimeoutDueToLeaderFail, rpctypes.ErrTimeoutDueToConnectionLost:
+		return true
+	}
+	return status.Code(err) == codes.Unavailable
+}
+
+// WithRetry wraps the coordinator to retry its operations failed with transient errors according to
+// given policy. Errors like ErrNotFound are returned without retries. Non-idempotent operations,
+// which are IncrementCounter and Commit with counters, are retried only if they are surely not applied.
+func WithRetry(crd Coordinator, opt RetryOptions) Coordinator {
+	log := logging.New("lrmr.coordinator")
+	return &retryingCoordinator{
+		retryingKV: retryingKV{kv: crd, opt: opt, log: log},
+		crd:        crd,
+	}
+}
+
+type retryingKV struct {
+	kv  KV
+	opt RetryOptions
+	log logging.Logger
+}
+
+// do calls fn until it succeeds, fails with a non-retryable error, or reaches the maximum attempts.
+func (r *retryingKV) do(ctx context.Context, idempotent bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.opt.MaxAttempts {
+			return err
+		}
+		if !isNotApplied(err) && !(idempotent && isTransient(err)) {
+			return err
+		}
+		delay := r.opt.delay(attempt)
+		r.log.Verbose("Retrying coordinator operation in {} after attempt {}: {}", delay, attempt, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (r *retryingKV) Put(ctx context.Context, key string, value interface{}, opts ...WriteOption) error {
+	return r.do(ctx, true, func() error {
+		return r.kv.Put(ctx, key, value, opts...)
+	})
+}
+
+func (r *retryingKV) Get(ctx context.Context, key string, valuePtr interface{}) error {
+	return r.do(ctx, true, func() error {
+		return r.kv.Get(ctx, key, valuePtr)
+	})
+}
+
+func (r *retryingKV) Scan(ctx context.Context, prefix string) (results []RawItem, err error) {
+	err = r.do(ctx, true, func() (err error) {
+		results, err = r.kv.Scan(ctx, prefix)
+		return err
+	})
+	return results, err
+}
+
+func (r *retryingKV) Delete(ctx context.Context, prefix string) (deleted int64, err error) {
+	err = r.do(ctx, true, func() (err error) {
+		deleted, err = r.kv.Delete(ctx, prefix)
+		return err
+	})
+	return deleted, err
+}
+
+func (r *retryingKV) Watch(ctx context.Context, prefix string) (events <-chan WatchEvent, err error) {
+	err = r.do(ctx, true, func() (err error) {
+		events, err = r.kv.Watch(ctx, prefix)
+		return err
+	})
+	return events, err
+}
+
+func (r *retryingKV) IncrementCounter(ctx context.Context, key string) (count int64, err error) {
+	err = r.do(ctx, false, func() (err error) {
+		count, err = r.kv.IncrementCounter(ctx, key)
+		return err
+	})
+	return count, err
+}
+
+func (r *retryingKV) ReadCounter(ctx context.Context, key string) (count int64, err error) {
+	err = r.do(ctx, true, func() (err error) {
+		count, err = r.kv.ReadCounter(ctx, key)
+		return err
+	})
+	return count, err
+}
+
+func (r *retryingKV) Commit(ctx context.Context, t *Txn, opts ...WriteOption) (results []TxnResult, err error) {
+	idempotent := true
+	for _, op := range t.Ops {
+		if op.Type == CounterEvent {
+			idempotent = false
+			break
+		}
+	}
+	err = r.do(ctx, idempotent, func() (err error) {
+		results, err = r.kv.Commit(ctx, t, opts...)
+		return err
+	})
+	return results, err
+}
+
+type retryingCoordinator struct {
+	retryingKV
+	crd Coordinator
+}
+
+func (r *retryingCoordinator) WithOptions(opts ...WriteOption) KV {
+	return &retryingKV{kv: r.crd.WithOptions(opts...), opt: r.opt, log: r.log}
+}
+
+func (r *retryingCoordinator) GrantLease(ctx context.Context, ttl time.Duration) (lease clientv3.LeaseID, err error) {
+	// an orphan lease granted by a retried attempt expires by itself
+	err = r.do(ctx, true, func() (err error) {
+		lease, err = r.crd.GrantLease(ctx, ttl)
+		return err
+	})
+	return lease, err
+}
+
+func (r *retryingCoordinator) KeepAlive(ctx context.Context, lease clientv3.LeaseID) error {
+	return r.do(ctx, true, func() error {
+		return r.crd.KeepAlive(ctx, lease)
+	})
+}
+
+func (r *retryingCoordinator) Close() error {
+	return r.crd.Close()
+}
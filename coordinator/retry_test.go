@@ -0,0 +1,123 @@
+package coordinator
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// flakyCoordinator fails the first given number of calls of Put and IncrementCounter with given error.
+type flakyCoordinator struct {
+	Coordinator
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyCoordinator) fail() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyCoordinator) Put(ctx gocontext.Context, key string, value interface{}, opts ...WriteOption) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	return f.Coordinator.Put(ctx, key, value, opts...)
+}
+
+func (f *flakyCoordinator) Get(ctx gocontext.Context, key string, valuePtr interface{}) error {
+	f.calls++
+	return f.Coordinator.Get(ctx, key, valuePtr)
+}
+
+func (f *flakyCoordinator) IncrementCounter(ctx gocontext.Context, key string) (int64, error) {
+	if err := f.fail(); err != nil {
+		return 0, err
+	}
+	return f.Coordinator.IncrementCounter(ctx, key)
+}
+
+func TestWithRetry(t *testing.T) {
+	Convey("Given a flaky coordinator wrapped with retries", t, func() {
+		ctx := gocontext.Background()
+		opt := RetryOptions{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, Jitter: 0.2}
+
+		Convey("It should retry until the operation succeeds", func() {
+			flaky := &flakyCoordinator{Coordinator: NewLocalMemory(), failures: 2, err: rpctypes.ErrLeaderChanged}
+			crd := WithRetry(flaky, opt)
+
+			So(crd.Put(ctx, "foo", "bar"), ShouldBeNil)
+			So(flaky.calls, ShouldEqual, 3)
+
+			var val string
+			So(crd.Get(ctx, "foo", &val), ShouldBeNil)
+			So(val, ShouldEqual, "bar")
+		})
+
+		Convey("It should give up after the maximum attempts", func() {
+			flaky := &flakyCoordinator{Coordinator: NewLocalMemory(), failures: 10, err: rpctypes.ErrNoLeader}
+			crd := WithRetry(flaky, opt)
+
+			So(crd.Put(ctx, "foo", "bar"), ShouldResemble, rpctypes.ErrNoLeader)
+			So(flaky.calls, ShouldEqual, 4)
+		})
+
+		Convey("It should not retry non-retryable errors", func() {
+			flaky := &flakyCoordinator{Coordinator: NewLocalMemory()}
+			crd := WithRetry(flaky, opt)
+
+			var val string
+			So(crd.Get(ctx, "nonexistent", &val), ShouldEqual, ErrNotFound)
+			So(flaky.calls, ShouldEqual, 1)
+		})
+
+		Convey("It should not retry non-idempotent operations which might have been applied", func() {
+			flaky := &flakyCoordinator{Coordinator: NewLocalMemory(), failures: 1, err: rpctypes.ErrTimeoutDueToLeaderFail}
+			crd := WithRetry(flaky, opt)
+
+			_, err := crd.IncrementCounter(ctx, "counter")
+			So(err, ShouldResemble, rpctypes.ErrTimeoutDueToLeaderFail)
+			So(flaky.calls, ShouldEqual, 1)
+
+			Convey("But retry them if they are surely not applied", func() {
+				flaky.calls, flaky.err = 0, rpctypes.ErrNoLeader
+
+				count, err := crd.IncrementCounter(ctx, "counter")
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 1)
+				So(flaky.calls, ShouldEqual, 2)
+			})
+		})
+
+		Convey("It should stop retrying when the context is done", func() {
+			flaky := &flakyCoordinator{Coordinator: NewLocalMemory(), failures: 10, err: rpctypes.ErrNoLeader}
+			crd := WithRetry(flaky, RetryOptions{MaxAttempts: 10, BaseDelay: time.Hour, MaxDelay: time.Hour})
+
+			cctx, cancel := gocontext.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			So(crd.Put(cctx, "foo", "bar"), ShouldResemble, rpctypes.ErrNoLeader)
+			So(flaky.calls, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestRetryOptions_delay(t *testing.T) {
+	Convey("Given the default retry options", t, func() {
+		opt := DefaultRetryOptions()
+		opt.Jitter = 0
+
+		Convey("The delay should be doubled up to the maximum", func() {
+			So(opt.delay(1), ShouldEqual, 100*time.Millisecond)
+			So(opt.delay(2), ShouldEqual, 200*time.Millisecond)
+			So(opt.delay(6), ShouldEqual, 3*time.Second)
+			So(opt.delay(100), ShouldEqual, 3*time.Second)
+		})
+	})
+}
@@ -2,6 +2,7 @@ package coordinator
 
 import (
 	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
@@ -22,6 +23,20 @@ type WatchEvent struct {
 	Counter int64
 }
 
+// Key returns the key which the event occurred on.
+func (e WatchEvent) Key() string {
+	return e.Item.Key
+}
+
+// Decode unmarshals the value put on the key into valuePtr. It fails on events other than PutEvent,
+// as they don't carry a value.
+func (e WatchEvent) Decode(valuePtr interface{}) error {
+	if e.Type != PutEvent {
+		return errors.Errorf("%s has no value to decode", e.Item.Key)
+	}
+	return e.Item.Unmarshal(valuePtr)
+}
+
 // RawItem is a data of item which isn't unmarshalled yet.
 type RawItem struct {
 	Key   string
@@ -0,0 +1,47 @@
+package lrmr
+
+import (
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+// Count runs the job and returns the number of rows in the dataset. See Session.Count for details.
+func (d *Dataset) Count() (int64, error) {
+	return d.session.Count(d)
+}
+
+// Count runs the dataset and returns the number of its rows. The rows are counted by the tasks
+// of the last stage in an accumulator, which is summed over the tasks at the master, so it is much
+// cheaper than Collect since no rows are sent to the master.
+func (s *Session) Count(ds *Dataset) (int64, error) {
+	name := ds.stageName(&rowCounter{})
+	ds.addStage(name, &rowCounter{Accumulator: name})
+
+	j, err := s.runWithJobRetries(ds, (*RunningJob).Wait)
+	if err != nil {
+		return 0, err
+	}
+	acc, err := j.Accumulators()
+	if err != nil {
+		return 0, errors.Wrap(err, "sum counts")
+	}
+	return acc[name], nil
+}
+
+// rowCounter counts the rows in the accumulator.
+type rowCounter struct {
+	Accumulator string
+}
+
+func (c *rowCounter) Apply(ctx transformation.Context, in chan *lrdd.Row, _ output.Output) error {
+	acc := ctx.Accumulator(c.Accumulator)
+
+	var n int64
+	for range in {
+		n++
+	}
+	acc.Add(n)
+	return nil
+}
@@ -1,15 +1,22 @@
 package lrmr
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"time"
 
+	"github.com/ab180/lrmr/internal/serialization"
 	"github.com/ab180/lrmr/internal/util"
 	"github.com/ab180/lrmr/job"
 	"github.com/ab180/lrmr/lrdd"
 	"github.com/ab180/lrmr/master"
+	"github.com/ab180/lrmr/output"
 	"github.com/ab180/lrmr/partitions"
 	"github.com/ab180/lrmr/stage"
 	"github.com/ab180/lrmr/transformation"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
 )
 
 // Dataset is less-resilient distributed dataset
@@ -60,6 +67,15 @@ func (d *Dataset) FlatMap(fm FlatMapper) *Dataset {
 	return d
 }
 
+// Filter keeps only the rows for which the Filter returns true. The output of the previous stage is
+// passed to the filter in the same worker unless it's partitioned explicitly or repartitioned,
+// so filtering itself never adds a shuffle.
+func (d *Dataset) Filter(f Filter) *Dataset {
+	d.preserveLastPlan()
+	d.addStage(d.stageName(f), &filterTransformation{f})
+	return d
+}
+
 func (d *Dataset) Reduce(r Reducer) *Dataset {
 	d.addStage(d.stageName(r), &reduceTransformation{r})
 	return d
@@ -70,6 +86,60 @@ func (d *Dataset) Sort(s Sorter) *Dataset {
 	return d
 }
 
+// Segment keeps only the keys satisfying aggregate-over-time conditions of given segment.
+// Since the rows are aggregated by key, it should be used after grouping by key.
+func (d *Dataset) Segment(s Segment) *Dataset {
+	if s.Until.IsZero() {
+		// fixed once, so that every task aggregates the same time windows
+		s.Until = time.Now()
+	}
+	d.addStage(d.stageName(s), &segmentTransformation{Segment: s})
+	return d
+}
+
+// Aggregate groups rows by key, or by the field given as Aggregation.GroupBy,
+// and emits the aggregated value of each group.
+func (d *Dataset) Aggregate(a Aggregation) *Dataset {
+	if a.GroupBy != "" {
+		d.Map(&fieldKeyMapper{Field: a.GroupBy})
+	}
+	d.GroupByKey()
+	d.addStage(d.stageName(a), &aggregateTransformation{Aggregation: a})
+	return d
+}
+
+// Fold groups rows by key, and folds the rows of each key into a row with the Folder.
+// The first row of each key is used as the initial accumulator.
+func (d *Dataset) Fold(f Folder) *Dataset {
+	d.GroupByKey()
+	d.addStage(d.stageName(f), &foldTransformation{f})
+	return d
+}
+
+// FoldWithCombiner is like Fold, but it also folds the rows in each partition before the shuffle
+// (i.e. map-side combine), so that only a row per key is sent from each partition over the network.
+// Since the rows are folded in arbitrary groups and order, the Folder must be associative and
+// commutative, and its output must be foldable again.
+func (d *Dataset) FoldWithCombiner(f Folder) *Dataset {
+	if len(d.stages) > 1 {
+		// combiner runs in the same worker, right after the upstream
+		d.lastPlan().Partitioner = partitions.NewPreservePartitioner()
+	}
+	d.addStage(d.stageName(f), &foldTransformation{f})
+	return d.Fold(f)
+}
+
+// MapGroups groups rows by key, and calls the GroupMapper once for each key with all rows of the key.
+//
+// A task holds all rows of its partition in memory until its input ends, as the rows of a key
+// can arrive at any time. Groups are not spilled to disk, so a partition should fit in the memory
+// of a worker; use Repartition to split them. Consider Reduce for the keys having too many rows.
+func (d *Dataset) MapGroups(g GroupMapper) *Dataset {
+	d.GroupByKey()
+	d.addStage(d.stageName(g), &groupMapTransformation{g})
+	return d
+}
+
 func (d *Dataset) GroupByKey() *Dataset {
 	d.lastPlan().Partitioner = partitions.NewHashKeyPartitioner()
 	return d
@@ -85,11 +155,33 @@ func (d *Dataset) Shuffle() *Dataset {
 	return d
 }
 
+// Repartition sets the number of partitions of the following stages. The rows are shuffled evenly
+// into the partitions, regardless of their keys.
 func (d *Dataset) Repartition(n int) *Dataset {
 	d.defaultPlan.DesiredCount = n
 	return d
 }
 
+// RepartitionByKey shuffles the rows into n partitions by the hash of their keys,
+// which is also the number of partitions of the following stages.
+func (d *Dataset) RepartitionByKey(n int) *Dataset {
+	d.lastPlan().Partitioner = partitions.NewHashKeyPartitioner()
+	d.defaultPlan.DesiredCount = n
+	return d
+}
+
+// Coalesce merges the partitions of the last stage into n partitions without shuffling the rows, which is
+// cheaper than Repartition for reducing the number of partitions (e.g. after a selective Filter). The partitions
+// on the same node are merged on the node, so their rows are not sent over the network as long as every node
+// has a merged partition. It keeps the number of partitions if there are n or less.
+func (d *Dataset) Coalesce(n int) *Dataset {
+	if len(d.stages) > 1 {
+		d.lastPlan().Partitioner = partitions.NewCoalescePartitioner(n)
+	}
+	d.defaultPlan.DesiredCount = n
+	return d
+}
+
 func (d *Dataset) PartitionedBy(p partitions.Partitioner) *Dataset {
 	d.plans[len(d.plans)-1].Partitioner = p
 	return d
@@ -110,26 +202,76 @@ func (d *Dataset) WithConcurrencyPerWorker(n int) *Dataset {
 	return d
 }
 
-func (d *Dataset) Collect() ([]*lrdd.Row, error) {
-	// add collect stage for the master
-	d.PartitionedBy(master.NewCollectPartitioner()).
-		Repartition(1).
-		WithWorkerCount(1).
-		WithConcurrencyPerWorker(1).
-		addStage(master.CollectStageName, &master.Collector{})
+// WithResourceHint attaches a hint of the resources used by each task of the last stage. The master places
+// the tasks with hints on the nodes with the least resources used by the other tasks of the job, so that
+// e.g. two memory-heavy tasks are not co-located if possible. See partitions.ResourceHint for details.
+func (d *Dataset) WithResourceHint(h partitions.ResourceHint) *Dataset {
+	d.lastPlan().Resources = h
+	return d
+}
 
-	j, err := d.session.Run(d)
-	if err != nil {
-		return nil, err
+// WithInputSchema makes the last stage validate its input rows against given schema. A task
+// receiving a row mismatching the schema fails, which catches the bugs like a typo in a field name
+// at the stage boundary rather than producing nil values downstream.
+func (d *Dataset) WithInputSchema(s lrdd.Schema) *Dataset {
+	d.lastStage().InputSchema = s
+	return d
+}
+
+// WithTaskTimeout limits the running time of each task in the last stage. A task running longer than
+// the timeout is aborted and fails the job. The running time excludes the time waiting for a slot and
+// while the job is paused. Transformations can observe the abort through the cancellation of Context.
+func (d *Dataset) WithTaskTimeout(timeout time.Duration) *Dataset {
+	d.lastStage().TaskTimeout = timeout
+	return d
+}
+
+// WithRetries makes the job failed by a task in the last stage with a retryable error (see RetryClassifier)
+// run again up to n times with exponential backoff. Like WithJobRetries, it applies to Collect, Count, Foreach
+// and WriteBatches, and the input of the dataset must be replayable.
+//
+// Since the rows streamed between the stages are not kept, the dataset is run again from the beginning
+// in a new job, whose tasks can be placed on other workers. So every stage must be idempotent.
+func (d *Dataset) WithRetries(n int) *Dataset {
+	d.lastStage().MaxRetries = n
+	return d
+}
+
+// WithNoOutputPolicy sets what the last stage does with its output rows for which the partitioner of the
+// next stage finds no partition (e.g. the keys unknown to partitions.FiniteKeyPartitioner). By default,
+// the rows are dropped and counted in the OutputDroppedRows metric of the tasks.
+func (d *Dataset) WithNoOutputPolicy(p output.NoOutputPolicy) *Dataset {
+	d.lastStage().NoOutputPolicy = p
+	return d
+}
+
+// Collect runs the dataset and returns its rows gathered in the master. The order of the rows depends on
+// the scheduling of the tasks, unless the session is created with WithOrderedCollect.
+func (d *Dataset) Collect() ([]*lrdd.Row, error) {
+	ordered := d.session.options.OrderedCollect
+	if ordered {
+		d.addOrderTags()
 	}
-	res, err := j.Collect()
+	d.addCollectStage(&master.Collector{})
+
+	var res []*lrdd.Row
+	j, err := d.session.runWithJobRetries(d, func(j *RunningJob) (err error) {
+		res, err = j.Collect()
+		return err
+	})
 	if err != nil {
-		if jobErr, ok := err.(*job.Error); ok {
+		var jobErr job.Error
+		if errors.As(err, &jobErr) {
 			log.Error("Job failed. Cause: {}", jobErr.Message)
 			log.Error("  (caused by task {})", jobErr.Task)
 		}
 		return nil, err
 	}
+	if ordered {
+		if res, err = sortByOrderTags(res); err != nil {
+			return nil, err
+		}
+	}
 	log.Verbose("Successfully collected {} results.", len(res))
 	go func() {
 		m, err := j.Metrics()
@@ -145,6 +287,21 @@ func (d *Dataset) Collect() ([]*lrdd.Row, error) {
 	return res, nil
 }
 
+// CollectStream runs the dataset and streams its result rows to the master as they arrive.
+// See Session.CollectStream for details.
+func (d *Dataset) CollectStream(ctx context.Context) (<-chan *lrdd.Row, <-chan error) {
+	return d.session.CollectStream(ctx, d)
+}
+
+// addCollectStage adds the stage sending the rows to the master.
+func (d *Dataset) addCollectStage(c *master.Collector) {
+	d.PartitionedBy(master.NewCollectPartitioner()).
+		Repartition(1).
+		WithWorkerCount(1).
+		WithConcurrencyPerWorker(1).
+		addStage(master.CollectStageName, c)
+}
+
 func (d *Dataset) stageName(v interface{}) string {
 	name := fmt.Sprintf("%s%d", util.NameOfType(v), d.NumStages)
 	d.NumStages += 1
@@ -155,6 +312,63 @@ func (d *Dataset) Run() (*RunningJob, error) {
 	return d.session.Run(d)
 }
 
+// validate checks that the stages and the partitioners in given plans can be sent to the workers,
+// so that running a dataset with unregistered types fails before creating the job with an error
+// naming the type, instead of failing to deserialize the stages on the workers.
+func (d *Dataset) validate(plans []partitions.Plan) error {
+	for _, st := range d.stages {
+		if _, err := jsoniter.Marshal(st.Function); err != nil {
+			return errors.Wrapf(err, "invalid stage %s", st.Name)
+		}
+	}
+	for i, p := range plans {
+		if err := serialization.Validate(p.Partitioner); err != nil {
+			return errors.Wrapf(err, "invalid partitioner of stage %s", d.stages[i].Name)
+		}
+	}
+	return nil
+}
+
+// physicalPlans returns a copy of the plans to run the dataset, replacing the shuffles already satisfied
+// with PreservePartitioner. If a stage keeps the keys of its input partitioned by a partitioner,
+// partitioning its output by an equal partitioner routes every row to the partition it's already in,
+// so the output can be passed to the next stage in the same worker instead of over the network.
+func (d *Dataset) physicalPlans() []partitions.Plan {
+	plans := append([]partitions.Plan(nil), d.plans...)
+
+	// partitionedBy is the partitioner of the input of the current stage, or nil if it's unknown
+	partitionedBy := plans[0].Partitioner
+	for i := 1; i < len(plans)-1; i++ {
+		cur, next := &plans[i], plans[i+1]
+		keysPreserved := transformation.PreservesKeys(d.stages[i].Function)
+		if cur.Partitioner == nil || partitions.IsPreserved(cur.Partitioner) {
+			// default partitioner is preserved only if adjacent plans are equal (see partitions.Schedule)
+			if !keysPreserved || (cur.Partitioner == nil && !cur.Equal(next)) {
+				partitionedBy = nil
+			}
+			continue
+		}
+		_, multi := partitions.UnwrapPartitioner(cur.Partitioner).(partitions.MultiPartitioner)
+		if partitionedBy != nil && keysPreserved && !multi &&
+			partitions.Equal(partitionedBy, cur.Partitioner) &&
+			cur.Equal(next) && reflect.DeepEqual(cur.DesiredNodeAffinity, next.DesiredNodeAffinity) {
+			cur.Partitioner = partitions.NewPreservePartitioner()
+			continue
+		}
+		partitionedBy = cur.Partitioner
+	}
+	return plans
+}
+
+// preserveLastPlan makes the output of the last stage stay in the same partition, unless the stage is
+// the input, its output is already partitioned explicitly (e.g. by GroupByKey), or the partitions of
+// the following stages are planned differently (e.g. by Repartition).
+func (d *Dataset) preserveLastPlan() {
+	if len(d.stages) > 1 && d.lastPlan().Partitioner == nil && d.lastPlan().Equal(d.defaultPlan) {
+		d.lastPlan().Partitioner = partitions.NewPreservePartitioner()
+	}
+}
+
 func (d *Dataset) lastStage() *stage.Stage {
 	return &d.stages[len(d.stages)-1]
 }
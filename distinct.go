@@ -0,0 +1,110 @@
+package lrmr
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+var _ = RegisterTypes(&wholeRowKeyer{})
+
+// RowKeyer extracts a key identifying a row, e.g. from a subset of its fields.
+type RowKeyer interface {
+	RowKey(row *lrdd.Row) string
+}
+
+// Distinct removes duplicated rows having the same key and value, emitting each unique row once.
+//
+// Rows are partitioned by a 128-bit hash of the row, so that identical rows meet in the same task.
+// Each task remembers the hashes of the rows it has emitted until its input ends, which takes
+// about 32 bytes plus map overhead per unique row in its partition. Use Repartition to spread them
+// if it doesn't fit in the memory of a worker. Hash collisions are ignored as being unlikely.
+func (d *Dataset) Distinct() *Dataset {
+	return d.DistinctBy(&wholeRowKeyer{})
+}
+
+// DistinctBy removes rows having the same key given by the RowKeyer as a preceding row,
+// keeping the first one arrived. See Distinct for details.
+func (d *Dataset) DistinctBy(k RowKeyer) *Dataset {
+	d.addStage(d.stageName(k), &distinctKeyTransformation{keyer: k})
+	d.GroupByKey()
+	d.addStage(d.stageName(&distinctTransformation{}), &distinctTransformation{})
+	return d
+}
+
+// wholeRowKeyer keys a row by its key and value.
+type wholeRowKeyer struct{}
+
+func (wholeRowKeyer) RowKey(row *lrdd.Row) string {
+	// encoded maps are not canonical, as their entries are encoded in random order.
+	// the value is re-encoded in JSON, whose map keys are sorted
+	var v interface{}
+	if err := row.DecodeValue(&v); err == nil {
+		if canonical, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v); err == nil {
+			return row.Key + "\x00" + string(canonical)
+		}
+	}
+	return row.Key + "\x00" + string(row.Value)
+}
+
+// distinctKeyTransformation wraps the rows into the rows keyed by the hash of their RowKey.
+type distinctKeyTransformation struct {
+	keyer RowKeyer
+}
+
+func (d *distinctKeyTransformation) Apply(_ transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	for row := range in {
+		h := fnv.New128a()
+		_, _ = h.Write([]byte(d.keyer.RowKey(row)))
+
+		raw, err := row.Marshal()
+		if err != nil {
+			return errors.Wrapf(err, "marshal row %s", row.Key)
+		}
+		if err := out.Write(&lrdd.Row{Key: hex.EncodeToString(h.Sum(nil)), Value: raw}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *distinctKeyTransformation) MarshalJSON() ([]byte, error) {
+	return serialization.SerializeStruct(d.keyer)
+}
+
+func (d *distinctKeyTransformation) UnmarshalJSON(data []byte) error {
+	keyer, err := serialization.DeserializeStruct(data)
+	if err != nil {
+		return err
+	}
+	d.keyer = keyer.(RowKeyer)
+	return nil
+}
+
+// distinctTransformation unwraps the first row of each hash.
+type distinctTransformation struct{}
+
+func (d *distinctTransformation) Apply(_ transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	seen := make(map[string]struct{})
+	for wrapped := range in {
+		if _, ok := seen[wrapped.Key]; ok {
+			continue
+		}
+		seen[wrapped.Key] = struct{}{}
+
+		row := new(lrdd.Row)
+		if err := row.Unmarshal(wrapped.Value); err != nil {
+			return errors.Wrap(err, "unmarshal row")
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
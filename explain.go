@@ -0,0 +1,111 @@
+package lrmr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ab180/lrmr/internal/util"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/pkg/errors"
+)
+
+// DAG is a plan of the job running a dataset, which is a chain of its stages.
+type DAG struct {
+	// Input is the type name of the input of the dataset.
+	Input string `json:"input"`
+
+	// EstimatedInputBytes is the estimated size of the input which the number of partitions is chosen from,
+	// if it is enabled by WithAdaptivePartitions.
+	EstimatedInputBytes int64 `json:"estimatedInputBytes,omitempty"`
+
+	Stages []DAGStage `json:"stages"`
+}
+
+// DAGStage is a planned stage in DAG.
+type DAGStage struct {
+	Name string `json:"name"`
+
+	// Partitions is the expected number of the partitions (i.e. tasks) of the stage.
+	Partitions int `json:"partitions"`
+
+	// Nodes is the expected number of the nodes which the partitions are assigned to.
+	Nodes int `json:"nodes"`
+
+	// OutputPartitioner is the type name of the partitioner distributing the output of the stage
+	// to the next stage. It is empty on the last stage.
+	OutputPartitioner string `json:"outputPartitioner,omitempty"`
+
+	// Shuffle indicates that the output of the stage is repartitioned to the next stage,
+	// rather than being preserved in the same partition (i.e. by PreservePartitioner).
+	Shuffle bool `json:"shuffle"`
+}
+
+// Explain plans the job running given dataset without executing it. The partitions are planned on
+// the workers available now, so the counts can differ from the ones of an actual run if the cluster changes.
+// The upstream of the inputs run as separate jobs, such as Cache, Barrier or Checkpoint, is not included.
+func (s *Session) Explain(ds *Dataset) (*DAG, error) {
+	plans := ds.physicalPlans()
+	inputSize, err := s.adaptPartitions(ds, plans)
+	if err != nil {
+		return nil, err
+	}
+	pp, assignments, err := s.master.PlanJob(s.ctx, plans, s.createJobOptions()...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "plan job")
+	}
+	dag := &DAG{Input: util.NameOfType(ds.input)}
+	if inputSize >= 0 {
+		dag.EstimatedInputBytes = inputSize
+	}
+	for i, st := range ds.stages {
+		hosts := make(map[string]struct{})
+		for _, a := range assignments[i] {
+			hosts[a.Host] = struct{}{}
+		}
+		planned := DAGStage{
+			Name:       st.Name,
+			Partitions: len(pp[i].Partitions),
+			Nodes:      len(hosts),
+		}
+		if i < len(pp)-1 {
+			planned.OutputPartitioner = util.NameOfType(partitions.UnwrapPartitioner(pp[i].Partitioner))
+			planned.Shuffle = !partitions.IsPreserved(pp[i].Partitioner)
+		}
+		dag.Stages = append(dag.Stages, planned)
+	}
+	return dag, nil
+}
+
+// ExplainDAG returns the plan of the job running given dataset in DOT (Graphviz) format.
+// See Explain for details, and DAG for the plan in JSON.
+func (s *Session) ExplainDAG(ds *Dataset) (string, error) {
+	dag, err := s.Explain(ds)
+	if err != nil {
+		return "", err
+	}
+	return dag.DOT(), nil
+}
+
+// DOT renders the DAG in DOT (Graphviz) format. Shuffles are drawn with bold edges,
+// and preserved partitions with dashed edges.
+func (d *DAG) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph lrmr {\n")
+	b.WriteString("  node [shape=box];\n")
+	for i, st := range d.Stages {
+		label := fmt.Sprintf("%s\\n%d partition(s) on %d node(s)", st.Name, st.Partitions, st.Nodes)
+		if i == 0 {
+			label = fmt.Sprintf("%s\\n%s", st.Name, d.Input)
+		}
+		fmt.Fprintf(&b, "  %q [label=\"%s\"];\n", st.Name, label)
+	}
+	for i, st := range d.Stages[:len(d.Stages)-1] {
+		style := "dashed"
+		if st.Shuffle {
+			style = "bold"
+		}
+		fmt.Fprintf(&b, "  %q -> %q [label=%q, style=%s];\n", st.Name, d.Stages[i+1].Name, st.OutputPartitioner, style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
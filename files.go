@@ -0,0 +1,203 @@
+package lrmr
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+type FileOptions struct {
+	// Compression compresses the files. Defaults to the compression of the session (see WithCompression).
+	Compression output.Compression
+
+	// CompressionLevel is the level of the compression. Defaults to output.DefaultLevel.
+	CompressionLevel int
+}
+
+type FileOption func(o *FileOptions)
+
+// WithFileCompression compresses the files with given compression and level,
+// overriding the compression of the session.
+func WithFileCompression(c output.Compression, level int) FileOption {
+	return func(o *FileOptions) {
+		o.Compression = c
+		o.CompressionLevel = level
+	}
+}
+
+// WriteFiles runs the job writing the rows in each partition to a file in given directory on the worker,
+// named "part-<partition ID>", and returns the paths of the written files. Unlike Collect, the rows are
+// not gathered in the memory of the master, so it is suitable for large results.
+//
+// The rows are written by lrdd.RowEncoder with values encoded by lrdd.DefaultCodec, and compressed by
+// the compression of the files. The compression is recorded in the FileManifest written next to each file,
+// so the files can be read back by OpenFile and lrdd.DecodeRows. A file is written under a temporary name and
+// renamed after the input of the task ends, so a failed or aborted task leaves no partial file.
+// The compression is validated before the job runs.
+func (d *Dataset) WriteFiles(dir string, opts ...FileOption) ([]string, error) {
+	o := FileOptions{Compression: d.session.options.Compression}
+	for _, optFn := range opts {
+		optFn(&o)
+	}
+	if err := o.Compression.ValidateFile(o.CompressionLevel); err != nil {
+		return nil, errors.WithMessage(err, "invalid compression of files")
+	}
+	w := &fileWriter{Dir: dir, Compression: o.Compression, CompressionLevel: o.CompressionLevel}
+	d.addStage(d.stageName(w), w)
+
+	rows, err := d.Collect()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(rows))
+	for i, row := range rows {
+		if err := row.DecodeValue(&paths[i]); err != nil {
+			return nil, errors.Wrap(err, "decode path")
+		}
+	}
+	return paths, nil
+}
+
+// FileManifest describes a file written by WriteFiles. It is written next to the file, in the path
+// returned by ManifestPathOf.
+type FileManifest struct {
+	Compression      output.Compression `json:"compression"`
+	CompressionLevel int                `json:"compressionLevel,omitempty"`
+	Rows             int                `json:"rows"`
+}
+
+// ManifestPathOf returns the path of the manifest of the file written by WriteFiles.
+func ManifestPathOf(path string) string {
+	return path + ".manifest"
+}
+
+// ReadFileManifest reads the manifest of the file written by WriteFiles.
+func ReadFileManifest(path string) (*FileManifest, error) {
+	data, err := ioutil.ReadFile(ManifestPathOf(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "read manifest of %s", path)
+	}
+	m := new(FileManifest)
+	if err := jsoniter.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrapf(err, "parse manifest of %s", path)
+	}
+	return m, nil
+}
+
+// OpenFile opens the file written by WriteFiles, decompressed by the compression recorded in its manifest.
+// The rows can be read by lrdd.DecodeRows.
+func OpenFile(path string) (io.ReadCloser, error) {
+	m, err := ReadFileManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := m.Compression.NewReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "decompress %s", path)
+	}
+	return &compressedFile{ReadCloser: r, file: f}, nil
+}
+
+// compressedFile closes the file along with its decompressor.
+type compressedFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (c *compressedFile) Close() error {
+	c.ReadCloser.Close()
+	return c.file.Close()
+}
+
+// fileWriter writes the rows to a file with its manifest, and emits its path after the file is completed.
+type fileWriter struct {
+	Dir              string
+	Compression      output.Compression
+	CompressionLevel int
+}
+
+func (w *fileWriter) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	if err := os.MkdirAll(w.Dir, 0755); err != nil {
+		return errors.Wrapf(err, "create directory %s", w.Dir)
+	}
+	name := "part-" + url.PathEscape(ctx.PartitionID())
+	path := filepath.Join(w.Dir, name)
+	tmpPath := filepath.Join(w.Dir, "."+name+".tmp")
+
+	m, err := w.write(ctx, tmpPath, in)
+	if err == nil {
+		// the manifest is written first, so that a completed file always has its manifest
+		err = writeFileManifest(path, m)
+	}
+	if err != nil {
+		if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+			log.Warn("Failed to remove partial file {}: {}", tmpPath, err)
+		}
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return errors.Wrapf(err, "rename %s", tmpPath)
+	}
+	return out.Write(lrdd.Value(path))
+}
+
+func (w *fileWriter) write(ctx transformation.Context, path string, in chan *lrdd.Row) (*FileManifest, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create %s", path)
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	cw, err := w.Compression.NewWriter(bw, w.CompressionLevel)
+	if err != nil {
+		return nil, errors.Wrapf(err, "compress %s", path)
+	}
+	m := &FileManifest{Compression: w.Compression, CompressionLevel: w.CompressionLevel}
+	enc := lrdd.NewRowEncoder(cw)
+	for row := range in {
+		if err := enc.Encode(row); err != nil {
+			return nil, errors.Wrapf(err, "write %s", path)
+		}
+		m.Rows++
+	}
+	// the input also ends when the task is aborted
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := cw.Close(); err != nil {
+		return nil, errors.Wrapf(err, "write %s", path)
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, errors.Wrapf(err, "write %s", path)
+	}
+	if err := f.Close(); err != nil {
+		return nil, errors.Wrapf(err, "close %s", path)
+	}
+	return m, nil
+}
+
+func writeFileManifest(path string, m *FileManifest) error {
+	data, err := jsoniter.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "encode manifest")
+	}
+	if err := ioutil.WriteFile(ManifestPathOf(path), data, 0644); err != nil {
+		return errors.Wrapf(err, "write manifest of %s", path)
+	}
+	return nil
+}
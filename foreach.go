@@ -0,0 +1,54 @@
+package lrmr
+
+import (
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+)
+
+// Foreacher runs a side effect on each row, e.g. writing it to a database.
+type Foreacher interface {
+	Foreach(Context, *lrdd.Row) error
+}
+
+// Foreach runs the job calling f on each row of the dataset, and waits for the job to complete.
+// See Session.Foreach for details.
+func (d *Dataset) Foreach(f Foreacher) error {
+	return d.session.Foreach(d, f)
+}
+
+// Foreach runs the dataset calling f on each row in the tasks of the last stage on the workers.
+// Unlike Collect, the rows are not sent to the master, and only the success or failure of the tasks
+// is reported. An error returned by f aborts the task, and fails the job with the error.
+func (s *Session) Foreach(ds *Dataset, f Foreacher) error {
+	ds.addStage(ds.stageName(f), &foreachTransformation{f})
+	_, err := s.runWithJobRetries(ds, (*RunningJob).Wait)
+	return err
+}
+
+type foreachTransformation struct {
+	foreacher Foreacher
+}
+
+func (f *foreachTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, _ output.Output) error {
+	for row := range in {
+		if err := f.foreacher.Foreach(ctx, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *foreachTransformation) MarshalJSON() ([]byte, error) {
+	return serialization.SerializeStruct(f.foreacher)
+}
+
+func (f *foreachTransformation) UnmarshalJSON(data []byte) error {
+	foreacher, err := serialization.DeserializeStruct(data)
+	if err != nil {
+		return err
+	}
+	f.foreacher = foreacher.(Foreacher)
+	return nil
+}
@@ -0,0 +1,164 @@
+package lrmr
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+
+	"github.com/ab180/lrmr/lrdd"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// funcs is a mapping of the name of a function to the function registered by RegisterFuncs.
+var funcs sync.Map
+
+// RegisterFuncs registers the functions used in the stages (e.g. by Dataset.MapFunc), so that the workers
+// can find them by their names. Like RegisterTypes, it should be called in the package declaring them:
+//
+//	var _ = lrmr.RegisterFuncs(parseEvent)
+//
+// Since only the names of the functions are sent to the workers, closures capturing variables run with
+// the variables captured at the registration, not the ones captured when the stage is added.
+func RegisterFuncs(fns ...interface{}) interface{} {
+	for _, fn := range fns {
+		funcs.Store(funcName(fn), fn)
+	}
+	return nil
+}
+
+// funcName returns the fully qualified name of the function, which is the same in every process
+// running the same binary.
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		panic("not a function: " + v.Type().String())
+	}
+	return runtime.FuncForPC(v.Pointer()).Name()
+}
+
+func lookupFunc(name string) (interface{}, error) {
+	fn, ok := funcs.Load(name)
+	if !ok {
+		return nil, errors.Errorf("function %s is not registered; register it with lrmr.RegisterFuncs", name)
+	}
+	return fn, nil
+}
+
+// MapFunc maps a row to another row. Returning a nil row drops the input row.
+type MapFunc func(*lrdd.Row) (*lrdd.Row, error)
+
+// MapFunc maps each row with given function, dropping the rows for which the function returns nil.
+// An error returned by the function fails the task. The function must be registered with RegisterFuncs.
+func (d *Dataset) MapFunc(fn MapFunc) *Dataset {
+	d.addStage(d.stageName(fn), &flatMapTransformation{&funcMapper{Func: funcName(fn), fn: fn}})
+	return d
+}
+
+// funcMapper is a FlatMapper calling a registered MapFunc.
+type funcMapper struct {
+	Func string
+
+	fn MapFunc
+}
+
+func (f *funcMapper) FlatMap(_ Context, row *lrdd.Row) ([]*lrdd.Row, error) {
+	outRow, err := f.fn(row)
+	if err != nil || outRow == nil {
+		return nil, err
+	}
+	return []*lrdd.Row{outRow}, nil
+}
+
+// MarshalJSON fails if the function is not registered, so that the stage fails on planning
+// instead of on the workers.
+func (f *funcMapper) MarshalJSON() ([]byte, error) {
+	return marshalFunc(f.Func)
+}
+
+// UnmarshalJSON resolves the function by its name, so that a function not registered in the worker
+// fails creating the task instead of running it.
+func (f *funcMapper) UnmarshalJSON(data []byte) error {
+	name, fn, err := unmarshalFunc(data)
+	if err != nil {
+		return err
+	}
+	switch fn := fn.(type) {
+	case MapFunc:
+		f.fn = fn
+	case func(*lrdd.Row) (*lrdd.Row, error):
+		f.fn = fn
+	default:
+		return errors.Errorf("function %s is not a MapFunc", name)
+	}
+	f.Func = name
+	return nil
+}
+
+// FilterFunc reports whether a row should be kept.
+type FilterFunc func(*lrdd.Row) bool
+
+// FilterFunc keeps only the rows for which given function returns true, without adding a shuffle
+// like Filter. The function must be registered with RegisterFuncs, and should be deterministic
+// so that a retried task outputs the same rows.
+func (d *Dataset) FilterFunc(fn FilterFunc) *Dataset {
+	d.preserveLastPlan()
+	d.addStage(d.stageName(fn), &filterTransformation{&funcFilter{Func: funcName(fn), fn: fn}})
+	return d
+}
+
+// funcFilter is a Filter calling a registered FilterFunc.
+type funcFilter struct {
+	Func string
+
+	fn FilterFunc
+}
+
+func (f *funcFilter) Filter(row *lrdd.Row) bool {
+	return f.fn(row)
+}
+
+func (f *funcFilter) MarshalJSON() ([]byte, error) {
+	return marshalFunc(f.Func)
+}
+
+func (f *funcFilter) UnmarshalJSON(data []byte) error {
+	name, fn, err := unmarshalFunc(data)
+	if err != nil {
+		return err
+	}
+	switch fn := fn.(type) {
+	case FilterFunc:
+		f.fn = fn
+	case func(*lrdd.Row) bool:
+		f.fn = fn
+	default:
+		return errors.Errorf("function %s is not a FilterFunc", name)
+	}
+	f.Func = name
+	return nil
+}
+
+func marshalFunc(name string) ([]byte, error) {
+	if _, err := lookupFunc(name); err != nil {
+		return nil, err
+	}
+	return jsoniter.Marshal(funcDesc{Func: name})
+}
+
+// unmarshalFunc decodes the name of a function encoded by marshalFunc, and looks up the function.
+func unmarshalFunc(data []byte) (name string, fn interface{}, err error) {
+	var desc funcDesc
+	if err := jsoniter.Unmarshal(data, &desc); err != nil {
+		return "", nil, err
+	}
+	fn, err = lookupFunc(desc.Func)
+	if err != nil {
+		return "", nil, err
+	}
+	return desc.Func, fn, nil
+}
+
+type funcDesc struct {
+	Func string
+}
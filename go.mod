@@ -3,23 +3,34 @@ module github.com/ab180/lrmr
 go 1.14
 
 require (
+	github.com/Shopify/sarama v1.27.2
 	github.com/airbloc/logger v1.4.5
+	github.com/aws/aws-sdk-go v1.36.33
 	github.com/creasty/defaults v1.3.0
+	github.com/go-redis/redis/v7 v7.4.0
 	github.com/gogo/protobuf v1.3.1
 	github.com/golang/protobuf v1.3.5
 	github.com/goombaio/namegenerator v0.0.0-20181006234301-989e774b106e
 	github.com/grpc-ecosystem/go-grpc-middleware v1.2.0
+	github.com/hashicorp/consul/api v1.8.1
 	github.com/jinzhu/copier v0.0.0-20190924061706-b57f9002281a
 	github.com/json-iterator/go v1.1.9
+	github.com/klauspost/compress v1.11.0
+	github.com/linkedin/goavro/v2 v2.10.1
 	github.com/maruel/panicparse v1.5.0 // indirect
 	github.com/modern-go/reflect2 v1.0.1
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.5.1
 	github.com/segmentio/fasthash v1.0.1
 	github.com/smartystreets/goconvey v1.6.4
 	github.com/thoas/go-funk v0.5.0
 	github.com/vmihailenco/msgpack/v5 v5.0.0-beta.1
+	github.com/xitongsys/parquet-go v1.5.4
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
 	go.etcd.io/etcd/api/v3 v3.0.0-20201026174226-7da5182f1d02
 	go.etcd.io/etcd/client/v3 v3.0.0-20201026174226-7da5182f1d02
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
 	go.uber.org/atomic v1.6.0
 	go.uber.org/goleak v1.1.10
 	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
@@ -3,10 +3,13 @@ package lrmr
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/ab180/lrmr/lrdd"
 	"github.com/ab180/lrmr/output"
 	"github.com/ab180/lrmr/partitions"
+	"github.com/pkg/errors"
+	"github.com/segmentio/fasthash/fnv1a"
 )
 
 type InputProvider interface {
@@ -14,13 +17,74 @@ type InputProvider interface {
 	FeedInput(out output.Output) error
 }
 
+// inputCommitter is an input which commits its progress after the job reading it succeeds,
+// so that the next job reading the same input resumes from it.
+type inputCommitter interface {
+	CommitInput() error
+}
+
+// inputReleaser is an input holding resources until the job reading it completes, successfully or not.
+type inputReleaser interface {
+	ReleaseInput()
+}
+
+// FileSplitStrategy decides the partitions which the files read by FromFile are assigned to.
+// Both strategies assign the same files to the same partition IDs across the runs.
+type FileSplitStrategy int
+
+const (
+	// SplitRoundRobin assigns the files to the partitions in turn, in lexical order of their paths.
+	// It balances the number of files in each partition, but adding or removing a file moves
+	// the files after it to other partitions.
+	SplitRoundRobin FileSplitStrategy = iota
+
+	// SplitByPathHash assigns each file to the partition given by the hash of its path, so a file
+	// stays in the same partition regardless of the other files.
+	SplitByPathHash
+)
+
+type FileInputOptions struct {
+	// Partitions is the number of partitions which the files are split into.
+	// Defaults to the number of executors available to the first stage.
+	Partitions int
+
+	// Split is the strategy assigning the files to the partitions. Defaults to SplitRoundRobin.
+	Split FileSplitStrategy
+}
+
+type FileInputOption func(o *FileInputOptions)
+
+// WithInputPartitions splits the files into given number of partitions,
+// regardless of the number of executors in the cluster.
+func WithInputPartitions(n int) FileInputOption {
+	return func(o *FileInputOptions) {
+		o.Partitions = n
+	}
+}
+
+// WithFileSplit sets the strategy assigning the files to the partitions.
+func WithFileSplit(s FileSplitStrategy) FileInputOption {
+	return func(o *FileInputOptions) {
+		o.Split = s
+	}
+}
+
+// localInput emits the paths of the files under Path, assigning them to the partitions by Split.
 type localInput struct {
-	partitions.ShuffledPartitioner
-	Path string
+	Path       string
+	Partitions int
+	Split      FileSplitStrategy
+
+	// currentSlot is the partition of the next file in SplitRoundRobin, reset by each FeedInput.
+	currentSlot int
 }
 
-func (l localInput) FeedInput(out output.Output) error {
+func (l *localInput) FeedInput(out output.Output) error {
+	l.currentSlot = 0
 	return filepath.Walk(l.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 		if info.IsDir() {
 			return nil
 		}
@@ -28,6 +92,30 @@ func (l localInput) FeedInput(out output.Output) error {
 	})
 }
 
+func (l *localInput) PlanNext(numExecutors int) []partitions.Partition {
+	if l.Partitions > 0 {
+		return partitions.PlanForNumberOf(l.Partitions)
+	}
+	return partitions.PlanForNumberOf(numExecutors)
+}
+
+func (l *localInput) DeterminePartition(_ partitions.Context, r *lrdd.Row, numOutputs int) (id string, err error) {
+	switch l.Split {
+	case SplitRoundRobin:
+		slot := l.currentSlot % numOutputs
+		l.currentSlot++
+		return strconv.Itoa(slot), nil
+	case SplitByPathHash:
+		var path string
+		if err := r.DecodeValue(&path); err != nil {
+			return "", errors.Wrap(err, "decode path")
+		}
+		return strconv.FormatUint(fnv1a.HashString64(path)%uint64(numOutputs), 10), nil
+	default:
+		return "", errors.Errorf("unknown file split strategy %d", l.Split)
+	}
+}
+
 type parallelizedInput struct {
 	partitions.ShuffledPartitioner
 	data []*lrdd.Row
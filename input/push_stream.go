@@ -1,15 +1,21 @@
 package input
 
 import (
+	"bytes"
 	"context"
 	"io"
 
 	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/lrdd"
 	"github.com/ab180/lrmr/lrmrpb"
 	"github.com/airbloc/logger"
 	"github.com/pkg/errors"
 )
 
+// streamChunkLength is the number of rows decoded from an encoded batch (see output.StreamCodec)
+// before queueing them to the reader.
+const streamChunkLength = 100
+
 type PushStream struct {
 	stream lrmrpb.Node_PushDataServer
 	reader *Reader
@@ -22,9 +28,11 @@ func NewPushStream(r *Reader, stream lrmrpb.Node_PushDataServer) *PushStream {
 	}
 }
 
+// Dispatch writes the rows received from the stream to the reader until the upstream closes the stream.
+// The input is done only if the stream is closed by the upstream; a broken stream (e.g. a crashed upstream)
+// leaves the input open, so that the task is not completed with partial inputs.
 func (p *PushStream) Dispatch(ctx context.Context) error {
 	p.reader.Add(p)
-	defer p.reader.Done()
 
 	errChan := make(chan error)
 	go func() {
@@ -39,13 +47,21 @@ func (p *PushStream) Dispatch(ctx context.Context) error {
 				errChan <- err
 				return
 			}
-			p.reader.C <- req.Data
+			if req.Encoded != nil {
+				if err := p.writeEncoded(req.Encoded); err != nil {
+					errChan <- err
+					return
+				}
+				continue
+			}
+			p.reader.Write(req.Data)
 		}
 	}()
 
 	select {
 	case err := <-errChan:
-		if err == io.EOF || err == context.Canceled {
+		if err == io.EOF {
+			p.reader.Done()
 			return nil
 		}
 		return errors.Wrap(err, "stream dispatch")
@@ -54,6 +70,24 @@ func (p *PushStream) Dispatch(ctx context.Context) error {
 	}
 }
 
+// writeEncoded decodes the rows of an encoded batch one by one, and writes them to the reader in chunks,
+// so that only the rows up to the limits of the input queue are held in memory at once.
+func (p *PushStream) writeEncoded(encoded []byte) error {
+	next, decodeErr := lrdd.DecodeRows(bytes.NewReader(encoded))
+	chunk := make([]*lrdd.Row, 0, streamChunkLength)
+	for row, ok := next(); ok; row, ok = next() {
+		chunk = append(chunk, row)
+		if len(chunk) == streamChunkLength {
+			p.reader.Write(chunk)
+			chunk = make([]*lrdd.Row, 0, streamChunkLength)
+		}
+	}
+	if len(chunk) > 0 {
+		p.reader.Write(chunk)
+	}
+	return errors.Wrap(decodeErr(), "decode rows")
+}
+
 func (p *PushStream) CloseWithStatus(st job.Status) error {
 	return p.stream.SendMsg(st)
 }
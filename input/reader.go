@@ -14,12 +14,122 @@ type Reader struct {
 	lock      sync.RWMutex
 	activeCnt atomic.Int64
 	closed    atomic.Bool
+	done      chan struct{}
+
+	// maxQueuedRows and maxQueuedBytes bound the rows written but not consumed yet.
+	maxQueuedRows  int
+	maxQueuedBytes int64
+	queuedRows     int
+	queuedBytes    int64
+	queueCond      *sync.Cond
+
+	full     chan struct{}
+	fullOnce sync.Once
 }
 
-func NewReader(queueLen int) *Reader {
+// NewReader creates a Reader queueing up to queueLen batches, maxQueuedRows rows and maxQueuedBytes bytes.
+// The writers (i.e. the streams from the upstream tasks) keep receiving the batches ahead of the consumer
+// until one of the limits is reached, so that the network transfer overlaps with the processing.
+// Rows and bytes are unbounded if their limits are not positive.
+func NewReader(queueLen, maxQueuedRows int, maxQueuedBytes int64) *Reader {
 	return &Reader{
-		C: make(chan []*lrdd.Row, queueLen),
+		C:              make(chan []*lrdd.Row, queueLen),
+		maxQueuedRows:  maxQueuedRows,
+		maxQueuedBytes: maxQueuedBytes,
+		queueCond:      sync.NewCond(new(sync.Mutex)),
+		full:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+}
+
+// Write queues the rows to C. It blocks while the queue is full, so that a slow consumer
+// throttles the writers. A batch larger than the limits is queued after the queue is drained.
+// Once the reader is closed, Write returns without queueing the rows.
+func (p *Reader) Write(rows []*lrdd.Row) {
+	// C is closed only after the writers in flight return
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed.Load() {
+		return
+	}
+	if p.bounded() {
+		size := batchSize(rows)
+		p.queueCond.L.Lock()
+		for !p.closed.Load() && p.queuedRows > 0 && p.exceeds(len(rows), size) {
+			p.markFull()
+			p.queueCond.Wait()
+		}
+		if p.closed.Load() {
+			p.queueCond.L.Unlock()
+			return
+		}
+		p.queuedRows += len(rows)
+		p.queuedBytes += size
+		p.queueCond.L.Unlock()
+	}
+	select {
+	case p.C <- rows:
+	default:
+		p.markFull()
+		select {
+		case p.C <- rows:
+		case <-p.done:
+		}
+	}
+}
+
+// Full returns a channel which is closed once a writer is blocked by the full queue,
+// i.e. the upstream is waiting for the consumer to make progress.
+func (p *Reader) Full() <-chan struct{} {
+	return p.full
+}
+
+func (p *Reader) markFull() {
+	p.fullOnce.Do(func() { close(p.full) })
+}
+
+func (p *Reader) bounded() bool {
+	return p.maxQueuedRows > 0 || p.maxQueuedBytes > 0
+}
+
+// exceeds returns true if queueing given rows exceeds the limits. It should be called with the lock held.
+func (p *Reader) exceeds(rows int, bytes int64) bool {
+	return (p.maxQueuedRows > 0 && p.queuedRows+rows > p.maxQueuedRows) ||
+		(p.maxQueuedBytes > 0 && p.queuedBytes+bytes > p.maxQueuedBytes)
+}
+
+func batchSize(rows []*lrdd.Row) (size int64) {
+	for _, r := range rows {
+		size += int64(r.Size())
+	}
+	return size
+}
+
+// Consumed notifies that the batch taken from C is consumed, allowing writers to queue more rows.
+func (p *Reader) Consumed(rows []*lrdd.Row) {
+	if !p.bounded() {
+		return
 	}
+	size := batchSize(rows)
+	p.queueCond.L.Lock()
+	p.queuedRows -= len(rows)
+	p.queuedBytes -= size
+	p.queueCond.L.Unlock()
+	p.queueCond.Broadcast()
+}
+
+// QueuedRows returns the number of rows written but not consumed yet.
+func (p *Reader) QueuedRows() int {
+	p.queueCond.L.Lock()
+	defer p.queueCond.L.Unlock()
+	return p.queuedRows
+}
+
+// QueuedBytes returns the size of the rows written but not consumed yet.
+func (p *Reader) QueuedBytes() int64 {
+	p.queueCond.L.Lock()
+	defer p.queueCond.L.Unlock()
+	return p.queuedBytes
 }
 
 func (p *Reader) Add(in Input) {
@@ -37,12 +147,21 @@ func (p *Reader) Done() {
 	}
 }
 
+// Close closes C after the inputs are done, or to stop the inputs of an aborted task.
+// The writers blocked by the full queue return without queueing their rows.
 func (p *Reader) Close() {
 	if swapped := p.closed.CAS(false, true); !swapped {
 		// p.closed was true
 		return
 	}
 	// with CAS, only one goroutines can enter here
+	close(p.done)
+	p.queueCond.L.Lock()
+	p.queueCond.Broadcast()
+	p.queueCond.L.Unlock()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
 	close(p.C)
 	p.inputs = nil
 }
@@ -0,0 +1,175 @@
+package input
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/lrdd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReader_Write(t *testing.T) {
+	Convey("Given a reader bounded to 10 rows", t, func() {
+		r := NewReader(1000, 10, 0)
+
+		Convey("When a writer is faster than a slow consumer", func() {
+			written := make(chan struct{})
+			go func() {
+				defer close(written)
+				for i := 0; i < 20; i++ {
+					r.Write(make([]*lrdd.Row, 5))
+				}
+			}()
+
+			Convey("It should keep the queued rows bounded", func() {
+				consumed := 0
+				for consumed < 100 {
+					time.Sleep(time.Millisecond)
+					So(r.QueuedRows(), ShouldBeLessThanOrEqualTo, 10)
+
+					rows := <-r.C
+					r.Consumed(rows)
+					consumed += len(rows)
+				}
+				<-written
+				So(r.QueuedRows(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When a writer is blocked by the full queue", func() {
+			r.Write(make([]*lrdd.Row, 10))
+			So(closedWithin(r.Full(), 0), ShouldBeFalse)
+			go r.Write(make([]*lrdd.Row, 1))
+
+			Convey("It should notify that the queue is full", func() {
+				So(closedWithin(r.Full(), time.Second), ShouldBeTrue)
+				r.Consumed(<-r.C)
+			})
+		})
+
+		Convey("When a batch larger than the limit is written", func() {
+			r.Write(make([]*lrdd.Row, 20))
+
+			Convey("It should be queued", func() {
+				So(r.QueuedRows(), ShouldEqual, 20)
+				So(<-r.C, ShouldHaveLength, 20)
+			})
+		})
+
+		Convey("When the reader is closed while a writer is blocked by the full queue", func() {
+			r.Write(make([]*lrdd.Row, 10))
+			written := make(chan struct{})
+			go func() {
+				defer close(written)
+				r.Write(make([]*lrdd.Row, 1))
+			}()
+			So(closedWithin(r.Full(), time.Second), ShouldBeTrue)
+			r.Close()
+
+			Convey("The writer should return", func() {
+				So(closedWithin(written, time.Second), ShouldBeTrue)
+				So(r.QueuedRows(), ShouldEqual, 10)
+			})
+		})
+	})
+
+	Convey("Given a reader with a short queue", t, func() {
+		r := NewReader(1, 0, 0)
+
+		Convey("When the reader is closed while writers are blocked on sending", func() {
+			written := make(chan struct{})
+			go func() {
+				defer close(written)
+				for i := 0; i < 10; i++ {
+					r.Write(make([]*lrdd.Row, 1))
+				}
+			}()
+			So(closedWithin(r.Full(), time.Second), ShouldBeTrue)
+			r.Close()
+
+			Convey("The writers should return, and C should be closed", func() {
+				So(closedWithin(written, time.Second), ShouldBeTrue)
+				<-r.C
+				_, ok := <-r.C
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestReader_MaxQueuedBytes(t *testing.T) {
+	Convey("Given a reader bounded to 100 bytes", t, func() {
+		r := NewReader(1000, 0, 100)
+		batch := []*lrdd.Row{{Value: make([]byte, 30)}}
+		size := int64(batch[0].Size())
+
+		Convey("When a writer is faster than a slow consumer", func() {
+			written := make(chan struct{})
+			go func() {
+				defer close(written)
+				for i := 0; i < 20; i++ {
+					r.Write(batch)
+				}
+			}()
+
+			Convey("It should keep the queued bytes bounded", func() {
+				for consumed := 0; consumed < 20; consumed++ {
+					time.Sleep(time.Millisecond)
+					So(r.QueuedBytes(), ShouldBeLessThanOrEqualTo, 100)
+					So(r.QueuedBytes(), ShouldBeGreaterThanOrEqualTo, size)
+
+					r.Consumed(<-r.C)
+				}
+				<-written
+				So(r.QueuedBytes(), ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+// BenchmarkReader_Prefetch simulates a task receiving batches over a network with occasional delays while
+// processing them. With a short queue, the task idles on the delays instead of processing the batches received ahead.
+func BenchmarkReader_Prefetch(b *testing.B) {
+	const (
+		batchLen   = 100
+		numBatches = 50
+		delayTime  = 5 * time.Millisecond
+		procTime   = time.Millisecond
+	)
+	batch := make([]*lrdd.Row, batchLen)
+	for i := range batch {
+		batch[i] = &lrdd.Row{Key: fmt.Sprint(i), Value: make([]byte, 100)}
+	}
+
+	for _, prefetch := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("Prefetch%d", prefetch), func(b *testing.B) {
+			b.SetBytes(batchSize(batch) * numBatches)
+			for i := 0; i < b.N; i++ {
+				r := NewReader(prefetch, prefetch*batchLen, 0)
+				go func() {
+					for j := 0; j < numBatches; j++ {
+						if j%10 == 0 {
+							time.Sleep(delayTime)
+						}
+						r.Write(batch)
+					}
+					close(r.C)
+				}()
+				for rows := range r.C {
+					time.Sleep(procTime)
+					r.Consumed(rows)
+				}
+			}
+		})
+	}
+}
+
+func closedWithin(c <-chan struct{}, d time.Duration) bool {
+	select {
+	case <-c:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
@@ -0,0 +1,133 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// StructCodec encodes the data of the structs serialized by SerializeStruct.
+// The name of the codec is recorded in the serialized struct, so the nodes decode it with the same codec
+// regardless of their own choice.
+type StructCodec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, ptrToV interface{}) error
+}
+
+var (
+	// JSONCodec encodes the data in JSON. It is the default codec.
+	JSONCodec StructCodec = jsonCodec{}
+
+	// GobCodec encodes the data with encoding/gob, which supports a wider range of Go types
+	// (e.g. maps with non-string keys). Types in the interface fields need to be registered with gob.Register.
+	// The values gob can't encode by themselves, i.e. the structs without exported fields and the types
+	// with their own JSON encoding (e.g. the built-in transformations), are encoded with JSONCodec.
+	GobCodec StructCodec = gobCodec{}
+)
+
+var codecs = map[string]StructCodec{
+	JSONCodec.Name(): JSONCodec,
+	GobCodec.Name():  GobCodec,
+}
+
+// structCodec holds a codecHolder, since atomic.Value requires the values of the same concrete type.
+var structCodec atomic.Value
+
+type codecHolder struct{ StructCodec }
+
+func init() {
+	structCodec.Store(codecHolder{JSONCodec})
+}
+
+// CodecByName returns the codec with given name.
+func CodecByName(name string) (StructCodec, error) {
+	c, ok := codecs[name]
+	if !ok {
+		return nil, errors.Errorf("unknown struct codec: %s", name)
+	}
+	return c, nil
+}
+
+// SetStructCodec sets the codec used by SerializeStruct in this process.
+func SetStructCodec(name string) error {
+	c, err := CodecByName(name)
+	if err != nil {
+		return err
+	}
+	structCodec.Store(codecHolder{c})
+	return nil
+}
+
+func currentStructCodec() StructCodec {
+	return structCodec.Load().(codecHolder).StructCodec
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// codecFor returns the codec encoding given value, which is the one set by SetStructCodec
+// unless the value can only be encoded by JSONCodec.
+func codecFor(v interface{}) StructCodec {
+	codec := currentStructCodec()
+	if codec == JSONCodec {
+		return codec
+	}
+	t := reflect.TypeOf(v)
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return JSONCodec
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && !hasExportedFields(t) {
+		// gob refuses to encode them, while they have no fields to encode anyway
+		return JSONCodec
+	}
+	return codec
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return jsoniter.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, ptrToV interface{}) error {
+	return jsoniter.Unmarshal(data, ptrToV)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, ptrToV interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(ptrToV)
+}
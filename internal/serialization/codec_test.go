@@ -0,0 +1,82 @@
+package serialization
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// statefulMapper has only unexported fields, which gob refuses to encode.
+type statefulMapper struct {
+	count int
+}
+
+var _ = Register(&statefulMapper{})
+
+type gobOnlyStruct struct {
+	Weights map[int]float64
+	Mapper  *registeredMapper
+}
+
+func TestGobCodec(t *testing.T) {
+	Convey("Given the gob codec", t, func() {
+		So(SetStructCodec(GobCodec.Name()), ShouldBeNil)
+		Reset(func() {
+			So(SetStructCodec(JSONCodec.Name()), ShouldBeNil)
+		})
+
+		Convey("Serializing a struct should record the codec", func() {
+			expected := gobOnlyStruct{
+				Weights: map[int]float64{1: 0.5, 2: 1.5},
+				Mapper:  &registeredMapper{Factor: 2},
+			}
+			data, err := SerializeStruct(expected)
+			So(err, ShouldBeNil)
+			So(bytes.Contains(data, []byte(`"@codec":"gob"`)), ShouldBeTrue)
+
+			Convey("It should be tagged with the format version introducing the codecs", func() {
+				So(bytes.Contains(data, []byte(`"@version":2`)), ShouldBeTrue)
+			})
+
+			Convey("It should be same after deserialization", func() {
+				actual, err := DeserializeStruct(data)
+				So(err, ShouldBeNil)
+				So(actual, ShouldResemble, expected)
+			})
+
+			Convey("It should be deserialized with gob even if the node uses another codec", func() {
+				So(SetStructCodec(JSONCodec.Name()), ShouldBeNil)
+				actual, err := DeserializeStruct(data)
+				So(err, ShouldBeNil)
+				So(actual, ShouldResemble, expected)
+			})
+		})
+
+		Convey("Serializing a struct without exported fields should fall back to JSON", func() {
+			expected := &statefulMapper{count: 1}
+			data, err := SerializeStruct(expected)
+			So(err, ShouldBeNil)
+			So(bytes.Contains(data, []byte(`"@codec"`)), ShouldBeFalse)
+			So(bytes.Contains(data, []byte(`"@version":1`)), ShouldBeTrue)
+
+			actual, err := DeserializeStruct(data)
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, &statefulMapper{})
+		})
+
+		Convey("Serializing a nil pointer should be deserialized as nil", func() {
+			var expected *registeredMapper
+			data, err := SerializeStruct(expected)
+			So(err, ShouldBeNil)
+
+			actual, err := DeserializeStruct(data)
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, expected)
+		})
+	})
+
+	Convey("Setting an unknown codec should return an error", t, func() {
+		So(SetStructCodec("xml"), ShouldNotBeNil)
+	})
+}
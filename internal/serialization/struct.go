@@ -2,41 +2,123 @@ package serialization
 
 import (
 	"bytes"
+	"fmt"
 	"reflect"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 )
 
+// FormatVersion is the version of the format serialized by SerializeStruct. It should be increased
+// when the format changes in a way that the nodes running the previous versions can't read.
+// Version 2 added the codecs other than JSONCodec.
+const FormatVersion = 2
+
+// jsonFormatVersion is the version of the data encoded by JSONCodec, which has not changed since version 1
+// so that the nodes not supporting the other codecs can read it.
+const jsonFormatVersion = 1
+
+// MinFormatVersion is the oldest version DeserializeStruct can read. Version 0 is the format
+// before the versioning, which has the same shape as version 1 without the version tag.
+const MinFormatVersion = 0
+
 type StructDesc struct {
-	Type Type        `json:"@type"`
+	Version int    `json:"@version"`
+	Type    Type   `json:"@type"`
+	Codec   string `json:"@codec,omitempty"`
+
+	// Data is the value encoded by the codec. Since the descriptor is JSON, the data encoded by JSONCodec
+	// is embedded as is, and the data encoded by the other codecs is embedded as a base64 string.
 	Data interface{} `json:"data"`
 }
 
+// VersionMismatchError is returned by DeserializeStruct if the data is serialized in a format version
+// not supported by this node, e.g. from the master running a newer version of lrmr during a rolling upgrade.
+type VersionMismatchError struct {
+	Version int
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("unsupported serialization format version %d (supported: %d-%d); "+
+		"the nodes of the cluster may be running incompatible versions of lrmr", e.Version, MinFormatVersion, FormatVersion)
+}
+
+// SerializeStruct serializes given value with its type, encoding the value with the codec set by
+// SetStructCodec. It returns an error if the value is unable to be deserialized on the other nodes;
+// see Validate.
 func SerializeStruct(v interface{}) ([]byte, error) {
 	if v == nil {
 		return jsoniter.Marshal(v)
 	}
-	return jsoniter.Marshal(StructDesc{
-		Type: TypeOf(v),
-		Data: v,
-	})
+	if err := Validate(v); err != nil {
+		return nil, err
+	}
+	desc := StructDesc{
+		Version: jsonFormatVersion,
+		Type:    TypeOf(v),
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return jsoniter.Marshal(desc)
+	}
+	codec := codecFor(v)
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encode %s with %s codec", desc.Type, codec.Name())
+	}
+	if codec == JSONCodec {
+		desc.Data = jsoniter.RawMessage(data)
+	} else {
+		// tagged with the version, so that the nodes not supporting the codec fail with VersionMismatchError
+		desc.Version = FormatVersion
+		desc.Codec = codec.Name()
+		desc.Data = data
+	}
+	return jsoniter.Marshal(desc)
 }
 
+// DeserializeStruct deserializes the value serialized by SerializeStruct. It returns VersionMismatchError
+// if the data is serialized in an unsupported format version.
 func DeserializeStruct(data []byte) (interface{}, error) {
 	if bytes.Equal(data, []byte("null")) {
 		return nil, nil
 	}
 	desc := new(struct {
-		Type Type                `json:"@type"`
-		Data jsoniter.RawMessage `json:"data"`
+		Version int                 `json:"@version"`
+		Type    string              `json:"@type"`
+		Codec   string              `json:"@codec"`
+		Data    jsoniter.RawMessage `json:"data"`
 	})
 	if err := jsoniter.Unmarshal(data, desc); err != nil {
 		return nil, errors.Wrap(err, "deserialize descriptor")
 	}
+	if desc.Version < MinFormatVersion || desc.Version > FormatVersion {
+		return nil, &VersionMismatchError{Version: desc.Version}
+	}
+	typ, err := TypeFromString(desc.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	codec := JSONCodec
+	if desc.Codec != "" {
+		codec, err = CodecByName(desc.Codec)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	v := desc.Type.New()
-	if err := jsoniter.Unmarshal(desc.Data, v); err != nil {
+	v := typ.New()
+	if len(desc.Data) == 0 || bytes.Equal(desc.Data, []byte("null")) {
+		// nil pointer
+		return reflect.ValueOf(v).Elem().Interface(), nil
+	}
+	encoded := []byte(desc.Data)
+	if codec != JSONCodec {
+		if err := jsoniter.Unmarshal(desc.Data, &encoded); err != nil {
+			return nil, errors.Wrapf(err, "deserialize %s data", codec.Name())
+		}
+	}
+	if err := codec.Unmarshal(encoded, v); err != nil {
 		return nil, errors.Wrapf(err, "deserialize struct data %s", string(desc.Data))
 	}
 	return reflect.ValueOf(v).Elem().Interface(), nil
@@ -1,9 +1,12 @@
 package serialization
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/ab180/lrmr/cluster/node"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -87,6 +90,49 @@ func TestSerializeStruct(t *testing.T) {
 	})
 }
 
+func TestDeserializeStruct_Version(t *testing.T) {
+	Convey("Given a struct serialized by SerializeStruct", t, func() {
+		data, err := SerializeStruct(&registeredMapper{Factor: 2})
+		So(err, ShouldBeNil)
+
+		Convey("It should be tagged with the format version", func() {
+			var desc struct {
+				Version int `json:"@version"`
+			}
+			So(jsoniter.Unmarshal(data, &desc), ShouldBeNil)
+			So(desc.Version, ShouldEqual, jsonFormatVersion)
+		})
+
+		Convey("When it's deserialized by a node not supporting its version", func() {
+			data := bytes.Replace(data, []byte(`"@version":1`), []byte(`"@version":3`), 1)
+			_, err := DeserializeStruct(data)
+
+			Convey("It should return VersionMismatchError", func() {
+				So(err, ShouldResemble, &VersionMismatchError{Version: 3})
+			})
+		})
+
+		Convey("When it's serialized by a node before the versioning", func() {
+			data := bytes.Replace(data, []byte(`"@version":1,`), nil, 1)
+			v, err := DeserializeStruct(data)
+
+			Convey("It should be deserialized as version 0", func() {
+				So(err, ShouldBeNil)
+				So(v, ShouldResemble, &registeredMapper{Factor: 2})
+			})
+		})
+	})
+
+	Convey("Given a struct of a type unknown to the node", t, func() {
+		data := []byte(`{"@version":1,"@type":"github.com/example/none.Mapper","data":{}}`)
+
+		Convey("It should return ErrUnresolved instead of panicking", func() {
+			_, err := DeserializeStruct(data)
+			So(errors.Cause(err), ShouldEqual, ErrUnresolved)
+		})
+	})
+}
+
 func serializeAndDeserialize(v interface{}) interface{} {
 	s, err := SerializeStruct(v)
 	if err != nil {
@@ -0,0 +1,127 @@
+package serialization
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/modern-go/reflect2"
+)
+
+// registered is a set of the descriptors of the named types registered by Register.
+var registered sync.Map
+
+// modulePath is the path of this module. Types of the module are always available in the nodes.
+var modulePath = strings.TrimSuffix(reflect.TypeOf(StructDesc{}).PkgPath(), "/internal/serialization")
+
+// resolvable reports whether the named type can be found by its package path and name on this process.
+// It can be replaced in tests.
+var resolvable = func(pkg, name string) bool {
+	return reflect2.TypeByPackageName(pkg, name) != nil
+}
+
+// Register registers the type of given value like TypeOf, and marks it as explicitly registered
+// so that Validate accepts it.
+func Register(v interface{}) Type {
+	t := TypeOf(v)
+	if v != nil {
+		registered.Store(serializeTypeInfo(namedType(reflect.TypeOf(v))), struct{}{})
+	}
+	return t
+}
+
+// UnregisteredTypeError is returned by Validate if the type of a value is neither registered nor resolvable,
+// which means the other nodes would fail to deserialize it.
+type UnregisteredTypeError struct {
+	Type string
+}
+
+func (e *UnregisteredTypeError) Error() string {
+	return fmt.Sprintf("type %s is not registered; register it with lrmr.RegisterTypes in the package "+
+		"declaring it, and make sure the workers import the package", e.Type)
+}
+
+// UnserializableFieldError is returned by Validate if a value has a field which would be lost
+// or fail in serialization, e.g. a function capturing the state of the caller.
+type UnserializableFieldError struct {
+	Type  string
+	Field string
+	Kind  reflect.Kind
+}
+
+func (e *UnserializableFieldError) Error() string {
+	return fmt.Sprintf("field %s of type %s is a %s, which cannot be serialized; use a registered "+
+		"struct type with exported fields instead", e.Field, e.Type, e.Kind)
+}
+
+// Validate checks that given value can be serialized by SerializeStruct and deserialized on the other nodes.
+// It returns UnregisteredTypeError if its type is not registered, or UnserializableFieldError if it has
+// non-nil function or channel fields. Values implementing json.Marshaler are trusted to serialize themselves.
+func Validate(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	typ := reflect.TypeOf(v)
+	if !isRegistered(typ) {
+		return &UnregisteredTypeError{Type: serializeTypeInfo(typ)}
+	}
+	return validateFields(reflect.ValueOf(v), serializeTypeInfo(typ))
+}
+
+func isRegistered(typ reflect.Type) bool {
+	named := namedType(typ)
+	pkg := named.PkgPath()
+	if pkg == "" {
+		// primitives
+		return true
+	}
+	if pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/") {
+		return true
+	}
+	if _, ok := registered.Load(serializeTypeInfo(named)); ok {
+		return true
+	}
+	return resolvable(pkg, named.Name())
+}
+
+// namedType strips the slices and pointers from given type, as serializeTypeInfo does.
+func namedType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Slice || typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func validateFields(v reflect.Value, typeName string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.Type().Implements(marshalerType) || reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		return nil
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field, fv := v.Type().Field(i), v.Field(i)
+		switch fv.Kind() {
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			if !fv.IsNil() {
+				return &UnserializableFieldError{Type: typeName, Field: field.Name, Kind: fv.Kind()}
+			}
+		case reflect.Struct, reflect.Ptr:
+			if field.PkgPath != "" && !field.Anonymous {
+				// unexported fields are not serialized
+				continue
+			}
+			if err := validateFields(fv, typeName); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
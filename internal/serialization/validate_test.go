@@ -0,0 +1,86 @@
+package serialization
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type registeredMapper struct {
+	Factor int
+}
+
+type unregisteredMapper struct {
+	Factor int
+}
+
+type closureMapper struct {
+	Fn func(int) int
+}
+
+type nestedClosureMapper struct {
+	Inner closureMapper
+}
+
+func TestValidate(t *testing.T) {
+	Convey("Given types outside of the module", t, func() {
+		modulePathBackup, resolvableBackup := modulePath, resolvable
+		modulePath = "github.com/example/none"
+		resolvable = func(pkg, name string) bool { return false }
+		Reset(func() {
+			modulePath, resolvable = modulePathBackup, resolvableBackup
+		})
+		Register(&registeredMapper{})
+		Register(closureMapper{})
+		Register(nestedClosureMapper{})
+
+		Convey("A registered type should be valid, regardless of its pointers and slices", func() {
+			So(Validate(registeredMapper{Factor: 2}), ShouldBeNil)
+			So(Validate(&registeredMapper{Factor: 2}), ShouldBeNil)
+			So(Validate([]*registeredMapper{{Factor: 2}}), ShouldBeNil)
+		})
+
+		Convey("Primitives should be valid", func() {
+			So(Validate(nil), ShouldBeNil)
+			So(Validate(1), ShouldBeNil)
+			So(Validate("foo"), ShouldBeNil)
+		})
+
+		Convey("An unregistered type should return an error naming the type", func() {
+			err := Validate(&unregisteredMapper{})
+			So(err, ShouldHaveSameTypeAs, &UnregisteredTypeError{})
+			So(err.(*UnregisteredTypeError).Type, ShouldEqual, "*"+modulePathBackup+"/internal/serialization.unregisteredMapper")
+
+			Convey("SerializeStruct should also fail", func() {
+				_, err := SerializeStruct(&unregisteredMapper{})
+				So(errors.Cause(err), ShouldHaveSameTypeAs, &UnregisteredTypeError{})
+			})
+		})
+
+		Convey("A type with a function should return an error naming the field", func() {
+			err := Validate(closureMapper{Fn: func(i int) int { return i }})
+			So(err, ShouldResemble, &UnserializableFieldError{
+				Type:  modulePathBackup + "/internal/serialization.closureMapper",
+				Field: "Fn",
+				Kind:  reflect.Func,
+			})
+
+			Convey("Even if it's in the nested struct", func() {
+				err := Validate(nestedClosureMapper{Inner: closureMapper{Fn: func(i int) int { return i }}})
+				So(err, ShouldHaveSameTypeAs, &UnserializableFieldError{})
+			})
+
+			Convey("Unless the function is nil", func() {
+				So(Validate(closureMapper{}), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given types of the module", t, func() {
+		Convey("They should be valid without registration", func() {
+			So(Validate(unregisteredMapper{}), ShouldBeNil)
+		})
+	})
+}
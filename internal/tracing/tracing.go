@@ -0,0 +1,64 @@
+// Package tracing creates OpenTelemetry spans of the jobs and the tasks, and propagates them between nodes.
+// Spans are created with the global TracerProvider and propagated with the global TextMapPropagator,
+// so they are exported only if the application sets them (e.g. configured by the standard OTEL_* env vars).
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+const tracerName = "github.com/ab180/lrmr"
+
+// Tracer returns the tracer of lrmr from the global TracerProvider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// InjectMetadata returns a context whose outgoing gRPC metadata carries the span in given context.
+func InjectMetadata(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// ExtractMetadata returns a context having the span carried by the incoming gRPC metadata of given context
+// as the parent, if any.
+func ExtractMetadata(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+}
+
+// metadataCarrier adapts gRPC metadata to propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
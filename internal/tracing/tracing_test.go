@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPropagation(t *testing.T) {
+	Convey("Given a context with a span", t, func() {
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		Reset(func() { otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator()) })
+
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+			TraceFlags: trace.FlagsSampled,
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+		Convey("When it is sent to another node through gRPC metadata", func() {
+			outgoing := InjectMetadata(metadata.AppendToOutgoingContext(ctx, "existing", "value"))
+			md, _ := metadata.FromOutgoingContext(outgoing)
+			received := ExtractMetadata(metadata.NewIncomingContext(context.Background(), md))
+
+			Convey("The span should be the remote parent of the received context", func() {
+				remote := trace.SpanContextFromContext(received)
+				So(remote.IsRemote(), ShouldBeTrue)
+				So(remote.TraceID(), ShouldEqual, sc.TraceID())
+				So(remote.SpanID(), ShouldEqual, sc.SpanID())
+			})
+
+			Convey("The existing metadata should be kept", func() {
+				So(md.Get("existing"), ShouldResemble, []string{"value"})
+			})
+		})
+
+		Convey("When a context without metadata is received", func() {
+			received := ExtractMetadata(context.Background())
+
+			Convey("It should have no span", func() {
+				So(trace.SpanContextFromContext(received).IsValid(), ShouldBeFalse)
+			})
+		})
+	})
+}
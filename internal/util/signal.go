@@ -5,13 +5,13 @@ import (
 	"os"
 	"os/signal"
 
-	"github.com/airbloc/logger"
+	"github.com/ab180/lrmr/logging"
 )
 
 func ContextWithSignal(parent context.Context, sig ...os.Signal) (context.Context, context.CancelFunc) {
-	log := logger.New("lrmr.util")
+	log := logging.New("lrmr.util")
 
-	sigChan := make(chan os.Signal)
+	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, sig...)
 
 	ctx, cancel := context.WithCancel(context.Background())
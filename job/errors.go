@@ -0,0 +1,183 @@
+package job
+
+import "github.com/pkg/errors"
+
+// ErrorKind classifies the cause of a task failure.
+type ErrorKind string
+
+const (
+	// UnknownErrorKind is the kind of the errors not classified by the worker.
+	UnknownErrorKind ErrorKind = ""
+
+	// UserErrorKind is the kind of UserError.
+	UserErrorKind ErrorKind = "user"
+
+	// SerializationErrorKind is the kind of SerializationError.
+	SerializationErrorKind ErrorKind = "serialization"
+
+	// TransportErrorKind is the kind of TransportError.
+	TransportErrorKind ErrorKind = "transport"
+
+	// TimeoutErrorKind is the kind of TimeoutError.
+	TimeoutErrorKind ErrorKind = "timeout"
+
+	// WorkerLostErrorKind is the kind of WorkerLostError.
+	WorkerLostErrorKind ErrorKind = "workerLost"
+)
+
+// The typed errors of the kinds implement both Unwrap and Cause, so that errors.Cause still finds
+// the root cause (e.g. context.Canceled) through them.
+
+// UserError is an error returned by a transformation, or a panic in it.
+type UserError struct {
+	error
+}
+
+// MarkUserError marks the error as an error of the transformation.
+func MarkUserError(err error) error {
+	return &UserError{err}
+}
+
+func (u *UserError) Unwrap() error {
+	return u.error
+}
+
+func (u *UserError) Cause() error {
+	return u.error
+}
+
+// SerializationError is an error on encoding or decoding the rows in the worker, e.g. while spilling them to disk.
+type SerializationError struct {
+	error
+}
+
+// MarkSerializationError marks the error as an error on encoding or decoding the rows.
+func MarkSerializationError(err error) error {
+	return &SerializationError{err}
+}
+
+func (s *SerializationError) Unwrap() error {
+	return s.error
+}
+
+func (s *SerializationError) Cause() error {
+	return s.error
+}
+
+// TransportError is an error on sending the rows to other nodes.
+type TransportError struct {
+	error
+}
+
+// MarkTransportError marks the error as an error on sending the rows.
+func MarkTransportError(err error) error {
+	return &TransportError{err}
+}
+
+func (t *TransportError) Unwrap() error {
+	return t.error
+}
+
+func (t *TransportError) Cause() error {
+	return t.error
+}
+
+// TimeoutError is an error of a task running longer than its timeout.
+type TimeoutError struct {
+	error
+}
+
+// MarkTimeout marks the error as a timeout of the task.
+func MarkTimeout(err error) error {
+	return &TimeoutError{err}
+}
+
+func (t *TimeoutError) Unwrap() error {
+	return t.error
+}
+
+func (t *TimeoutError) Cause() error {
+	return t.error
+}
+
+// WorkerLostError is an error of a task whose worker has left the cluster without finishing it,
+// e.g. due to a crash.
+type WorkerLostError struct {
+	error
+}
+
+// MarkWorkerLost marks the error as a loss of the worker running the task.
+func MarkWorkerLost(err error) error {
+	return &WorkerLostError{err}
+}
+
+func (w *WorkerLostError) Unwrap() error {
+	return w.error
+}
+
+func (w *WorkerLostError) Cause() error {
+	return w.error
+}
+
+// KindOf returns the kind of the error, found in the error or its causes. If the error has several kinds,
+// the outermost one is returned.
+func KindOf(err error) ErrorKind {
+	for ; err != nil; err = unwrap(err) {
+		switch err.(type) {
+		case *UserError:
+			return UserErrorKind
+		case *SerializationError:
+			return SerializationErrorKind
+		case *TransportError:
+			return TransportErrorKind
+		case *TimeoutError:
+			return TimeoutErrorKind
+		case *WorkerLostError:
+			return WorkerLostErrorKind
+		}
+	}
+	return UnknownErrorKind
+}
+
+func unwrap(err error) error {
+	if u := errors.Unwrap(err); u != nil {
+		return u
+	}
+	if c, ok := err.(interface{ Cause() error }); ok {
+		return c.Cause()
+	}
+	return nil
+}
+
+// As finds the typed error of the kind of the failure (e.g. *UserError), so that the error can be
+// tested with errors.As. The found error wraps the Error.
+func (e Error) As(target interface{}) bool {
+	switch t := target.(type) {
+	case **UserError:
+		if e.Kind == UserErrorKind {
+			*t = &UserError{e}
+			return true
+		}
+	case **SerializationError:
+		if e.Kind == SerializationErrorKind {
+			*t = &SerializationError{e}
+			return true
+		}
+	case **TransportError:
+		if e.Kind == TransportErrorKind {
+			*t = &TransportError{e}
+			return true
+		}
+	case **TimeoutError:
+		if e.Kind == TimeoutErrorKind {
+			*t = &TimeoutError{e}
+			return true
+		}
+	case **WorkerLostError:
+		if e.Kind == WorkerLostErrorKind {
+			*t = &WorkerLostError{e}
+			return true
+		}
+	}
+	return false
+}
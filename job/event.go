@@ -0,0 +1,47 @@
+package job
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// EventType is a type of Event.
+type EventType string
+
+const (
+	TaskStarted   EventType = "taskStarted"
+	TaskSucceeded EventType = "taskSucceeded"
+	TaskFailed    EventType = "taskFailed"
+
+	// TaskRetryScheduled is recorded in the job failed by the task, when its stages are going to be run again
+	// by another job.
+	TaskRetryScheduled EventType = "taskRetryScheduled"
+)
+
+// Event is a record of what happened to a task of the job. Unlike logs, events are kept
+// in the cluster state after the job completes, which is useful for investigating failed jobs.
+type Event struct {
+	Type EventType `json:"type"`
+	Task TaskID    `json:"task"`
+	Time time.Time `json:"time"`
+
+	// Metrics is the metrics of the task on TaskSucceeded.
+	Metrics Metrics `json:"metrics,omitempty"`
+
+	// Error is the error failed the task on TaskFailed, or the job on TaskRetryScheduled.
+	Error string `json:"error,omitempty"`
+
+	// Retries is the number of the runs of the stages retried, including the scheduled one on TaskRetryScheduled.
+	Retries int `json:"retries,omitempty"`
+}
+
+func newEvent(typ EventType, task TaskID) Event {
+	return Event{Type: typ, Task: task, Time: time.Now()}
+}
+
+// key returns the key of the event, which is ordered by the time in the job.
+func (e Event) key() string {
+	id := fmt.Sprintf("%020d-%s-%s-%s", e.Time.UnixNano(), e.Type, e.Task.StageName, e.Task.PartitionID)
+	return path.Join(jobEventNs, e.Task.JobID, id)
+}
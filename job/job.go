@@ -3,6 +3,7 @@ package job
 import (
 	"time"
 
+	"github.com/ab180/lrmr/output"
 	"github.com/ab180/lrmr/partitions"
 	"github.com/ab180/lrmr/stage"
 )
@@ -12,7 +13,21 @@ type Job struct {
 	Name        string                   `json:"name"`
 	Stages      []stage.Stage            `json:"stages"`
 	Partitions  []partitions.Assignments `json:"partitions"`
+	Priority    int                      `json:"priority,omitempty"`
+	Compression output.Compression       `json:"compression,omitempty"`
 	SubmittedAt time.Time                `json:"submittedAt"`
+
+	// Retry is set if the job runs its stages again after the previous runs failed.
+	Retry *Retry `json:"retry,omitempty"`
+}
+
+// Retry records the failed runs of the stages before a job running them again.
+type Retry struct {
+	// Count is the number of the failed runs.
+	Count int `json:"count"`
+
+	// LastError is the error failed the last run.
+	LastError string `json:"lastError"`
 }
 
 func (j *Job) GetStage(name string) *stage.Stage {
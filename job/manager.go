@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/ab180/lrmr/cluster"
 	"github.com/ab180/lrmr/coordinator"
 	"github.com/ab180/lrmr/internal/util"
+	"github.com/ab180/lrmr/logging"
+	"github.com/ab180/lrmr/output"
 	"github.com/ab180/lrmr/partitions"
 	"github.com/ab180/lrmr/stage"
-	"github.com/airbloc/logger"
 	"github.com/pkg/errors"
 )
 
@@ -23,28 +27,35 @@ const (
 	taskStatusNs  = "status/tasks/"
 	jobStatusNs   = "status/jobs"
 	jobErrorNs    = "errors/jobs"
+	jobPauseNs    = "pause/jobs"
+	jobStopNs     = "stop/jobs"
+	jobCounterNs  = "counters/jobs"
+	jobEventNs    = "events/jobs"
 )
 
 type Manager struct {
 	clusterState cluster.State
-	log          logger.Logger
+	log          logging.Logger
 }
 
 func NewManager(cs cluster.State) *Manager {
 	return &Manager{
 		clusterState: cs,
-		log:          logger.New("lrmr/job.Manager"),
+		log:          logging.New("lrmr/job.Manager"),
 	}
 }
 
-func (m *Manager) CreateJob(ctx context.Context, name string, stages []stage.Stage, assignments []partitions.Assignments) (*Job, error) {
+func (m *Manager) CreateJob(ctx context.Context, name string, priority int, compression output.Compression, stages []stage.Stage, assignments []partitions.Assignments, retry *Retry) (*Job, error) {
 	js := newStatus()
 	j := &Job{
 		ID:          util.GenerateID("J"),
 		Name:        name,
 		Stages:      stages,
 		Partitions:  assignments,
+		Priority:    priority,
+		Compression: compression,
 		SubmittedAt: js.SubmittedAt,
+		Retry:       retry,
 	}
 	txn := coordinator.NewTxn().
 		Put(path.Join(jobNs, j.ID), j).
@@ -56,7 +67,7 @@ func (m *Manager) CreateJob(ctx context.Context, name string, stages []stage.Sta
 	if _, err := m.clusterState.Commit(ctx, txn); err != nil {
 		return nil, errors.Wrap(err, "etcd write")
 	}
-	m.log.Debug("Job created: {} ({})", j.Name, j.ID)
+	m.log.Verbose("Job created: {} ({})", j.Name, j.ID)
 	return j, nil
 }
 
@@ -101,20 +112,188 @@ func (m *Manager) GetJobErrors(ctx context.Context, jobID string) ([]Error, erro
 	return errs, nil
 }
 
-func (m *Manager) WatchJobErrors(ctx context.Context, jobID string) chan Error {
+func (m *Manager) WatchJobErrors(ctx context.Context, jobID string) (chan Error, error) {
+	events, err := m.clusterState.Watch(ctx, path.Join(jobErrorNs, jobID))
+	if err != nil {
+		return nil, err
+	}
 	errChan := make(chan Error)
 	go func() {
-		for event := range m.clusterState.Watch(ctx, path.Join(jobErrorNs, jobID)) {
+		defer close(errChan)
+		for event := range events {
+			if event.Type != coordinator.PutEvent {
+				continue
+			}
 			var e Error
-			if err := event.Item.Unmarshal(&e); err != nil {
+			if err := event.Decode(&e); err != nil {
 				m.log.Error("Failed to unmarshal error desc {}: {}", err, string(event.Item.Value))
 				continue
 			}
-			errChan <- e
+			select {
+			case errChan <- e:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(errChan)
 	}()
-	return errChan
+	return errChan, nil
+}
+
+// WatchTaskStatuses subscribes the updates of the task statuses in the job, including the ones flushed
+// periodically while the tasks are running.
+func (m *Manager) WatchTaskStatuses(ctx context.Context, jobID string) (chan *TaskStatus, error) {
+	events, err := m.clusterState.Watch(ctx, path.Join(taskStatusNs, jobID)+"/")
+	if err != nil {
+		return nil, err
+	}
+	statusChan := make(chan *TaskStatus)
+	go func() {
+		defer close(statusChan)
+		for event := range events {
+			if event.Type != coordinator.PutEvent {
+				continue
+			}
+			status := new(TaskStatus)
+			if err := event.Decode(status); err != nil {
+				m.log.Error("Failed to unmarshal task status {}: {}", event.Key(), err)
+				continue
+			}
+			select {
+			case statusChan <- status:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return statusChan, nil
+}
+
+// PauseJob marks the job as paused. Tasks of the paused job stop pulling new inputs
+// until the job is resumed, while holding their states and buffers in memory.
+func (m *Manager) PauseJob(ctx context.Context, jobID string) error {
+	return m.clusterState.Put(ctx, path.Join(jobPauseNs, jobID), time.Now())
+}
+
+// ResumeJob resumes the paused job. It does nothing if the job is not paused.
+func (m *Manager) ResumeJob(ctx context.Context, jobID string) error {
+	_, err := m.clusterState.Delete(ctx, path.Join(jobPauseNs, jobID))
+	return err
+}
+
+func (m *Manager) IsJobPaused(ctx context.Context, jobID string) (bool, error) {
+	var pausedAt time.Time
+	if err := m.clusterState.Get(ctx, path.Join(jobPauseNs, jobID), &pausedAt); err != nil {
+		if err == coordinator.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchJobPause subscribes pause and resume of the job. True is sent when the job is paused,
+// and false is sent when the job is resumed.
+func (m *Manager) WatchJobPause(ctx context.Context, jobID string) (chan bool, error) {
+	key := path.Join(jobPauseNs, jobID)
+	events, err := m.clusterState.Watch(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	pauseChan := make(chan bool)
+	go func() {
+		defer close(pauseChan)
+		for event := range events {
+			if event.Key() != key {
+				continue
+			}
+			select {
+			case pauseChan <- event.Type == coordinator.PutEvent:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return pauseChan, nil
+}
+
+// StopInputs makes the tasks of given stage and its preceding stages stop pulling inputs as if their
+// inputs have ended, so that the job finishes early without processing the rest of the inputs.
+func (m *Manager) StopInputs(ctx context.Context, jobID, stageName string) error {
+	return m.clusterState.Put(ctx, path.Join(jobStopNs, jobID, stageName), time.Now())
+}
+
+// ListStoppedStages returns the stages given to StopInputs in the job.
+func (m *Manager) ListStoppedStages(ctx context.Context, jobID string) ([]string, error) {
+	items, err := m.clusterState.Scan(ctx, path.Join(jobStopNs, jobID)+"/")
+	if err != nil {
+		return nil, err
+	}
+	stageNames := make([]string, len(items))
+	for i, item := range items {
+		stageNames[i] = path.Base(item.Key)
+	}
+	return stageNames, nil
+}
+
+// WatchStoppedStages subscribes the stages given to StopInputs in the job.
+func (m *Manager) WatchStoppedStages(ctx context.Context, jobID string) (chan string, error) {
+	prefix := path.Join(jobStopNs, jobID) + "/"
+	events, err := m.clusterState.Watch(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	stopChan := make(chan string)
+	go func() {
+		defer close(stopChan)
+		for event := range events {
+			if event.Type != coordinator.PutEvent {
+				continue
+			}
+			select {
+			case stopChan <- path.Base(event.Key()):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return stopChan, nil
+}
+
+// IncrementJobCounter atomically increments the counter of given name shared by the tasks in the job,
+// and returns the incremented value.
+func (m *Manager) IncrementJobCounter(ctx context.Context, jobID, name string) (int64, error) {
+	return m.clusterState.IncrementCounter(ctx, path.Join(jobCounterNs, jobID, name))
+}
+
+// RecordRetry records TaskRetryScheduled in the job failed by given error, whose stages are going to be
+// run again for the retries-th time.
+func (m *Manager) RecordRetry(ctx context.Context, jobErr Error, retries int) error {
+	task, err := ParseTaskID(jobErr.Task)
+	if err != nil {
+		return err
+	}
+	ev := newEvent(TaskRetryScheduled, task)
+	ev.Error = jobErr.Message
+	ev.Retries = retries
+	return m.clusterState.Put(ctx, ev.key(), ev)
+}
+
+// ListJobEvents returns the events of the tasks in the job, in the order of their time.
+func (m *Manager) ListJobEvents(ctx context.Context, jobID string) ([]Event, error) {
+	items, err := m.clusterState.Scan(ctx, path.Join(jobEventNs, jobID)+"/")
+	if err != nil {
+		return nil, errors.Wrap(err, "scan events")
+	}
+	events := make([]Event, len(items))
+	for i, item := range items {
+		if err := item.Unmarshal(&events[i]); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal event %s", item.Key)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, nil
 }
 
 func (m *Manager) ListJobs(ctx context.Context, prefixFormat string, args ...interface{}) ([]*Job, error) {
@@ -172,3 +351,26 @@ func (m *Manager) ListTaskStatusesInJob(ctx context.Context, jobID string) ([]*T
 	}
 	return statuses, nil
 }
+
+// ListTaskStatusesByID returns the statuses of the tasks in the job keyed by their IDs. Since a task has
+// only one status, finished tasks are counted once even if they are reported as finished again (e.g. aborted after success).
+func (m *Manager) ListTaskStatusesByID(ctx context.Context, jobID string) (map[TaskID]*TaskStatus, error) {
+	prefix := path.Join(taskStatusNs, jobID) + "/"
+	items, err := m.clusterState.Scan(ctx, prefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "get task")
+	}
+	statuses := make(map[TaskID]*TaskStatus, len(items))
+	for _, item := range items {
+		frags := strings.SplitN(strings.TrimPrefix(item.Key, prefix), "/", 2)
+		if len(frags) != 2 {
+			continue
+		}
+		status := new(TaskStatus)
+		if err := item.Unmarshal(status); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal task status %s", item.Key)
+		}
+		statuses[TaskID{JobID: jobID, StageName: frags[0], PartitionID: frags[1]}] = status
+	}
+	return statuses, nil
+}
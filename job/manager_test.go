@@ -0,0 +1,52 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/stage"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestManager_ListTaskStatusesByID(t *testing.T) {
+	Convey("Given tasks of jobs", t, func() {
+		ctx := context.Background()
+		crd := coordinator.NewLocalMemory()
+		jm := NewManager(crd)
+
+		stages := []stage.Stage{{Name: "_input"}, {Name: "Map1"}}
+		assignments := []partitions.Assignments{
+			{{PartitionID: "0", Host: "master"}},
+			{{PartitionID: "0", Host: "worker"}, {PartitionID: "1", Host: "worker"}},
+		}
+		j := &Job{ID: "J1", Stages: stages, Partitions: assignments}
+		other := &Job{ID: "J10", Stages: stages, Partitions: assignments}
+
+		report := func(j *Job, partitionID string) *TaskReporter {
+			task := TaskID{JobID: j.ID, StageName: "Map1", PartitionID: partitionID}
+			status := NewTaskStatus()
+			So(crd.Put(ctx, "status/tasks/"+task.String(), status), ShouldBeNil)
+			return NewTaskReporter(ctx, crd, j, task, status)
+		}
+		finished := report(j, "0")
+		report(j, "1")
+		report(other, "0")
+
+		Convey("When a task is reported as finished more than once", func() {
+			So(finished.ReportSuccess(), ShouldBeNil)
+			So(finished.ReportFailure(nil), ShouldBeNil)
+
+			Convey("It should be listed once", func() {
+				statuses, err := jm.ListTaskStatusesByID(ctx, j.ID)
+				So(err, ShouldBeNil)
+				So(statuses, ShouldHaveLength, 2)
+
+				s := statuses[TaskID{JobID: j.ID, StageName: "Map1", PartitionID: "0"}]
+				So(s, ShouldNotBeNil)
+				So(s.CompletedAt, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -9,11 +9,15 @@ import (
 
 	"github.com/ab180/lrmr/cluster"
 	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/logging"
 	"github.com/airbloc/logger"
 	"github.com/pkg/errors"
 	"go.uber.org/atomic"
 )
 
+// DefaultFlushInterval is the default interval of flushing the updated task status.
+const DefaultFlushInterval = time.Second
+
 type TaskReporter struct {
 	clusterState cluster.State
 
@@ -23,8 +27,12 @@ type TaskReporter struct {
 	flushMu sync.Mutex
 	dirty   atomic.Bool
 
+	// writeMu serializes writes of the status, so that a periodic flush in flight
+	// does not overwrite the final status written by ReportSuccess or ReportFailure.
+	writeMu sync.Mutex
+
 	ctx context.Context
-	log logger.Logger
+	log logging.Logger
 }
 
 func NewTaskReporter(ctx context.Context, cs cluster.State, j *Job, task TaskID, s *TaskStatus) *TaskReporter {
@@ -34,7 +42,7 @@ func NewTaskReporter(ctx context.Context, cs cluster.State, j *Job, task TaskID,
 		job:          j,
 		status:       s,
 		ctx:          ctx,
-		log:          logger.New("lrmr.jobReporter"),
+		log:          logging.New("lrmr.jobReporter"),
 	}
 }
 
@@ -49,15 +57,39 @@ func (r *TaskReporter) UpdateMetric(mutator func(Metrics)) {
 	r.UpdateStatus(func(ts *TaskStatus) { mutator(ts.Metrics) })
 }
 
+// AddAccumulator adds delta to the partial value of the accumulator in the task.
+func (r *TaskReporter) AddAccumulator(name string, delta int64) {
+	r.UpdateStatus(func(ts *TaskStatus) {
+		if ts.Accumulators == nil {
+			ts.Accumulators = make(map[string]int64)
+		}
+		ts.Accumulators[name] += delta
+	})
+}
+
+// ReportStart records the start of the task in the events of the job.
+func (r *TaskReporter) ReportStart() {
+	ev := newEvent(TaskStarted, r.task)
+	if err := r.clusterState.Put(r.ctx, ev.key(), ev); err != nil {
+		r.log.Warn("Failed to record start of task {}: {}", r.task, err)
+	}
+}
+
 func (r *TaskReporter) ReportSuccess() error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
 	r.flushMu.Lock()
 	defer r.flushMu.Unlock()
 
 	r.status.Complete(Succeeded)
 
+	ev := newEvent(TaskSucceeded, r.task)
+	ev.Metrics = r.status.Metrics
+
 	txn := coordinator.NewTxn().
 		Put(path.Join(taskStatusNs, r.task.String()), r.status).
-		IncrementCounter(stageStatusKey(r.task, "doneTasks"))
+		IncrementCounter(stageStatusKey(r.task, "doneTasks")).
+		Put(ev.key(), ev)
 
 	res, err := r.clusterState.Commit(r.ctx, txn)
 	if err != nil {
@@ -73,6 +105,8 @@ func (r *TaskReporter) ReportSuccess() error {
 // ReportFailure marks the task as failed. If the error is non-nil, it's added to the error list of the job.
 // Passing nil in error will only cancel the task.
 func (r *TaskReporter) ReportFailure(err error) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
 	r.flushMu.Lock()
 	defer r.flushMu.Unlock()
 
@@ -81,16 +115,28 @@ func (r *TaskReporter) ReportFailure(err error) error {
 		r.status.Error = err.Error()
 	}
 
+	ev := newEvent(TaskFailed, r.task)
+	ev.Error = r.status.Error
+
 	txn := coordinator.NewTxn().
 		Put(path.Join(taskStatusNs, r.task.String()), r.status).
 		IncrementCounter(stageStatusKey(r.task, "doneTasks")).
-		IncrementCounter(stageStatusKey(r.task, "failedTasks"))
+		IncrementCounter(stageStatusKey(r.task, "failedTasks")).
+		Put(ev.key(), ev)
 
 	if err != nil {
 		errDesc := Error{
 			Task:       r.task.String(),
 			Message:    err.Error(),
 			Stacktrace: fmt.Sprintf("%+v", err),
+			Retryable:  IsRetryable(err),
+			Kind:       KindOf(err),
+		}
+		var panicErr *logger.PanicError
+		if errors.As(err, &panicErr) {
+			// the stack is reported separately, rather than in the message
+			errDesc.Message = panicErr.Reason
+			errDesc.PanicStack = panicErr.Stack
 		}
 		txn = txn.Put(jobErrorKey(r.task), errDesc)
 	}
@@ -99,11 +145,10 @@ func (r *TaskReporter) ReportFailure(err error) error {
 		return errors.Wrap(etcdErr, "write etcd")
 	}
 	elapsed := r.status.CompletedAt.Sub(r.status.SubmittedAt)
-	switch err.(type) {
-	case *logger.PanicError:
-		panicErr := err.(*logger.PanicError)
+	var panicErr *logger.PanicError
+	if errors.As(err, &panicErr) {
 		r.log.Error("Task {} failed after {} with {}", r.task, elapsed, panicErr.Pretty())
-	default:
+	} else {
 		r.log.Error("Task {} failed after {} with error: {}", r.task, elapsed, err)
 	}
 
@@ -179,9 +224,14 @@ func (r *TaskReporter) reportJobCompletion(status RunningState) error {
 	return nil
 }
 
-func (r *TaskReporter) Start() {
+// Start flushes the status of the task updated by UpdateStatus in given interval until the context is done.
+// If the interval is not positive, DefaultFlushInterval is used.
+func (r *TaskReporter) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
 	go func() {
-		t := time.NewTicker(1 * time.Second)
+		t := time.NewTicker(interval)
 		for {
 			select {
 			case <-t.C:
@@ -200,12 +250,25 @@ func (r *TaskReporter) flushTaskStatus() error {
 	if !r.dirty.Load() {
 		return nil
 	}
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
 
+	// the status is cloned under the lock, so that updates are not blocked while writing it
 	r.flushMu.Lock()
+	if r.status.CompletedAt != nil {
+		// final status is already written by ReportSuccess or ReportFailure
+		r.flushMu.Unlock()
+		return nil
+	}
 	status := r.status.Clone()
+	r.dirty.Store(false)
 	r.flushMu.Unlock()
 
-	return r.clusterState.Put(r.ctx, path.Join(taskStatusNs, r.task.String()), status)
+	if err := r.clusterState.Put(r.ctx, path.Join(taskStatusNs, r.task.String()), status); err != nil {
+		r.dirty.Store(true)
+		return err
+	}
+	return nil
 }
 
 // stageStatusKey returns a key of stage summary entry with given name.
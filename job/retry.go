@@ -0,0 +1,22 @@
+package job
+
+import "github.com/pkg/errors"
+
+// RetryableError marks an error as transient, which means that the failed task can be retried.
+type RetryableError struct {
+	error
+}
+
+func MarkRetryable(err error) error {
+	return &RetryableError{err}
+}
+
+func (r *RetryableError) Unwrap() error {
+	return r.error
+}
+
+// IsRetryable returns true if given error or one of its causes is marked as retryable.
+func IsRetryable(err error) bool {
+	var r *RetryableError
+	return errors.As(err, &r)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 type RunningState string
@@ -40,6 +42,17 @@ type Status struct {
 	Errors []Error `json:"errors,omitempty"`
 }
 
+// Err returns the first error which failed the job, or nil if the job has not failed.
+func (s Status) Err() error {
+	if s.Status != Failed {
+		return nil
+	}
+	if len(s.Errors) == 0 {
+		return errors.New("job failed without errors reported")
+	}
+	return s.Errors[0]
+}
+
 func newStatus() Status {
 	return Status{baseStatus: newBaseStatus()}
 }
@@ -58,6 +71,16 @@ type Error struct {
 	Task       string
 	Message    string
 	Stacktrace string
+
+	// Retryable is true if the error is transient, as classified by the transformation.
+	Retryable bool
+
+	// Kind classifies the cause of the failure. Use errors.As with the typed errors of the kind
+	// (e.g. *UserError) to branch on it.
+	Kind ErrorKind
+
+	// PanicStack is the stack of the goroutine captured on recovering the panic, if the task panicked.
+	PanicStack string
 }
 
 func (e Error) Error() string {
@@ -68,6 +91,10 @@ func (e Error) Format(s fmt.State, verb rune) {
 	switch verb {
 	case 'v':
 		if s.Flag('+') {
+			if e.PanicStack != "" {
+				_, _ = io.WriteString(s, fmt.Sprintf("(from %s) %s\n\n%s", e.Task, e.Message, e.PanicStack))
+				return
+			}
 			_, _ = io.WriteString(s, fmt.Sprintf("(from %s) %s", e.Message, e.Stacktrace))
 			return
 		}
@@ -2,10 +2,12 @@ package job
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ab180/lrmr/cluster/node"
 	"github.com/ab180/lrmr/stage"
+	"github.com/pkg/errors"
 )
 
 type Task struct {
@@ -44,10 +46,25 @@ func (tid TaskID) String() string {
 	return fmt.Sprintf("%s/%s/%s", tid.JobID, tid.StageName, tid.PartitionID)
 }
 
+// ParseTaskID parses the TaskID formatted by TaskID.String.
+func ParseTaskID(s string) (TaskID, error) {
+	frags := strings.SplitN(s, "/", 3)
+	if len(frags) < 3 {
+		return TaskID{}, errors.Errorf("invalid task ID: %s", s)
+	}
+	return TaskID{JobID: frags[0], StageName: frags[1], PartitionID: frags[2]}, nil
+}
+
 type TaskStatus struct {
 	baseStatus
 	Error   string  `json:"error,omitempty"`
 	Metrics Metrics `json:"metrics"`
+
+	// Progress is a fraction of work done reported by the task, if any.
+	Progress *float64 `json:"progress,omitempty"`
+
+	// Accumulators are the partial values of the accumulators added by the task.
+	Accumulators map[string]int64 `json:"accumulators,omitempty"`
 }
 
 func NewTaskStatus() *TaskStatus {
@@ -57,14 +74,39 @@ func NewTaskStatus() *TaskStatus {
 	}
 }
 
+// EstimateProgress returns the fraction of work done in the task. It uses the progress
+// reported by the task if present, otherwise the task is considered done only after completion.
+func (ts TaskStatus) EstimateProgress() float64 {
+	if ts.CompletedAt != nil {
+		return 1
+	}
+	if ts.Progress != nil {
+		return *ts.Progress
+	}
+	return 0
+}
+
 func (ts TaskStatus) Clone() TaskStatus {
 	m := make(Metrics)
 	for k, v := range ts.Metrics {
 		m[k] = v
 	}
 	return TaskStatus{
-		baseStatus: ts.baseStatus,
-		Error:      ts.Error,
-		Metrics:    m,
+		baseStatus:   ts.baseStatus,
+		Error:        ts.Error,
+		Metrics:      m,
+		Progress:     ts.Progress,
+		Accumulators: copyAccumulators(ts.Accumulators),
+	}
+}
+
+func copyAccumulators(acc map[string]int64) map[string]int64 {
+	if acc == nil {
+		return nil
+	}
+	c := make(map[string]int64, len(acc))
+	for k, v := range acc {
+		c[k] = v
 	}
+	return c
 }
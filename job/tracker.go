@@ -8,7 +8,9 @@ import (
 
 	"github.com/ab180/lrmr/cluster"
 	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/logging"
 	"github.com/airbloc/logger"
+	"github.com/pkg/errors"
 )
 
 // JobTracker tracks and updates jobs and their tasks' status.
@@ -19,7 +21,7 @@ type Tracker struct {
 	activeJobs    sync.Map
 	stopTrack     context.CancelFunc
 
-	log logger.Logger
+	log logging.Logger
 }
 
 type subscriptionHolder struct {
@@ -29,14 +31,21 @@ type subscriptionHolder struct {
 	mu     sync.RWMutex
 }
 
-func NewJobTracker(cs cluster.State, jm *Manager) *Tracker {
+func NewJobTracker(cs cluster.State, jm *Manager) (*Tracker, error) {
+	wctx, cancel := context.WithCancel(context.Background())
+	events, err := cs.Watch(wctx, statusNs)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "watch status")
+	}
 	t := &Tracker{
 		clusterState: cs,
 		jobManager:   jm,
-		log:          logger.New("lrmr.jobTracker"),
+		stopTrack:    cancel,
+		log:          logging.New("lrmr.jobTracker"),
 	}
-	go t.watch()
-	return t
+	go t.watch(events)
+	return t, nil
 }
 
 // OnJobCompletion registers callback for completion events of given job.
@@ -73,21 +82,51 @@ func (t *Tracker) OnTaskCompletion(job *Job, callback func(job *Job, stageName s
 	sub.mu.Unlock()
 }
 
+// WaitForCompletion blocks until given job succeeds or fails, and returns its final status.
+// If the context is done first, it returns the error of the context without affecting the job.
+// Use Status.Err to get the error which failed the job.
+func (t *Tracker) WaitForCompletion(ctx context.Context, job *Job) (*Status, error) {
+	completion := make(chan *Status, 1)
+	t.OnJobCompletion(job, func(_ *Job, status *Status) {
+		select {
+		case completion <- status:
+		default:
+		}
+	})
+
+	// checked after the subscription to prevent missing the completion in between
+	js, err := t.jobManager.GetJobStatus(ctx, job.ID)
+	if err != nil && errors.Cause(err) != coordinator.ErrNotFound {
+		return nil, errors.Wrap(err, "get job status")
+	}
+	if err == nil && (js.Status == Succeeded || js.Status == Failed) {
+		return &js, nil
+	}
+
+	select {
+	case status := <-completion:
+		return status, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func (t *Tracker) AddJob(job *Job) {
 	t.activeJobs.Store(job.ID, job)
 }
 
-func (t *Tracker) watch() {
-	defer t.log.Recover()
-
-	wctx, cancel := context.WithCancel(context.Background())
-	t.stopTrack = cancel
+func (t *Tracker) watch(events <-chan coordinator.WatchEvent) {
+	defer func() {
+		if err := logger.WrapRecover(recover()); err != nil {
+			t.log.Error("Panic occurred during tracking jobs: {}", err.Pretty())
+		}
+	}()
 
-	for event := range t.clusterState.Watch(wctx, statusNs) {
-		if strings.HasPrefix(event.Item.Key, stageStatusNs) {
+	for event := range events {
+		if strings.HasPrefix(event.Key(), stageStatusNs) {
 			t.trackStageStatus(event)
 		}
-		if strings.HasPrefix(event.Item.Key, jobStatusNs) {
+		if strings.HasPrefix(event.Key(), jobStatusNs) {
 			t.trackJobStatus(event)
 		}
 	}
@@ -106,10 +145,10 @@ func (t *Tracker) trackStageStatus(e coordinator.WatchEvent) {
 	job := j.(*Job)
 	stageName := frags[3]
 
-	if len(frags) == 4 {
+	if len(frags) == 4 && e.Type == coordinator.PutEvent {
 		// stage status update
 		st := new(StageStatus)
-		if err := e.Item.Unmarshal(st); err != nil {
+		if err := e.Decode(st); err != nil {
 			t.log.Error("Failed to unmarshal stage status on {}", err, e.Item.Key)
 			return
 		}
@@ -144,15 +183,22 @@ func (t *Tracker) trackJobStatus(e coordinator.WatchEvent) {
 
 	if len(frags) == 3 && e.Type == coordinator.PutEvent {
 		// job status update
-		ctx, cancel := context.WithTimeout(context.TODO(), 3*time.Second)
-		defer cancel()
-
-		jobStatus, err := t.jobManager.GetJobStatus(ctx, job.ID)
-		if err != nil {
-			t.log.Error("Failed to get job status of {}", job.ID)
+		var jobStatus Status
+		if err := e.Decode(&jobStatus); err != nil {
+			t.log.Error("Failed to unmarshal job status on {}", err, e.Key())
 			return
 		}
 		if jobStatus.Status == Succeeded || jobStatus.Status == Failed {
+			ctx, cancel := context.WithTimeout(context.TODO(), 3*time.Second)
+			defer cancel()
+
+			errs, err := t.jobManager.GetJobErrors(ctx, job.ID)
+			if err != nil {
+				t.log.Error("Failed to get errors of job {}", err, job.ID)
+				return
+			}
+			jobStatus.Errors = errs
+
 			sub, release := t.getSubscription(job.ID)
 			defer release()
 
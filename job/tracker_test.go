@@ -0,0 +1,81 @@
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/stage"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.uber.org/goleak"
+)
+
+func TestTracker_WaitForCompletion(t *testing.T) {
+	Convey("Given a running job", t, func() {
+		ctx := context.Background()
+		crd := coordinator.NewLocalMemory()
+		jm := NewManager(crd)
+		tracker, err := NewJobTracker(crd, jm)
+		So(err, ShouldBeNil)
+		Reset(tracker.Close)
+
+		stages := []stage.Stage{{Name: "_input"}, {Name: "Map1"}}
+		assignments := []partitions.Assignments{
+			{{PartitionID: "0", Host: "master"}},
+			{{PartitionID: "0", Host: "worker"}},
+		}
+		j := &Job{ID: "J1", Stages: stages, Partitions: assignments}
+		So(jm.SetJobStatus(ctx, j.ID, newStatus()), ShouldBeNil)
+
+		Convey("When the context is done before the job completes", func() {
+			ignored := goleak.IgnoreCurrent()
+			waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+
+			status, err := tracker.WaitForCompletion(waitCtx, j)
+
+			Convey("It should return the error of the context", func() {
+				So(status, ShouldBeNil)
+				So(err, ShouldBeError, context.DeadlineExceeded)
+			})
+
+			Convey("It should not leak goroutines", func() {
+				So(goleak.Find(ignored), ShouldBeNil)
+			})
+		})
+
+		Convey("When a task fails", func() {
+			task := TaskID{JobID: j.ID, StageName: "Map1", PartitionID: "0"}
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				_ = crd.Put(ctx, jobErrorKey(task), Error{Task: task.String(), Message: "boom"})
+				js := newStatus()
+				js.Complete(Failed)
+				_ = jm.SetJobStatus(ctx, j.ID, js)
+			}()
+			status, err := tracker.WaitForCompletion(ctx, j)
+			So(err, ShouldBeNil)
+
+			Convey("It should return the failed status with the error of the task", func() {
+				So(status.Status, ShouldEqual, Failed)
+				So(status.Err(), ShouldNotBeNil)
+				So(status.Err().Error(), ShouldContainSubstring, "boom")
+			})
+		})
+
+		Convey("When the job has already succeeded", func() {
+			js := newStatus()
+			js.Complete(Succeeded)
+			So(jm.SetJobStatus(ctx, j.ID, js), ShouldBeNil)
+
+			Convey("It should return immediately without an error", func() {
+				status, err := tracker.WaitForCompletion(ctx, j)
+				So(err, ShouldBeNil)
+				So(status.Status, ShouldEqual, Succeeded)
+				So(status.Err(), ShouldBeNil)
+			})
+		})
+	})
+}
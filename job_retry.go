@@ -0,0 +1,88 @@
+package lrmr
+
+import (
+	"time"
+
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/stage"
+	"github.com/pkg/errors"
+)
+
+// runWithJobRetries runs the dataset and waits for the job by wait. The dataset is run again in a new job,
+// whose partitions are assigned anew on the workers available:
+//
+//   - If the job fails due to a lost worker, up to SessionOptions.JobRetries times.
+//   - If the job fails with a retryable error of a stage, up to the MaxRetries of the stage
+//     (see Dataset.WithRetries), with exponential backoff.
+//
+// The number of the failed runs and the last error are recorded in job.Job.Retry of the new job.
+// It returns the last job run.
+func (s *Session) runWithJobRetries(ds *Dataset, wait func(*RunningJob) error) (*RunningJob, error) {
+	var (
+		retry        *job.Retry
+		lostRetries  int
+		stageRetries = make(map[string]int)
+	)
+	for {
+		j, err := s.run(ds, retry)
+		if err != nil {
+			return nil, err
+		}
+		err = wait(j)
+		if err == nil {
+			return j, nil
+		}
+
+		var lost *job.WorkerLostError
+		if errors.As(err, &lost) && lostRetries < s.options.JobRetries {
+			lostRetries++
+			log.Warn("Job {} failed due to a lost worker, rescheduling (#{}): {}", j.Job.ID, lostRetries, err)
+		} else if jobErr, st, ok := retryableFailureOf(j.Job, err); ok && stageRetries[st.Name] < st.MaxRetries {
+			stageRetries[st.Name]++
+			retries := stageRetries[st.Name]
+			log.Warn("Job {} failed on stage {}, retrying (#{}): {}", j.Job.ID, st.Name, retries, err)
+			if err := s.master.JobManager.RecordRetry(s.ctx, jobErr, retries); err != nil {
+				log.Warn("Failed to record retry of job {}: {}", j.Job.ID, err)
+			}
+			select {
+			case <-time.After(retryBackoff(retries - 1)):
+			case <-s.ctx.Done():
+				return j, s.ctx.Err()
+			}
+		} else {
+			return j, err
+		}
+
+		count := 1
+		if retry != nil {
+			count += retry.Count
+		}
+		retry = &job.Retry{Count: count, LastError: err.Error()}
+	}
+}
+
+// retryableFailureOf returns the error of the task failed the job and the stage of the task,
+// if the error is retryable.
+func retryableFailureOf(j *job.Job, err error) (jobErr job.Error, st *stage.Stage, ok bool) {
+	if !errors.As(err, &jobErr) || !jobErr.Retryable {
+		return jobErr, nil, false
+	}
+	task, parseErr := job.ParseTaskID(jobErr.Task)
+	if parseErr != nil {
+		return jobErr, nil, false
+	}
+	st = j.GetStage(task.StageName)
+	return jobErr, st, st != nil
+}
+
+// retryBackoff returns the delay before the retry after given number of attempts.
+func retryBackoff(attempt int) time.Duration {
+	const (
+		initialBackoff = 100 * time.Millisecond
+		maxBackoff     = 10 * time.Second
+	)
+	if attempt >= 7 {
+		return maxBackoff
+	}
+	return initialBackoff << attempt
+}
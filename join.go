@@ -0,0 +1,189 @@
+package lrmr
+
+import (
+	"strconv"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/stage"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+	"github.com/segmentio/fasthash/fnv1a"
+)
+
+// JoinMode decides which rows are emitted by Join.
+type JoinMode string
+
+const (
+	// InnerJoin emits only the rows having matching rows in the other dataset.
+	InnerJoin JoinMode = "inner"
+
+	// LeftOuterJoin emits every row of the dataset, with nil Right if there's no matching row in the other dataset.
+	LeftOuterJoin JoinMode = "leftOuter"
+)
+
+// Joined is the value of the rows emitted by Join.
+type Joined struct {
+	Left  *lrdd.Row `msgpack:"left" json:"left"`
+	Right *lrdd.Row `msgpack:"right" json:"right"`
+}
+
+// Join joins the rows of the dataset with the rows of the other dataset having the same key,
+// emitting a row whose value is a Joined for each pair of them. Use Map to re-key the rows
+// for joining on the other fields.
+//
+// Both datasets are run as separate jobs whose tasks keep their partitions, hash-partitioned by key like
+// GroupByKey, in the memory of the workers running them (see Barrier). Then one of them (the build side)
+// stays on its workers, while the partitions of the other one (the probe side) are read on their workers and
+// shuffled by the same hash to the workers keeping the matching partitions of the build side. Each task loads
+// its partition of the build side in memory and streams the probe side. The smaller dataset is the build side
+// on InnerJoin, while the other dataset is always the build side on LeftOuterJoin. Both datasets are freed
+// after the join.
+func (d *Dataset) Join(other *Dataset, mode JoinMode) *Dataset {
+	in := &joinedInput{
+		left:        d.clone(),
+		right:       other.clone(),
+		partitioner: &coPartitioner{},
+		join:        &joinTransformation{Mode: mode},
+	}
+	joined := newDataset(d.session, in)
+	r := &blockReader{}
+	joined.addStage(joined.stageName(r), r)
+	joined.lastPlan().Partitioner = in.partitioner
+	joined.addStage(joined.stageName(in.join), in.join)
+	return joined
+}
+
+// joinedInput feeds the partitions of the probe side of a join to the blockReader running on the workers
+// storing them. The co-partitioner and the transformation of the join are bound to the blocks on materialize.
+type joinedInput struct {
+	left, right *Dataset
+	probe       blockInput
+	build       *storedBlock
+	partitioner *coPartitioner
+	join        *joinTransformation
+}
+
+// materialize stores both sides of the join as blocks partitioned by the hash of the key.
+func (j *joinedInput) materialize() error {
+	left, err := storeBlock(j.left.clone().GroupByKey())
+	if err != nil {
+		return errors.WithMessage(err, "left")
+	}
+	right, err := storeBlock(j.right.clone().GroupByKey())
+	if err != nil {
+		freeBlock(j.left.session.master.Cluster.States(), left)
+		return errors.WithMessage(err, "right")
+	}
+	j.probe.source, j.probe.block, j.build = j.left, left, right
+	j.join.BuildIsLeft = false
+	if j.join.Mode == InnerJoin && left.Rows < right.Rows {
+		j.probe.block, j.build = right, left
+		j.join.BuildIsLeft = true
+	}
+	j.join.BuildBlockID = j.build.ID
+	j.partitioner.locations = j.build.Locations
+	return nil
+}
+
+// ReleaseInput frees both sides of the join.
+func (j *joinedInput) ReleaseInput() {
+	if j.build == nil {
+		return
+	}
+	j.probe.ReleaseInput()
+	freeBlock(j.left.session.master.Cluster.States(), j.build)
+}
+
+func (j *joinedInput) PlanNext(numExecutors int) []partitions.Partition {
+	return j.probe.PlanNext(numExecutors)
+}
+
+func (j *joinedInput) DeterminePartition(c partitions.Context, r *lrdd.Row, numOutputs int) (string, error) {
+	return j.probe.DeterminePartition(c, r, numOutputs)
+}
+
+func (j *joinedInput) FeedInput(out output.Output) error {
+	return j.probe.FeedInput(out)
+}
+
+// unbind returns the stages with the transformation of the join not bound to the blocks,
+// so that the hash of the plan is stable across the runs.
+func (j *joinedInput) unbind(stages []stage.Stage) []stage.Stage {
+	unbound := append([]stage.Stage(nil), stages...)
+	for i, s := range unbound {
+		if s.Function.Transformation == j.join {
+			unbound[i].Function = transformation.Serializable{Transformation: &joinTransformation{Mode: j.join.Mode}}
+		}
+	}
+	return unbound
+}
+
+// coPartitioner partitions the rows by the hash of the key like GroupByKey, into the partitions
+// of the build side of a join. Each partition is pinned to the worker storing it.
+type coPartitioner struct {
+	// locations are only used to plan the partitions on master.
+	locations partitions.Assignments
+}
+
+func (c *coPartitioner) PlanNext(int) []partitions.Partition {
+	pp := make([]partitions.Partition, len(c.locations))
+	for i, l := range c.locations {
+		pp[i] = partitions.Partition{
+			ID:                 l.PartitionID,
+			IsElastic:          false,
+			AssignmentAffinity: map[string]string{"Host": l.Host},
+		}
+	}
+	return pp
+}
+
+func (c *coPartitioner) DeterminePartition(_ partitions.Context, r *lrdd.Row, numOutputs int) (string, error) {
+	slot := fnv1a.HashString64(r.Key) % uint64(numOutputs)
+	return strconv.FormatUint(slot, 10), nil
+}
+
+// joinTransformation loads its partition of the build side from the worker, and joins the rows of
+// the probe side with it.
+type joinTransformation struct {
+	Mode         JoinMode
+	BuildBlockID string
+	BuildIsLeft  bool
+}
+
+func (j *joinTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	blocks, err := blocksOf(ctx)
+	if err != nil {
+		return err
+	}
+	built := make(map[string][]*lrdd.Row)
+	err = readBlock(blocks, j.BuildBlockID, ctx.PartitionID(), func(row *lrdd.Row) error {
+		built[row.Key] = append(built[row.Key], row)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for row := range in {
+		matches := built[row.Key]
+		if len(matches) == 0 && j.Mode == LeftOuterJoin {
+			if err := out.Write(lrdd.KeyValue(row.Key, Joined{Left: row})); err != nil {
+				return err
+			}
+			continue
+		}
+		rows := make([]*lrdd.Row, len(matches))
+		for i, match := range matches {
+			joined := Joined{Left: row, Right: match}
+			if j.BuildIsLeft {
+				joined = Joined{Left: match, Right: row}
+			}
+			rows[i] = lrdd.KeyValue(row.Key, joined)
+		}
+		if err := out.Write(rows...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package lrmr
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+type KafkaOptions struct {
+	// Version is the version of the Kafka brokers (e.g. "2.6.0"), which decides the protocol used.
+	// Defaults to the one of the client library.
+	Version string
+
+	// Decoder decodes the value of each message into a row. If not set, the message is emitted
+	// as a row having its key, value, offset and partition.
+	Decoder RecordDecoder
+}
+
+type KafkaOption func(o *KafkaOptions)
+
+// WithKafkaVersion sets the version of the Kafka brokers.
+func WithKafkaVersion(version string) KafkaOption {
+	return func(o *KafkaOptions) {
+		o.Version = version
+	}
+}
+
+// WithKafkaDecoder sets the decoder of the values of the messages.
+func WithKafkaDecoder(d RecordDecoder) KafkaOption {
+	return func(o *KafkaOptions) {
+		o.Decoder = d
+	}
+}
+
+// KafkaInput creates new Dataset by reading messages of the topic, from the offsets committed by
+// the consumer group (or the oldest offsets if not committed) to the latest offsets at the start of the job.
+// Each message is emitted as a row keyed by the message key, whose value is a map having
+// "key", "value", "offset" and "partition" fields, unless a RecordDecoder is given by WithKafkaDecoder.
+//
+// The partitions of the topic are distributed across the executors, so an executor reads one or more
+// partitions. The offsets read are committed through the group only after the job succeeds, so the next job
+// resumes from them. Messages are delivered at least once: if the job fails or is cancelled, or the session
+// is closed before the completion, nothing is committed and the next job reads the messages again.
+func (s *Session) KafkaInput(brokers []string, topic, group string, opts ...KafkaOption) *Dataset {
+	var o KafkaOptions
+	for _, optFn := range opts {
+		optFn(&o)
+	}
+	src := kafkaSource{Brokers: brokers, Topic: topic, Version: o.Version}
+
+	d := newDataset(s, &kafkaInput{Source: src, Group: group})
+	reader := &kafkaReader{Source: src, Decoder: serializableDecoder{o.Decoder}}
+	d.addStage(d.stageName(reader), reader)
+	return d
+}
+
+// kafkaSource is a topic and the brokers serving it.
+type kafkaSource struct {
+	Brokers []string
+	Topic   string
+	Version string
+}
+
+func (k kafkaSource) config() (*sarama.Config, error) {
+	cfg := sarama.NewConfig()
+	cfg.ClientID = "lrmr"
+	if k.Version != "" {
+		v, err := sarama.ParseKafkaVersion(k.Version)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Version = v
+	}
+	cfg.Consumer.Return.Errors = true
+	cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	cfg.Consumer.Offsets.AutoCommit.Enable = false
+	return cfg, nil
+}
+
+func (k kafkaSource) client() (sarama.Client, error) {
+	cfg, err := k.config()
+	if err != nil {
+		return nil, err
+	}
+	cli, err := sarama.NewClient(k.Brokers, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "connect to kafka")
+	}
+	return cli, nil
+}
+
+// kafkaPartitionRange is a range of offsets of a partition, from Start (inclusive) to End (exclusive).
+type kafkaPartitionRange struct {
+	Partition int32
+	Start     int64
+	End       int64
+}
+
+// kafkaInput emits the ranges of the partitions to read, and commits the end of them after the job succeeds.
+type kafkaInput struct {
+	partitions.ShuffledPartitioner
+	Source kafkaSource
+	Group  string
+
+	// ranges are the ranges fed to the job.
+	ranges []kafkaPartitionRange
+}
+
+func (k *kafkaInput) FeedInput(out output.Output) error {
+	cli, err := k.Source.client()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ps, err := cli.Partitions(k.Source.Topic)
+	if err != nil {
+		return errors.Wrapf(err, "get partitions of %s", k.Source.Topic)
+	}
+	om, err := sarama.NewOffsetManagerFromClient(k.Group, cli)
+	if err != nil {
+		return errors.Wrap(err, "create offset manager")
+	}
+	defer om.Close()
+
+	k.ranges = nil
+	for _, p := range ps {
+		r, err := k.partitionRange(cli, om, p)
+		if err != nil {
+			return errors.Wrapf(err, "get offsets of %s/%d", k.Source.Topic, p)
+		}
+		if r.Start >= r.End {
+			continue
+		}
+		if err := out.Write(lrdd.Value(r)); err != nil {
+			return err
+		}
+		k.ranges = append(k.ranges, r)
+	}
+	return nil
+}
+
+func (k *kafkaInput) partitionRange(cli sarama.Client, om sarama.OffsetManager, p int32) (r kafkaPartitionRange, err error) {
+	pom, err := om.ManagePartition(k.Source.Topic, p)
+	if err != nil {
+		return r, err
+	}
+	committed, _ := pom.NextOffset()
+	pom.AsyncClose()
+
+	oldest, err := cli.GetOffset(k.Source.Topic, p, sarama.OffsetOldest)
+	if err != nil {
+		return r, err
+	}
+	newest, err := cli.GetOffset(k.Source.Topic, p, sarama.OffsetNewest)
+	if err != nil {
+		return r, err
+	}
+	r = kafkaPartitionRange{Partition: p, Start: committed, End: newest}
+	if r.Start < oldest {
+		// not committed yet, or the committed messages are removed by the retention
+		r.Start = oldest
+	}
+	return r, nil
+}
+
+// CommitInput commits the end of the ranges fed to the job as the offsets of the group.
+func (k *kafkaInput) CommitInput() error {
+	if len(k.ranges) == 0 {
+		return nil
+	}
+	cli, err := k.Source.client()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	om, err := sarama.NewOffsetManagerFromClient(k.Group, cli)
+	if err != nil {
+		return errors.Wrap(err, "create offset manager")
+	}
+	poms := make([]sarama.PartitionOffsetManager, 0, len(k.ranges))
+	for _, r := range k.ranges {
+		pom, err := om.ManagePartition(k.Source.Topic, r.Partition)
+		if err != nil {
+			_ = closeOffsetManager(om, poms)
+			return errors.Wrapf(err, "manage offset of %s/%d", k.Source.Topic, r.Partition)
+		}
+		pom.MarkOffset(r.End, "")
+		poms = append(poms, pom)
+	}
+	if err := closeOffsetManager(om, poms); err != nil {
+		return errors.Wrap(err, "commit offset")
+	}
+	return nil
+}
+
+// closeOffsetManager closes the offset manager, which flushes the offsets marked, and returns the errors of
+// the partition offset managers. The errors channel of a partition offset manager is closed only after
+// the offset manager is closed, since its offset can't be committed until then on failures.
+func closeOffsetManager(om sarama.OffsetManager, poms []sarama.PartitionOffsetManager) error {
+	for _, pom := range poms {
+		pom.AsyncClose()
+	}
+	if err := om.Close(); err != nil {
+		return err
+	}
+	var errs sarama.ConsumerErrors
+	for _, pom := range poms {
+		for err := range pom.Errors() {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// kafkaReader reads the messages in each range.
+type kafkaReader struct {
+	Source  kafkaSource
+	Decoder serializableDecoder
+}
+
+func (k *kafkaReader) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	cfg, err := k.Source.config()
+	if err != nil {
+		return err
+	}
+	consumer, err := sarama.NewConsumer(k.Source.Brokers, cfg)
+	if err != nil {
+		return errors.Wrap(err, "connect to kafka")
+	}
+	defer consumer.Close()
+
+	for row := range in {
+		var r kafkaPartitionRange
+		if err := row.DecodeValue(&r); err != nil {
+			return errors.Wrap(err, "decode partition range")
+		}
+		if err := k.readRange(ctx, consumer, r, out); err != nil {
+			return errors.Wrapf(err, "read %s/%d from %d to %d", k.Source.Topic, r.Partition, r.Start, r.End)
+		}
+	}
+	return nil
+}
+
+func (k *kafkaReader) readRange(ctx transformation.Context, consumer sarama.Consumer, r kafkaPartitionRange, out output.Output) error {
+	pc, err := consumer.ConsumePartition(k.Source.Topic, r.Partition, r.Start)
+	if err != nil {
+		return err
+	}
+	defer pc.AsyncClose()
+
+	// offsets can be skipped in compacted topics, but the last message of the range always remains
+	for next := r.Start; next < r.End; {
+		select {
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				return errors.New("consumer closed")
+			}
+			if msg.Offset >= r.End {
+				return nil
+			}
+			next = msg.Offset + 1
+
+			row, err := k.decode(msg)
+			if err != nil {
+				return errors.Wrapf(err, "decode message at %d", msg.Offset)
+			}
+			if row == nil {
+				continue
+			}
+			if err := out.Write(row); err != nil {
+				return err
+			}
+
+		case err := <-pc.Errors():
+			return err
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (k *kafkaReader) decode(msg *sarama.ConsumerMessage) (*lrdd.Row, error) {
+	if k.Decoder.RecordDecoder != nil {
+		return k.Decoder.Decode(msg.Value)
+	}
+	return lrdd.KeyValue(string(msg.Key), map[string]interface{}{
+		"key":       string(msg.Key),
+		"value":     string(msg.Value),
+		"offset":    msg.Offset,
+		"partition": msg.Partition,
+	}), nil
+}
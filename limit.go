@@ -0,0 +1,78 @@
+package lrmr
+
+import (
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+// Limit emits at most n rows of the dataset, and stops the job early once n rows are emitted
+// across all partitions, instead of processing the rest of the input.
+//
+// Tasks of the limit stage claim each row by atomically incrementing a counter of the job in
+// the coordinator, and emit only the rows claimed with a count not greater than n. The task claiming
+// the n-th row stops the inputs of the limit stage and its preceding stages through the coordinator,
+// then the stopped tasks discard the rest of their inputs and finish successfully with the rows
+// processed so far. Since a row costs a round-trip to the coordinator, Limit is meant for
+// a small n; use Sample or SampleN for a large subset of the dataset.
+//
+// Which rows are emitted depends on the order of their arrival, so it is not deterministic.
+func (d *Dataset) Limit(n int) *Dataset {
+	name := d.stageName(&limitTransformation{})
+	d.addStage(name, &limitTransformation{N: n, Counter: name})
+	return d
+}
+
+// CollectN returns at most n rows of the dataset, stopping the job once n rows are gathered across
+// all partitions like Limit. It is cheap to peek at a few rows, e.g. for debugging, since the rest of
+// the dataset is neither processed nor sent to the master. See Session.CollectN for details.
+func (d *Dataset) CollectN(n int) ([]*lrdd.Row, error) {
+	return d.session.CollectN(d, n)
+}
+
+// CollectN runs the dataset and returns at most n rows of its result. Which rows are returned is not
+// deterministic, as in Limit.
+func (s *Session) CollectN(ds *Dataset, n int) ([]*lrdd.Row, error) {
+	return ds.Limit(n).Collect()
+}
+
+type limitTransformation struct {
+	N int
+
+	// Counter is the name of the counter shared by the tasks of the stage.
+	Counter string
+}
+
+func (l *limitTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	reached := l.N <= 0
+	if reached {
+		if err := ctx.StopInputs(); err != nil {
+			return errors.Wrap(err, "stop inputs")
+		}
+	}
+	for row := range in {
+		if reached {
+			// drains the rows pulled before the inputs are stopped
+			continue
+		}
+		count, err := ctx.IncrementCounter(l.Counter)
+		if err != nil {
+			return errors.Wrap(err, "increment counter")
+		}
+		if count > int64(l.N) {
+			reached = true
+			continue
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+		if count == int64(l.N) {
+			reached = true
+			if err := ctx.StopInputs(); err != nil {
+				return errors.Wrap(err, "stop inputs")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package lrmr
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ab180/lrmr/cluster/node"
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/stage"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/airbloc/logger"
+	"github.com/goombaio/namegenerator"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// localInputQueueLength is the number of rows buffered in the input of each task in local mode.
+const localInputQueueLength = 1000
+
+// errInputStopped is returned to the input writes after the inputs of the first stage are stopped.
+var errInputStopped = errors.New("input stopped")
+
+// RunLocal runs the dataset in this process with given parallelism and returns the output rows of its last
+// stage. See Session.RunLocal for details.
+func (d *Dataset) RunLocal(parallelism int) ([]*lrdd.Row, error) {
+	return d.session.RunLocal(d, parallelism)
+}
+
+// RunLocal runs the dataset in this process without a cluster, which is useful for development and tests.
+// The session can be created with a nil master. The stages are planned as in a cluster with a worker of
+// given number of executors, and each task runs in a goroutine exchanging rows with the others through
+// channels, partitioned by the partitioners of the stages. Like on a worker, each task runs its own copy
+// of the transformation deserialized from the stage, so the transformations should be registered.
+//
+// Counters are kept in a LocalMemory coordinator, and StopInputs works as in a cluster. Task retries,
+// metrics and accumulators are not supported in local mode. The order of the returned rows depends on
+// the scheduling of the tasks.
+func (s *Session) RunLocal(ds *Dataset, parallelism int) ([]*lrdd.Row, error) {
+	if parallelism < 1 {
+		return nil, errors.Errorf("parallelism must be positive, got %d", parallelism)
+	}
+	if m, ok := ds.input.(materializedInput); ok {
+		if err := m.materialize(); err != nil {
+			return nil, errors.WithMessage(err, "materialize input")
+		}
+	}
+	plans := ds.physicalPlans()
+	if err := ds.validate(plans); err != nil {
+		return nil, err
+	}
+	serialized, err := serialization.SerializeBroadcast(s.broadcasts)
+	if err != nil {
+		return nil, err
+	}
+	broadcasts, err := serialization.DeserializeBroadcast(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := s.ctx
+	if s.options.Timeout > 0 {
+		tctx, cancel := context.WithTimeout(ctx, s.options.Timeout)
+		ctx = tctx
+		defer cancel()
+	}
+	jobName := s.options.Name
+	if jobName == "" {
+		jobName = namegenerator.NewNameGenerator(time.Now().UnixNano()).Generate()
+	}
+	r, err := newLocalRunner(jobName, ds.stages, plans, parallelism, broadcasts)
+	if err != nil {
+		return nil, err
+	}
+	return r.run(ctx, ds.input)
+}
+
+type localRunner struct {
+	jobID      string
+	stages     []stage.Stage
+	partitions []partitions.Partitions
+
+	// inputPartitioner partitions the rows fed by the input, as the master does.
+	inputPartitioner partitions.Partitioner
+
+	broadcasts serialization.Broadcast
+	jobManager *job.Manager
+
+	// functions and partitioners are the serialized transformations and output partitioners of the stages,
+	// which are deserialized for each task.
+	functions    [][]byte
+	partitioners [][]byte
+
+	// stopped are closed when the inputs of the stages are stopped.
+	stopped  []chan struct{}
+	stopOnce []sync.Once
+
+	collected   []*lrdd.Row
+	collectedMu sync.Mutex
+}
+
+func newLocalRunner(jobID string, stages []stage.Stage, plans []partitions.Plan, parallelism int, broadcasts serialization.Broadcast) (*localRunner, error) {
+	master := &node.Node{Host: "localhost", Type: node.Master, Executors: 1}
+	worker := &node.Node{Host: "localhost", Type: node.Worker, Executors: parallelism}
+	pp, _, err := partitions.Schedule([]*node.Node{worker}, plans, partitions.WithMaster(master))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &localRunner{
+		jobID:            jobID,
+		stages:           stages,
+		partitions:       pp,
+		inputPartitioner: plans[0].Partitioner,
+		broadcasts:       broadcasts,
+		jobManager:       job.NewManager(coordinator.NewLocalMemory()),
+		functions:        make([][]byte, len(stages)),
+		partitioners:     make([][]byte, len(stages)),
+		stopped:          make([]chan struct{}, len(stages)),
+		stopOnce:         make([]sync.Once, len(stages)),
+	}
+	for i, st := range stages {
+		var err error
+		if r.functions[i], err = jsoniter.Marshal(st.Function); err != nil {
+			return nil, errors.Wrapf(err, "serialize stage %s", st.Name)
+		}
+		if r.partitioners[i], err = jsoniter.Marshal(pp[i].Partitioner); err != nil {
+			return nil, errors.Wrapf(err, "serialize partitioner of stage %s", st.Name)
+		}
+		r.stopped[i] = make(chan struct{})
+	}
+	return r, nil
+}
+
+func (r *localRunner) run(ctx context.Context, in InputProvider) ([]*lrdd.Row, error) {
+	wg, ctx := errgroup.WithContext(ctx)
+
+	// inputs are the inputs of the tasks of each stage, keyed by partition ID.
+	// They are closed after all tasks of the previous stage finish.
+	inputs := make([]map[string]chan *lrdd.Row, len(r.stages))
+	finished := make([]*sync.WaitGroup, len(r.stages))
+	for i := range r.stages {
+		finished[i] = new(sync.WaitGroup)
+		if i == 0 {
+			finished[i].Add(1)
+			continue
+		}
+		inputs[i] = make(map[string]chan *lrdd.Row)
+		for _, p := range r.partitions[i].Partitions {
+			inputs[i][p.ID] = make(chan *lrdd.Row, localInputQueueLength)
+		}
+		finished[i].Add(len(inputs[i]))
+		prev, cur := finished[i-1], inputs[i]
+		go func() {
+			prev.Wait()
+			for _, c := range cur {
+				close(c)
+			}
+		}()
+	}
+
+	wg.Go(func() error {
+		defer finished[0].Done()
+		out := r.newOutput(ctx, 0, r.inputPartitioner, "0", inputs)
+		if err := in.FeedInput(out); err != nil && errors.Cause(err) != errInputStopped {
+			return errors.Wrap(err, "feed input")
+		}
+		return nil
+	})
+	for i := 1; i < len(r.stages); i++ {
+		for id, c := range inputs[i] {
+			stageIdx, partitionID, raw := i, id, c
+			wg.Go(func() error {
+				defer finished[stageIdx].Done()
+				return r.runTask(ctx, stageIdx, partitionID, raw, inputs)
+			})
+		}
+	}
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+	return r.collected, nil
+}
+
+func (r *localRunner) runTask(ctx context.Context, stageIdx int, partitionID string, raw chan *lrdd.Row, inputs []map[string]chan *lrdd.Row) (err error) {
+	st := r.stages[stageIdx]
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "task %s/%s", st.Name, partitionID)
+		}
+	}()
+
+	var fn transformation.Serializable
+	if err := jsoniter.Unmarshal(r.functions[stageIdx], &fn); err != nil {
+		return errors.Wrap(err, "deserialize stage")
+	}
+	var sp partitions.SerializablePartitioner
+	if err := jsoniter.Unmarshal(r.partitioners[stageIdx], &sp); err != nil {
+		return errors.Wrap(err, "deserialize partitioner")
+	}
+	out := r.newOutput(ctx, stageIdx, partitions.UnwrapPartitioner(sp), partitionID, inputs)
+	if w, ok := out.(*output.Writer); ok {
+		w.SetProjection(transformation.ProjectionOf(fn))
+		w.SetNoOutputPolicy(st.NoOutputPolicy)
+	}
+
+	taskCtx, cancel := context.WithCancel(ctx)
+	if st.TaskTimeout > 0 {
+		taskCtx, cancel = context.WithTimeout(ctx, st.TaskTimeout)
+	}
+	defer cancel()
+
+	in := make(chan *lrdd.Row)
+	go r.relay(taskCtx, r.stopped[stageIdx], raw, in)
+	defer func() {
+		// the rest of the input is discarded if the transformation returns without consuming it,
+		// so that the previous stage doesn't block on writing to the input
+		go func() {
+			for range raw {
+			}
+		}()
+	}()
+	defer func() {
+		if panicErr := logger.WrapRecover(recover()); panicErr != nil {
+			err = job.MarkUserError(panicErr)
+		}
+	}()
+	return fn.Apply(&localTaskContext{Context: taskCtx, runner: r, stageIdx: stageIdx, partitionID: partitionID}, in, out)
+}
+
+// relay passes the rows from raw to in, until raw is closed or the inputs of the stage are stopped.
+func (r *localRunner) relay(ctx context.Context, stopped <-chan struct{}, raw <-chan *lrdd.Row, in chan<- *lrdd.Row) {
+	defer close(in)
+	for {
+		select {
+		case row, ok := <-raw:
+			if !ok {
+				return
+			}
+			select {
+			case in <- row:
+			case <-stopped:
+				return
+			case <-ctx.Done():
+				return
+			}
+		case <-stopped:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// newOutput returns the output of a task in given stage, which writes to the inputs of the next stage
+// partitioned by given partitioner. The output of the last stage is collected.
+func (r *localRunner) newOutput(ctx context.Context, stageIdx int, p partitions.Partitioner, partitionID string, inputs []map[string]chan *lrdd.Row) output.Output {
+	if stageIdx == len(r.stages)-1 {
+		return localCollector{runner: r}
+	}
+	next := stageIdx + 1
+	outs := make(map[string]output.Output, len(inputs[next]))
+	for id, c := range inputs[next] {
+		outs[id] = &localOutput{
+			ctx:        ctx,
+			rows:       c,
+			stopped:    r.stopped[next],
+			failOnStop: stageIdx == 0,
+		}
+	}
+	return output.NewWriter(partitionID, p, outs)
+}
+
+// stopInputs stops the inputs of given stage and its preceding stages.
+func (r *localRunner) stopInputs(stageIdx int) {
+	for i := 1; i <= stageIdx; i++ {
+		r.stopOnce[i].Do(func() {
+			close(r.stopped[i])
+		})
+	}
+}
+
+// localOutput writes the rows to the input of a task.
+type localOutput struct {
+	ctx     context.Context
+	rows    chan<- *lrdd.Row
+	stopped <-chan struct{}
+
+	// failOnStop makes the writes fail after the input is stopped, so that the input of the job stops
+	// feeding. Otherwise, the rows are discarded.
+	failOnStop bool
+}
+
+func (l *localOutput) Write(rows ...*lrdd.Row) error {
+	for _, row := range rows {
+		select {
+		case l.rows <- row:
+		case <-l.stopped:
+			if l.failOnStop {
+				return errInputStopped
+			}
+			return nil
+		case <-l.ctx.Done():
+			return l.ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (l *localOutput) Close() error {
+	return nil
+}
+
+// localCollector gathers the output rows of the last stage.
+type localCollector struct {
+	runner *localRunner
+}
+
+func (c localCollector) Write(rows ...*lrdd.Row) error {
+	c.runner.collectedMu.Lock()
+	defer c.runner.collectedMu.Unlock()
+
+	c.runner.collected = append(c.runner.collected, rows...)
+	return nil
+}
+
+func (c localCollector) Close() error {
+	return nil
+}
+
+type localTaskContext struct {
+	context.Context
+	runner      *localRunner
+	stageIdx    int
+	partitionID string
+}
+
+func (c *localTaskContext) Broadcast(key string) interface{} {
+	return c.runner.broadcasts[key]
+}
+
+func (c *localTaskContext) WorkerLocalOption(string) interface{} {
+	return nil
+}
+
+func (c *localTaskContext) PartitionID() string {
+	return c.partitionID
+}
+
+func (c *localTaskContext) JobID() string {
+	return c.runner.jobID
+}
+
+func (c *localTaskContext) AddMetric(string, int) {}
+
+func (c *localTaskContext) SetMetric(string, int) {}
+
+func (c *localTaskContext) ReportProgress(float64) {}
+
+func (c *localTaskContext) IncrementCounter(name string) (int64, error) {
+	return c.runner.jobManager.IncrementJobCounter(c, c.runner.jobID, name)
+}
+
+func (c *localTaskContext) Accumulator(string) transformation.Accumulator {
+	return localAccumulator{}
+}
+
+func (c *localTaskContext) StopInputs() error {
+	c.runner.stopInputs(c.stageIdx)
+	return nil
+}
+
+// localAccumulator discards the values, since accumulators are not reported in local mode.
+type localAccumulator struct{}
+
+func (localAccumulator) Add(int64) {}
+
+// localTaskContext implements transformation.Context.
+var _ transformation.Context = (*localTaskContext)(nil)
@@ -0,0 +1,54 @@
+// Package logging routes the logs of lrmr to the logger of the host application.
+package logging
+
+import (
+	"sync/atomic"
+
+	"github.com/airbloc/logger"
+)
+
+// Logger is a leveled logger. Messages have "{}" placeholders, which are substituted with
+// the arguments in order (e.g. Info("Task {} done", id)). Use Format to render them.
+type Logger interface {
+	Verbose(msg string, v ...interface{})
+	Info(msg string, v ...interface{})
+	Warn(msg string, v ...interface{})
+	Error(msg string, v ...interface{})
+}
+
+var injected atomic.Value
+
+type holder struct{ Logger }
+
+// SetLogger routes the logs of lrmr to given logger. By default, they are written by airbloc/logger.
+// Passing nil restores the default. It affects the loggers created before the call too.
+func SetLogger(l Logger) {
+	injected.Store(holder{l})
+}
+
+// New returns a logger bound to given name, which writes to the logger set by SetLogger if any.
+func New(name string) Logger {
+	return &delegator{fallback: logger.New(name)}
+}
+
+type delegator struct {
+	fallback Logger
+}
+
+func (d *delegator) target() Logger {
+	if h, ok := injected.Load().(holder); ok && h.Logger != nil {
+		return h.Logger
+	}
+	return d.fallback
+}
+
+func (d *delegator) Verbose(msg string, v ...interface{}) { d.target().Verbose(msg, v...) }
+func (d *delegator) Info(msg string, v ...interface{})    { d.target().Info(msg, v...) }
+func (d *delegator) Warn(msg string, v ...interface{})    { d.target().Warn(msg, v...) }
+func (d *delegator) Error(msg string, v ...interface{})   { d.target().Error(msg, v...) }
+
+// Format substitutes the "{}" placeholders of the message with given arguments.
+func Format(msg string, v ...interface{}) string {
+	formatted, _ := logger.Format(msg, *logger.MergeAttrs(v))
+	return formatted
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordingLogger struct {
+	logs []string
+}
+
+func (r *recordingLogger) Verbose(msg string, v ...interface{}) { r.record("verbose", msg, v) }
+func (r *recordingLogger) Info(msg string, v ...interface{})    { r.record("info", msg, v) }
+func (r *recordingLogger) Warn(msg string, v ...interface{})    { r.record("warn", msg, v) }
+func (r *recordingLogger) Error(msg string, v ...interface{})   { r.record("error", msg, v) }
+
+func (r *recordingLogger) record(level, msg string, v []interface{}) {
+	r.logs = append(r.logs, level+": "+Format(msg, v...))
+}
+
+func TestSetLogger(t *testing.T) {
+	Convey("Given a logger created before injecting a logger", t, func() {
+		log := New("test")
+		rec := &recordingLogger{}
+		SetLogger(rec)
+		Reset(func() { SetLogger(nil) })
+
+		Convey("Its logs should be routed to the injected logger", func() {
+			log.Info("Task {} started", "t1")
+			log.Error("Task {} failed: {}", "t1", "boom")
+			So(rec.logs, ShouldResemble, []string{
+				"info: Task t1 started",
+				"error: Task t1 failed: boom",
+			})
+		})
+
+		Convey("When the injected logger is removed", func() {
+			SetLogger(nil)
+
+			Convey("Its logs should not be routed to the logger anymore", func() {
+				log.Verbose("Task {} started", "t1")
+				So(rec.logs, ShouldBeEmpty)
+			})
+		})
+	})
+}
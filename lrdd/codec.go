@@ -0,0 +1,45 @@
+package lrdd
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes and decodes the values of rows.
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, ptr interface{}) error
+}
+
+// DefaultCodec is the codec used for the values of rows. Since the rows are exchanged between
+// the nodes, every node in the cluster must use the same codec.
+var DefaultCodec Codec = MsgpackCodec{}
+
+// SetCodec replaces DefaultCodec. It must be called before any rows are created.
+func SetCodec(c Codec) {
+	DefaultCodec = c
+}
+
+// MsgpackCodec encodes values in MessagePack. Integers are decoded in their encoded width
+// (e.g. int8) if the type is not specified.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Encode(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Decode(data []byte, ptr interface{}) error {
+	return msgpack.Unmarshal(data, ptr)
+}
+
+// JSONCodec encodes values in JSON, which is slower and larger than MsgpackCodec but human-readable.
+// It is useful for debugging. Numbers are decoded as float64 if the type is not specified.
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(v interface{}) ([]byte, error) {
+	return jsoniter.Marshal(v)
+}
+
+func (JSONCodec) Decode(data []byte, ptr interface{}) error {
+	return jsoniter.Unmarshal(data, ptr)
+}
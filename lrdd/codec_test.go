@@ -0,0 +1,49 @@
+package lrdd
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCodec(t *testing.T) {
+	for _, codec := range []Codec{MsgpackCodec{}, JSONCodec{}} {
+		Convey(fmt.Sprintf("Given %T", codec), t, func() {
+			prev := DefaultCodec
+			SetCodec(codec)
+			defer SetCodec(prev)
+
+			Convey("Values should be preserved", func() {
+				row := KeyValue("foo", &testStruct{Foo: 1.5, Bar: "good"})
+				So(row.Key, ShouldEqual, "foo")
+
+				var decoded testStruct
+				So(row.DecodeValue(&decoded), ShouldBeNil)
+				So(decoded, ShouldResemble, testStruct{Foo: 1.5, Bar: "good"})
+
+				n, ok := row.GetFloat64("Foo")
+				So(ok, ShouldBeTrue)
+				So(n, ShouldEqual, 1.5)
+			})
+
+			Convey("Encoding an unsupported value should return an error", func() {
+				row := new(Row)
+				So(row.EncodeValue(make(chan int)), ShouldNotBeNil)
+				So(func() { Value(make(chan int)) }, ShouldPanic)
+			})
+		})
+	}
+
+	Convey("Given a value encoded in JSON", t, func() {
+		prev := DefaultCodec
+		SetCodec(JSONCodec{})
+		defer SetCodec(prev)
+
+		row := Value(map[string]interface{}{"n": 3})
+
+		Convey("It should be human-readable", func() {
+			So(string(row.Value), ShouldEqual, `{"n":3}`)
+		})
+	})
+}
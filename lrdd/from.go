@@ -1,11 +1,37 @@
 package lrdd
 
-import "reflect"
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
 
 var rowType = reflect.TypeOf((*Row)(nil))
 
-func From(values interface{}) (rows []*Row) {
+// From creates rows from given values. Slices and arrays create a row for each element.
+// Maps and structs create a row for each entry keyed by its key, or for each element keyed by
+// the key if the entry is a slice or an array. A slice in an interface value of a map is kept
+// in a single row, while the one in a field of a struct is exploded. Other values create a single row.
+//
+// The order of the rows from a map or a struct is not deterministic.
+// Use FromSorted if reproducible order is needed.
+func From(values interface{}) []*Row {
+	return from(values, false)
+}
+
+// FromSorted is same as From, except that the rows from a map or a struct are sorted by their key.
+// Elements of an entry keep their order.
+func FromSorted(values interface{}) []*Row {
+	return from(values, true)
+}
+
+func from(values interface{}, sorted bool) (rows []*Row) {
 	inputVal := reflect.ValueOf(values)
+	if inputVal.Kind() == reflect.Ptr && inputVal.Elem().Kind() == reflect.Struct {
+		inputVal = inputVal.Elem()
+	}
 	switch inputVal.Kind() {
 	case reflect.Slice, reflect.Array:
 		if inputVal.Type().Elem() == rowType {
@@ -15,20 +41,118 @@ func From(values interface{}) (rows []*Row) {
 			rows = append(rows, Value(inputVal.Index(i).Interface()))
 		}
 	case reflect.Map:
-		iter := inputVal.MapRange()
-		for iter.Next() {
-			k := iter.Key().String()
-			v := iter.Value()
-			if v.Kind() == reflect.Array || v.Kind() == reflect.Slice {
-				for i := 0; i < v.Len(); i++ {
-					rows = append(rows, KeyValue(k, v.Index(i).Interface()))
-				}
-			} else {
-				rows = append(rows, KeyValue(k, v.Interface()))
-			}
+		return fromEntries(inputVal, sorted, false)
+	case reflect.Struct:
+		if isOpaque(inputVal.Type()) {
+			return []*Row{Value(values)}
 		}
+		// fields are stored in interface values, whose lists are still exploded as the typed ones
+		return fromEntries(reflect.ValueOf(fieldsOf(inputVal)), sorted, true)
 	default:
 		rows = append(rows, Value(values))
 	}
 	return
 }
+
+// fromEntries creates a row for each entry of the map, or for each element if the entry is a slice or an array.
+// Slices stored in interface values are exploded only if explodeInterfaces is true.
+func fromEntries(m reflect.Value, sorted, explodeInterfaces bool) (rows []*Row) {
+	keys := m.MapKeys()
+	if sorted {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	}
+	for _, key := range keys {
+		k := key.String()
+		v := m.MapIndex(key)
+		if explodeInterfaces && v.Kind() == reflect.Interface && isList(v.Elem()) {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Array || v.Kind() == reflect.Slice {
+			for i := 0; i < v.Len(); i++ {
+				rows = append(rows, KeyValue(k, v.Index(i).Interface()))
+			}
+		} else {
+			rows = append(rows, KeyValue(k, v.Interface()))
+		}
+	}
+	return rows
+}
+
+// isList returns true if the value is a slice or an array, except for bytes.
+func isList(v reflect.Value) bool {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	return v.Type().Elem().Kind() != reflect.Uint8
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isOpaque returns true if the struct type has its own encoding (e.g. time.Time),
+// so that it shouldn't be exploded into fields.
+func isOpaque(t reflect.Type) bool {
+	for _, typ := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if typ.Implements(jsonMarshalerType) || typ.Implements(textMarshalerType) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldsOf converts the exported fields of the struct into a map, recursing into nested structs.
+// Fields are named by the msgpack tag, the json tag, or the field name in order, and the fields
+// tagged with "-" are skipped. Fields of embedded structs without a name are promoted.
+func fieldsOf(v reflect.Value) map[string]interface{} {
+	fields := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		exported := f.PkgPath == ""
+		if !exported && !f.Anonymous {
+			continue
+		}
+		name, tagged := fieldName(f)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && !isOpaque(fv.Type()) {
+			nested := fieldsOf(fv)
+			if f.Anonymous && !tagged {
+				for k, nv := range nested {
+					if _, ok := fields[k]; !ok {
+						fields[k] = nv
+					}
+				}
+				continue
+			}
+			if exported {
+				fields[name] = nested
+			}
+			continue
+		}
+		if exported {
+			fields[name] = fv.Interface()
+		}
+	}
+	return fields
+}
+
+func fieldName(f reflect.StructField) (name string, tagged bool) {
+	for _, key := range []string{"msgpack", "json"} {
+		tag, ok := f.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name, true
+		}
+	}
+	return f.Name, false
+}
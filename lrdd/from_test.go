@@ -1,8 +1,10 @@
 package lrdd
 
 import (
-	. "github.com/smartystreets/goconvey/convey"
 	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
 )
 
 func TestFrom(t *testing.T) {
@@ -135,5 +137,100 @@ func TestFrom(t *testing.T) {
 				So(count, ShouldEqual, 3)
 			})
 		})
+
+		Convey("When calling with map containing interface array", func() {
+			Convey("It should keep the array in a single row", func() {
+				rows := FromSorted(map[string]interface{}{
+					"foo": []string{"goo", "hoo"},
+					"bar": []byte("baz"),
+				})
+				So(rows, ShouldHaveLength, 2)
+
+				var actual []string
+				So(func() { rows[1].UnmarshalValue(&actual) }, ShouldNotPanic)
+				So(actual, ShouldResemble, []string{"goo", "hoo"})
+			})
+		})
+
+		Convey("When calling with struct", func() {
+			type address struct {
+				City string `json:"city"`
+			}
+			type base struct {
+				ID string
+			}
+			type user struct {
+				base
+				Name      string    `msgpack:"name" json:"userName"`
+				Age       int       `json:"age,omitempty"`
+				Tags      []string  `json:"tags"`
+				Address   *address  `json:"address"`
+				CreatedAt time.Time `json:"createdAt"`
+				Secret    string    `json:"-"`
+				internal  string
+			}
+			u := user{
+				base:      base{ID: "u1"},
+				Name:      "alice",
+				Age:       20,
+				Tags:      []string{"a", "b"},
+				Address:   &address{City: "Seoul"},
+				CreatedAt: time.Unix(0, 0),
+				Secret:    "s3cr3t",
+				internal:  "x",
+			}
+
+			Convey("It should create a row for each field keyed by its name", func() {
+				rows := From(&u)
+				keyCount := make(map[string]int)
+				for _, row := range rows {
+					keyCount[row.Key]++
+				}
+				So(keyCount, ShouldResemble, map[string]int{
+					"ID":        1,
+					"name":      1,
+					"age":       1,
+					"tags":      2,
+					"address":   1,
+					"createdAt": 1,
+				})
+
+				for _, row := range rows {
+					if row.Key == "address" {
+						city, ok := row.GetString("city")
+						So(ok, ShouldBeTrue)
+						So(city, ShouldEqual, "Seoul")
+					}
+					if row.Key == "createdAt" {
+						var createdAt time.Time
+						So(func() { row.UnmarshalValue(&createdAt) }, ShouldNotPanic)
+						So(createdAt.Equal(u.CreatedAt), ShouldBeTrue)
+					}
+				}
+			})
+		})
+	})
+}
+
+func TestFromSorted(t *testing.T) {
+	Convey("When calling lrdd.FromSorted with map", t, func() {
+		rows := FromSorted(map[string][]int{
+			"c": {1, 2},
+			"a": {3},
+			"b": {4, 5},
+		})
+
+		Convey("It should create rows sorted by key, keeping order of elements", func() {
+			var keys []string
+			var values []int
+			for _, row := range rows {
+				var v int
+				row.UnmarshalValue(&v)
+				keys = append(keys, row.Key)
+				values = append(values, v)
+			}
+			So(keys, ShouldResemble, []string{"a", "b", "b", "c", "c"})
+			So(values, ShouldResemble, []int{3, 4, 5, 1, 2})
+		})
 	})
 }
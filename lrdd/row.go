@@ -1,28 +1,276 @@
 package lrdd
 
 import (
-	"github.com/vmihailenco/msgpack/v5"
+	"math"
+
+	"github.com/pkg/errors"
 )
 
+// UnmarshalValue decodes the value of the row into ptr. It panics if the value can't be decoded.
 func (m Row) UnmarshalValue(ptr interface{}) {
-	err := msgpack.Unmarshal(m.Value, ptr)
-	if err != nil {
+	if err := m.DecodeValue(ptr); err != nil {
 		panic(err)
 	}
 }
 
+// DecodeValue decodes the value of the row into ptr with DefaultCodec.
+func (m Row) DecodeValue(ptr interface{}) error {
+	return DefaultCodec.Decode(m.Value, ptr)
+}
+
+// EncodeValue sets the value of the row to v encoded with DefaultCodec.
+func (m *Row) EncodeValue(v interface{}) error {
+	raw, err := DefaultCodec.Encode(v)
+	if err != nil {
+		return err
+	}
+	m.Value = raw
+	return nil
+}
+
 func Value(v interface{}) *Row {
-	return &Row{Value: mustEncode(v)}
+	return KeyValue("", v)
 }
 
+// KeyValue creates a row with given key and value. It panics if the value can't be encoded;
+// use Row.EncodeValue to handle the error.
 func KeyValue(k string, v interface{}) *Row {
-	return &Row{Key: k, Value: mustEncode(v)}
+	row := &Row{Key: k}
+	if err := row.EncodeValue(v); err != nil {
+		panic(err)
+	}
+	return row
 }
 
-func mustEncode(v interface{}) []byte {
-	raw, err := msgpack.Marshal(v)
-	if err != nil {
-		panic(err)
+// Project returns a row with the same key, whose value only has the fields of given keys.
+// The keys which don't exist are omitted. The row is returned as is if its value is not a map.
+func (m Row) Project(keys ...string) Row {
+	var fields map[string]interface{}
+	if err := m.DecodeValue(&fields); err != nil {
+		return m
+	}
+	projected := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			projected[k] = v
+		}
+	}
+	row := Row{Key: m.Key}
+	if err := row.EncodeValue(projected); err != nil {
+		return m
+	}
+	return row
+}
+
+// Rename returns a row whose value has the field of key old renamed to new, replacing the field of new if exists.
+// The row is returned as is if its value is not a map or it doesn't have the field.
+func (m Row) Rename(old, new string) Row {
+	return m.reshape(func(fields map[string]interface{}) bool {
+		v, ok := fields[old]
+		if !ok {
+			return false
+		}
+		delete(fields, old)
+		fields[new] = v
+		return true
+	})
+}
+
+// Drop returns a row whose value doesn't have the fields of given keys.
+// The row is returned as is if its value is not a map.
+func (m Row) Drop(keys ...string) Row {
+	return m.reshape(func(fields map[string]interface{}) bool {
+		for _, k := range keys {
+			delete(fields, k)
+		}
+		return true
+	})
+}
+
+// With returns a row whose value has the field of given key set to val. An empty value is regarded
+// as an empty map. The row is returned as is if its value is not a map, or val can't be encoded.
+func (m Row) With(key string, val interface{}) Row {
+	return m.reshape(func(fields map[string]interface{}) bool {
+		fields[key] = val
+		return true
+	})
+}
+
+// reshape returns a row with the same key, whose value is the map of the fields modified by fn.
+// Since the value is decoded into a new map, m is not modified. The row is returned as is
+// if its value is not a map, or fn returns false.
+func (m Row) reshape(fn func(fields map[string]interface{}) bool) Row {
+	fields := make(map[string]interface{})
+	if len(m.Value) > 0 {
+		if err := m.DecodeValue(&fields); err != nil || fields == nil {
+			return m
+		}
+	}
+	if !fn(fields) {
+		return m
+	}
+	row := Row{Key: m.Key}
+	if err := row.EncodeValue(fields); err != nil {
+		return m
+	}
+	return row
+}
+
+// Merge returns a row with the key of m, whose value has the fields of m and a. The fields of a
+// override those of m. The values of both rows are expected to be maps. See MergeMany.
+func (m Row) Merge(a Row) (Row, error) {
+	return MergeMany(m, a)
+}
+
+// MergeMany returns a row with the key of the first row, whose value has the fields of all given rows.
+// The fields of the later rows override those of the earlier rows. The values of the rows are expected
+// to be maps. Since all values are decoded into a single map and encoded once, merging many rows at once
+// allocates much less than merging them one by one.
+func MergeMany(rows ...Row) (Row, error) {
+	if len(rows) == 0 {
+		return Row{}, nil
+	}
+	merged := Row{Key: rows[0].Key}
+	if err := MergeInto(&merged, rows...); err != nil {
+		return Row{}, err
+	}
+	return merged, nil
+}
+
+// MergeInto merges the fields of others into the value of dst in place, keeping its key.
+// The fields of the later rows override those of the earlier rows and dst. The value of dst
+// can be empty; otherwise the values of all rows are expected to be maps.
+func MergeInto(dst *Row, others ...Row) error {
+	fields := make(map[string]interface{})
+	if len(dst.Value) > 0 {
+		if err := dst.DecodeValue(&fields); err != nil {
+			return errors.Wrap(err, "decode value of destination row")
+		}
+	}
+	for i, r := range others {
+		// decoding into a non-nil map adds the fields to the map
+		if err := r.DecodeValue(&fields); err != nil {
+			return errors.Wrapf(err, "decode value of row #%d", i)
+		}
+	}
+	return dst.EncodeValue(fields)
+}
+
+// GetOr returns the field with given key in the value of the row, which is expected to be a map.
+// It returns def if the value is not a map or the field doesn't exist.
+func (m Row) GetOr(key string, def interface{}) interface{} {
+	v, ok := m.field(key)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// GetString returns the field with given key as a string.
+// It returns false if the field doesn't exist or isn't a string.
+func (m Row) GetString(key string) (string, bool) {
+	v, _ := m.field(key)
+	s, ok := v.(string)
+	return s, ok
+}
+
+// GetInt64 returns the field with given key as an int64, converting integers of any width.
+// Floats are converted only if they have no fractional part. It returns false if the field
+// doesn't exist, isn't a number or overflows int64.
+func (m Row) GetInt64(key string) (int64, bool) {
+	v, _ := m.field(key)
+	return toInt64(v)
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return uintToInt64(uint64(n))
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return uintToInt64(n)
+	case float32:
+		return floatToInt64(float64(n))
+	case float64:
+		return floatToInt64(n)
+	}
+	return 0, false
+}
+
+func uintToInt64(n uint64) (int64, bool) {
+	if n > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(n), true
+}
+
+func floatToInt64(f float64) (int64, bool) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// GetFloat64 returns the field with given key as a float64, converting numbers of any type.
+// It returns false if the field doesn't exist or isn't a number.
+func (m Row) GetFloat64(key string) (float64, bool) {
+	v, _ := m.field(key)
+	return ToFloat64(v)
+}
+
+// ToFloat64 converts a number of any type decoded from a row value, such as an integer of any width, to a float64.
+// It returns false if given value isn't a number.
+func ToFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// field decodes the value of the row as a map and returns the field with given key.
+func (m Row) field(key string) (interface{}, bool) {
+	var fields map[string]interface{}
+	if err := m.DecodeValue(&fields); err != nil {
+		return nil, false
 	}
-	return raw
+	v, ok := fields[key]
+	return v, ok
 }
@@ -2,6 +2,7 @@ package lrdd
 
 import (
 	"math"
+	"strconv"
 	"testing"
 
 	"github.com/gogo/protobuf/proto"
@@ -66,7 +67,259 @@ func TestRow_Encode(t *testing.T) {
 	})
 }
 
+func TestRow_Get(t *testing.T) {
+	Convey("Given a row with a map value", t, func() {
+		row := Value(map[string]interface{}{
+			"str":      "foo",
+			"small":    int8(12),
+			"big":      uint64(math.MaxUint64),
+			"integral": 3.0,
+			"pi":       math.Pi,
+			"nil":      nil,
+		})
+
+		Convey("GetString should return strings only", func() {
+			s, ok := row.GetString("str")
+			So(ok, ShouldBeTrue)
+			So(s, ShouldEqual, "foo")
+
+			_, ok = row.GetString("small")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("GetInt64 should convert integers of any width", func() {
+			n, ok := row.GetInt64("small")
+			So(ok, ShouldBeTrue)
+			So(n, ShouldEqual, 12)
+
+			n, ok = row.GetInt64("integral")
+			So(ok, ShouldBeTrue)
+			So(n, ShouldEqual, 3)
+
+			for _, key := range []string{"big", "pi", "str", "absent"} {
+				n, ok = row.GetInt64(key)
+				So(ok, ShouldBeFalse)
+				So(n, ShouldEqual, 0)
+			}
+		})
+
+		Convey("GetFloat64 should convert numbers of any type", func() {
+			f, ok := row.GetFloat64("small")
+			So(ok, ShouldBeTrue)
+			So(f, ShouldEqual, 12)
+
+			f, ok = row.GetFloat64("pi")
+			So(ok, ShouldBeTrue)
+			So(f, ShouldEqual, math.Pi)
+
+			_, ok = row.GetFloat64("str")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("GetOr should return the default only if the field is absent", func() {
+			So(row.GetOr("str", "bar"), ShouldEqual, "foo")
+			So(row.GetOr("nil", "bar"), ShouldBeNil)
+			So(row.GetOr("absent", "bar"), ShouldEqual, "bar")
+		})
+	})
+
+	Convey("Given a row with a struct value", t, func() {
+		row := Value(&testStruct{Foo: math.Pi, Bar: "good"})
+
+		Convey("Its fields should be accessible", func() {
+			s, ok := row.GetString("Bar")
+			So(ok, ShouldBeTrue)
+			So(s, ShouldEqual, "good")
+		})
+	})
+
+	Convey("Given a row with a non-map value", t, func() {
+		row := Value(1234)
+
+		Convey("It should return nothing", func() {
+			_, ok := row.GetInt64("foo")
+			So(ok, ShouldBeFalse)
+			So(row.GetOr("foo", 1), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestRow_Project(t *testing.T) {
+	Convey("Given a row with a map value", t, func() {
+		row := KeyValue("foo", map[string]interface{}{"a": 1, "b": "2", "c": 3.0})
+
+		Convey("It should keep only the projected fields", func() {
+			projected := row.Project("a", "c", "absent")
+			So(projected.Key, ShouldEqual, "foo")
+
+			var fields map[string]interface{}
+			projected.UnmarshalValue(&fields)
+			So(fields, ShouldHaveLength, 2)
+			So(fields, ShouldContainKey, "a")
+			So(fields, ShouldContainKey, "c")
+			So(projected.Size(), ShouldBeLessThan, row.Size())
+		})
+	})
+
+	Convey("Given a row with a non-map value", t, func() {
+		row := KeyValue("foo", 1234)
+
+		Convey("It should be returned as is", func() {
+			So(row.Project("a").Value, ShouldResemble, row.Value)
+		})
+	})
+}
+
+func TestRow_Reshape(t *testing.T) {
+	Convey("Given a row with a map value", t, func() {
+		row := KeyValue("foo", map[string]interface{}{"a": 1, "b": "2"})
+		value := append([]byte(nil), row.Value...)
+
+		fieldsOf := func(r Row) map[string]interface{} {
+			var fields map[string]interface{}
+			r.UnmarshalValue(&fields)
+			return fields
+		}
+
+		Convey("Rename should rename the field", func() {
+			renamed := row.Rename("a", "c")
+			So(renamed.Key, ShouldEqual, "foo")
+			So(fieldsOf(renamed), ShouldNotContainKey, "a")
+			So(renamed.GetOr("c", nil), ShouldEqual, 1)
+
+			Convey("Renaming an absent field should return the row as is", func() {
+				So(row.Rename("absent", "c").Value, ShouldResemble, row.Value)
+			})
+		})
+
+		Convey("Drop should remove the fields", func() {
+			dropped := row.Drop("a", "absent")
+			So(fieldsOf(dropped), ShouldHaveLength, 1)
+			So(fieldsOf(dropped), ShouldContainKey, "b")
+		})
+
+		Convey("With should set the field", func() {
+			So(row.With("c", 3).GetOr("c", nil), ShouldEqual, 3)
+			So(row.With("a", "replaced").GetOr("a", nil), ShouldEqual, "replaced")
+		})
+
+		Convey("They should be chainable", func() {
+			reshaped := row.Rename("a", "x").Drop("b").With("y", true)
+			So(fieldsOf(reshaped), ShouldHaveLength, 2)
+			So(reshaped.GetOr("x", nil), ShouldEqual, 1)
+			So(reshaped.GetOr("y", nil), ShouldEqual, true)
+		})
+
+		Reset(func() {
+			// the receiver should never be modified
+			So(row.Value, ShouldResemble, value)
+			So(fieldsOf(*row), ShouldHaveLength, 2)
+		})
+	})
+
+	Convey("Given a row with an empty value", t, func() {
+		row := Row{Key: "foo"}
+
+		Convey("With should create a map value", func() {
+			So(row.With("a", 1).GetOr("a", nil), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given a row with a non-map value", t, func() {
+		row := KeyValue("foo", 1234)
+
+		Convey("It should be returned as is", func() {
+			So(row.Rename("a", "b").Value, ShouldResemble, row.Value)
+			So(row.Drop("a").Value, ShouldResemble, row.Value)
+			So(row.With("a", 1).Value, ShouldResemble, row.Value)
+		})
+	})
+}
+
 type testStruct struct {
 	Foo float64
 	Bar string
 }
+
+func TestRow_Merge(t *testing.T) {
+	Convey("Given rows with map values", t, func() {
+		a := KeyValue("a", map[string]interface{}{"x": 1, "y": "a"})
+		b := KeyValue("b", map[string]interface{}{"y": "b", "z": true})
+		c := KeyValue("c", map[string]interface{}{"z": false})
+
+		Convey("Merge should override the fields with the latter", func() {
+			merged, err := a.Merge(*b)
+			So(err, ShouldBeNil)
+			So(merged.Key, ShouldEqual, "a")
+			x, _ := merged.GetInt64("x")
+			So(x, ShouldEqual, 1)
+			So(merged.GetOr("y", nil), ShouldEqual, "b")
+			So(merged.GetOr("z", nil), ShouldEqual, true)
+		})
+
+		Convey("MergeMany should merge all rows", func() {
+			merged, err := MergeMany(*a, *b, *c)
+			So(err, ShouldBeNil)
+			So(merged.Key, ShouldEqual, "a")
+			So(merged.GetOr("y", nil), ShouldEqual, "b")
+			So(merged.GetOr("z", nil), ShouldEqual, false)
+		})
+
+		Convey("MergeInto should merge the rows in place", func() {
+			So(MergeInto(c, *a, *b), ShouldBeNil)
+			So(c.Key, ShouldEqual, "c")
+			So(c.GetOr("y", nil), ShouldEqual, "b")
+			So(c.GetOr("z", nil), ShouldEqual, true)
+
+			Convey("Source rows should not be changed", func() {
+				So(a.GetOr("y", nil), ShouldEqual, "a")
+			})
+		})
+
+		Convey("Merging a row with a non-map value should return an error", func() {
+			_, err := a.Merge(*KeyValue("d", 1234))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func benchmarkRows(n int) []Row {
+	rows := make([]Row, n)
+	for i := range rows {
+		rows[i] = *KeyValue("k", map[string]interface{}{
+			"field" + strconv.Itoa(i): i,
+			"common":                  i,
+		})
+	}
+	return rows
+}
+
+// BenchmarkRow_Merge merges the rows one by one.
+func BenchmarkRow_Merge(b *testing.B) {
+	rows := benchmarkRows(16)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		merged := rows[0]
+		for _, r := range rows[1:] {
+			var err error
+			if merged, err = merged.Merge(r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkRow_MergeMany merges the rows at once.
+func BenchmarkRow_MergeMany(b *testing.B) {
+	rows := benchmarkRows(16)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := MergeMany(rows...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
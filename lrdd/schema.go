@@ -0,0 +1,81 @@
+package lrdd
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// FieldType is an expected type of a field in Schema.
+type FieldType string
+
+const (
+	String FieldType = "string"
+	Bool   FieldType = "bool"
+
+	// Int accepts integers of any width, and floats without fractional part.
+	Int FieldType = "int"
+
+	// Float accepts numbers of any type.
+	Float FieldType = "float"
+
+	Map  FieldType = "map"
+	List FieldType = "list"
+
+	// Any only checks the presence of the field.
+	Any FieldType = "any"
+)
+
+// Schema describes the fields expected in the map value of rows, keyed by the field name.
+type Schema map[string]FieldType
+
+// Validate checks whether the value of the row is a map having every field declared in the schema
+// with the expected type.
+func (m Row) Validate(s Schema) error {
+	var fields map[string]interface{}
+	if err := m.DecodeValue(&fields); err != nil {
+		return errors.Wrapf(err, "row %s is not a map", m.Key)
+	}
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v, ok := fields[name]
+		if !ok {
+			return errors.Errorf("row %s: missing field %s", m.Key, name)
+		}
+		if !s[name].Matches(v) {
+			return errors.Errorf("row %s: expected field %s to be %s, but got %T", m.Key, name, s[name], v)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if given value is acceptable as a field of the type.
+func (t FieldType) Matches(v interface{}) bool {
+	switch t {
+	case String:
+		_, ok := v.(string)
+		return ok
+	case Bool:
+		_, ok := v.(bool)
+		return ok
+	case Int:
+		_, ok := toInt64(v)
+		return ok
+	case Float:
+		_, ok := ToFloat64(v)
+		return ok
+	case Map:
+		return v != nil && reflect.TypeOf(v).Kind() == reflect.Map
+	case List:
+		return v != nil && reflect.TypeOf(v).Kind() == reflect.Slice
+	case Any:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package lrdd
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRow_Validate(t *testing.T) {
+	Convey("Given a schema", t, func() {
+		s := Schema{
+			"name":   String,
+			"age":    Int,
+			"score":  Float,
+			"tags":   List,
+			"extra":  Map,
+			"active": Bool,
+			"note":   Any,
+		}
+
+		Convey("A row satisfying the schema should be valid", func() {
+			row := Value(map[string]interface{}{
+				"name":   "alice",
+				"age":    uint8(20),
+				"score":  3,
+				"tags":   []string{"a"},
+				"extra":  map[string]int{"a": 1},
+				"active": true,
+				"note":   nil,
+				"other":  "ignored",
+			})
+			So(row.Validate(s), ShouldBeNil)
+		})
+
+		Convey("A row missing a field should be invalid", func() {
+			row := Value(map[string]interface{}{"nmae": "alice"})
+			So(row.Validate(Schema{"name": String}), ShouldBeError, "row : missing field name")
+		})
+
+		Convey("A row with a field of wrong type should be invalid", func() {
+			row := KeyValue("foo", map[string]interface{}{"age": 1.5})
+			So(row.Validate(Schema{"age": Int}), ShouldBeError, "row foo: expected field age to be int, but got float64")
+		})
+
+		Convey("A row with a non-map value should be invalid", func() {
+			So(Value(1234).Validate(s), ShouldNotBeNil)
+		})
+	})
+}
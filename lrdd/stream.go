@@ -0,0 +1,103 @@
+package lrdd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// RowEncoder writes rows to a stream. Each row is encoded in protobuf, prefixed with its size in uvarint.
+type RowEncoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+func NewRowEncoder(w io.Writer) *RowEncoder {
+	return &RowEncoder{w: w}
+}
+
+func (e *RowEncoder) Encode(rows ...*Row) error {
+	for _, row := range rows {
+		size := row.Size()
+		if cap(e.buf) < binary.MaxVarintLen64+size {
+			e.buf = make([]byte, binary.MaxVarintLen64+size)
+		}
+		n := binary.PutUvarint(e.buf, uint64(size))
+		if _, err := row.MarshalToSizedBuffer(e.buf[n : n+size]); err != nil {
+			return err
+		}
+		if _, err := e.w.Write(e.buf[:n+size]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RowDecoder reads rows written by RowEncoder from a stream one by one, so that the memory usage
+// is bounded by the size of a row rather than the size of the whole stream.
+type RowDecoder struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func NewRowDecoder(r io.Reader) *RowDecoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &RowDecoder{r: br}
+}
+
+// Decode reads the next row from the stream. It returns io.EOF if there are no more rows,
+// or io.ErrUnexpectedEOF if the stream ends in the middle of a row.
+func (d *RowDecoder) Decode() (*Row, error) {
+	size, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return nil, err
+	}
+	if size > math.MaxInt32 {
+		return nil, ErrInvalidLengthRow
+	}
+	if cap(d.buf) < int(size) {
+		d.buf = make([]byte, size)
+	}
+	d.buf = d.buf[:size]
+	if _, err := io.ReadFull(d.r, d.buf); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	// Unmarshal copies the fields, so the buffer can be reused
+	row := new(Row)
+	if err := row.Unmarshal(d.buf); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// DecodeRows returns an iterator decoding the rows in the stream one by one. The iterator returns
+// false after the last row or on an error, which is returned by the err function.
+func DecodeRows(r io.Reader) (iter func() (*Row, bool), err func() error) {
+	d := NewRowDecoder(r)
+	var lastErr error
+	iter = func() (*Row, bool) {
+		if lastErr != nil {
+			return nil, false
+		}
+		row, err := d.Decode()
+		if err != nil {
+			lastErr = err
+			return nil, false
+		}
+		return row, true
+	}
+	err = func() error {
+		if lastErr == io.EOF {
+			return nil
+		}
+		return lastErr
+	}
+	return iter, err
+}
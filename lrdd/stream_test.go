@@ -0,0 +1,106 @@
+package lrdd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRowDecoder(t *testing.T) {
+	Convey("Given a stream of encoded rows", t, func() {
+		var buf bytes.Buffer
+		rows := make([]*Row, 100)
+		for i := range rows {
+			rows[i] = KeyValue(strconv.Itoa(i), i)
+		}
+		So(NewRowEncoder(&buf).Encode(rows...), ShouldBeNil)
+
+		Convey("It should decode rows one by one", func() {
+			next, errFn := DecodeRows(bytes.NewReader(buf.Bytes()))
+			var decoded []*Row
+			for row, ok := next(); ok; row, ok = next() {
+				decoded = append(decoded, row)
+			}
+			So(errFn(), ShouldBeNil)
+			So(decoded, ShouldHaveLength, len(rows))
+			for i, row := range decoded {
+				So(row.Key, ShouldEqual, rows[i].Key)
+				So(row.Value, ShouldResemble, rows[i].Value)
+			}
+		})
+
+		Convey("It should report a truncated stream", func() {
+			d := NewRowDecoder(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+			var err error
+			for err == nil {
+				_, err = d.Decode()
+			}
+			So(err, ShouldEqual, io.ErrUnexpectedEOF)
+		})
+	})
+}
+
+func encodedRows(b *testing.B, n int) []byte {
+	var buf bytes.Buffer
+	enc := NewRowEncoder(&buf)
+	value := make([]byte, 1024)
+	for i := 0; i < n; i++ {
+		if err := enc.Encode(KeyValue(strconv.Itoa(i), value)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// BenchmarkDecodeRows_Batch decodes the whole batch into a slice before processing it.
+func BenchmarkDecodeRows_Batch(b *testing.B) {
+	data := encodedRows(b, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		raw, err := ioutil.ReadAll(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		d := NewRowDecoder(bytes.NewReader(raw))
+		var rows []*Row
+		for {
+			row, err := d.Decode()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) != 10000 {
+			b.Fatalf("expected 10000 rows, got %d", len(rows))
+		}
+	}
+}
+
+// BenchmarkDecodeRows_Streaming processes the rows one by one as they are decoded.
+func BenchmarkDecodeRows_Streaming(b *testing.B) {
+	data := encodedRows(b, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		next, errFn := DecodeRows(bytes.NewReader(data))
+		n := 0
+		for _, ok := next(); ok; _, ok = next() {
+			n++
+		}
+		if err := errFn(); err != nil {
+			b.Fatal(err)
+		}
+		if n != 10000 {
+			b.Fatalf("expected 10000 rows, got %d", n)
+		}
+	}
+}
@@ -1,19 +1,21 @@
 package lrmr
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
 
 	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/logging"
 	"github.com/ab180/lrmr/master"
 	"github.com/ab180/lrmr/worker"
-	"github.com/airbloc/logger"
 )
 
 var (
-	log = logger.New("lrmr")
+	log = logging.New("lrmr")
 )
 
 func RunMaster(optionalOpt ...Options) (*master.Master, error) {
@@ -22,7 +24,7 @@ func RunMaster(optionalOpt ...Options) (*master.Master, error) {
 		opt = optionalOpt[0]
 	}
 
-	etcd, err := coordinator.NewEtcd(opt.EtcdEndpoints, opt.EtcdNamespace)
+	etcd, err := coordinator.NewEtcd(opt.EtcdEndpoints, opt.EtcdNamespace, coordinator.WithRetryOptions(opt.EtcdRetry))
 	if err != nil {
 		return nil, fmt.Errorf("connect etcd: %w", err)
 	}
@@ -37,7 +39,7 @@ func RunWorker(optionalOpt ...Options) error {
 		opt = optionalOpt[0]
 	}
 
-	etcd, err := coordinator.NewEtcd(opt.EtcdEndpoints, opt.EtcdNamespace)
+	etcd, err := coordinator.NewEtcd(opt.EtcdEndpoints, opt.EtcdNamespace, coordinator.WithRetryOptions(opt.EtcdRetry))
 	if err != nil {
 		return fmt.Errorf("connect etcd: %w", err)
 	}
@@ -45,19 +47,38 @@ func RunWorker(optionalOpt ...Options) error {
 	if err != nil {
 		return fmt.Errorf("init worker: %w", err)
 	}
+	log.Info("Starting worker on {}", opt.Worker.ListenHost)
 	go func() {
 		if err := w.Start(); err != nil {
-			log.Wtf("failed to start worker", err)
+			log.Error("Failed to start worker: {}", err)
 			return
 		}
 	}()
+	if opt.Worker.MetricsListenHost != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", w.MetricsHandler())
+		mux.Handle("/status", w.StatusHandler())
+		metricsServer := &http.Server{Addr: opt.Worker.MetricsListenHost, Handler: mux}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("Failed to serve metrics: {}", err)
+			}
+		}()
+		defer metricsServer.Close()
+	}
 
 	waitForExit := make(chan os.Signal)
 	signal.Notify(waitForExit, os.Interrupt, os.Kill)
 	<-waitForExit
+	log.Info("Stopping worker")
 
+	ctx, cancel := context.WithTimeout(context.Background(), opt.Worker.DrainTimeout)
+	defer cancel()
+	if err := w.Drain(ctx); err != nil {
+		log.Warn("Failed to drain running tasks: {}", err)
+	}
 	if err := w.Close(); err != nil {
-		log.Error("failed to shutdown historical node", err)
+		log.Error("Failed to shutdown worker: {}", err)
 	}
 	log.Info("Bye")
 	return nil
@@ -413,6 +413,9 @@ func (m *CreateTaskResponse) GetTaskID() string {
 // metadata with key "header" and value of DataHeader is required.
 type PushDataRequest struct {
 	Data []*lrdd.Row `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty"`
+	// Encoded is a stream of rows encoded by lrdd.RowEncoder, used instead of Data
+	// when the sender is configured with the stream codec.
+	Encoded []byte `protobuf:"bytes,2,opt,name=encoded,proto3" json:"encoded,omitempty"`
 }
 
 func (m *PushDataRequest) Reset()         { *m = PushDataRequest{} }
@@ -455,6 +458,13 @@ func (m *PushDataRequest) GetData() []*lrdd.Row {
 	return nil
 }
 
+func (m *PushDataRequest) GetEncoded() []byte {
+	if m != nil {
+		return m.Encoded
+	}
+	return nil
+}
+
 // PollDataRequest is a request to poll data for a worker to process.
 // metadata with key "header" and value of DataHeader is required.
 type PollDataRequest struct {
@@ -626,49 +636,50 @@ func init() {
 func init() { proto.RegisterFile("lrmrpb/rpc.proto", fileDescriptor_f4e130d388338f6d) }
 
 var fileDescriptor_f4e130d388338f6d = []byte{
-	// 665 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0xdd, 0x4e, 0xdb, 0x48,
-	0x14, 0xce, 0xe4, 0x4f, 0xc9, 0x81, 0x4d, 0xa2, 0x59, 0xc4, 0x5a, 0xde, 0xdd, 0x10, 0x19, 0xa9,
-	0x4d, 0xab, 0xca, 0x41, 0xf4, 0xa6, 0xad, 0x84, 0x54, 0x28, 0xb4, 0x84, 0x02, 0x89, 0x06, 0xfa,
-	0x00, 0x13, 0x3c, 0x04, 0x17, 0xc7, 0xe3, 0x7a, 0x26, 0x45, 0x79, 0x8b, 0xbe, 0x55, 0x2b, 0xf5,
-	0x86, 0xcb, 0x5e, 0x22, 0x78, 0x91, 0x6a, 0x66, 0x6c, 0x6a, 0x87, 0x42, 0x6f, 0xa2, 0x73, 0xce,
-	0xf7, 0x63, 0x9f, 0xcf, 0x99, 0x81, 0x56, 0x10, 0x4f, 0xe2, 0x68, 0xd4, 0x8b, 0xa3, 0x13, 0x37,
-	0x8a, 0xb9, 0xe4, 0xb8, 0x6a, 0x26, 0xf6, 0xd2, 0x98, 0x8f, 0xb9, 0x1e, 0xf5, 0x54, 0x65, 0x50,
-	0xfb, 0xdf, 0x31, 0xe7, 0xe3, 0x80, 0xf5, 0x74, 0x37, 0x9a, 0x9e, 0xf6, 0xd8, 0x24, 0x92, 0xb3,
-	0x04, 0x6c, 0x04, 0xb1, 0xe7, 0xf5, 0x62, 0x7e, 0x91, 0xf4, 0xff, 0xf9, 0xa1, 0x64, 0x71, 0x48,
-	0x83, 0x5e, 0x34, 0x92, 0xb3, 0x88, 0x89, 0x9e, 0xfe, 0x35, 0xa8, 0xf3, 0xb5, 0x08, 0xf8, 0x4d,
-	0xcc, 0xa8, 0x64, 0xc7, 0x54, 0x9c, 0x0b, 0xc2, 0x3e, 0x4d, 0x99, 0x90, 0x78, 0x05, 0x4a, 0x1f,
-	0xf9, 0xc8, 0x42, 0x1d, 0xd4, 0x5d, 0x58, 0xff, 0xcb, 0x4d, 0x94, 0xee, 0xde, 0xd1, 0xe0, 0x90,
-	0x28, 0x04, 0x2f, 0x41, 0x45, 0x48, 0x3a, 0x66, 0x56, 0xb1, 0x83, 0xba, 0x75, 0x62, 0x1a, 0xec,
-	0xc0, 0x62, 0x44, 0x63, 0xe9, 0x4b, 0x9f, 0x87, 0xfd, 0x6d, 0x61, 0x95, 0x3a, 0xa5, 0x6e, 0x9d,
-	0xe4, 0x66, 0x78, 0x15, 0x2a, 0x7e, 0x18, 0x4d, 0xa5, 0x55, 0xee, 0x94, 0xb4, 0xb9, 0x59, 0xd5,
-	0xed, 0xab, 0x21, 0x31, 0x18, 0x7e, 0x04, 0x55, 0x3e, 0x95, 0x8a, 0x55, 0xd1, 0xaf, 0xd0, 0x48,
-	0x59, 0x03, 0x3d, 0x25, 0x09, 0x8a, 0xf7, 0x00, 0x46, 0x31, 0xa7, 0xde, 0x09, 0x15, 0x52, 0x58,
-	0x55, 0xed, 0xf8, 0x34, 0xe5, 0xde, 0xdd, 0xcb, 0xdd, 0xba, 0x25, 0xef, 0x84, 0x32, 0x9e, 0x91,
-	0x8c, 0xda, 0xde, 0x80, 0xe6, 0x1c, 0x8c, 0x5b, 0x50, 0x3a, 0x67, 0x33, 0x1d, 0x43, 0x9d, 0xa8,
-	0x52, 0xed, 0xfd, 0x99, 0x06, 0x53, 0xb3, 0xf7, 0x22, 0x31, 0xcd, 0xab, 0xe2, 0x0b, 0xe4, 0x3c,
-	0x81, 0xd2, 0x1e, 0x1f, 0xe1, 0x06, 0x14, 0x7d, 0x2f, 0x51, 0x14, 0x7d, 0x0f, 0x63, 0x28, 0x87,
-	0x74, 0x92, 0xe6, 0xa4, 0x6b, 0xe7, 0x3d, 0x54, 0xfa, 0xc9, 0x9a, 0x65, 0x15, 0xac, 0xa6, 0x37,
-	0xd6, 0x71, 0x2e, 0x0a, 0xf7, 0x78, 0x16, 0x31, 0xa2, 0x71, 0xc7, 0x86, 0xb2, 0xea, 0x70, 0x0d,
-	0xca, 0xc3, 0x0f, 0x47, 0xbb, 0xad, 0x82, 0xae, 0x06, 0xfb, 0xfb, 0x2d, 0xe4, 0x5c, 0x21, 0xa8,
-	0x9a, 0x54, 0xf0, 0xe3, 0x9c, 0xdd, 0xdf, 0xf9, 0xcc, 0x32, 0x7e, 0xf8, 0x00, 0x9a, 0xb7, 0xdf,
-	0xe4, 0x98, 0xef, 0x72, 0x21, 0xad, 0xa2, 0xce, 0x6e, 0x75, 0x4e, 0x33, 0xcc, 0xb3, 0x4c, 0x68,
-	0xf3, 0x5a, 0x7b, 0x0b, 0x96, 0x7e, 0x47, 0xfc, 0x53, 0x7c, 0xf5, 0x6c, 0x7c, 0x0f, 0xad, 0xf8,
-	0x12, 0x16, 0x94, 0xe9, 0x01, 0x8d, 0x22, 0x3f, 0x1c, 0xab, 0x48, 0xcf, 0xd4, 0x2b, 0x1b, 0x5f,
-	0x5d, 0xe3, 0x65, 0xa8, 0x4a, 0x2a, 0xce, 0xfb, 0xdb, 0x89, 0x73, 0xd2, 0x39, 0xcf, 0xb2, 0x7f,
-	0x6f, 0xc2, 0x44, 0xc4, 0x43, 0xc1, 0x32, 0x6c, 0x94, 0x63, 0xaf, 0x41, 0x73, 0x38, 0x15, 0x67,
-	0xdb, 0x54, 0xd2, 0xf4, 0x24, 0xfc, 0x0f, 0x65, 0x8f, 0x4a, 0x6a, 0x21, 0x9d, 0x4f, 0xdd, 0x55,
-	0xa7, 0xcb, 0x25, 0xfc, 0x82, 0xe8, 0xb1, 0xb3, 0x02, 0xcd, 0x21, 0x0f, 0x82, 0xac, 0x62, 0x11,
-	0x50, 0xa8, 0x7d, 0x4b, 0x04, 0x85, 0xce, 0x3b, 0x68, 0xfd, 0x22, 0x24, 0x8f, 0x7f, 0xd8, 0x53,
-	0x85, 0xe4, 0x8b, 0x9d, 0xc1, 0x5b, 0xbd, 0x4a, 0x8d, 0x98, 0xc6, 0x79, 0x0d, 0xa0, 0x4c, 0x76,
-	0x19, 0xf5, 0x58, 0x7c, 0xdf, 0x06, 0xd8, 0x86, 0xda, 0x69, 0xcc, 0x27, 0xc9, 0x27, 0x55, 0xc8,
-	0x6d, 0xbf, 0xfe, 0x1d, 0x41, 0xf9, 0x90, 0x7b, 0x0c, 0x6f, 0xc2, 0x42, 0xe6, 0x6c, 0x60, 0xfb,
-	0xfe, 0x03, 0x63, 0x2f, 0xbb, 0xe6, 0xae, 0x71, 0xd3, 0xbb, 0xc6, 0xdd, 0x51, 0x77, 0x0d, 0xde,
-	0x80, 0x5a, 0x9a, 0x14, 0xfe, 0x27, 0xd5, 0xcf, 0x65, 0x77, 0x9f, 0xb8, 0x8b, 0xf0, 0x26, 0xd4,
-	0xd2, 0x54, 0x32, 0xf2, 0x7c, 0x90, 0xb6, 0x75, 0x17, 0x30, 0x01, 0x76, 0xd1, 0x1a, 0xda, 0xb2,
-	0xbe, 0x5d, 0xb7, 0xd1, 0xe5, 0x75, 0x1b, 0x5d, 0x5d, 0xb7, 0xd1, 0x97, 0x9b, 0x76, 0xe1, 0xf2,
-	0xa6, 0x5d, 0xf8, 0x71, 0xd3, 0x2e, 0x8c, 0xaa, 0xfa, 0x71, 0xcf, 0x7f, 0x06, 0x00, 0x00, 0xff,
-	0xff, 0xd1, 0xb6, 0xd2, 0x89, 0x57, 0x05, 0x00, 0x00,
+	// 677 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0x5f, 0x4f, 0xdb, 0x3a,
+	0x14, 0xaf, 0xfb, 0xef, 0xb6, 0x07, 0x6e, 0x5b, 0xf9, 0x56, 0xdc, 0x28, 0xf7, 0xae, 0x54, 0x41,
+	0xda, 0xba, 0x69, 0x4a, 0x27, 0xf6, 0xb2, 0x4d, 0x42, 0x1a, 0x0c, 0x36, 0xda, 0x01, 0xad, 0x0c,
+	0xfb, 0x00, 0x2e, 0x31, 0x25, 0xa3, 0x8d, 0xb3, 0xd8, 0x1d, 0xea, 0xb7, 0xd8, 0xb7, 0xda, 0xa4,
+	0xbd, 0xf0, 0xb8, 0x47, 0x04, 0x5f, 0x64, 0xb2, 0x9d, 0xb0, 0xa4, 0x8c, 0xf1, 0x12, 0x9d, 0x73,
+	0x7e, 0xbf, 0xf3, 0xb3, 0xcf, 0x2f, 0xb6, 0xa1, 0x31, 0x89, 0xa6, 0x51, 0x38, 0xea, 0x46, 0xe1,
+	0xb1, 0x1b, 0x46, 0x5c, 0x72, 0x5c, 0x36, 0x15, 0xbb, 0x39, 0xe6, 0x63, 0xae, 0x4b, 0x5d, 0x15,
+	0x19, 0xd4, 0xfe, 0x6f, 0xcc, 0xf9, 0x78, 0xc2, 0xba, 0x3a, 0x1b, 0xcd, 0x4e, 0xba, 0x6c, 0x1a,
+	0xca, 0x79, 0x0c, 0xd6, 0x26, 0x91, 0xe7, 0x75, 0x23, 0x7e, 0x1e, 0xe7, 0xff, 0xfb, 0x81, 0x64,
+	0x51, 0x40, 0x27, 0xdd, 0x70, 0x24, 0xe7, 0x21, 0x13, 0x5d, 0xfd, 0x35, 0xa8, 0xf3, 0x35, 0x0f,
+	0xf8, 0x4d, 0xc4, 0xa8, 0x64, 0x47, 0x54, 0x9c, 0x09, 0xc2, 0x3e, 0xcd, 0x98, 0x90, 0x78, 0x15,
+	0x0a, 0x1f, 0xf9, 0xc8, 0x42, 0x6d, 0xd4, 0x59, 0x5a, 0xff, 0xdb, 0x8d, 0x3b, 0xdd, 0xfe, 0xe1,
+	0xe0, 0x80, 0x28, 0x04, 0x37, 0xa1, 0x24, 0x24, 0x1d, 0x33, 0x2b, 0xdf, 0x46, 0x9d, 0x2a, 0x31,
+	0x09, 0x76, 0x60, 0x39, 0xa4, 0x91, 0xf4, 0xa5, 0xcf, 0x83, 0xde, 0xb6, 0xb0, 0x0a, 0xed, 0x42,
+	0xa7, 0x4a, 0x32, 0x35, 0xbc, 0x06, 0x25, 0x3f, 0x08, 0x67, 0xd2, 0x2a, 0xb6, 0x0b, 0x5a, 0xdc,
+	0x8c, 0xea, 0xf6, 0x54, 0x91, 0x18, 0x0c, 0x3f, 0x84, 0x32, 0x9f, 0x49, 0xc5, 0x2a, 0xe9, 0x2d,
+	0xd4, 0x12, 0xd6, 0x40, 0x57, 0x49, 0x8c, 0xe2, 0x3e, 0xc0, 0x28, 0xe2, 0xd4, 0x3b, 0xa6, 0x42,
+	0x0a, 0xab, 0xac, 0x15, 0x9f, 0x24, 0xdc, 0xdb, 0x73, 0xb9, 0x5b, 0x37, 0xe4, 0x9d, 0x40, 0x46,
+	0x73, 0x92, 0xea, 0xb6, 0x37, 0xa0, 0xbe, 0x00, 0xe3, 0x06, 0x14, 0xce, 0xd8, 0x5c, 0xdb, 0x50,
+	0x25, 0x2a, 0x54, 0x73, 0x7f, 0xa6, 0x93, 0x99, 0x99, 0x7b, 0x99, 0x98, 0xe4, 0x55, 0xfe, 0x05,
+	0x72, 0x1e, 0x43, 0xa1, 0xcf, 0x47, 0xb8, 0x06, 0x79, 0xdf, 0x8b, 0x3b, 0xf2, 0xbe, 0x87, 0x31,
+	0x14, 0x03, 0x3a, 0x4d, 0x7c, 0xd2, 0xb1, 0xf3, 0x1e, 0x4a, 0xbd, 0x78, 0xcc, 0xa2, 0x32, 0x56,
+	0xd3, 0x6b, 0xeb, 0x38, 0x63, 0x85, 0x7b, 0x34, 0x0f, 0x19, 0xd1, 0xb8, 0x63, 0x43, 0x51, 0x65,
+	0xb8, 0x02, 0xc5, 0xe1, 0x87, 0xc3, 0xdd, 0x46, 0x4e, 0x47, 0x83, 0xbd, 0xbd, 0x06, 0x72, 0x2e,
+	0x11, 0x94, 0x8d, 0x2b, 0xf8, 0x51, 0x46, 0xee, 0x9f, 0xac, 0x67, 0x29, 0x3d, 0xbc, 0x0f, 0xf5,
+	0x9b, 0x7f, 0x72, 0xc4, 0x77, 0xb9, 0x90, 0x56, 0x5e, 0x7b, 0xb7, 0xb6, 0xd0, 0x33, 0xcc, 0xb2,
+	0x8c, 0x69, 0x8b, 0xbd, 0xf6, 0x16, 0x34, 0x7f, 0x47, 0xbc, 0xcf, 0xbe, 0x6a, 0xda, 0xbe, 0x3f,
+	0x8d, 0xf8, 0x12, 0x96, 0x94, 0xe8, 0x3e, 0x0d, 0x43, 0x3f, 0x18, 0x2b, 0x4b, 0x4f, 0xd5, 0x96,
+	0x8d, 0xae, 0x8e, 0xf1, 0x0a, 0x94, 0x25, 0x15, 0x67, 0xbd, 0xed, 0x58, 0x39, 0xce, 0x9c, 0xa7,
+	0xe9, 0xe3, 0x4d, 0x98, 0x08, 0x79, 0x20, 0x58, 0x8a, 0x8d, 0x32, 0xec, 0x3e, 0xd4, 0x87, 0x33,
+	0x71, 0xba, 0x4d, 0x25, 0x4d, 0x6e, 0xc2, 0x03, 0x28, 0x7a, 0x54, 0x52, 0x0b, 0x69, 0x7f, 0xaa,
+	0xae, 0xba, 0x5d, 0x2e, 0xe1, 0xe7, 0x44, 0x97, 0xb1, 0x05, 0x7f, 0xb1, 0xe0, 0x98, 0x7b, 0xcc,
+	0x8b, 0x4f, 0x44, 0x92, 0x3a, 0xab, 0x50, 0x1f, 0xf2, 0xc9, 0x24, 0xad, 0xb5, 0x0c, 0x28, 0xd0,
+	0x2b, 0x16, 0x08, 0x0a, 0x9c, 0x77, 0xd0, 0xf8, 0x45, 0x88, 0x37, 0x76, 0xcf, 0x6a, 0x4d, 0x28,
+	0xf9, 0x62, 0x67, 0xf0, 0x56, 0xaf, 0x55, 0x21, 0x26, 0x71, 0x5e, 0x03, 0x28, 0x91, 0x5d, 0x46,
+	0x3d, 0x16, 0xdd, 0x35, 0x1b, 0xb6, 0xa1, 0x72, 0x12, 0xf1, 0x69, 0xfc, 0xb3, 0x15, 0x72, 0x93,
+	0xaf, 0x7f, 0x47, 0x50, 0x3c, 0xe0, 0x1e, 0xc3, 0x9b, 0xb0, 0x94, 0xba, 0x35, 0xd8, 0xbe, 0xfb,
+	0x2a, 0xd9, 0x2b, 0xae, 0x79, 0x85, 0xdc, 0xe4, 0x15, 0x72, 0x77, 0xd4, 0x2b, 0x84, 0x37, 0xa0,
+	0x92, 0x78, 0x88, 0xff, 0x4d, 0xfa, 0x17, 0x5c, 0xbd, 0xab, 0xb9, 0x83, 0xf0, 0x26, 0x54, 0x12,
+	0x57, 0x52, 0xed, 0x59, 0x23, 0x6d, 0xeb, 0x36, 0x60, 0x0c, 0xec, 0xa0, 0x67, 0x68, 0xcb, 0xfa,
+	0x76, 0xd5, 0x42, 0x17, 0x57, 0x2d, 0x74, 0x79, 0xd5, 0x42, 0x5f, 0xae, 0x5b, 0xb9, 0x8b, 0xeb,
+	0x56, 0xee, 0xc7, 0x75, 0x2b, 0x37, 0x2a, 0xeb, 0xe5, 0x9e, 0xff, 0x1c, 0x00, 0xb8, 0x2e, 0xac,
+	0xaa, 0x71, 0x05, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1187,6 +1198,13 @@ func (m *PushDataRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Encoded) > 0 {
+		i -= len(m.Encoded)
+		copy(dAtA[i:], m.Encoded)
+		i = encodeVarintRpc(dAtA, i, uint64(len(m.Encoded)))
+		i--
+		dAtA[i] = 0x12
+	}
 	if len(m.Data) > 0 {
 		for iNdEx := len(m.Data) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1463,6 +1481,10 @@ func (m *PushDataRequest) Size() (n int) {
 			n += 1 + l + sovRpc(uint64(l))
 		}
 	}
+	l = len(m.Encoded)
+	if l > 0 {
+		n += 1 + l + sovRpc(uint64(l))
+	}
 	return n
 }
 
@@ -2523,6 +2545,40 @@ func (m *PushDataRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Encoded", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRpc
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthRpc
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRpc
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Encoded = append(m.Encoded[:0], dAtA[iNdEx:postIndex]...)
+			if m.Encoded == nil {
+				m.Encoded = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRpc(dAtA[iNdEx:])
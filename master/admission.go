@@ -0,0 +1,124 @@
+package master
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// admissionQueue limits the number of concurrently running jobs. When the limit is reached,
+// jobs wait for admission in the order of their priority, and then in the order of arrival.
+// Running jobs are never preempted by the jobs with higher priority.
+type admissionQueue struct {
+	maxRunning int
+	running    int
+	waiting    admissionHeap
+	seq        uint64
+	mu         sync.Mutex
+}
+
+func newAdmissionQueue(maxRunning int) *admissionQueue {
+	return &admissionQueue{maxRunning: maxRunning}
+}
+
+// Admit blocks until a job with given priority is admitted to run. The returned release function
+// must be called after the job finishes. Admission is unlimited if the maximum is not positive.
+func (q *admissionQueue) Admit(ctx context.Context, priority int) (release func(), err error) {
+	if q.maxRunning <= 0 {
+		return func() {}, nil
+	}
+	q.mu.Lock()
+	if q.running < q.maxRunning && q.waiting.Len() == 0 {
+		q.running++
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	a := &admission{
+		priority: priority,
+		seq:      q.seq,
+		admitted: make(chan struct{}),
+	}
+	q.seq++
+	heap.Push(&q.waiting, a)
+	q.mu.Unlock()
+
+	select {
+	case <-a.admitted:
+		return q.releaseFunc(), nil
+
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		select {
+		case <-a.admitted:
+			// admitted right before the cancellation. give the slot to the next one
+			q.running--
+			q.dispatch()
+		default:
+			heap.Remove(&q.waiting, a.index)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (q *admissionQueue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+
+			q.running--
+			q.dispatch()
+		})
+	}
+}
+
+// dispatch admits waiting jobs while slots are available. q.mu must be held.
+func (q *admissionQueue) dispatch() {
+	for q.running < q.maxRunning && q.waiting.Len() > 0 {
+		a := heap.Pop(&q.waiting).(*admission)
+		q.running++
+		close(a.admitted)
+	}
+}
+
+type admission struct {
+	priority int
+	seq      uint64
+	admitted chan struct{}
+	index    int
+}
+
+type admissionHeap []*admission
+
+func (h admissionHeap) Len() int { return len(h) }
+
+func (h admissionHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h admissionHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *admissionHeap) Push(x interface{}) {
+	a := x.(*admission)
+	a.index = len(*h)
+	*h = append(*h, a)
+}
+
+func (h *admissionHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	a := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return a
+}
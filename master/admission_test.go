@@ -0,0 +1,52 @@
+package master
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdmissionQueue(t *testing.T) {
+	Convey("Given an admission queue allowing one running job", t, func() {
+		q := newAdmissionQueue(1)
+		release, err := q.Admit(context.TODO(), 0)
+		So(err, ShouldBeNil)
+
+		Convey("When jobs are waiting for admission", func() {
+			admitted := make(chan int, 3)
+			for i, priority := range []int{0, 10, 5} {
+				n, p := i, priority
+				go func() {
+					r, err := q.Admit(context.TODO(), p)
+					if err != nil {
+						return
+					}
+					admitted <- n
+					r()
+				}()
+				// ensure the arrival order
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			Convey("It should admit jobs in the order of their priority", func() {
+				release()
+				So(<-admitted, ShouldEqual, 1)
+				So(<-admitted, ShouldEqual, 2)
+				So(<-admitted, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When a waiting job is canceled", func() {
+			ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+			defer cancel()
+			_, err := q.Admit(ctx, 0)
+
+			Convey("It should return the context error", func() {
+				So(err, ShouldBeError, context.DeadlineExceeded)
+				So(q.waiting.Len(), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -28,9 +28,37 @@ func getCollectedResultChan(jobID string) (chan []*lrdd.Row, error) {
 	return v.(chan []*lrdd.Row), nil
 }
 
-type Collector struct{}
+// collectStreams stores channel of the row batches streamed by the collectors with StreamID.
+var collectStreams sync.Map
+
+// collectStreamBatchSize is the maximum number of rows in a batch streamed by a collector.
+const collectStreamBatchSize = 100
+
+// OpenCollectStream returns a channel receiving the rows collected by the Collector with given stream ID
+// in batches, as they arrive. The channel is closed after the collector received every row of the job,
+// and is not closed if the job fails. It must be called before the job starts, and the stream should
+// be freed by CloseCollectStream.
+func OpenCollectStream(streamID string) <-chan []*lrdd.Row {
+	stream := make(chan []*lrdd.Row, 1)
+	collectStreams.Store(streamID, stream)
+	return stream
+}
+
+// CloseCollectStream frees the stream opened by OpenCollectStream.
+func CloseCollectStream(streamID string) {
+	collectStreams.Delete(streamID)
+}
+
+type Collector struct {
+	// StreamID makes the collector send the rows to the stream opened by OpenCollectStream as they arrive,
+	// instead of sending all of them at once after the job.
+	StreamID string
+}
 
 func (c *Collector) Apply(ctx transformation.Context, in chan *lrdd.Row, _ output.Output) error {
+	if c.StreamID != "" {
+		return c.stream(ctx, in)
+	}
 	resultChan, err := getCollectedResultChan(ctx.JobID())
 	if err != nil {
 		return errors.Errorf("unknown job: %s", ctx.JobID())
@@ -44,6 +72,37 @@ func (c *Collector) Apply(ctx transformation.Context, in chan *lrdd.Row, _ outpu
 	return nil
 }
 
+func (c *Collector) stream(ctx transformation.Context, in chan *lrdd.Row) error {
+	v, ok := collectStreams.Load(c.StreamID)
+	if !ok {
+		return errors.Errorf("unknown collect stream: %s", c.StreamID)
+	}
+	stream := v.(chan []*lrdd.Row)
+
+	batch := make([]*lrdd.Row, 0, collectStreamBatchSize)
+	for row := range in {
+		batch = append(batch, row)
+		if len(batch) < collectStreamBatchSize && len(in) > 0 {
+			continue
+		}
+		select {
+		case stream <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		batch = make([]*lrdd.Row, 0, collectStreamBatchSize)
+	}
+	if len(batch) > 0 {
+		select {
+		case stream <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	close(stream)
+	return nil
+}
+
 type CollectPartitioner struct{}
 
 func NewCollectPartitioner() partitions.Partitioner {
@@ -11,21 +11,23 @@ import (
 	"github.com/ab180/lrmr/cluster/node"
 	"github.com/ab180/lrmr/coordinator"
 	"github.com/ab180/lrmr/internal/pbtypes"
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/internal/tracing"
 	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/logging"
 	"github.com/ab180/lrmr/lrdd"
 	"github.com/ab180/lrmr/lrmrpb"
 	"github.com/ab180/lrmr/output"
 	"github.com/ab180/lrmr/partitions"
 	"github.com/ab180/lrmr/stage"
 	"github.com/ab180/lrmr/worker"
-	"github.com/airbloc/logger"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 )
 
 var ErrNoAvailableWorkers = errors.New("no available workers")
 
-var log = logger.New("lrmr")
+var log = logging.New("lrmr")
 
 type Master struct {
 	executor *worker.Worker
@@ -35,10 +37,17 @@ type Master struct {
 	JobManager *job.Manager
 	JobTracker *job.Tracker
 
-	opt Options
+	admission *admissionQueue
+	workers   *workerMonitor
+	opt       Options
 }
 
 func New(crd coordinator.Coordinator, opt Options) (*Master, error) {
+	if opt.StructCodec != "" {
+		if err := serialization.SetStructCodec(opt.StructCodec); err != nil {
+			return nil, err
+		}
+	}
 	c, err := cluster.OpenRemote(crd, cluster.DefaultOptions())
 	if err != nil {
 		return nil, err
@@ -51,18 +60,27 @@ func New(crd coordinator.Coordinator, opt Options) (*Master, error) {
 	wopt.AdvertisedHost = opt.AdvertisedHost
 	wopt.Input.MaxRecvSize = opt.Input.MaxRecvSize
 	wopt.Output.BufferLength = opt.Output.BufferLength
+	wopt.Output.BufferBytes = opt.Output.BufferBytes
 	wopt.Output.MaxSendMsgSize = opt.Output.MaxSendMsgSize
+	wopt.Output.Codec = opt.Output.Codec
+	wopt.RPC = opt.RPC
 	w, err := worker.New(crd, wopt)
 	if err != nil {
 		return nil, errors.Wrap(err, "init master task executor")
 	}
 
 	jm := job.NewManager(crd)
+	jt, err := job.NewJobTracker(crd, jm)
+	if err != nil {
+		return nil, errors.Wrap(err, "init job tracker")
+	}
 	return &Master{
 		executor:   w,
 		Cluster:    c,
 		JobManager: jm,
-		JobTracker: job.NewJobTracker(crd, jm),
+		JobTracker: jt,
+		admission:  newAdmissionQueue(opt.MaxConcurrentJobs),
+		workers:    newWorkerMonitor(c, jm, opt.WorkerCheckInterval),
 		opt:        opt,
 	}, nil
 }
@@ -73,6 +91,7 @@ func (m *Master) Start() {
 			log.Error("Failed to start master task executor", err)
 		}
 	}()
+	m.workers.Start()
 }
 
 func (m *Master) Workers() ([]WorkerHolder, error) {
@@ -90,22 +109,28 @@ func (m *Master) Workers() ([]WorkerHolder, error) {
 	return wh, nil
 }
 
-func (m *Master) CreateJob(ctx context.Context, name string, plans []partitions.Plan, stages []stage.Stage, opt ...CreateJobOption) (*job.Job, error) {
+// CreateJob schedules and creates a job. If Options.MaxConcurrentJobs is set, it blocks
+// until the job is admitted by its priority.
+func (m *Master) CreateJob(ctx context.Context, name string, plans []partitions.Plan, stages []stage.Stage, opt ...CreateJobOption) (j *job.Job, err error) {
 	opts := buildCreateJobOptions(opt)
-
-	listOpts := cluster.ListOption{Type: node.Worker}
-	if opts.NodeSelector != nil {
-		listOpts.Tag = opts.NodeSelector
+	if err := opts.Compression.Validate(); err != nil {
+		return nil, err
 	}
-	workers, err := m.Cluster.List(ctx, listOpts)
+
+	release, err := m.admission.Admit(ctx, opts.Priority)
 	if err != nil {
-		return nil, errors.WithMessage(err, "list available workers")
-	}
-	if len(workers) == 0 {
-		return nil, ErrNoAvailableWorkers
+		return nil, errors.Wrap(err, "wait for admission")
 	}
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
 
-	pp, assignments := partitions.Schedule(workers, plans, partitions.WithMaster(m.executor.Node.Info()))
+	pp, assignments, err := m.schedule(ctx, plans, opts)
+	if err != nil {
+		return nil, err
+	}
 	for i, p := range pp {
 		stages[i].Output.Partitioner = p.Partitioner
 
@@ -114,7 +139,7 @@ func (m *Master) CreateJob(ctx context.Context, name string, plans []partitions.
 			name, stages[i].Name, partitionerName, assignments[i].Pretty())
 	}
 
-	j, err := m.JobManager.CreateJob(ctx, name, stages, assignments)
+	j, err = m.JobManager.CreateJob(ctx, name, opts.Priority, opts.Compression, stages, assignments, opts.Retry)
 	if err != nil {
 		return nil, errors.WithMessage(err, "create job")
 	}
@@ -126,6 +151,8 @@ func (m *Master) CreateJob(ctx context.Context, name string, plans []partitions.
 		log.Verbose("Stage {}/{} {}.", j.ID, stageName, stageStatus.Status)
 	})
 	m.JobTracker.OnJobCompletion(j, func(j *job.Job, status *job.Status) {
+		release()
+		m.workers.Untrack(j)
 		log.Info("Job {} {}. Total elapsed {}", j.ID, status.Status, time.Since(j.SubmittedAt))
 		for i, errDesc := range status.Errors {
 			log.Info(" - Error #{} caused by {}: {}", i, errDesc.Task, errDesc.Message)
@@ -134,7 +161,53 @@ func (m *Master) CreateJob(ctx context.Context, name string, plans []partitions.
 	return j, nil
 }
 
-// StartTasks create tasks to the nodes with the plan.
+// FailJob fails the job with given error, reported as a failure of the input stage fed by the master.
+// The tasks of the job are canceled, and the resources held by the job (e.g. the admission) are freed
+// on its completion.
+func (m *Master) FailJob(ctx context.Context, j *job.Job, err error) error {
+	ref := job.TaskID{
+		JobID:       j.ID,
+		StageName:   j.Stages[0].Name,
+		PartitionID: "__master",
+	}
+	reporter := job.NewTaskReporter(ctx, m.Cluster.States(), j, ref, job.NewTaskStatus())
+	return reporter.ReportFailure(err)
+}
+
+// PlanJob schedules the partitions of a job on the available workers as CreateJob does, without creating
+// the job. Since the partitioners left nil in the plans are filled with the default ones, the caller should
+// pass a copy of the plans if it wants to keep them.
+func (m *Master) PlanJob(ctx context.Context, plans []partitions.Plan, opt ...CreateJobOption) ([]partitions.Partitions, []partitions.Assignments, error) {
+	return m.schedule(ctx, plans, buildCreateJobOptions(opt))
+}
+
+func (m *Master) schedule(ctx context.Context, plans []partitions.Plan, opts CreateJobOptions) ([]partitions.Partitions, []partitions.Assignments, error) {
+	listOpts := cluster.ListOption{Type: node.Worker}
+	if opts.NodeSelector != nil {
+		listOpts.Tag = opts.NodeSelector
+	}
+	workers, err := m.Cluster.List(ctx, listOpts)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "list available workers")
+	}
+	if len(workers) == 0 {
+		return nil, nil, ErrNoAvailableWorkers
+	}
+	scheduleOpts := []partitions.ScheduleOption{partitions.WithMaster(m.executor.Node.Info())}
+	if opts.AssignmentPolicy != nil {
+		scheduleOpts = append(scheduleOpts, partitions.WithAssignmentPolicy(opts.AssignmentPolicy))
+	}
+	pp, assignments, err := partitions.Schedule(workers, plans, scheduleOpts...)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "schedule")
+	}
+	return pp, assignments, nil
+}
+
+// StartJob creates the tasks of every stage to the nodes with the plan. The stages are created at once,
+// in reverse order so that the outputs of a stage can connect to the tasks of the next stage. Therefore
+// workers joining the cluster after the job starts are not used by the job, but by the jobs created later,
+// e.g. the jobs following a Barrier, Cache or Checkpoint of a dataset, or lrmr.WithStagewiseScheduling.
 func (m *Master) StartJob(ctx context.Context, j *job.Job, broadcasts map[string][]byte) error {
 	prepareCollect(j.ID)
 	marshalledJob := pbtypes.MustMarshalJSON(j)
@@ -159,7 +232,7 @@ func (m *Master) StartJob(ctx context.Context, j *job.Job, broadcasts map[string
 			reqTmpl.Output.PartitionToHost = make(map[string]string, 0)
 		}
 
-		t := log.Timer()
+		startedAt := time.Now()
 		wg, wctx := errgroup.WithContext(ctx)
 		for h, ps := range j.Partitions[i].GroupIDsByHost() {
 			host, partitionIDs := h, ps
@@ -171,7 +244,8 @@ func (m *Master) StartJob(ctx context.Context, j *job.Job, broadcasts map[string
 				}
 				req := reqTmpl
 				req.PartitionIDs = partitionIDs
-				if _, err := lrmrpb.NewNodeClient(conn).CreateTasks(wctx, &req); err != nil {
+				// propagates the span of the job, so that the spans of the tasks become its children
+				if _, err := lrmrpb.NewNodeClient(conn).CreateTasks(tracing.InjectMetadata(wctx), &req); err != nil {
 					return errors.Wrapf(err, "call CreateTask on %s", host)
 				}
 				return nil
@@ -180,8 +254,9 @@ func (m *Master) StartJob(ctx context.Context, j *job.Job, broadcasts map[string
 		if err := wg.Wait(); err != nil {
 			return err
 		}
-		t.End("Initialized stage {}/{}", j.ID, s.Name)
+		log.Verbose("Initialized stage {}/{} in {}", j.ID, s.Name, time.Since(startedAt))
 	}
+	m.workers.Track(j)
 	return nil
 }
 
@@ -200,7 +275,7 @@ func (m *Master) OpenInputWriter(ctx context.Context, j *job.Job, stageName stri
 		assigned := t
 		wg.Go(func() error {
 			taskID := path.Join(j.ID, stageName, assigned.PartitionID)
-			out, err := output.OpenPushStream(jobCtx, m.Cluster, m.Node, assigned.Host, taskID)
+			out, err := output.OpenPushStream(jobCtx, m.Cluster, m.Node, assigned.Host, taskID, m.opt.Output.Codec, j.Compression)
 			if err != nil {
 				return errors.Wrapf(err, "connect %s", assigned.Host)
 			}
@@ -217,6 +292,37 @@ func (m *Master) OpenInputWriter(ctx context.Context, j *job.Job, stageName stri
 	return out, nil
 }
 
+// WaitJob blocks until given job completes, and returns the first error of its tasks if it failed.
+// Unlike lrmr.RunningJob.WaitWithContext, the job keeps running if the context is done first.
+func (m *Master) WaitJob(ctx context.Context, j *job.Job) error {
+	status, err := m.JobTracker.WaitForCompletion(ctx, j)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// PauseJob holds the running job, e.g. to free the cluster for a job with higher priority.
+// Tasks of the paused job stop pulling new inputs instead of aborting, so the job continues
+// without losing progress when resumed by ResumeJob. Note that paused tasks keep holding their
+// states and buffered rows in memory, and the upstream tasks block once the buffers are full.
+func (m *Master) PauseJob(ctx context.Context, jobID string) error {
+	if err := m.JobManager.PauseJob(ctx, jobID); err != nil {
+		return errors.Wrapf(err, "pause job %s", jobID)
+	}
+	log.Info("Job {} paused.", jobID)
+	return nil
+}
+
+// ResumeJob resumes the job paused by PauseJob.
+func (m *Master) ResumeJob(ctx context.Context, jobID string) error {
+	if err := m.JobManager.ResumeJob(ctx, jobID); err != nil {
+		return errors.Wrapf(err, "resume job %s", jobID)
+	}
+	log.Info("Job {} resumed.", jobID)
+	return nil
+}
+
 func (m *Master) CollectedResults(jobID string) ([]*lrdd.Row, error) {
 	watchCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -225,16 +331,21 @@ func (m *Master) CollectedResults(jobID string) ([]*lrdd.Row, error) {
 	if err != nil {
 		return nil, err
 	}
+	errChan, err := m.JobManager.WatchJobErrors(watchCtx, jobID)
+	if err != nil {
+		return nil, errors.Wrap(err, "watch job errors")
+	}
 	select {
 	case result := <-resultChan:
 		return result, nil
 
-	case err := <-m.JobManager.WatchJobErrors(watchCtx, jobID):
+	case err := <-errChan:
 		return nil, err
 	}
 }
 
 func (m *Master) Stop() {
+	m.workers.Close()
 	if err := m.executor.Close(); err != nil {
 		log.Error("failed to close worker")
 	}
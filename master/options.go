@@ -1,8 +1,12 @@
 package master
 
 import (
+	"time"
+
 	"github.com/ab180/lrmr/cluster"
+	"github.com/ab180/lrmr/job"
 	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
 	"github.com/creasty/defaults"
 )
 
@@ -12,6 +16,20 @@ type Options struct {
 
 	CollectQueueSize int `default:"1000"`
 
+	// MaxConcurrentJobs limits the number of jobs running at the same time. Jobs exceeding
+	// the limit wait for admission in the order of their priority. Zero means unlimited.
+	MaxConcurrentJobs int `default:"0"`
+
+	// WorkerCheckInterval is the interval of checking whether the nodes running the tasks of the jobs are alive.
+	// The unfinished tasks on the nodes which have left the cluster (e.g. crashed) are failed, so that
+	// their jobs don't wait forever. Zero disables it.
+	WorkerCheckInterval time.Duration `default:"3s"`
+
+	// StructCodec is the codec encoding the transformations and partitioners sent to the workers,
+	// either "json" or "gob". Gob supports a wider range of Go types, e.g. maps with non-string keys.
+	// The workers decode them with the codec recorded in the payloads, so it only needs to be set on the master.
+	StructCodec string `default:"json"`
+
 	RPC   cluster.Options
 	Input struct {
 		MaxRecvSize int `default:"67108864"`
@@ -27,7 +45,11 @@ func DefaultOptions() (o Options) {
 }
 
 type CreateJobOptions struct {
-	NodeSelector map[string]string
+	NodeSelector     map[string]string
+	Priority         int
+	Compression      output.Compression
+	AssignmentPolicy partitions.AssignmentPolicy
+	Retry            *job.Retry
 }
 
 type CreateJobOption func(o *CreateJobOptions)
@@ -38,6 +60,36 @@ func WithNodeSelector(ns map[string]string) CreateJobOption {
 	}
 }
 
+// WithPriority sets priority of the job. A job with higher priority is admitted first
+// when the number of running jobs exceeds Options.MaxConcurrentJobs.
+func WithPriority(p int) CreateJobOption {
+	return func(o *CreateJobOptions) {
+		o.Priority = p
+	}
+}
+
+// WithCompression sets the compression of the rows sent between the nodes in the job.
+func WithCompression(c output.Compression) CreateJobOption {
+	return func(o *CreateJobOptions) {
+		o.Compression = c
+	}
+}
+
+// WithAssignmentPolicy places the partitions of the job on the workers by given policy.
+// Defaults to partitions.DefaultAssignmentPolicy.
+func WithAssignmentPolicy(p partitions.AssignmentPolicy) CreateJobOption {
+	return func(o *CreateJobOptions) {
+		o.AssignmentPolicy = p
+	}
+}
+
+// WithRetry records that the job runs the stages again after the failed runs.
+func WithRetry(r *job.Retry) CreateJobOption {
+	return func(o *CreateJobOptions) {
+		o.Retry = r
+	}
+}
+
 func buildCreateJobOptions(opts []CreateJobOption) (o CreateJobOptions) {
 	for _, optFn := range opts {
 		optFn(&o)
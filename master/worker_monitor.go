@@ -0,0 +1,126 @@
+package master
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ab180/lrmr/cluster"
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/job"
+	"github.com/pkg/errors"
+)
+
+// workerMonitor fails the unfinished tasks of the running jobs on the nodes which have left the cluster,
+// e.g. crashed without draining their tasks. Since a node is registered with a lease kept alive by the node,
+// its registration expires if the node stops sending heartbeats. Otherwise, the jobs would wait forever
+// for the tasks which will never finish.
+type workerMonitor struct {
+	cluster    cluster.Cluster
+	jobManager *job.Manager
+	interval   time.Duration
+
+	// jobs is a mapping of job ID to *job.Job of the running jobs.
+	jobs sync.Map
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newWorkerMonitor(c cluster.Cluster, jm *job.Manager, interval time.Duration) *workerMonitor {
+	return &workerMonitor{
+		cluster:    c,
+		jobManager: jm,
+		interval:   interval,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start checks the nodes of the running jobs periodically until Close is called.
+// It does nothing if the interval is not set.
+func (w *workerMonitor) Start() {
+	if w.interval == 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(w.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				w.check()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Track monitors the nodes running the tasks of the job until Untrack is called.
+func (w *workerMonitor) Track(j *job.Job) {
+	w.jobs.Store(j.ID, j)
+}
+
+func (w *workerMonitor) Untrack(j *job.Job) {
+	w.jobs.Delete(j.ID)
+}
+
+func (w *workerMonitor) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), w.interval)
+	defer cancel()
+
+	nodes, err := w.cluster.List(ctx)
+	if err != nil {
+		log.Warn("Failed to list nodes for checking liveness: {}", err)
+		return
+	}
+	alive := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		alive[n.Host] = true
+	}
+	w.jobs.Range(func(_, v interface{}) bool {
+		j := v.(*job.Job)
+		if err := w.failTasksOnLostNodes(ctx, j, alive); err != nil {
+			log.Warn("Failed to check tasks of job {} on lost nodes: {}", j.ID, err)
+		}
+		return true
+	})
+}
+
+// failTasksOnLostNodes reports failures of the unfinished tasks of the job assigned to the nodes not alive.
+// The failures are retryable, and have the error of job.WorkerLostErrorKind.
+func (w *workerMonitor) failTasksOnLostNodes(ctx context.Context, j *job.Job, alive map[string]bool) error {
+	// the first stage is the input fed by the master
+	for i := 1; i < len(j.Stages); i++ {
+		for _, a := range j.Partitions[i] {
+			if alive[a.Host] {
+				continue
+			}
+			ref := job.TaskID{JobID: j.ID, StageName: j.Stages[i].Name, PartitionID: a.PartitionID}
+			status, err := w.jobManager.GetTaskStatus(ctx, ref)
+			if err != nil {
+				if errors.Cause(err) != coordinator.ErrNotFound {
+					return err
+				}
+				// the task has not been created yet
+				status = job.NewTaskStatus()
+			}
+			if status.CompletedAt != nil {
+				continue
+			}
+			log.Warn("Node {} running task {} has left the cluster.", a.Host, ref)
+			taskErr := errors.Errorf("node %s has left the cluster before the task finished", a.Host)
+			reporter := job.NewTaskReporter(ctx, w.cluster.States(), j, ref, status)
+			if err := reporter.ReportFailure(job.MarkRetryable(job.MarkWorkerLost(taskErr))); err != nil {
+				return errors.Wrapf(err, "report failure of task %s", ref)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *workerMonitor) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
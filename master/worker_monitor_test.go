@@ -0,0 +1,64 @@
+package master
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/cluster"
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/stage"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkerMonitor(t *testing.T) {
+	Convey("Given a job running on two nodes", t, func() {
+		ctx := context.Background()
+		crd := coordinator.NewLocalMemory()
+		c, err := cluster.OpenRemote(crd, cluster.DefaultOptions())
+		So(err, ShouldBeNil)
+		Reset(func() { _ = c.Close() })
+
+		jm := job.NewManager(crd)
+		stages := []stage.Stage{{Name: "_input"}, {Name: "Map1"}}
+		assignments := []partitions.Assignments{
+			{{PartitionID: "0", Host: "master"}},
+			{{PartitionID: "0", Host: "alive"}, {PartitionID: "1", Host: "lost"}, {PartitionID: "2", Host: "lost"}},
+		}
+		j, err := jm.CreateJob(ctx, "test", 0, output.NoCompression, stages, assignments, nil)
+		So(err, ShouldBeNil)
+
+		// a task on the lost node has finished before the node is lost
+		finished := job.TaskID{JobID: j.ID, StageName: "Map1", PartitionID: "2"}
+		So(job.NewTaskReporter(ctx, crd, j, finished, job.NewTaskStatus()).ReportSuccess(), ShouldBeNil)
+
+		m := newWorkerMonitor(c, jm, time.Second)
+
+		Convey("When a node has left the cluster", func() {
+			err := m.failTasksOnLostNodes(ctx, j, map[string]bool{"master": true, "alive": true})
+			So(err, ShouldBeNil)
+
+			Convey("Its unfinished tasks should fail the job with a retryable WorkerLostError", func() {
+				status, err := jm.GetJobStatus(ctx, j.ID)
+				So(err, ShouldBeNil)
+				So(status.Status, ShouldEqual, job.Failed)
+				So(status.Errors, ShouldHaveLength, 1)
+				So(status.Errors[0].Task, ShouldEqual, j.ID+"/Map1/1")
+				So(status.Errors[0].Retryable, ShouldBeTrue)
+
+				var lost *job.WorkerLostError
+				So(errors.As(status.Errors[0], &lost), ShouldBeTrue)
+			})
+
+			Convey("Its finished tasks should not be failed", func() {
+				ts, err := jm.GetTaskStatus(ctx, finished)
+				So(err, ShouldBeNil)
+				So(ts.Status, ShouldEqual, job.Succeeded)
+			})
+		})
+	})
+}
@@ -1,6 +1,7 @@
 package lrmr
 
 import (
+	"github.com/ab180/lrmr/coordinator"
 	"github.com/ab180/lrmr/master"
 	"github.com/ab180/lrmr/worker"
 	"github.com/creasty/defaults"
@@ -12,6 +13,9 @@ type Options struct {
 	EtcdEndpoints []string `default:"[\"127.0.0.1:2379\"]"`
 	EtcdNamespace string   `default:"lrmr/"`
 
+	// EtcdRetry is the policy of retrying etcd operations failed with transient errors.
+	EtcdRetry coordinator.RetryOptions
+
 	Master master.Options
 	Worker worker.Options
 }
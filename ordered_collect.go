@@ -0,0 +1,64 @@
+package lrmr
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+// addOrderTags adds the stage wrapping the rows of the last stage with its partition ID,
+// which are sorted by sortByOrderTags after they are collected.
+func (d *Dataset) addOrderTags() {
+	if len(d.stages) > 1 {
+		// the tags are given in the same partition as the last stage; the input is partitioned by itself
+		d.PartitionedBy(partitions.NewPreservePartitioner())
+	}
+	d.addStage(d.stageName(&orderTagger{}), &orderTagger{})
+}
+
+// orderTagger wraps each row, keyed by the partition ID.
+type orderTagger struct{}
+
+func (o *orderTagger) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	for row := range in {
+		raw, err := row.Marshal()
+		if err != nil {
+			return errors.Wrapf(err, "marshal row %s", row.Key)
+		}
+		if err := out.Write(&lrdd.Row{Key: ctx.PartitionID(), Value: raw}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortByOrderTags unwraps the rows wrapped by orderTagger, and sorts them by the partition ID and then by
+// the key and the value of the rows. The order within a partition does not depend on the order that
+// the rows arrived at the task, which varies across runs if the task has several upstream tasks.
+func sortByOrderTags(tagged []*lrdd.Row) ([]*lrdd.Row, error) {
+	rows := make([]*lrdd.Row, len(tagged))
+	partitionOf := make(map[*lrdd.Row]string, len(tagged))
+	for i, t := range tagged {
+		row := new(lrdd.Row)
+		if err := row.Unmarshal(t.Value); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal row in partition %s", t.Key)
+		}
+		rows[i] = row
+		partitionOf[row] = t.Key
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if pi, pj := partitionOf[rows[i]], partitionOf[rows[j]]; pi != pj {
+			return pi < pj
+		}
+		if rows[i].Key != rows[j].Key {
+			return rows[i].Key < rows[j].Key
+		}
+		return bytes.Compare(rows[i].Value, rows[j].Value) < 0
+	})
+	return rows, nil
+}
@@ -1,34 +1,83 @@
 package output
 
 import (
+	"sync"
+	"time"
+
 	"github.com/ab180/lrmr/lrdd"
 	"github.com/pkg/errors"
 )
 
-// BufferedOutput wraps Output with buffering.
+// BufferedOutput wraps Output with buffering. Rows are written to the original output in a batch
+// when the buffer is full, when the buffered rows exceed the size given by WithMaxBufferBytes,
+// when no rows are written for the duration given by WithIdleFlush, or on Close.
 type BufferedOutput struct {
 	buf    []*lrdd.Row
 	offset int
 	output Output
+
+	maxBytes      int
+	bufferedBytes int
+
+	// idleTimeout is the duration after the last write which the buffered rows are flushed after.
+	// If it's set, the buffer is guarded by mu as it's also flushed by idleTimer.
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+	idleErr     error
+	closed      bool
+	mu          sync.Mutex
+}
+
+type BufferedOutputOption func(b *BufferedOutput)
+
+// WithMaxBufferBytes makes the buffer flushed once the size of the buffered rows reaches given bytes.
+func WithMaxBufferBytes(n int) BufferedOutputOption {
+	return func(b *BufferedOutput) {
+		b.maxBytes = n
+	}
+}
+
+// WithIdleFlush makes the buffered rows flushed once no rows are written for given duration, so that
+// the rows of a slow stream are sent with bounded latency instead of waiting for the batch to be full.
+// An error of the flush is returned by the next Write or Close. Zero disables it.
+func WithIdleFlush(d time.Duration) BufferedOutputOption {
+	return func(b *BufferedOutput) {
+		b.idleTimeout = d
+	}
 }
 
-func NewBufferedOutput(output Output, size int) *BufferedOutput {
+func NewBufferedOutput(output Output, size int, opts ...BufferedOutputOption) *BufferedOutput {
 	if size == 0 {
 		panic("buffer size cannot be 0.")
 	}
-	return &BufferedOutput{
+	b := &BufferedOutput{
 		output: output,
 		buf:    make([]*lrdd.Row, size),
 	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
 }
 
 func (b *BufferedOutput) Write(d ...*lrdd.Row) error {
+	if b.idleTimeout > 0 {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.idleErr != nil {
+			return b.idleErr
+		}
+		defer b.resetIdleTimer()
+	}
 	// log.Verbose("Start write {} rows (Offset: {}/{})", len(d), b.offset, len(b.buf))
+	if b.maxBytes > 0 {
+		return b.writeBounded(d)
+	}
 	for len(d) > 0 {
 		writeLen := min(len(d), len(b.buf)-b.offset)
 		b.offset += copy(b.buf[b.offset:], d[:writeLen])
 		if b.offset == len(b.buf) {
-			err := b.Flush()
+			err := b.flush()
 			if err != nil {
 				return err
 			}
@@ -40,16 +89,75 @@ func (b *BufferedOutput) Write(d ...*lrdd.Row) error {
 	return nil
 }
 
+// writeBounded buffers the rows one by one, flushing when either the number or the size of the rows reaches the limit.
+func (b *BufferedOutput) writeBounded(d []*lrdd.Row) error {
+	for _, row := range d {
+		b.buf[b.offset] = row
+		b.offset++
+		b.bufferedBytes += row.Size()
+		if b.offset == len(b.buf) || b.bufferedBytes >= b.maxBytes {
+			if err := b.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resetIdleTimer schedules the idle flush after the last write, if there are buffered rows.
+// It should be called with mu held.
+func (b *BufferedOutput) resetIdleTimer() {
+	if b.offset == 0 {
+		return
+	}
+	if b.idleTimer == nil {
+		b.idleTimer = time.AfterFunc(b.idleTimeout, b.flushIdle)
+		return
+	}
+	b.idleTimer.Reset(b.idleTimeout)
+}
+
+func (b *BufferedOutput) flushIdle() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || b.offset == 0 || b.idleErr != nil {
+		return
+	}
+	if err := b.flush(); err != nil {
+		b.idleErr = errors.Wrap(err, "flush on idle")
+	}
+}
+
 func (b *BufferedOutput) Flush() error {
+	if b.idleTimeout > 0 {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+	}
+	return b.flush()
+}
+
+func (b *BufferedOutput) flush() error {
 	if err := b.output.Write(b.buf[:b.offset]...); err != nil {
 		return err
 	}
 	b.offset = 0
+	b.bufferedBytes = 0
 	return nil
 }
 
 func (b *BufferedOutput) Close() error {
-	if err := b.Flush(); err != nil {
+	if b.idleTimeout > 0 {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.idleTimer != nil {
+			b.idleTimer.Stop()
+		}
+		b.closed = true
+		if b.idleErr != nil {
+			return b.idleErr
+		}
+	}
+	if err := b.flush(); err != nil {
 		return errors.Wrap(err, "flush")
 	}
 	return b.output.Close()
@@ -5,6 +5,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"strconv"
 	"testing"
+	"time"
 )
 
 const bufSize = 10
@@ -107,6 +108,94 @@ func TestBufferedOutput_Flush(t *testing.T) {
 	})
 }
 
+func TestBufferedOutput_MaxBufferBytes(t *testing.T) {
+	Convey("Given BufferedOutput with the maximum size of the buffered rows", t, func() {
+		m := &outputMock{}
+		it := items(bufSize / 2)
+		o := NewBufferedOutput(m, bufSize, WithMaxBufferBytes(it[0].Size()*2))
+
+		Convey("When writing rows larger than the size", func() {
+			So(o.Write(it...), ShouldBeNil)
+
+			Convey("It should flush before the buffer is full", func() {
+				So(m.Calls.Write, ShouldEqual, 2)
+				So(m.Rows, ShouldResemble, it[:4])
+			})
+
+			Convey("When closing the output", func() {
+				So(o.Close(), ShouldBeNil)
+
+				Convey("It should write rest of the rows", func() {
+					So(m.Rows, ShouldResemble, it)
+				})
+			})
+		})
+	})
+}
+
+func TestBufferedOutput_IdleFlush(t *testing.T) {
+	Convey("Given BufferedOutput flushed on idle", t, func() {
+		m := &notifyingOutput{written: make(chan []*lrdd.Row, 10)}
+		o := NewBufferedOutput(m, bufSize, WithIdleFlush(20*time.Millisecond))
+
+		Convey("When rows are written less than the buffer size", func() {
+			it := items(bufSize / 2)
+			So(o.Write(it...), ShouldBeNil)
+
+			Convey("They should be flushed after the output is idle", func() {
+				select {
+				case rows := <-m.written:
+					So(rows, ShouldResemble, it)
+				case <-time.After(time.Second):
+					So("not flushed", ShouldBeEmpty)
+				}
+
+				Convey("Closing the output should not write them again", func() {
+					So(o.Close(), ShouldBeNil)
+					So(<-m.written, ShouldBeEmpty)
+				})
+			})
+		})
+
+		Convey("When rows are written continuously", func() {
+			for i := 0; i < 5; i++ {
+				So(o.Write(items(1)...), ShouldBeNil)
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			Convey("They should not be flushed until the output is idle", func() {
+				So(m.written, ShouldBeEmpty)
+				So(<-m.written, ShouldHaveLength, 5)
+			})
+		})
+
+		Convey("When the output is closed before it's idle", func() {
+			So(o.Write(items(1)...), ShouldBeNil)
+			So(o.Close(), ShouldBeNil)
+			So(<-m.written, ShouldHaveLength, 1)
+
+			Convey("The rows should not be flushed again", func() {
+				time.Sleep(40 * time.Millisecond)
+				So(m.written, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+// notifyingOutput sends the rows of each write to the channel, which is safe to be read by another goroutine.
+type notifyingOutput struct {
+	written chan []*lrdd.Row
+}
+
+func (n *notifyingOutput) Write(rows ...*lrdd.Row) error {
+	n.written <- append([]*lrdd.Row(nil), rows...)
+	return nil
+}
+
+func (n *notifyingOutput) Close() error {
+	return nil
+}
+
 func items(length int) (rr []*lrdd.Row) {
 	for i := 0; i < length; i++ {
 		rr = append(rr, lrdd.Value(strconv.Itoa(i)))
@@ -0,0 +1,29 @@
+package output
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Codec is the format of the batches of rows sent to other nodes. The receivers accept both formats,
+// so the nodes in a cluster can be configured with different codecs.
+type Codec string
+
+const (
+	// BatchCodec sends each batch as a list of rows, which are decoded by the receiver at once.
+	// It is the default.
+	BatchCodec Codec = ""
+
+	// StreamCodec sends each batch as a stream of rows encoded by lrdd.RowEncoder. The receiver decodes
+	// the rows one by one and queues them in small chunks as the task consumes them, so that its memory
+	// usage is bounded by the input queue rather than the size of the batches. It suits the stages
+	// processing rows one by one with large output batches (see Options.BufferLength).
+	StreamCodec Codec = "stream"
+)
+
+// Validate returns an error if the codec is unknown.
+func (c Codec) Validate() error {
+	if c != BatchCodec && c != StreamCodec {
+		return errors.Errorf("unknown codec %q", c)
+	}
+	return nil
+}
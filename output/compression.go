@@ -0,0 +1,176 @@
+package output
+
+import (
+	"bytes"
+	stdgzip "compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Compression is an algorithm compressing the rows sent to other nodes. Each batch of rows written
+// to a PushStream is compressed as a whole, and decompressed by the receiver according to its header,
+// so the receiver does not need to know the compression in advance.
+type Compression string
+
+const (
+	// NoCompression sends the rows as they are. It is the default.
+	NoCompression Compression = ""
+
+	// Gzip compresses moderately with high CPU usage.
+	Gzip Compression = gzip.Name
+
+	// Zstd compresses better and faster than Gzip.
+	Zstd Compression = "zstd"
+
+	// Snappy compresses fastest with a lower ratio. It is only supported by the files (e.g. Dataset.WriteFiles),
+	// not between the nodes.
+	Snappy Compression = "snappy"
+)
+
+// DefaultLevel is the default compression level of each compression.
+const DefaultLevel = 0
+
+func init() {
+	encoding.RegisterCompressor(newZstdCompressor())
+}
+
+// Validate returns an error if the compression is unknown.
+func (c Compression) Validate() error {
+	if c != NoCompression && encoding.GetCompressor(string(c)) == nil {
+		return errors.Errorf("unknown compression %q", c)
+	}
+	return nil
+}
+
+func (c Compression) callOptions() []grpc.CallOption {
+	if c == NoCompression {
+		return nil
+	}
+	return []grpc.CallOption{grpc.UseCompressor(string(c))}
+}
+
+// zstdCompressor compresses each message at once, with the encoder and the decoder shared across the streams.
+type zstdCompressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCompressor() *zstdCompressor {
+	// they never fail without options
+	enc, _ := zstd.NewWriter(nil)
+	dec, _ := zstd.NewReader(nil)
+	return &zstdCompressor{encoder: enc, decoder: dec}
+}
+
+func (z *zstdCompressor) Name() string {
+	return string(Zstd)
+}
+
+func (z *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return &zstdMessageWriter{encoder: z.encoder, w: w}, nil
+}
+
+func (z *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	compressed, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := z.decoder.DecodeAll(compressed, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// zstdMessageWriter buffers a message, and writes it compressed on Close.
+type zstdMessageWriter struct {
+	encoder *zstd.Encoder
+	w       io.Writer
+	buf     bytes.Buffer
+}
+
+func (m *zstdMessageWriter) Write(p []byte) (int, error) {
+	return m.buf.Write(p)
+}
+
+func (m *zstdMessageWriter) Close() error {
+	_, err := m.w.Write(m.encoder.EncodeAll(m.buf.Bytes(), nil))
+	return err
+}
+
+// ValidateFile returns an error if the compression is unknown to the files, or the level is out of
+// the range of the compression: 1-9 for Gzip, 1-22 for Zstd, and DefaultLevel only for the others.
+func (c Compression) ValidateFile(level int) error {
+	min, max := DefaultLevel, DefaultLevel
+	switch c {
+	case NoCompression, Snappy:
+	case Gzip:
+		min, max = stdgzip.BestSpeed, stdgzip.BestCompression
+	case Zstd:
+		min, max = 1, 22
+	default:
+		return errors.Errorf("unknown compression %q", c)
+	}
+	if level != DefaultLevel && (level < min || level > max) {
+		return errors.Errorf("level %d is not supported by compression %q", level, c)
+	}
+	return nil
+}
+
+// NewWriter returns a writer compressing the data written into w with the level. The data is flushed on Close,
+// which does not close w.
+func (c Compression) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if err := c.ValidateFile(level); err != nil {
+		return nil, err
+	}
+	switch c {
+	case Gzip:
+		if level == DefaultLevel {
+			level = stdgzip.DefaultCompression
+		}
+		return stdgzip.NewWriterLevel(w, level)
+	case Zstd:
+		var opts []zstd.EOption
+		if level != DefaultLevel {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		return zstd.NewWriter(w, opts...)
+	case Snappy:
+		return snappy.NewBufferedWriter(w), nil
+	}
+	return nopWriteCloser{w}, nil
+}
+
+// NewReader returns a reader decompressing the data written by the writer from NewWriter.
+func (c Compression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case NoCompression:
+		return ioutil.NopCloser(r), nil
+	case Gzip:
+		return stdgzip.NewReader(r)
+	case Zstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	case Snappy:
+		return ioutil.NopCloser(snappy.NewReader(r)), nil
+	}
+	return nil, errors.Errorf("unknown compression %q", c)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,175 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/lrmrpb"
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCompression(t *testing.T) {
+	Convey("Given a batch of rows", t, func() {
+		msg, err := (&lrmrpb.PushDataRequest{Data: sampleBatch(100)}).Marshal()
+		So(err, ShouldBeNil)
+
+		for _, c := range []Compression{Gzip, Zstd} {
+			Convey(fmt.Sprintf("When compressed with %s", c), func() {
+				So(c.Validate(), ShouldBeNil)
+				compressed, err := compress(c, msg)
+				So(err, ShouldBeNil)
+
+				Convey("It should be smaller", func() {
+					So(len(compressed), ShouldBeLessThan, len(msg))
+				})
+
+				Convey("It should be decompressed into the batch", func() {
+					decompressed, err := decompress(c, compressed)
+					So(err, ShouldBeNil)
+					So(decompressed, ShouldResemble, msg)
+				})
+			})
+		}
+	})
+
+	Convey("Unknown compression should be invalid", t, func() {
+		So(Snappy.Validate(), ShouldBeError)
+		So(NoCompression.Validate(), ShouldBeNil)
+		So(Compression("lzma").Validate(), ShouldBeError)
+	})
+}
+
+func TestFileCompression(t *testing.T) {
+	Convey("Given rows encoded", t, func() {
+		data, err := (&lrmrpb.PushDataRequest{Data: sampleBatch(1000)}).Marshal()
+		So(err, ShouldBeNil)
+
+		for _, c := range []Compression{NoCompression, Gzip, Zstd, Snappy} {
+			Convey(fmt.Sprintf("When written with %q", c), func() {
+				var buf bytes.Buffer
+				w, err := c.NewWriter(&buf, DefaultLevel)
+				So(err, ShouldBeNil)
+				_, err = w.Write(data)
+				So(err, ShouldBeNil)
+				So(w.Close(), ShouldBeNil)
+
+				Convey("It should be read by the reader of the compression", func() {
+					r, err := c.NewReader(&buf)
+					So(err, ShouldBeNil)
+					read, err := ioutil.ReadAll(r)
+					So(err, ShouldBeNil)
+					So(r.Close(), ShouldBeNil)
+					So(read, ShouldResemble, data)
+				})
+			})
+		}
+
+		Convey("When written with the best level", func() {
+			// encoded without maps, whose keys are encoded in random order
+			var text bytes.Buffer
+			for i := 0; i < 10000; i++ {
+				fmt.Fprintf(&text, "user-%d,purchase,%d\n", i%100, i*100)
+			}
+			sizeOf := func(c Compression, level int) int {
+				var buf bytes.Buffer
+				w, err := c.NewWriter(&buf, level)
+				So(err, ShouldBeNil)
+				_, err = w.Write(text.Bytes())
+				So(err, ShouldBeNil)
+				So(w.Close(), ShouldBeNil)
+				return buf.Len()
+			}
+
+			Convey("It should be smaller than with the fastest level", func() {
+				So(sizeOf(Gzip, 9), ShouldBeLessThanOrEqualTo, sizeOf(Gzip, 1))
+				So(sizeOf(Zstd, 22), ShouldBeLessThanOrEqualTo, sizeOf(Zstd, 1))
+			})
+		})
+	})
+
+	Convey("Levels out of the range of the compression should be invalid", t, func() {
+		So(Gzip.ValidateFile(9), ShouldBeNil)
+		So(Gzip.ValidateFile(10), ShouldBeError)
+		So(Zstd.ValidateFile(22), ShouldBeNil)
+		So(Zstd.ValidateFile(23), ShouldBeError)
+		So(Snappy.ValidateFile(DefaultLevel), ShouldBeNil)
+		So(Snappy.ValidateFile(3), ShouldBeError)
+		So(Compression("lzma").ValidateFile(DefaultLevel), ShouldBeError)
+	})
+}
+
+// BenchmarkCompression measures the CPU time of compressing and decompressing a batch sent
+// by BufferedOutput, and reports the size of the batch on the wire as wire_bytes/op.
+func BenchmarkCompression(b *testing.B) {
+	msg, err := (&lrmrpb.PushDataRequest{Data: sampleBatch(DefaultOptions().BufferLength)}).Marshal()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("none", func(b *testing.B) {
+		// nothing to do but reporting the size of the batch as it is
+		b.ReportMetric(float64(len(msg)), "wire_bytes/op")
+	})
+	for _, c := range []Compression{Gzip, Zstd} {
+		compressed, err := compress(c, msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(string(c)+"/compress", func(b *testing.B) {
+			b.SetBytes(int64(len(msg)))
+			for i := 0; i < b.N; i++ {
+				if _, err := compress(c, msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(compressed)), "wire_bytes/op")
+		})
+		b.Run(string(c)+"/decompress", func(b *testing.B) {
+			b.SetBytes(int64(len(msg)))
+			for i := 0; i < b.N; i++ {
+				if _, err := decompress(c, compressed); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func sampleBatch(n int) []*lrdd.Row {
+	countries := []string{"KR", "US", "JP", "DE"}
+	rows := make([]*lrdd.Row, n)
+	for i := range rows {
+		rows[i] = lrdd.KeyValue(fmt.Sprintf("user-%d", i%100), map[string]interface{}{
+			"event":   "purchase",
+			"amount":  i * 100,
+			"country": countries[i%len(countries)],
+		})
+	}
+	return rows
+}
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := encoding.GetCompressor(string(c)).Compress(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(c Compression, data []byte) ([]byte, error) {
+	r, err := encoding.GetCompressor(string(c)).Decompress(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
@@ -1,11 +1,30 @@
 package output
 
 import (
+	"time"
+
 	"github.com/creasty/defaults"
 )
 
 type Options struct {
-	BufferLength   int `default:"10000"`
+	// BufferLength is the maximum number of rows in a batch sent to another node. Rows written to
+	// the output are buffered until the batch is full or the task finishes. Small batches lower
+	// the latency of streaming jobs, while large batches give better throughput for bulk jobs.
+	// Since each batch is compressed as a whole (see Compression), larger batches also compress better.
+	BufferLength int `default:"10000"`
+
+	// BufferBytes flushes the batch once the size of its rows reaches it, even if it has fewer rows
+	// than BufferLength. It bounds the size of the batches of large rows. Zero disables it.
+	BufferBytes int `default:"0"`
+
+	// IdleFlushTimeout flushes the batch once no rows are written to the output for the duration, even if
+	// the batch is not full. It bounds the latency of the rows of slow streams (e.g. Kafka input) at the cost
+	// of smaller batches. Zero disables it, flushing only full batches.
+	IdleFlushTimeout time.Duration `default:"0"`
+
+	// Codec is the format of the batches sent to other nodes. Defaults to BatchCodec.
+	Codec Codec
+
 	MaxSendMsgSize int `default:"2147483647"`
 }
 
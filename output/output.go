@@ -1,11 +1,11 @@
 package output
 
 import (
+	"github.com/ab180/lrmr/logging"
 	"github.com/ab180/lrmr/lrdd"
-	"github.com/airbloc/logger"
 )
 
-var log = logger.New("output")
+var log = logging.New("output")
 
 type Output interface {
 	Write(...*lrdd.Row) error
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"bytes"
 	"context"
 	"io"
 
@@ -16,9 +17,13 @@ import (
 type PushStream struct {
 	stream lrmrpb.Node_PushDataClient
 	conn   io.Closer
+	codec  Codec
+	buf    bytes.Buffer
 }
 
-func OpenPushStream(ctx context.Context, cluster cluster.Cluster, n *node.Node, host, taskID string) (*PushStream, error) {
+// OpenPushStream opens a stream pushing rows to the task on given host. The rows are encoded
+// by given codec and compressed by given compression.
+func OpenPushStream(ctx context.Context, cluster cluster.Cluster, n *node.Node, host, taskID string, codec Codec, c Compression) (*PushStream, error) {
 	conn, err := cluster.Connect(ctx, host)
 	if err != nil {
 		return nil, errors.Wrapf(err, "connect %s", host)
@@ -36,18 +41,27 @@ func OpenPushStream(ctx context.Context, cluster cluster.Cluster, n *node.Node,
 	runCtx := metadata.AppendToOutgoingContext(ctx, "dataHeader", rawHead)
 
 	worker := lrmrpb.NewNodeClient(conn)
-	stream, err := worker.PushData(runCtx)
+	stream, err := worker.PushData(runCtx, c.callOptions()...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "open stream to %s", host)
 	}
 	return &PushStream{
 		stream: stream,
 		conn:   conn,
+		codec:  codec,
 	}, nil
 }
 
 func (p *PushStream) Write(data ...*lrdd.Row) (err error) {
-	return p.stream.Send(&lrmrpb.PushDataRequest{Data: data})
+	if p.codec != StreamCodec {
+		return p.stream.Send(&lrmrpb.PushDataRequest{Data: data})
+	}
+	// the message is serialized on Send, so the buffer can be reused after it
+	p.buf.Reset()
+	if err := lrdd.NewRowEncoder(&p.buf).Encode(data...); err != nil {
+		return errors.Wrap(err, "encode rows")
+	}
+	return p.stream.Send(&lrmrpb.PushDataRequest{Encoded: p.buf.Bytes()})
 }
 
 func (p *PushStream) Close() error {
@@ -1,29 +1,85 @@
 package output
 
 import (
+	"sync"
+
 	"github.com/ab180/lrmr/lrdd"
 	"github.com/ab180/lrmr/partitions"
 	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+)
+
+// NoOutputPolicy decides what the Writer does with a row for which the partitioner finds
+// no partition, i.e. returns partitions.ErrNoOutput.
+type NoOutputPolicy int
+
+const (
+	// DropOnNoOutput drops the rows, counting them in Writer.RowsDropped. It is the default.
+	DropOnNoOutput NoOutputPolicy = iota
+
+	// FailOnNoOutput fails the write, and therefore the task.
+	FailOnNoOutput
 )
 
 type Writer struct {
-	context     partitions.Context
-	partitioner partitions.Partitioner
-	isPreserved bool
+	context        partitions.Context
+	partitioner    partitions.Partitioner
+	multi          partitions.MultiPartitioner
+	isPreserved    bool
+	projection     []string
+	noOutputPolicy NoOutputPolicy
 
 	// outputs is a mapping of partition ID to an output.
 	outputs map[string]Output
+
+	bytesWritten atomic.Int64
+	rowsWritten  atomic.Int64
+	rowsDropped  atomic.Int64
+
+	// partitionRows is the number of rows written to each output partition.
+	partitionRows   map[string]int64
+	partitionRowsMu sync.Mutex
 }
 
 func NewWriter(partitionID string, p partitions.Partitioner, outputs map[string]Output) *Writer {
+	multi, _ := partitions.UnwrapPartitioner(p).(partitions.MultiPartitioner)
 	return &Writer{
 		context:     partitions.NewContext(partitionID),
 		partitioner: p,
+		multi:       multi,
 		isPreserved: partitions.IsPreserved(p),
 		outputs:     outputs,
 	}
 }
 
+// SetProjection makes the writer project the rows to the fields of given keys before writing.
+// See lrdd.Row.Project for details.
+func (w *Writer) SetProjection(keys []string) {
+	w.projection = keys
+}
+
+// SetNoOutputPolicy sets the policy on the rows for which the partitioner finds no partition.
+func (w *Writer) SetNoOutputPolicy(p NoOutputPolicy) {
+	w.noOutputPolicy = p
+}
+
+// handleNoOutput drops the row or returns an error by the NoOutputPolicy.
+func (w *Writer) handleNoOutput(row *lrdd.Row) error {
+	if w.noOutputPolicy == FailOnNoOutput {
+		return errors.Wrapf(partitions.ErrNoOutput, "no partition for the row with key %q", row.Key)
+	}
+	w.rowsDropped.Inc()
+	return nil
+}
+
+func (w *Writer) project(row *lrdd.Row) *lrdd.Row {
+	if len(w.projection) == 0 {
+		return row
+	}
+	projected := row.Project(w.projection...)
+	return &projected
+}
+
 func (w *Writer) Write(data ...*lrdd.Row) error {
 	if w.isPreserved {
 		output := w.outputs[w.context.PartitionID()]
@@ -31,19 +87,52 @@ func (w *Writer) Write(data ...*lrdd.Row) error {
 			// probably the last stage
 			return nil
 		}
+		if len(w.projection) > 0 {
+			projected := make([]*lrdd.Row, len(data))
+			for i, row := range data {
+				projected[i] = w.project(row)
+			}
+			data = projected
+		}
+		w.bytesWritten.Add(int64(sizeOf(data)))
+		w.rowsWritten.Add(int64(len(data)))
 		return output.Write(data...)
 	}
 	writes := make(map[string][]*lrdd.Row)
 	for _, row := range data {
+		if w.multi != nil {
+			ids, err := w.multi.DeterminePartitions(w.context, row, len(w.outputs))
+			if err != nil {
+				if err == partitions.ErrNoOutput {
+					if err := w.handleNoOutput(row); err != nil {
+						return err
+					}
+					continue
+				}
+				return err
+			}
+			if len(ids) == 0 {
+				// dropped by the partitioner on purpose (e.g. partitions.DropUnkeyed)
+				w.rowsDropped.Inc()
+				continue
+			}
+			projected := w.project(row)
+			for _, id := range ids {
+				writes[id] = append(writes[id], projected)
+			}
+			continue
+		}
 		id, err := w.partitioner.DeterminePartition(w.context, row, len(w.outputs))
 		if err != nil {
 			if err == partitions.ErrNoOutput {
-				// TODO: add alert if too many outputs are skipped
+				if err := w.handleNoOutput(row); err != nil {
+					return err
+				}
 				continue
 			}
 			return err
 		}
-		writes[id] = append(writes[id], row)
+		writes[id] = append(writes[id], w.project(row))
 	}
 	for id, rows := range writes {
 		out, ok := w.outputs[id]
@@ -53,10 +142,66 @@ func (w *Writer) Write(data ...*lrdd.Row) error {
 		if err := out.Write(rows...); err != nil {
 			return errors.Wrapf(err, "write %d rows to partition %s", len(rows), id)
 		}
+		w.bytesWritten.Add(int64(sizeOf(rows)))
+		w.rowsWritten.Add(int64(len(rows)))
 	}
+	w.countPartitionRows(writes)
 	return nil
 }
 
+// countPartitionRows adds the numbers of rows in the writes grouped by partition ID.
+func (w *Writer) countPartitionRows(writes map[string][]*lrdd.Row) {
+	w.partitionRowsMu.Lock()
+	defer w.partitionRowsMu.Unlock()
+
+	if w.partitionRows == nil {
+		w.partitionRows = make(map[string]int64, len(writes))
+	}
+	for id, rows := range writes {
+		w.partitionRows[id] += int64(len(rows))
+	}
+}
+
+// BytesWritten returns the total size of the rows written to the outputs, in wire format.
+// Rows skipped by the partitioner or written to no output are not counted.
+func (w *Writer) BytesWritten() int {
+	return int(w.bytesWritten.Load())
+}
+
+// RowsWritten returns the number of the rows written to the outputs. A row written to
+// multiple partitions is counted for each of them.
+func (w *Writer) RowsWritten() int {
+	return int(w.rowsWritten.Load())
+}
+
+// RowsDropped returns the number of the rows dropped since the partitioner found no partition for them.
+func (w *Writer) RowsDropped() int {
+	return int(w.rowsDropped.Load())
+}
+
+// PartitionCounts returns the number of rows routed to each output partition by the partitioner.
+// It returns nil if the partitions are preserved, as the rows are not routed.
+func (w *Writer) PartitionCounts() map[string]int64 {
+	w.partitionRowsMu.Lock()
+	defer w.partitionRowsMu.Unlock()
+
+	if w.partitionRows == nil {
+		return nil
+	}
+	counts := make(map[string]int64, len(w.partitionRows))
+	for id, n := range w.partitionRows {
+		counts[id] = n
+	}
+	return counts
+}
+
+func sizeOf(rows []*lrdd.Row) (size int) {
+	for _, r := range rows {
+		size += r.Size()
+	}
+	return size
+}
+
 func (w *Writer) Dispatch(taskID string, n int) ([]*lrdd.Row, error) {
 	o, ok := w.outputs[taskID]
 	if !ok {
@@ -68,7 +213,7 @@ func (w *Writer) Dispatch(taskID string, n int) ([]*lrdd.Row, error) {
 	return nil, nil
 }
 
-func (w Writer) NumOutputs() int {
+func (w *Writer) NumOutputs() int {
 	return len(w.outputs)
 }
 
@@ -0,0 +1,129 @@
+package output
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriter_Write(t *testing.T) {
+	Convey("Given a Writer with a replicating partitioner", t, func() {
+		outs := map[string]Output{
+			"0": &outputMock{},
+			"1": &outputMock{},
+			"2": &outputMock{},
+		}
+		w := NewWriter("0", partitions.NewReplicatingPartitioner(2), outs)
+
+		Convey("When writing rows", func() {
+			So(w.Write(lrdd.KeyValue("a", 1), lrdd.KeyValue("b", 2)), ShouldBeNil)
+
+			Convey("Each row should be written to the replicas", func() {
+				total := 0
+				for _, o := range outs {
+					total += len(o.(*outputMock).Rows)
+				}
+				So(total, ShouldEqual, 4)
+			})
+		})
+	})
+}
+
+func TestWriter_PartitionCounts(t *testing.T) {
+	Convey("Given a Writer with a hash key partitioner", t, func() {
+		outs := map[string]Output{
+			"0": &outputMock{},
+			"1": &outputMock{},
+			"2": &outputMock{},
+			"3": &outputMock{},
+		}
+		p := partitions.NewHashKeyPartitioner()
+		w := NewWriter("0", p, outs)
+
+		Convey("It should count rows routed to each partition", func() {
+			expected := make(map[string]int64)
+			for i := 0; i < 100; i++ {
+				row := &lrdd.Row{Key: strconv.Itoa(i % 10)}
+				id, err := p.DeterminePartition(partitions.NewContext("0"), row, len(outs))
+				So(err, ShouldBeNil)
+				expected[id]++
+
+				So(w.Write(row), ShouldBeNil)
+			}
+			So(w.PartitionCounts(), ShouldResemble, expected)
+		})
+	})
+
+	Convey("Given a Writer preserving partitions", t, func() {
+		w := NewWriter("0", partitions.NewPreservePartitioner(), map[string]Output{"0": &outputMock{}})
+
+		Convey("It should not count rows", func() {
+			So(w.Write(&lrdd.Row{Key: "a"}), ShouldBeNil)
+			So(w.PartitionCounts(), ShouldBeNil)
+		})
+	})
+}
+
+func TestWriter_SetProjection(t *testing.T) {
+	Convey("Given a Writer with a projection", t, func() {
+		out := &outputMock{}
+		w := NewWriter("0", partitions.NewHashKeyPartitioner(), map[string]Output{"0": out})
+		w.SetProjection([]string{"a"})
+
+		Convey("When writing rows", func() {
+			So(w.Write(lrdd.KeyValue("foo", map[string]interface{}{"a": 1, "b": 2})), ShouldBeNil)
+
+			Convey("Rows should be projected", func() {
+				So(out.Rows, ShouldHaveLength, 1)
+				So(out.Rows[0].Key, ShouldEqual, "foo")
+
+				var fields map[string]interface{}
+				out.Rows[0].UnmarshalValue(&fields)
+				So(fields, ShouldHaveLength, 1)
+				So(fields, ShouldContainKey, "a")
+			})
+		})
+	})
+}
+
+func TestWriter_NoOutputPolicy(t *testing.T) {
+	Convey("Given a Writer with a partitioner returning ErrNoOutput for unknown keys", t, func() {
+		out := &outputMock{}
+		w := NewWriter("0", partitions.NewFiniteKeyPartitioner([]string{"a"}), map[string]Output{"a": out})
+
+		Convey("It should drop and count the rows with unknown keys by default", func() {
+			So(w.Write(lrdd.KeyValue("a", 1), lrdd.KeyValue("b", 2), lrdd.KeyValue("c", 3)), ShouldBeNil)
+			So(out.Rows, ShouldHaveLength, 1)
+			So(w.RowsDropped(), ShouldEqual, 2)
+		})
+
+		Convey("With FailOnNoOutput, it should fail on the rows with unknown keys", func() {
+			w.SetNoOutputPolicy(FailOnNoOutput)
+			err := w.Write(lrdd.KeyValue("a", 1), lrdd.KeyValue("b", 2))
+			So(errors.Cause(err), ShouldEqual, partitions.ErrNoOutput)
+			So(w.RowsDropped(), ShouldEqual, 0)
+		})
+	})
+
+	Convey("Given a Writer with a partitioner dropping unkeyed rows", t, func() {
+		out := &outputMock{}
+		p := partitions.WithUnkeyedPolicy(partitions.NewFiniteKeyPartitioner([]string{"a"}), partitions.DropUnkeyed())
+		w := NewWriter("0", p, map[string]Output{"a": out})
+		w.SetNoOutputPolicy(FailOnNoOutput)
+
+		Convey("Unkeyed rows should be dropped and counted even with FailOnNoOutput", func() {
+			So(w.Write(lrdd.KeyValue("a", 1), lrdd.Value(2)), ShouldBeNil)
+			So(out.Rows, ShouldHaveLength, 1)
+			So(w.RowsDropped(), ShouldEqual, 1)
+		})
+
+		Convey("Rows with unknown keys should fail", func() {
+			err := w.Write(lrdd.KeyValue("b", 1))
+			So(errors.Cause(err), ShouldEqual, partitions.ErrNoOutput)
+		})
+	})
+}
@@ -0,0 +1,409 @@
+package lrmr
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	defaultParquetSampleSize   = 1000
+	defaultParquetRowGroupSize = 128 * 1024 * 1024
+)
+
+type ParquetOptions struct {
+	// Schema is the schema of the files. Fields of the rows not in the schema are dropped.
+	// If not set, the schema is inferred from the first rows of each partition.
+	Schema lrdd.Schema
+
+	// SampleSize is the number of the first rows of each partition which the schema is inferred from.
+	// Defaults to 1000.
+	SampleSize int
+
+	// RowGroupSize is the size of the row groups in bytes, which the rows are buffered and flushed by.
+	// Defaults to 128MiB.
+	RowGroupSize int64
+
+	// Compression compresses the pages of the files. Defaults to the compression of the session
+	// (see WithCompression), or output.Snappy if the session has no compression.
+	// The levels of the compressions are not configurable.
+	Compression output.Compression
+}
+
+type ParquetOption func(o *ParquetOptions)
+
+// WithParquetSchema sets the schema of the files, instead of inferring it from the rows.
+func WithParquetSchema(s lrdd.Schema) ParquetOption {
+	return func(o *ParquetOptions) {
+		o.Schema = s
+	}
+}
+
+// WithParquetSampleSize sets the number of the rows which the schema is inferred from.
+func WithParquetSampleSize(n int) ParquetOption {
+	return func(o *ParquetOptions) {
+		o.SampleSize = n
+	}
+}
+
+// WithRowGroupSize sets the size of the row groups in bytes.
+func WithRowGroupSize(size int64) ParquetOption {
+	return func(o *ParquetOptions) {
+		o.RowGroupSize = size
+	}
+}
+
+// WithParquetCompression compresses the pages of the files with given compression,
+// overriding the compression of the session.
+func WithParquetCompression(c output.Compression) ParquetOption {
+	return func(o *ParquetOptions) {
+		o.Compression = c
+	}
+}
+
+// parquetCodecs are the compressions supported by parquet.
+var parquetCodecs = map[output.Compression]parquet.CompressionCodec{
+	output.NoCompression: parquet.CompressionCodec_UNCOMPRESSED,
+	output.Snappy:        parquet.CompressionCodec_SNAPPY,
+	output.Gzip:          parquet.CompressionCodec_GZIP,
+	output.Zstd:          parquet.CompressionCodec_ZSTD,
+}
+
+// WriteParquet runs the job writing the map values of the rows in each partition to a Parquet file
+// in given directory on the worker, named "part-<partition ID>.parquet", instead of collecting them.
+// Every column is optional, so the fields missing in a row are written as null. Map, list and Any fields
+// are written as JSON strings.
+//
+// Unless the schema is given by WithParquetSchema, it is inferred from the first rows of the partition.
+// When a later row has a field not seen so far, or a value of the type mismatching the column,
+// the file is closed and the rest of the rows are written to a new file ("part-<partition ID>-<n>.parquet")
+// with the schema extended by the row, which readers like Spark and Athena merge on reading the directory.
+//
+// The compression is recorded in the metadata of the files, which the readers decompress the pages by.
+// Files are closed after the input of the task ends. An unfinished file of a failed task is removed.
+func (d *Dataset) WriteParquet(dir string, opts ...ParquetOption) (*RunningJob, error) {
+	o := ParquetOptions{
+		SampleSize:   defaultParquetSampleSize,
+		RowGroupSize: defaultParquetRowGroupSize,
+		Compression:  d.session.options.Compression,
+	}
+	if o.Compression == output.NoCompression {
+		o.Compression = output.Snappy
+	}
+	for _, optFn := range opts {
+		optFn(&o)
+	}
+	if o.SampleSize <= 0 {
+		return nil, errors.Errorf("invalid sample size %d", o.SampleSize)
+	}
+	codec, ok := parquetCodecs[o.Compression]
+	if !ok {
+		return nil, errors.Errorf("compression %q is not supported by parquet", o.Compression)
+	}
+	w := &parquetWriter{
+		Dir:          dir,
+		Schema:       o.Schema,
+		SampleSize:   o.SampleSize,
+		RowGroupSize: o.RowGroupSize,
+		Codec:        codec,
+	}
+	d.addStage(d.stageName(w), w)
+	return d.session.Run(d)
+}
+
+type parquetWriter struct {
+	Dir          string
+	Schema       lrdd.Schema
+	SampleSize   int
+	RowGroupSize int64
+	Codec        parquet.CompressionCodec
+}
+
+func (w *parquetWriter) Apply(ctx transformation.Context, in chan *lrdd.Row, _ output.Output) (err error) {
+	if err := os.MkdirAll(w.Dir, 0755); err != nil {
+		return errors.Wrapf(err, "create directory %s", w.Dir)
+	}
+	f := &parquetPartFile{
+		Prefix:       filepath.Join(w.Dir, "part-"+url.PathEscape(ctx.PartitionID())),
+		RowGroupSize: w.RowGroupSize,
+		Codec:        w.Codec,
+	}
+	defer func() {
+		if err != nil {
+			f.Abort()
+		}
+	}()
+
+	schema := w.Schema
+	var sample []map[string]interface{}
+	writeAll := func(records ...map[string]interface{}) error {
+		for _, record := range records {
+			if w.Schema == nil {
+				if merged, changed := mergeParquetSchema(schema, record); changed {
+					if err := f.Close(); err != nil {
+						return err
+					}
+					schema = merged
+				}
+			}
+			if err := f.Write(schema, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for row := range in {
+		var record map[string]interface{}
+		if err := row.DecodeValue(&record); err != nil {
+			return errors.Wrapf(err, "row %s is not a map", row.Key)
+		}
+		if schema != nil {
+			if err := writeAll(record); err != nil {
+				return err
+			}
+			continue
+		}
+		sample = append(sample, record)
+		if len(sample) < w.SampleSize {
+			continue
+		}
+		schema, _ = mergeParquetSchema(lrdd.Schema{}, sample...)
+		if err := writeAll(sample...); err != nil {
+			return err
+		}
+		sample = nil
+	}
+	if len(sample) > 0 {
+		schema, _ = mergeParquetSchema(lrdd.Schema{}, sample...)
+		if err := writeAll(sample...); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// parquetPartFile is the file which the rows of a partition are being written to.
+// The file is opened on the first write, and a new file is opened after closing it.
+type parquetPartFile struct {
+	Prefix       string
+	RowGroupSize int64
+	Codec        parquet.CompressionCodec
+
+	seq    int
+	path   string
+	file   source.ParquetFile
+	writer *writer.JSONWriter
+	schema lrdd.Schema
+}
+
+func (p *parquetPartFile) Write(s lrdd.Schema, record map[string]interface{}) error {
+	if p.writer == nil {
+		if err := p.open(s); err != nil {
+			return err
+		}
+	}
+	js, err := encodeParquetRecord(p.schema, record)
+	if err != nil {
+		return errors.Wrapf(err, "write %s", p.path)
+	}
+	if err := p.writer.Write(js); err != nil {
+		return errors.Wrapf(err, "write %s", p.path)
+	}
+	return nil
+}
+
+func (p *parquetPartFile) open(s lrdd.Schema) error {
+	p.path = p.Prefix + ".parquet"
+	if p.seq > 0 {
+		p.path = fmt.Sprintf("%s-%d.parquet", p.Prefix, p.seq)
+	}
+	p.seq++
+
+	schemaJSON, err := parquetSchemaJSON(s)
+	if err != nil {
+		return err
+	}
+	f, err := local.NewLocalFileWriter(p.path)
+	if err != nil {
+		return errors.Wrapf(err, "create %s", p.path)
+	}
+	w, err := writer.NewJSONWriter(schemaJSON, f, 1)
+	if err != nil {
+		f.Close()
+		return errors.Wrapf(err, "create parquet writer of %s", p.path)
+	}
+	w.RowGroupSize = p.RowGroupSize
+	w.CompressionType = p.Codec
+	p.file, p.writer, p.schema = f, w, s
+	return nil
+}
+
+// Close flushes the remaining row group and the footer of the current file, if opened.
+func (p *parquetPartFile) Close() error {
+	if p.writer == nil {
+		return nil
+	}
+	w, f := p.writer, p.file
+	p.writer, p.file = nil, nil
+
+	if err := w.WriteStop(); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "finish %s", p.path)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "close %s", p.path)
+	}
+	return nil
+}
+
+// Abort closes and removes the current file, which is unreadable without the footer.
+func (p *parquetPartFile) Abort() {
+	if p.writer == nil {
+		return
+	}
+	p.file.Close()
+	p.writer, p.file = nil, nil
+	if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to remove unfinished file {}: {}", p.path, err)
+	}
+}
+
+// mergeParquetSchema extends the schema by the fields of the records. A field having values of
+// different types is widened to Float if they are numbers, or to Any otherwise. Nil values are ignored.
+func mergeParquetSchema(s lrdd.Schema, records ...map[string]interface{}) (merged lrdd.Schema, changed bool) {
+	merged = s
+	for _, record := range records {
+		for name, v := range record {
+			t, ok := parquetFieldTypeOf(v)
+			if !ok {
+				continue
+			}
+			prev, exists := merged[name]
+			if exists && prev.Matches(v) {
+				continue
+			}
+			if exists {
+				t = widenFieldType(prev, t)
+			}
+			if !changed {
+				merged = make(lrdd.Schema, len(s)+1)
+				for k, v := range s {
+					merged[k] = v
+				}
+				changed = true
+			}
+			merged[name] = t
+		}
+	}
+	return merged, changed
+}
+
+func parquetFieldTypeOf(v interface{}) (lrdd.FieldType, bool) {
+	if v == nil {
+		return "", false
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.String:
+		return lrdd.String, true
+	case reflect.Bool:
+		return lrdd.Bool, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return lrdd.Int, true
+	case reflect.Float32, reflect.Float64:
+		return lrdd.Float, true
+	case reflect.Map:
+		return lrdd.Map, true
+	case reflect.Slice, reflect.Array:
+		return lrdd.List, true
+	}
+	return lrdd.Any, true
+}
+
+func widenFieldType(a, b lrdd.FieldType) lrdd.FieldType {
+	if a == b {
+		return a
+	}
+	if (a == lrdd.Int || a == lrdd.Float) && (b == lrdd.Int || b == lrdd.Float) {
+		return lrdd.Float
+	}
+	return lrdd.Any
+}
+
+// parquetSchemaJSON returns the schema in the format of parquet-go JSON writer.
+func parquetSchemaJSON(s lrdd.Schema) (string, error) {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type field struct {
+		Tag string
+	}
+	fields := make([]field, 0, len(names))
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, ",=") {
+			return "", errors.Errorf("field name %q is not supported in parquet", name)
+		}
+		var typ string
+		switch s[name] {
+		case lrdd.Bool:
+			typ = "type=BOOLEAN"
+		case lrdd.Int:
+			typ = "type=INT64"
+		case lrdd.Float:
+			typ = "type=DOUBLE"
+		default:
+			typ = "type=UTF8"
+		}
+		fields = append(fields, field{Tag: fmt.Sprintf("name=%s, %s, repetitiontype=OPTIONAL", name, typ)})
+	}
+	return jsoniter.MarshalToString(struct {
+		Tag    string
+		Fields []field
+	}{
+		Tag:    "name=parquet_go_root, repetitiontype=REQUIRED",
+		Fields: fields,
+	})
+}
+
+// encodeParquetRecord encodes the fields of the record in the schema into a JSON object.
+// Map, list and Any fields are encoded into JSON strings.
+func encodeParquetRecord(s lrdd.Schema, record map[string]interface{}) (string, error) {
+	fields := make(map[string]interface{}, len(s))
+	for name, t := range s {
+		v, ok := record[name]
+		if !ok || v == nil {
+			continue
+		}
+		if !t.Matches(v) {
+			return "", errors.Errorf("expected field %s to be %s, but got %T", name, t, v)
+		}
+		switch t {
+		case lrdd.Map, lrdd.List, lrdd.Any:
+			js, err := jsoniter.MarshalToString(v)
+			if err != nil {
+				return "", errors.Wrapf(err, "encode field %s", name)
+			}
+			v = js
+		}
+		fields[name] = v
+	}
+	return jsoniter.MarshalToString(fields)
+}
@@ -0,0 +1,120 @@
+package partitions
+
+import (
+	"github.com/ab180/lrmr/cluster/node"
+	"github.com/pkg/errors"
+)
+
+// Candidate is a node which the tasks of a stage can be assigned to.
+type Candidate struct {
+	*node.Node
+
+	// Tasks is the number of the tasks assigned to the node in the preceding stages of the job.
+	Tasks int
+
+	// CPUWeight and MemoryBytes are the sums of the resource hints of the tasks assigned to the node.
+	CPUWeight   float64
+	MemoryBytes int64
+}
+
+// AssignmentPolicy places the partitions of a stage on the nodes, e.g. bin-packing or spreading the tasks.
+// The candidates are chosen by the plan of the stage (e.g. DesiredNodeAffinity and MaxNodes) in ascending
+// order of their tasks. The partitions of the stages preserving the partitions of the previous stage are
+// not passed to the policy, since they have the same assignments as the previous stage.
+//
+// Schedule fails if the policy leaves a partition unassigned, assigns a partition twice or assigns
+// a partition to a node which is neither a candidate nor the master.
+type AssignmentPolicy interface {
+	// Assign returns the assignments of given partitions in the same order. The partitions may have
+	// AssignmentAffinity and PreferredHosts. A partition can be assigned to the master if it's non-nil,
+	// which is not in the candidates.
+	Assign(plan Plan, partitions []Partition, candidates []Candidate, master *node.Node) Assignments
+}
+
+// DefaultAssignmentPolicy assigns the partitions to the candidates in round-robin, up to the number of
+// executors of each node. Partitions with AssignmentAffinity are assigned to the nodes satisfying it,
+// and those with PreferredHosts are assigned to the hosts if they have a free executor. If the plan has
+// a resource hint, the partitions are assigned to the node with the least resources used by its tasks.
+type DefaultAssignmentPolicy struct{}
+
+func (DefaultAssignmentPolicy) Assign(plan Plan, partitions []Partition, candidates []Candidate, master *node.Node) Assignments {
+	nn := make([]nodeWithStats, len(candidates))
+	for i, c := range candidates {
+		nn[i] = nodeWithStats{
+			Node:         c.Node,
+			currentTasks: c.Tasks,
+			cpuWeight:    c.CPUWeight,
+			memoryBytes:  c.MemoryBytes,
+		}
+	}
+	curSlot := 0
+	assignments := make(Assignments, len(partitions))
+	for j, p := range partitions {
+		var selected *nodeWithStats
+		if len(p.AssignmentAffinity) > 0 {
+			selected, curSlot = selectNextNodeWithAffinity(nn, master, p.AssignmentAffinity, curSlot)
+			if selected == nil {
+				log.Warn("Unable to find node satisfying affinity rule {} for partition {}.", p.AssignmentAffinity, p.ID)
+				selected, curSlot = selectNextNode(nn, &plan, curSlot)
+			}
+		} else if n := selectPreferredNode(nn, &plan, p.PreferredHosts); n != nil {
+			selected = n
+		} else {
+			selected, curSlot = selectNextNode(nn, &plan, curSlot)
+		}
+		selected.addTask(plan.Resources)
+		assignments[j] = Assignment{
+			PartitionID: p.ID,
+			Host:        selected.Node.Host,
+		}
+	}
+	return assignments
+}
+
+// validateAssignments checks that every partition is assigned exactly once to a candidate or the master.
+func validateAssignments(assignments Assignments, partitions []Partition, candidates []Candidate, master *node.Node) error {
+	hosts := make(map[string]bool, len(candidates)+1)
+	for _, c := range candidates {
+		hosts[c.Host] = true
+	}
+	if master != nil {
+		hosts[master.Host] = true
+	}
+	unassigned := make(map[string]bool, len(partitions))
+	for _, p := range partitions {
+		unassigned[p.ID] = true
+	}
+	assigned := make(map[string]bool, len(assignments))
+	for _, a := range assignments {
+		if assigned[a.PartitionID] {
+			return errors.Errorf("partition %s is assigned more than once", a.PartitionID)
+		}
+		if !unassigned[a.PartitionID] {
+			return errors.Errorf("unknown partition %s is assigned", a.PartitionID)
+		}
+		if !hosts[a.Host] {
+			return errors.Errorf("partition %s is assigned to unknown host %s", a.PartitionID, a.Host)
+		}
+		assigned[a.PartitionID] = true
+		delete(unassigned, a.PartitionID)
+	}
+	for _, p := range partitions {
+		if unassigned[p.ID] {
+			return errors.Errorf("partition %s is not assigned", p.ID)
+		}
+	}
+	return nil
+}
+
+func toCandidates(nn []nodeWithStats) []Candidate {
+	candidates := make([]Candidate, len(nn))
+	for i, n := range nn {
+		candidates[i] = Candidate{
+			Node:        n.Node,
+			Tasks:       n.currentTasks,
+			CPUWeight:   n.cpuWeight,
+			MemoryBytes: n.memoryBytes,
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,127 @@
+package partitions
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/segmentio/fasthash/fnv1a"
+)
+
+// Coalescer is a Partitioner planning the next partitions from the assignments of the current stage,
+// instead of the number of executors. Schedule replaces it with the partitioner returned by PlanCoalesced,
+// which routes the rows by the partitions planned.
+type Coalescer interface {
+	Partitioner
+	PlanCoalesced(current Assignments) ([]Partition, Partitioner)
+}
+
+// coalescePartitioner merges the partitions of a stage into N partitions, sending all rows of a partition
+// to the same partition. Partitions on the same node are merged into the partitions on the node,
+// so that the rows are passed without network transfer unless a node is left without a merged partition.
+type coalescePartitioner struct {
+	N int
+
+	// Sources are the IDs of the partitions merged into the partition of each index. It's set by PlanCoalesced.
+	Sources [][]string
+
+	targets     map[string]string
+	targetsOnce sync.Once
+}
+
+// NewCoalescePartitioner creates a partitioner merging the partitions of a stage into n partitions
+// without shuffling the rows. If the stage has less than n partitions, they are kept as is.
+func NewCoalescePartitioner(n int) Partitioner {
+	return &coalescePartitioner{N: n}
+}
+
+func (c *coalescePartitioner) PlanNext(int) []Partition {
+	return PlanForNumberOf(c.N)
+}
+
+// PlanCoalesced assigns the merged partitions to the nodes in proportion to the number of current partitions
+// on them, by the largest remainder method. Each merged partition is pinned to its node with AssignmentAffinity.
+func (c *coalescePartitioner) PlanCoalesced(current Assignments) ([]Partition, Partitioner) {
+	n := c.N
+	if n > len(current) {
+		n = len(current)
+	}
+	if n <= 0 {
+		return PlanForNumberOf(c.N), c
+	}
+	byHost := make(map[string][]string)
+	var hosts []string
+	for _, a := range current {
+		if _, ok := byHost[a.Host]; !ok {
+			hosts = append(hosts, a.Host)
+		}
+		byHost[a.Host] = append(byHost[a.Host], a.PartitionID)
+	}
+	sort.Strings(hosts)
+
+	counts := make(map[string]int, len(hosts))
+	remainders := make(map[string]int, len(hosts))
+	allocated := 0
+	for _, host := range hosts {
+		counts[host] = n * len(byHost[host]) / len(current)
+		remainders[host] = n * len(byHost[host]) % len(current)
+		allocated += counts[host]
+	}
+	byRemainder := append([]string(nil), hosts...)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		return remainders[byRemainder[i]] > remainders[byRemainder[j]]
+	})
+	for i := 0; allocated < n; i++ {
+		counts[byRemainder[i%len(byRemainder)]]++
+		allocated++
+	}
+
+	planned := make([]Partition, 0, n)
+	coalesced := &coalescePartitioner{N: n, Sources: make([][]string, n)}
+	var orphans []string
+	for _, host := range hosts {
+		if counts[host] == 0 {
+			// no merged partition on the node; its partitions are sent over the network
+			orphans = append(orphans, byHost[host]...)
+			continue
+		}
+		first := len(planned)
+		for i := 0; i < counts[host]; i++ {
+			planned = append(planned, Partition{
+				ID:                 strconv.Itoa(len(planned)),
+				AssignmentAffinity: map[string]string{"Host": host},
+			})
+		}
+		for i, id := range byHost[host] {
+			// splits the partitions of the node into contiguous chunks
+			slot := first + i*counts[host]/len(byHost[host])
+			coalesced.Sources[slot] = append(coalesced.Sources[slot], id)
+		}
+	}
+	for i, id := range orphans {
+		coalesced.Sources[i%n] = append(coalesced.Sources[i%n], id)
+	}
+	return planned, coalesced
+}
+
+// DeterminePartition sends the row to the partition merging the current partition. If the partitions are not
+// planned by PlanCoalesced, the current partition is merged into the partition by its number or hash.
+func (c *coalescePartitioner) DeterminePartition(ctx Context, _ *lrdd.Row, numOutputs int) (id string, err error) {
+	c.targetsOnce.Do(func() {
+		c.targets = make(map[string]string)
+		for i, sources := range c.Sources {
+			for _, source := range sources {
+				c.targets[source] = strconv.Itoa(i)
+			}
+		}
+	})
+	cur := ctx.PartitionID()
+	if id, ok := c.targets[cur]; ok {
+		return id, nil
+	}
+	if n, err := strconv.Atoi(cur); err == nil && n >= 0 {
+		return strconv.Itoa(n % numOutputs), nil
+	}
+	return strconv.FormatUint(fnv1a.HashString64(cur)%uint64(numOutputs), 10), nil
+}
@@ -0,0 +1,92 @@
+package partitions
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/cluster/node"
+	"github.com/ab180/lrmr/lrdd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCoalescePartitioner(t *testing.T) {
+	Convey("Given partitions on two nodes", t, func() {
+		current := Assignments{
+			{PartitionID: "0", Host: "a"},
+			{PartitionID: "1", Host: "b"},
+			{PartitionID: "2", Host: "a"},
+			{PartitionID: "3", Host: "b"},
+			{PartitionID: "4", Host: "a"},
+			{PartitionID: "5", Host: "b"},
+		}
+
+		Convey("When merging them into fewer partitions than the nodes have", func() {
+			planned, p := NewCoalescePartitioner(4).(Coalescer).PlanCoalesced(current)
+
+			Convey("It should plan the given number of partitions, pinned to the nodes evenly", func() {
+				So(planned, ShouldHaveLength, 4)
+				hosts := make(map[string]int)
+				for _, partition := range planned {
+					hosts[partition.AssignmentAffinity["Host"]]++
+				}
+				So(hosts, ShouldResemble, map[string]int{"a": 2, "b": 2})
+			})
+
+			Convey("Every row of a partition should be sent to a merged partition on the same node", func() {
+				hostOf := make(map[string]string)
+				for _, partition := range planned {
+					hostOf[partition.ID] = partition.AssignmentAffinity["Host"]
+				}
+				for _, a := range current {
+					id, err := p.DeterminePartition(NewContext(a.PartitionID), lrdd.Value(1), len(planned))
+					So(err, ShouldBeNil)
+					So(hostOf[id], ShouldEqual, a.Host)
+				}
+			})
+		})
+
+		Convey("When merging them into fewer partitions than the nodes", func() {
+			planned, p := NewCoalescePartitioner(1).(Coalescer).PlanCoalesced(current)
+
+			Convey("Every partition should be merged into the only partition", func() {
+				So(planned, ShouldHaveLength, 1)
+				for _, a := range current {
+					id, err := p.DeterminePartition(NewContext(a.PartitionID), lrdd.Value(1), len(planned))
+					So(err, ShouldBeNil)
+					So(id, ShouldEqual, "0")
+				}
+			})
+		})
+
+		Convey("When merging them into more partitions than they are", func() {
+			planned, _ := NewCoalescePartitioner(10).(Coalescer).PlanCoalesced(current)
+
+			Convey("The number of partitions should be kept", func() {
+				So(planned, ShouldHaveLength, 6)
+			})
+		})
+	})
+
+	Convey("Given a plan coalescing a stage", t, func() {
+		nn := []*node.Node{
+			{Host: "localhost:1001", Executors: 3},
+			{Host: "localhost:1002", Executors: 3},
+		}
+		pp, aa := mustSchedule(nn, []Plan{
+			{DesiredCount: 1},
+			{DesiredCount: 6, Partitioner: NewCoalescePartitioner(2)},
+			{DesiredCount: 2},
+		})
+
+		Convey("The coalesced stage should have the given number of partitions", func() {
+			So(pp[1].Partitions, ShouldHaveLength, 6)
+			So(pp[2].Partitions, ShouldHaveLength, 2)
+			So(aa[2].ToMap(), ShouldResemble, map[string]string{"0": "localhost:1001", "1": "localhost:1002"})
+		})
+
+		Convey("The partitioner should be replaced with the planned one", func() {
+			coalesced, ok := UnwrapPartitioner(pp[1].Partitioner).(*coalescePartitioner)
+			So(ok, ShouldBeTrue)
+			So(coalesced.Sources, ShouldHaveLength, 2)
+		})
+	})
+}
@@ -1,16 +1,25 @@
 package partitions
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
 	"strconv"
+	"sync"
 
 	"github.com/ab180/lrmr/internal/serialization"
 	"github.com/ab180/lrmr/lrdd"
 	"github.com/segmentio/fasthash/fnv1a"
+	"go.uber.org/atomic"
 )
 
 // ErrNoOutput is returned by Partitioner.DeterminePartition when there's no
-// corresponding partition found with the key of given row.
+// corresponding partition found with the key of given row. Among the partitioners of this package,
+// FiniteKeyPartitioner returns it for the keys not in its key set, LocalityPartitioner returns it
+// if it has no locations, and the partitioners wrapped by WithUnkeyedPolicy with DropUnkeyed return it
+// for the rows without keys. The output writer drops such rows or fails the task by output.NoOutputPolicy.
 var ErrNoOutput = errors.New("no output")
 
 type Partitioner interface {
@@ -18,6 +27,13 @@ type Partitioner interface {
 	DeterminePartition(c Context, r *lrdd.Row, numOutputs int) (id string, err error)
 }
 
+// MultiPartitioner is a Partitioner which can send a row to multiple partitions (e.g. for replicated joins).
+// If a partitioner implements it, DeterminePartitions is used instead of DeterminePartition.
+type MultiPartitioner interface {
+	Partitioner
+	DeterminePartitions(c Context, r *lrdd.Row, numOutputs int) (ids []string, err error)
+}
+
 type SerializablePartitioner struct {
 	Partitioner
 }
@@ -26,11 +42,25 @@ func WrapPartitioner(p Partitioner) SerializablePartitioner {
 	return SerializablePartitioner{p}
 }
 
+// PlanTransformer is a partitioner wrapper which modifies the partitions planned by the wrapped one
+// (e.g. adding affinity rules). Wrappers implementing it can be composed with Chain.
+type PlanTransformer interface {
+	TransformPlan(planned []Partition) []Partition
+}
+
+// UnwrapPartitioner returns the innermost partitioner of given partitioner,
+// removing SerializablePartitioner and the wrappers like WithAssignmentToMaster or Chain.
 func UnwrapPartitioner(p Partitioner) Partitioner {
-	if sp, ok := p.(SerializablePartitioner); ok {
-		return sp.Partitioner
+	for {
+		switch w := p.(type) {
+		case SerializablePartitioner:
+			p = w.Partitioner
+		case interface{ Unwrap() Partitioner }:
+			p = w.Unwrap()
+		default:
+			return p
+		}
 	}
-	return p
 }
 
 func (s SerializablePartitioner) MarshalJSON() ([]byte, error) {
@@ -42,10 +72,24 @@ func (s *SerializablePartitioner) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return err
 	}
+	if v == nil {
+		s.Partitioner = nil
+		return nil
+	}
 	s.Partitioner = v.(Partitioner)
 	return nil
 }
 
+// GobEncode encodes the partitioner as MarshalJSON does, so that it can be a field of the structs
+// encoded by serialization.GobCodec without registering the partitioner to gob.
+func (s SerializablePartitioner) GobEncode() ([]byte, error) {
+	return s.MarshalJSON()
+}
+
+func (s *SerializablePartitioner) GobDecode(data []byte) error {
+	return s.UnmarshalJSON(data)
+}
+
 // PlanForNumberOf creates partition for the number of executors.
 // It uses its index number for each partition's ID.
 func PlanForNumberOf(numExecutors int) []Partition {
@@ -91,6 +135,101 @@ func (f *FiniteKeyPartitioner) DeterminePartition(c Context, r *lrdd.Row, numOut
 	return r.Key, nil
 }
 
+// LocalityPartitioner creates a partition for each key, preferring to run on the hosts holding the data of
+// the key (e.g. the node holding a block of a file), so that the data is read without a network hop.
+// The preference is a soft constraint; see Partition.PreferredHosts. Partition IDs are the indices of
+// the sorted keys, and the rows with unknown keys are distributed to the partitions by the hash of their keys.
+type LocalityPartitioner struct {
+	// Locations are the preferred hosts of the keys.
+	Locations map[string][]string
+
+	keys      []string
+	indexOnce sync.Once
+}
+
+func NewLocalityPartitioner(locations map[string][]string) Partitioner {
+	return &LocalityPartitioner{Locations: locations}
+}
+
+func (l *LocalityPartitioner) sortedKeys() []string {
+	l.indexOnce.Do(func() {
+		l.keys = make([]string, 0, len(l.Locations))
+		for k := range l.Locations {
+			l.keys = append(l.keys, k)
+		}
+		sort.Strings(l.keys)
+	})
+	return l.keys
+}
+
+// PlanNext creates partitions for the number of keys, with the preferred hosts of the keys.
+func (l *LocalityPartitioner) PlanNext(int) []Partition {
+	keys := l.sortedKeys()
+	partitions := make([]Partition, len(keys))
+	for i, k := range keys {
+		partitions[i] = Partition{
+			ID:             strconv.Itoa(i),
+			IsElastic:      false,
+			PreferredHosts: l.Locations[k],
+		}
+	}
+	return partitions
+}
+
+func (l *LocalityPartitioner) DeterminePartition(c Context, r *lrdd.Row, numOutputs int) (id string, err error) {
+	keys := l.sortedKeys()
+	if len(keys) == 0 {
+		return "", ErrNoOutput
+	}
+	if _, ok := l.Locations[r.Key]; ok {
+		return strconv.Itoa(sort.SearchStrings(keys, r.Key)), nil
+	}
+	return strconv.FormatUint(fnv1a.HashString64(r.Key)%uint64(len(keys)), 10), nil
+}
+
+// RangePartitioner partitions rows by the ranges of their keys, divided by ordered split points.
+// For split points ["f", "m", "s"], keys are partitioned into (-∞, "f"), ["f", "m"), ["m", "s") and ["s", ∞).
+// Partition IDs are the indices of the ranges, so outputs are sorted across the partitions if each is sorted.
+type RangePartitioner struct {
+	SplitPoints []string
+}
+
+// NewRangePartitioner creates a RangePartitioner. The split points are sorted and deduplicated.
+// If no split points are given, every row goes to a single partition.
+func NewRangePartitioner(splitPoints []string) Partitioner {
+	sorted := append([]string(nil), splitPoints...)
+	sort.Strings(sorted)
+
+	deduped := sorted[:0]
+	for i, s := range sorted {
+		if i > 0 && s == sorted[i-1] {
+			continue
+		}
+		deduped = append(deduped, s)
+	}
+	return &RangePartitioner{SplitPoints: deduped}
+}
+
+// PlanNext creates a partition for each range, which is the number of split points plus a tail partition.
+func (r *RangePartitioner) PlanNext(int) []Partition {
+	partitions := make([]Partition, len(r.SplitPoints)+1)
+	for i := range partitions {
+		partitions[i] = Partition{
+			ID:        strconv.Itoa(i),
+			IsElastic: false,
+		}
+	}
+	return partitions
+}
+
+func (r *RangePartitioner) DeterminePartition(c Context, row *lrdd.Row, numOutputs int) (id string, err error) {
+	// number of split points less than or equal to the key is the index of the range
+	i := sort.Search(len(r.SplitPoints), func(i int) bool {
+		return r.SplitPoints[i] > row.Key
+	})
+	return strconv.Itoa(i), nil
+}
+
 type hashKeyPartitioner struct{}
 
 func NewHashKeyPartitioner() Partitioner {
@@ -126,6 +265,243 @@ func (f *ShuffledPartitioner) DeterminePartition(c Context, r *lrdd.Row, numOutp
 	return strconv.Itoa(slot), nil
 }
 
+// WeightedPartitioner distributes keys to the nodes in proportion to their weights,
+// which is useful for clusters with nodes of heterogeneous capacities.
+type WeightedPartitioner struct {
+	// Weights is a relative capacity of each node, keyed by the host of the node.
+	Weights map[string]int
+}
+
+func NewWeightedPartitioner(weights map[string]int) Partitioner {
+	return &WeightedPartitioner{Weights: weights}
+}
+
+// PlanNext allocates the partitions to the nodes in proportion to their weights, by the
+// largest remainder method. Each partition is pinned to its node with AssignmentAffinity.
+func (w *WeightedPartitioner) PlanNext(numExecutors int) []Partition {
+	hosts := make([]string, 0, len(w.Weights))
+	total := 0
+	for host, weight := range w.Weights {
+		if weight <= 0 {
+			continue
+		}
+		hosts = append(hosts, host)
+		total += weight
+	}
+	if total == 0 {
+		return PlanForNumberOf(numExecutors)
+	}
+	sort.Strings(hosts)
+
+	counts := make(map[string]int, len(hosts))
+	remainders := make(map[string]int, len(hosts))
+	allocated := 0
+	for _, host := range hosts {
+		counts[host] = numExecutors * w.Weights[host] / total
+		remainders[host] = numExecutors * w.Weights[host] % total
+		allocated += counts[host]
+	}
+	byRemainder := append([]string(nil), hosts...)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		return remainders[byRemainder[i]] > remainders[byRemainder[j]]
+	})
+	for i := 0; allocated < numExecutors; i++ {
+		counts[byRemainder[i%len(byRemainder)]]++
+		allocated++
+	}
+
+	pp := make([]Partition, 0, numExecutors)
+	for _, host := range hosts {
+		for i := 0; i < counts[host]; i++ {
+			pp = append(pp, Partition{
+				ID:                 strconv.Itoa(len(pp)),
+				AssignmentAffinity: map[string]string{"Host": host},
+			})
+		}
+	}
+	return pp
+}
+
+// DeterminePartition hashes the key evenly into the partitions. Since the number of partitions
+// of each node is proportional to its weight, so is the share of the keys.
+func (w *WeightedPartitioner) DeterminePartition(c Context, r *lrdd.Row, numOutputs int) (id string, err error) {
+	slot := fnv1a.HashString64(r.Key) % uint64(numOutputs)
+	return strconv.FormatUint(slot, 10), nil
+}
+
+// ConsistentHashPartitioner maps keys to partitions with a consistent hash ring, so that
+// changing the number of partitions from N to N+1 moves only about 1/(N+1) of the keys.
+type ConsistentHashPartitioner struct {
+	// Replicas is the number of virtual nodes of each partition on the ring.
+	// More replicas distribute keys more evenly.
+	Replicas int
+
+	ring *hashRing
+	mu   sync.Mutex
+}
+
+func NewConsistentHashPartitioner(replicas int) Partitioner {
+	if replicas < 1 {
+		replicas = 1
+	}
+	return &ConsistentHashPartitioner{Replicas: replicas}
+}
+
+func (c *ConsistentHashPartitioner) PlanNext(numExecutors int) []Partition {
+	c.ringOf(numExecutors)
+	return PlanForNumberOf(numExecutors)
+}
+
+func (c *ConsistentHashPartitioner) DeterminePartition(_ Context, r *lrdd.Row, numOutputs int) (id string, err error) {
+	return c.ringOf(numOutputs).lookup(r.Key), nil
+}
+
+// ringOf returns a ring with given number of partitions. The ring is built lazily and
+// rebuilt only if the number of partitions changes.
+func (c *ConsistentHashPartitioner) ringOf(numPartitions int) *hashRing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ring == nil || c.ring.numPartitions != numPartitions {
+		c.ring = newHashRing(numPartitions, c.Replicas)
+	}
+	return c.ring
+}
+
+type hashRing struct {
+	numPartitions int
+	points        []uint64
+	owners        map[uint64]string
+}
+
+func newHashRing(numPartitions, replicas int) *hashRing {
+	r := &hashRing{
+		numPartitions: numPartitions,
+		owners:        make(map[uint64]string, numPartitions*replicas),
+	}
+	for i := 0; i < numPartitions; i++ {
+		id := strconv.Itoa(i)
+		for j := 0; j < replicas; j++ {
+			point := ringHash(id + "#" + strconv.Itoa(j))
+			if _, ok := r.owners[point]; ok {
+				// collision; first one wins
+				continue
+			}
+			r.owners[point] = id
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// lookup returns the partition owning the nearest point clockwise from the key.
+func (r *hashRing) lookup(key string) string {
+	h := ringHash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// ringHash is FNV-1a followed by a 64-bit finalizer, since FNV-1a alone distributes
+// short and similar strings like virtual node names poorly on the ring.
+func ringHash(s string) uint64 {
+	h := fnv1a.HashString64(s)
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// replicatingPartitioner sends a row to N consecutive partitions, starting from the partition
+// determined by the hash of its key.
+type replicatingPartitioner struct {
+	Replicas int
+}
+
+// NewReplicatingPartitioner creates a MultiPartitioner which replicates each row into n partitions.
+// If n is greater than the number of outputs, the row is sent to every partition.
+func NewReplicatingPartitioner(n int) Partitioner {
+	return &replicatingPartitioner{Replicas: n}
+}
+
+func (r *replicatingPartitioner) PlanNext(numExecutors int) []Partition {
+	return PlanForNumberOf(numExecutors)
+}
+
+// DeterminePartition returns the first partition of the replicas.
+func (r *replicatingPartitioner) DeterminePartition(c Context, row *lrdd.Row, numOutputs int) (id string, err error) {
+	slot := fnv1a.HashString64(row.Key) % uint64(numOutputs)
+	return strconv.FormatUint(slot, 10), nil
+}
+
+func (r *replicatingPartitioner) DeterminePartitions(c Context, row *lrdd.Row, numOutputs int) (ids []string, err error) {
+	n := r.Replicas
+	if n > numOutputs {
+		n = numOutputs
+	}
+	start := fnv1a.HashString64(row.Key) % uint64(numOutputs)
+	for i := 0; i < n; i++ {
+		slot := (start + uint64(i)) % uint64(numOutputs)
+		ids = append(ids, strconv.FormatUint(slot, 10))
+	}
+	return ids, nil
+}
+
+// roundRobinPartitioner distributes rows evenly regardless of their keys.
+// Unlike ShuffledPartitioner, it is safe to be called concurrently.
+type roundRobinPartitioner struct {
+	counter atomic.Uint64
+}
+
+func NewRoundRobinPartitioner() Partitioner {
+	return &roundRobinPartitioner{}
+}
+
+func (r *roundRobinPartitioner) PlanNext(numExecutors int) []Partition {
+	return PlanForNumberOf(numExecutors)
+}
+
+func (r *roundRobinPartitioner) DeterminePartition(c Context, _ *lrdd.Row, numOutputs int) (id string, err error) {
+	slot := (r.counter.Inc() - 1) % uint64(numOutputs)
+	return strconv.FormatUint(slot, 10), nil
+}
+
+// seededShuffledPartitioner distributes input randomly, with a random generator from given seed.
+// Assignments are reproducible across reruns of the same job with the same seed.
+type seededShuffledPartitioner struct {
+	Seed int64
+
+	rng *rand.Rand
+	mu  sync.Mutex
+}
+
+// NewSeededShuffledPartitioner creates a partitioner distributing input randomly with given seed.
+// Unlike NewShuffledPartitioner which assigns rows in round-robin, rows are assigned randomly
+// while the assignment is still reproducible with the same seed.
+func NewSeededShuffledPartitioner(seed int64) Partitioner {
+	return &seededShuffledPartitioner{Seed: seed}
+}
+
+func (s *seededShuffledPartitioner) PlanNext(numExecutors int) []Partition {
+	return PlanForNumberOf(numExecutors)
+}
+
+func (s *seededShuffledPartitioner) DeterminePartition(c Context, r *lrdd.Row, numOutputs int) (id string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rng == nil {
+		// generator is created lazily, because the partitioner is deserialized on the workers
+		s.rng = rand.New(rand.NewSource(s.Seed))
+	}
+	return strconv.Itoa(s.rng.Intn(numOutputs)), nil
+}
+
 type PreservePartitioner struct{}
 
 func NewPreservePartitioner() Partitioner {
@@ -145,18 +521,39 @@ func IsPreserved(p Partitioner) bool {
 	return ok
 }
 
+// Equal returns true if the partitioners are of the same type with the same parameters, so that
+// they route a row to the same partition. Unexported states like the counts of PartitionStats are ignored.
+func Equal(a, b Partitioner) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	da, err := WrapPartitioner(a).MarshalJSON()
+	if err != nil {
+		return false
+	}
+	db, err := WrapPartitioner(b).MarshalJSON()
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(da, db)
+}
+
 type masterAssigner struct {
 	Partitioner SerializablePartitioner
 }
 
 // WithAssignmentToMaster wraps existing partitioner to assign partition to master nodes.
+// The partitioner can be nil if the wrapper is used in Chain.
 func WithAssignmentToMaster(p Partitioner) Partitioner {
 	return &masterAssigner{Partitioner: WrapPartitioner(p)}
 }
 
 // PlanNext overrides wrapped plans from partitioner with adding affinity to master nodes.
 func (m masterAssigner) PlanNext(numExecutors int) []Partition {
-	planned := m.Partitioner.PlanNext(numExecutors)
+	return m.TransformPlan(m.Partitioner.PlanNext(numExecutors))
+}
+
+func (m masterAssigner) TransformPlan(planned []Partition) []Partition {
 	for i := range planned {
 		if len(planned[i].AssignmentAffinity) == 0 {
 			planned[i].AssignmentAffinity = make(map[string]string)
@@ -169,3 +566,47 @@ func (m masterAssigner) PlanNext(numExecutors int) []Partition {
 func (m masterAssigner) DeterminePartition(c Context, r *lrdd.Row, numOutputs int) (id string, err error) {
 	return m.Partitioner.DeterminePartition(c, r, numOutputs)
 }
+
+func (m masterAssigner) Unwrap() Partitioner {
+	return m.Partitioner.Partitioner
+}
+
+type chainedPartitioner struct {
+	Partitioners []SerializablePartitioner
+}
+
+// Chain composes partitioner wrappers with a base partitioner, which is the last one.
+// The partitioners except the last one must implement PlanTransformer; wrappers like
+// WithAssignmentToMaster can be given a nil partitioner since only their plan transforms are used.
+//
+// The plan from the base partitioner is transformed by the wrappers from the inner (right) to
+// the outer (left), so Chain(a, b, base) plans like a(b(base)). Rows are routed by the base partitioner.
+func Chain(partitioners ...Partitioner) Partitioner {
+	if len(partitioners) == 0 {
+		panic("at least one partitioner is required")
+	}
+	c := &chainedPartitioner{Partitioners: make([]SerializablePartitioner, len(partitioners))}
+	for i, p := range partitioners {
+		if _, ok := p.(PlanTransformer); !ok && i < len(partitioners)-1 {
+			panic(fmt.Sprintf("partitioner #%d (%T) is not a PlanTransformer", i, p))
+		}
+		c.Partitioners[i] = WrapPartitioner(p)
+	}
+	return c
+}
+
+func (c *chainedPartitioner) PlanNext(numExecutors int) []Partition {
+	planned := c.Unwrap().PlanNext(numExecutors)
+	for i := len(c.Partitioners) - 2; i >= 0; i-- {
+		planned = c.Partitioners[i].Partitioner.(PlanTransformer).TransformPlan(planned)
+	}
+	return planned
+}
+
+func (c *chainedPartitioner) DeterminePartition(ctx Context, r *lrdd.Row, numOutputs int) (id string, err error) {
+	return c.Unwrap().DeterminePartition(ctx, r, numOutputs)
+}
+
+func (c *chainedPartitioner) Unwrap() Partitioner {
+	return c.Partitioners[len(c.Partitioners)-1].Partitioner
+}
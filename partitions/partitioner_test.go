@@ -0,0 +1,345 @@
+package partitions
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/lrdd"
+	jsoniter "github.com/json-iterator/go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRangePartitioner(t *testing.T) {
+	Convey("Given a RangePartitioner", t, func() {
+		p := NewRangePartitioner([]string{"s", "f", "m", "f"})
+
+		Convey("It should plan a partition for each range with a tail partition", func() {
+			pp := p.PlanNext(8)
+			So(pp, ShouldHaveLength, 4)
+			for i, id := range []string{"0", "1", "2", "3"} {
+				So(pp[i].ID, ShouldEqual, id)
+			}
+		})
+
+		Convey("It should determine partition by the range containing the key", func() {
+			cases := map[string]string{
+				"":      "0",
+				"apple": "0",
+				"f":     "1",
+				"grape": "1",
+				"m":     "2",
+				"pear":  "2",
+				"s":     "3",
+				"zebra": "3",
+			}
+			for key, expected := range cases {
+				id, err := p.DeterminePartition(NewContext("0"), &lrdd.Row{Key: key}, 4)
+				So(err, ShouldBeNil)
+				So(id, ShouldEqual, expected)
+			}
+		})
+
+		Convey("It should be serializable", func() {
+			data, err := jsoniter.Marshal(WrapPartitioner(p))
+			So(err, ShouldBeNil)
+
+			var sp SerializablePartitioner
+			So(jsoniter.Unmarshal(data, &sp), ShouldBeNil)
+			So(sp.Partitioner, ShouldResemble, p)
+		})
+
+		Convey("With no split points, it should put every row into a single partition", func() {
+			p := NewRangePartitioner(nil)
+			So(p.PlanNext(8), ShouldHaveLength, 1)
+
+			id, err := p.DeterminePartition(NewContext("0"), &lrdd.Row{Key: "any"}, 1)
+			So(err, ShouldBeNil)
+			So(id, ShouldEqual, "0")
+		})
+	})
+}
+
+func TestLocalityPartitioner(t *testing.T) {
+	Convey("Given a LocalityPartitioner", t, func() {
+		p := NewLocalityPartitioner(map[string][]string{
+			"b.txt": {"localhost:1002"},
+			"a.txt": {"localhost:1001", "localhost:1003"},
+		})
+
+		Convey("It should plan a partition for each key with its preferred hosts", func() {
+			pp := p.PlanNext(8)
+			So(pp, ShouldHaveLength, 2)
+			So(pp[0].ID, ShouldEqual, "0")
+			So(pp[0].PreferredHosts, ShouldResemble, []string{"localhost:1001", "localhost:1003"})
+			So(pp[1].ID, ShouldEqual, "1")
+			So(pp[1].PreferredHosts, ShouldResemble, []string{"localhost:1002"})
+		})
+
+		Convey("It should determine partition by the key", func() {
+			id, err := p.DeterminePartition(NewContext("0"), &lrdd.Row{Key: "b.txt"}, 2)
+			So(err, ShouldBeNil)
+			So(id, ShouldEqual, "1")
+		})
+
+		Convey("It should distribute unknown keys to the partitions", func() {
+			id, err := p.DeterminePartition(NewContext("0"), &lrdd.Row{Key: "c.txt"}, 2)
+			So(err, ShouldBeNil)
+			So(id, ShouldBeIn, []string{"0", "1"})
+		})
+	})
+}
+
+func TestSeededShuffledPartitioner(t *testing.T) {
+	Convey("Given seeded shuffled partitioners", t, func() {
+		assign := func(p Partitioner) (ids []string) {
+			for i := 0; i < 100; i++ {
+				id, err := p.DeterminePartition(NewContext("0"), &lrdd.Row{}, 10)
+				So(err, ShouldBeNil)
+				ids = append(ids, id)
+			}
+			return ids
+		}
+
+		Convey("It should assign partitions reproducibly with the same seed", func() {
+			So(assign(NewSeededShuffledPartitioner(42)), ShouldResemble, assign(NewSeededShuffledPartitioner(42)))
+			So(assign(NewSeededShuffledPartitioner(42)), ShouldNotResemble, assign(NewSeededShuffledPartitioner(7)))
+		})
+
+		Convey("It should keep the seed after serialization", func() {
+			data, err := jsoniter.Marshal(WrapPartitioner(NewSeededShuffledPartitioner(42)))
+			So(err, ShouldBeNil)
+
+			var sp SerializablePartitioner
+			So(jsoniter.Unmarshal(data, &sp), ShouldBeNil)
+			So(assign(sp.Partitioner), ShouldResemble, assign(NewSeededShuffledPartitioner(42)))
+		})
+	})
+}
+
+func TestRoundRobinPartitioner(t *testing.T) {
+	Convey("Given a round-robin partitioner", t, func() {
+		p := NewRoundRobinPartitioner()
+
+		Convey("It should distribute keyless rows evenly", func() {
+			counts := make(map[string]int)
+			for i := 0; i < 100; i++ {
+				id, err := p.DeterminePartition(NewContext("0"), &lrdd.Row{}, 4)
+				So(err, ShouldBeNil)
+				counts[id]++
+			}
+			So(counts, ShouldResemble, map[string]int{"0": 25, "1": 25, "2": 25, "3": 25})
+		})
+	})
+}
+
+func TestReplicatingPartitioner(t *testing.T) {
+	Convey("Given a replicating partitioner", t, func() {
+		p := NewReplicatingPartitioner(2).(MultiPartitioner)
+
+		Convey("It should determine consecutive partitions", func() {
+			ids, err := p.DeterminePartitions(NewContext("0"), &lrdd.Row{Key: "a"}, 3)
+			So(err, ShouldBeNil)
+			So(ids, ShouldHaveLength, 2)
+
+			first, err := p.DeterminePartition(NewContext("0"), &lrdd.Row{Key: "a"}, 3)
+			So(err, ShouldBeNil)
+			So(ids[0], ShouldEqual, first)
+			So(ids[1], ShouldNotEqual, first)
+		})
+
+		Convey("It should not replicate more than the number of outputs", func() {
+			ids, err := NewReplicatingPartitioner(5).(MultiPartitioner).DeterminePartitions(NewContext("0"), &lrdd.Row{Key: "a"}, 3)
+			So(err, ShouldBeNil)
+			So(ids, ShouldHaveLength, 3)
+		})
+	})
+}
+
+func TestConsistentHashPartitioner(t *testing.T) {
+	Convey("Given a consistent hash partitioner", t, func() {
+		p := NewConsistentHashPartitioner(100)
+
+		Convey("It should survive serialization", func() {
+			data, err := jsoniter.Marshal(WrapPartitioner(p))
+			So(err, ShouldBeNil)
+
+			var sp SerializablePartitioner
+			So(jsoniter.Unmarshal(data, &sp), ShouldBeNil)
+
+			for i := 0; i < 100; i++ {
+				row := &lrdd.Row{Key: strconv.Itoa(i)}
+				expected, _ := p.DeterminePartition(NewContext("0"), row, 8)
+				actual, _ := sp.Partitioner.DeterminePartition(NewContext("0"), row, 8)
+				So(actual, ShouldEqual, expected)
+			}
+		})
+
+		Convey("When the number of partitions grows from N to N+1", func() {
+			movedKeys := func(p Partitioner) (moved int) {
+				for i := 0; i < 10000; i++ {
+					row := &lrdd.Row{Key: "key-" + strconv.Itoa(i)}
+					before, _ := p.DeterminePartition(NewContext("0"), row, 10)
+					after, _ := p.DeterminePartition(NewContext("0"), row, 11)
+					if before != after {
+						moved++
+					}
+				}
+				return moved
+			}
+
+			Convey("It should move substantially fewer keys than hash modulo", func() {
+				consistent := movedKeys(p)
+				modulo := movedKeys(NewHashKeyPartitioner())
+
+				So(consistent, ShouldBeLessThan, 2000)
+				So(modulo, ShouldBeGreaterThan, 8000)
+			})
+		})
+	})
+}
+
+func TestWeightedPartitioner(t *testing.T) {
+	Convey("Given a weighted partitioner", t, func() {
+		p := NewWeightedPartitioner(map[string]int{
+			"big:7466":   3,
+			"small:7466": 1,
+			"dead:7466":  0,
+		})
+
+		Convey("It should allocate partitions in proportion to the weights", func() {
+			pp := p.PlanNext(8)
+			So(pp, ShouldHaveLength, 8)
+
+			perHost := make(map[string]int)
+			for i, partition := range pp {
+				So(partition.ID, ShouldEqual, strconv.Itoa(i))
+				perHost[partition.AssignmentAffinity["Host"]]++
+			}
+			So(perHost, ShouldResemble, map[string]int{"big:7466": 6, "small:7466": 2})
+		})
+
+		Convey("It should allocate all partitions when they can't be divided exactly", func() {
+			So(p.PlanNext(5), ShouldHaveLength, 5)
+		})
+
+		Convey("It should survive serialization", func() {
+			data, err := jsoniter.Marshal(WrapPartitioner(p))
+			So(err, ShouldBeNil)
+
+			var sp SerializablePartitioner
+			So(jsoniter.Unmarshal(data, &sp), ShouldBeNil)
+			So(sp.Partitioner, ShouldResemble, p)
+		})
+	})
+}
+
+func TestChain(t *testing.T) {
+	Convey("Given a chain of partitioners", t, func() {
+		p := Chain(WithAssignmentToMaster(nil), NewWeightedPartitioner(map[string]int{"localhost:7466": 1}))
+
+		Convey("It should apply plan transforms to the plan of the base partitioner", func() {
+			pp := p.PlanNext(2)
+			So(pp, ShouldHaveLength, 2)
+			for _, partition := range pp {
+				So(partition.AssignmentAffinity, ShouldResemble, map[string]string{
+					"Host": "localhost:7466",
+					"Type": "master",
+				})
+			}
+		})
+
+		Convey("It should determine partition with the base partitioner", func() {
+			for i := 0; i < 100; i++ {
+				row := &lrdd.Row{Key: strconv.Itoa(i)}
+				expected, _ := UnwrapPartitioner(p).DeterminePartition(NewContext("0"), row, 2)
+				actual, err := p.DeterminePartition(NewContext("0"), row, 2)
+				So(err, ShouldBeNil)
+				So(actual, ShouldEqual, expected)
+			}
+		})
+
+		Convey("It should survive serialization", func() {
+			data, err := jsoniter.Marshal(WrapPartitioner(p))
+			So(err, ShouldBeNil)
+
+			var sp SerializablePartitioner
+			So(jsoniter.Unmarshal(data, &sp), ShouldBeNil)
+			So(sp.PlanNext(2), ShouldResemble, p.PlanNext(2))
+		})
+
+		Convey("It should be unwrapped to the base partitioner", func() {
+			So(UnwrapPartitioner(WrapPartitioner(p)), ShouldHaveSameTypeAs, &WeightedPartitioner{})
+			So(IsPreserved(Chain(WithAssignmentToMaster(nil), NewPreservePartitioner())), ShouldBeTrue)
+		})
+
+		Convey("It should panic if a wrapper doesn't transform plans", func() {
+			So(func() { Chain(NewHashKeyPartitioner(), NewPreservePartitioner()) }, ShouldPanic)
+		})
+	})
+}
+
+func TestEqual(t *testing.T) {
+	Convey("Given partitioners", t, func() {
+		Convey("The partitioners of the same type and parameters should be equal", func() {
+			used := NewHashKeyPartitioner()
+			_, _ = used.DeterminePartition(NewContext("0"), &lrdd.Row{Key: "a"}, 3)
+
+			So(Equal(used, NewHashKeyPartitioner()), ShouldBeTrue)
+			So(Equal(NewRangePartitioner([]string{"a", "m"}), NewRangePartitioner([]string{"a", "m"})), ShouldBeTrue)
+		})
+
+		Convey("The partitioners of different types or parameters should not be equal", func() {
+			So(Equal(NewHashKeyPartitioner(), NewShuffledPartitioner()), ShouldBeFalse)
+			So(Equal(NewRangePartitioner([]string{"a", "m"}), NewRangePartitioner([]string{"a", "z"})), ShouldBeFalse)
+			So(Equal(NewHashKeyPartitioner(), nil), ShouldBeFalse)
+		})
+	})
+}
+
+func TestSerializablePartitioner_Gob(t *testing.T) {
+	Convey("Given the gob codec", t, func() {
+		So(serialization.SetStructCodec(serialization.GobCodec.Name()), ShouldBeNil)
+		Reset(func() {
+			So(serialization.SetStructCodec(serialization.JSONCodec.Name()), ShouldBeNil)
+		})
+
+		builtins := map[string]Partitioner{
+			"FiniteKey":      NewFiniteKeyPartitioner([]string{"a", "b"}),
+			"Locality":       NewLocalityPartitioner(map[string][]string{"host1": {"a"}}),
+			"Range":          NewRangePartitioner([]string{"m"}),
+			"HashKey":        NewHashKeyPartitioner(),
+			"Shuffled":       NewShuffledPartitioner(),
+			"Weighted":       NewWeightedPartitioner(map[string]int{"a": 1, "b": 2}),
+			"ConsistentHash": NewConsistentHashPartitioner(10),
+			"Replicating":    NewReplicatingPartitioner(2),
+			"RoundRobin":     NewRoundRobinPartitioner(),
+			"SeededShuffled": NewSeededShuffledPartitioner(42),
+			"Preserve":       NewPreservePartitioner(),
+			"Coalesce":       NewCoalescePartitioner(2),
+			"Master":         WithAssignmentToMaster(NewHashKeyPartitioner()),
+			"Chain":          Chain(WithAssignmentToMaster(nil), NewHashKeyPartitioner()),
+			"Unkeyed":        WithUnkeyedPolicy(NewHashKeyPartitioner(), DropUnkeyed()),
+		}
+		for name, p := range builtins {
+			expected := p
+			Convey(name+" partitioner should be same after the serialization", func() {
+				data, err := WrapPartitioner(expected).MarshalJSON()
+				So(err, ShouldBeNil)
+
+				var actual SerializablePartitioner
+				So(actual.UnmarshalJSON(data), ShouldBeNil)
+				So(actual.Partitioner, ShouldHaveSameTypeAs, expected)
+
+				ctx := NewContext("0")
+				for i := 0; i < 10; i++ {
+					row := &lrdd.Row{Key: strconv.Itoa(i)}
+					expectedID, expectedErr := expected.DeterminePartition(ctx, row, 4)
+					actualID, actualErr := actual.DeterminePartition(ctx, row, 4)
+					So(actualID, ShouldEqual, expectedID)
+					So(actualErr, ShouldEqual, expectedErr)
+				}
+			})
+		}
+	})
+}
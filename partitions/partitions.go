@@ -22,8 +22,15 @@ type Partition struct {
 	ID string
 
 	// IsElastic indicates that this partition allows work stealing from other executors.
+	// Elastic partitions of the stages following a shuffle can be assigned to the workers joining
+	// in the middle of a dataset, with lrmr.WithStagewiseScheduling.
 	IsElastic bool
 
 	// AssignmentAffinity is a set of equality rules to place partition into physical nodes.
 	AssignmentAffinity map[string]string
+
+	// PreferredHosts are the hosts preferred to run the partition, e.g. the nodes holding its data.
+	// Unlike AssignmentAffinity, it's a soft constraint: the partition is placed on the first preferred host
+	// having a free executor, or on any other node if all of them are busy or absent.
+	PreferredHosts []string
 }
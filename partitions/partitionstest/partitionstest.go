@@ -0,0 +1,169 @@
+// Package partitionstest provides utilities for testing partitioners, including the custom ones.
+//
+//	res, err := partitionstest.Run(myPartitioner, rows, 8)
+//	So(err, ShouldBeNil)
+//	So(res, partitionstest.ShouldCoverAllPartitions)
+//	So(res, partitionstest.ShouldBeBalanced, 0.1)
+package partitionstest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/pkg/errors"
+)
+
+// Result is the partitions planned by a partitioner, and the rows routed to them.
+type Result struct {
+	// Planned are the partitions planned by PlanNext.
+	Planned []partitions.Partition
+
+	// Distribution is the number of rows routed to each partition ID. A row routed to multiple partitions
+	// by a partitions.MultiPartitioner is counted in each of them.
+	Distribution map[string]int
+
+	// Dropped is the number of rows dropped by the partitioner (i.e. with partitions.ErrNoOutput).
+	Dropped int
+}
+
+// Options configures how Run routes the rows.
+type Options struct {
+	// PartitionID is the ID of the current partition given to the partitioner by the context. Defaults to "0".
+	PartitionID string
+}
+
+// Option modifies the Options of Run.
+type Option func(o *Options)
+
+// FromPartition routes the rows as if they are in the partition of given ID.
+func FromPartition(id string) Option {
+	return func(o *Options) {
+		o.PartitionID = id
+	}
+}
+
+// Run plans the partitions with the partitioner for given number of executors, and routes the rows
+// to the planned partitions. It fails on the first error of the partitioner other than partitions.ErrNoOutput.
+func Run(p partitions.Partitioner, rows []*lrdd.Row, numExecutors int, opts ...Option) (*Result, error) {
+	o := Options{PartitionID: "0"}
+	for _, optFn := range opts {
+		optFn(&o)
+	}
+	res := &Result{
+		Planned:      p.PlanNext(numExecutors),
+		Distribution: make(map[string]int),
+	}
+	ctx := partitions.NewContext(o.PartitionID)
+	// routes the rows as output.Writer does, which sees through the wrapped partitioners
+	multi, isMulti := partitions.UnwrapPartitioner(p).(partitions.MultiPartitioner)
+	for i, row := range rows {
+		var ids []string
+		var err error
+		if isMulti {
+			ids, err = multi.DeterminePartitions(ctx, row, len(res.Planned))
+		} else {
+			var id string
+			id, err = p.DeterminePartition(ctx, row, len(res.Planned))
+			ids = []string{id}
+		}
+		if errors.Cause(err) == partitions.ErrNoOutput || (err == nil && len(ids) == 0) {
+			res.Dropped++
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "determine partition of row #%d (key %q)", i, row.Key)
+		}
+		for _, id := range ids {
+			res.Distribution[id]++
+		}
+	}
+	return res, nil
+}
+
+// Uncovered returns the IDs of the planned partitions which no rows are routed to.
+func (r *Result) Uncovered() (ids []string) {
+	for _, p := range r.Planned {
+		if r.Distribution[p.ID] == 0 {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+// Unplanned returns the IDs of the partitions which rows are routed to, but are not planned.
+func (r *Result) Unplanned() (ids []string) {
+	planned := make(map[string]bool, len(r.Planned))
+	for _, p := range r.Planned {
+		planned[p.ID] = true
+	}
+	for id := range r.Distribution {
+		if !planned[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Imbalance returns the largest deviation of the number of rows in a planned partition from the mean,
+// relative to the mean. Zero means that the rows are evenly distributed.
+func (r *Result) Imbalance() float64 {
+	if len(r.Planned) == 0 {
+		return 0
+	}
+	total := 0
+	for _, p := range r.Planned {
+		total += r.Distribution[p.ID]
+	}
+	mean := float64(total) / float64(len(r.Planned))
+	if mean == 0 {
+		return 0
+	}
+	var maxDeviation float64
+	for _, p := range r.Planned {
+		maxDeviation = math.Max(maxDeviation, math.Abs(float64(r.Distribution[p.ID])-mean))
+	}
+	return maxDeviation / mean
+}
+
+// ShouldCoverAllPartitions is a goconvey assertion which passes if every planned partition of the Result
+// has a row, and no rows are routed to the partitions not planned.
+func ShouldCoverAllPartitions(actual interface{}, expected ...interface{}) string {
+	if len(expected) != 0 {
+		return "This assertion requires no expected values."
+	}
+	res, ok := actual.(*Result)
+	if !ok {
+		return fmt.Sprintf("Expected a *partitionstest.Result, but got %T.", actual)
+	}
+	if unplanned := res.Unplanned(); len(unplanned) > 0 {
+		return fmt.Sprintf("Expected rows to be routed to the planned partitions, but some were routed to %v.", unplanned)
+	}
+	if uncovered := res.Uncovered(); len(uncovered) > 0 {
+		return fmt.Sprintf("Expected every planned partition to have rows, but %v had none.", uncovered)
+	}
+	return ""
+}
+
+// ShouldBeBalanced is a goconvey assertion which passes if the Imbalance of the Result is
+// at most the expected tolerance (e.g. 0.1 for 10% from the mean).
+func ShouldBeBalanced(actual interface{}, expected ...interface{}) string {
+	if len(expected) != 1 {
+		return "This assertion requires exactly 1 tolerance value."
+	}
+	tolerance, ok := expected[0].(float64)
+	if !ok {
+		return fmt.Sprintf("Expected the tolerance to be a float64, but got %T.", expected[0])
+	}
+	res, ok := actual.(*Result)
+	if !ok {
+		return fmt.Sprintf("Expected a *partitionstest.Result, but got %T.", actual)
+	}
+	if imbalance := res.Imbalance(); imbalance > tolerance {
+		return fmt.Sprintf("Expected the rows to be balanced within %.2f of the mean, but the imbalance was %.2f: %v",
+			tolerance, imbalance, res.Distribution)
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package partitionstest
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/partitions"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func rowsWithKeys(n int) []*lrdd.Row {
+	rows := make([]*lrdd.Row, n)
+	for i := range rows {
+		rows[i] = &lrdd.Row{Key: strconv.Itoa(i)}
+	}
+	return rows
+}
+
+func TestRun(t *testing.T) {
+	Convey("Given a hash key partitioner", t, func() {
+		p := partitions.NewHashKeyPartitioner()
+
+		Convey("When routing rows with distinct keys", func() {
+			res, err := Run(p, rowsWithKeys(10000), 8)
+			So(err, ShouldBeNil)
+
+			Convey("The rows should be distributed over every partition evenly", func() {
+				So(res.Planned, ShouldHaveLength, 8)
+				So(res, ShouldCoverAllPartitions)
+				So(res, ShouldBeBalanced, 0.1)
+			})
+		})
+
+		Convey("When routing rows with the same key", func() {
+			rows := make([]*lrdd.Row, 100)
+			for i := range rows {
+				rows[i] = &lrdd.Row{Key: "hot"}
+			}
+			res, err := Run(p, rows, 4)
+			So(err, ShouldBeNil)
+
+			Convey("The assertions should fail", func() {
+				So(res.Uncovered(), ShouldHaveLength, 3)
+				So(ShouldCoverAllPartitions(res), ShouldNotBeEmpty)
+				So(res.Imbalance(), ShouldEqual, 3)
+				So(ShouldBeBalanced(res, 0.1), ShouldNotBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a partitioner dropping rows", t, func() {
+		p := partitions.NewFiniteKeyPartitioner([]string{"a", "b"})
+
+		Convey("The dropped rows should be counted", func() {
+			rows := []*lrdd.Row{{Key: "a"}, {Key: "b"}, {Key: "c"}}
+			res, err := Run(p, rows, 2)
+			So(err, ShouldBeNil)
+			So(res.Dropped, ShouldEqual, 1)
+			So(res, ShouldCoverAllPartitions)
+		})
+	})
+
+	Convey("Given a multi partitioner", t, func() {
+		p := partitions.NewReplicatingPartitioner(2)
+
+		Convey("A row should be counted in every partition it's routed to", func() {
+			res, err := Run(p, rowsWithKeys(10), 4)
+			So(err, ShouldBeNil)
+
+			total := 0
+			for _, n := range res.Distribution {
+				total += n
+			}
+			So(total, ShouldEqual, 20)
+			So(res.Unplanned(), ShouldBeEmpty)
+		})
+
+		Convey("A wrapped one should be routed in the same way", func() {
+			res, err := Run(partitions.WrapPartitioner(p), rowsWithKeys(10), 4)
+			So(err, ShouldBeNil)
+
+			total := 0
+			for _, n := range res.Distribution {
+				total += n
+			}
+			So(total, ShouldEqual, 20)
+		})
+	})
+
+	Convey("Given a partitioner routing rows by the current partition", t, func() {
+		p := partitions.NewPreservePartitioner()
+
+		Convey("Rows should be routed from the given partition", func() {
+			res, err := Run(p, rowsWithKeys(10), 4, FromPartition("2"))
+			So(err, ShouldBeNil)
+			So(res.Distribution, ShouldResemble, map[string]int{"2": 10})
+		})
+	})
+}
@@ -4,24 +4,47 @@ import (
 	"sort"
 
 	"github.com/ab180/lrmr/cluster/node"
-	"github.com/airbloc/logger"
+	"github.com/ab180/lrmr/logging"
+	"github.com/pkg/errors"
 	"github.com/thoas/go-funk"
 )
 
-var log = logger.New("partition")
+var log = logging.New("partition")
 
 type nodeWithStats struct {
 	*node.Node
 	currentTasks int
+
+	// cpuWeight and memoryBytes are the sums of the resource hints of the tasks in the node.
+	cpuWeight   float64
+	memoryBytes int64
+}
+
+func (n *nodeWithStats) addTask(hint ResourceHint) {
+	n.currentTasks += 1
+	n.cpuWeight += hint.CPUWeight
+	n.memoryBytes += hint.MemoryBytes
+}
+
+// lighterThan returns true if the node has less resources used by its tasks than the other.
+func (n *nodeWithStats) lighterThan(o *nodeWithStats) bool {
+	if n.memoryBytes != o.memoryBytes {
+		return n.memoryBytes < o.memoryBytes
+	}
+	return n.cpuWeight < o.cpuWeight
 }
 
 func newNodeWithStats(n *node.Node) nodeWithStats {
 	return nodeWithStats{Node: n, currentTasks: 0}
 }
 
-// Schedule creates partition partition to the nodes by given options.
-func Schedule(workers []*node.Node, plans []Plan, opt ...ScheduleOption) (pp []Partitions, aa []Assignments) {
+// Schedule creates partition partition to the nodes by given options. It returns an error if the assignments
+// returned by the AssignmentPolicy are invalid.
+func Schedule(workers []*node.Node, plans []Plan, opt ...ScheduleOption) (pp []Partitions, aa []Assignments, err error) {
 	opts := buildScheduleOptions(opt)
+	if opts.AssignmentPolicy == nil {
+		opts.AssignmentPolicy = DefaultAssignmentPolicy{}
+	}
 
 	nn := funk.Map(workers, newNodeWithStats)
 	if !opts.DisableShufflingNodes {
@@ -86,6 +109,11 @@ func Schedule(workers []*node.Node, plans []Plan, opt ...ScheduleOption) (pp []P
 			partitions = []Partition{{ID: InputPartitionID}}
 		} else if IsPreserved(plans[i-1].Partitioner) && len(pp) > 0 {
 			partitions = pp[i-1].Partitions
+		} else if c, ok := plans[i-1].Partitioner.(Coalescer); ok && len(aa) > 0 {
+			// planned from the assignments of the previous stage, and routed by the planned partitioner
+			var coalesced Partitioner
+			partitions, coalesced = c.PlanCoalesced(aa[i-1])
+			pp[i-1] = New(coalesced, pp[i-1].Partitions)
 		} else {
 			partitions = plans[i-1].Partitioner.PlanNext(numExecutors)
 		}
@@ -95,35 +123,28 @@ func Schedule(workers []*node.Node, plans []Plan, opt ...ScheduleOption) (pp []P
 			if IsPreserved(plans[i-1].Partitioner) && len(aa) > 0 {
 				// ensure that adjacent preserved partitions have exact same assignments
 				aa = append(aa, aa[i-1])
+				if !plan.Resources.IsZero() {
+					addTasksOnHosts(nodes, aa[i-1], plan.Resources)
+				}
 				continue
 			}
 		}
 
-		curSlot := 0
-		assignments := make([]Assignment, len(partitions))
-		for j, p := range partitions {
-			var selected *nodeWithStats
-			if len(p.AssignmentAffinity) > 0 {
-				selected, curSlot = selectNextNodeWithAffinity(candidates, opts.Master, p.AssignmentAffinity, curSlot)
-				if selected == nil {
-					log.Warn("Unable to find node satisfying affinity rule {} for partition {}.", p.AssignmentAffinity, p.ID)
-					selected, curSlot = selectNextNode(candidates, plan, curSlot)
-				}
-			} else {
-				selected, curSlot = selectNextNode(candidates, plan, curSlot)
-			}
-			selected.currentTasks += 1
-			assignments[j] = Assignment{
-				PartitionID: p.ID,
-				Host:        selected.Node.Host,
-			}
+		cc := toCandidates(candidates)
+		assignments := opts.AssignmentPolicy.Assign(*plan, partitions, cc, opts.Master)
+		if err := validateAssignments(assignments, partitions, cc, opts.Master); err != nil {
+			return nil, nil, errors.Wrapf(err, "assign partitions of plan #%d", i)
 		}
+		addAssignedTasks(nodes, assignments, plan.Resources)
 		aa = append(aa, assignments)
 	}
-	return pp, aa
+	return pp, aa, nil
 }
 
 func selectNextNode(nn []nodeWithStats, plan *Plan, curSlot int) (selected *nodeWithStats, nextSlot int) {
+	if !plan.Resources.IsZero() {
+		return selectLightestNode(nn, plan, curSlot)
+	}
 	for slot := curSlot; slot < curSlot+len(nn); slot++ {
 		n := &nn[slot%len(nn)]
 		maxCount := n.Executors
@@ -139,6 +160,77 @@ func selectNextNode(nn []nodeWithStats, plan *Plan, curSlot int) (selected *node
 	return &nn[curSlot%len(nn)], curSlot + 1
 }
 
+// selectPreferredNode selects the first node of given hosts having a free executor. It returns nil if there's none.
+func selectPreferredNode(nn []nodeWithStats, plan *Plan, hosts []string) *nodeWithStats {
+	for _, host := range hosts {
+		for i := range nn {
+			n := &nn[i]
+			if n.Host != host {
+				continue
+			}
+			maxCount := n.Executors
+			if plan.ExecutorsPerNode != Auto {
+				maxCount = plan.ExecutorsPerNode
+			}
+			if n.currentTasks < maxCount {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+// selectLightestNode selects the node with the least resources used by its tasks among the nodes having
+// a free executor, so that the heavy tasks are not co-located if possible. Ties are broken in round-robin.
+func selectLightestNode(nn []nodeWithStats, plan *Plan, curSlot int) (selected *nodeWithStats, nextSlot int) {
+	var fallback *nodeWithStats
+	fallbackSlot := curSlot
+	for slot := curSlot; slot < curSlot+len(nn); slot++ {
+		n := &nn[slot%len(nn)]
+		if fallback == nil || n.lighterThan(fallback) {
+			fallback, fallbackSlot = n, slot
+		}
+		maxCount := n.Executors
+		if plan.ExecutorsPerNode != Auto {
+			maxCount = plan.ExecutorsPerNode
+		}
+		if n.currentTasks >= maxCount {
+			continue
+		}
+		if selected == nil || n.lighterThan(selected) {
+			selected, nextSlot = n, slot+1
+		}
+	}
+	if selected == nil {
+		// not found. ignore max task rule
+		return fallback, fallbackSlot + 1
+	}
+	return selected, nextSlot
+}
+
+// addAssignedTasks counts the tasks of the assignments in the nodes.
+func addAssignedTasks(nn []nodeWithStats, assignments Assignments, hint ResourceHint) {
+	for _, a := range assignments {
+		for i := range nn {
+			if nn[i].Host == a.Host {
+				nn[i].addTask(hint)
+			}
+		}
+	}
+}
+
+// addTasksOnHosts counts the tasks of the assignments in the nodes.
+func addTasksOnHosts(nn []nodeWithStats, assignments Assignments, hint ResourceHint) {
+	for _, a := range assignments {
+		for i := range nn {
+			if nn[i].Host == a.Host {
+				nn[i].cpuWeight += hint.CPUWeight
+				nn[i].memoryBytes += hint.MemoryBytes
+			}
+		}
+	}
+}
+
 func selectNextNodeWithAffinity(nn []nodeWithStats, maybeMaster *node.Node, rules map[string]string, curSlot int) (selected *nodeWithStats, next int) {
 	if expectedTyp, ok := rules["Type"]; ok && expectedTyp == string(node.Master) && maybeMaster != nil {
 		// explicit selection of master node
@@ -171,6 +263,7 @@ func satisfiesAffinity(n *node.Node, rules map[string]string) bool {
 type ScheduleOptions struct {
 	DisableShufflingNodes bool
 	Master                *node.Node
+	AssignmentPolicy      AssignmentPolicy
 }
 
 type ScheduleOption func(o *ScheduleOptions)
@@ -181,6 +274,13 @@ func WithoutShufflingNodes() ScheduleOption {
 	}
 }
 
+// WithAssignmentPolicy places the partitions on the nodes by given policy. Defaults to DefaultAssignmentPolicy.
+func WithAssignmentPolicy(p AssignmentPolicy) ScheduleOption {
+	return func(o *ScheduleOptions) {
+		o.AssignmentPolicy = p
+	}
+}
+
 func WithMaster(n *node.Node) ScheduleOption {
 	if n.Type != node.Master {
 		panic("given node " + n.Host + " is not a master")
@@ -19,7 +19,7 @@ func TestScheduler_AffinityRule(t *testing.T) {
 			}
 
 			Convey("When partition counts in plans are all automatic", func() {
-				pp, _ := Schedule(nn, []Plan{
+				pp, _ := mustSchedule(nn, []Plan{
 					{DesiredCount: Auto},
 					{DesiredCount: Auto},
 					{DesiredCount: Auto},
@@ -39,7 +39,7 @@ func TestScheduler_AffinityRule(t *testing.T) {
 			})
 
 			Convey("When an affinity rule is given with an Partitioner", func() {
-				_, aa := Schedule(nn, []Plan{
+				_, aa := mustSchedule(nn, []Plan{
 					{Partitioner: partitionerStub{[]Partition{
 						{ID: "familiarWithWorld", AssignmentAffinity: map[string]string{"Host": "localhost:1002"}},
 						{ID: "familiarWithFoo", AssignmentAffinity: map[string]string{"CustomTag": "foo"}},
@@ -76,7 +76,7 @@ func TestScheduler_AffinityRule(t *testing.T) {
 			}
 
 			Convey("When an affinity rule is given with an LogicalPlanner", func() {
-				_, pp := Schedule(nn, []Plan{
+				_, pp := mustSchedule(nn, []Plan{
 					{Partitioner: partitionerStub{[]Partition{
 						{ID: "familiarWithWorld", AssignmentAffinity: map[string]string{"Host": "localhost:1002"}},
 						{ID: "familiarWithFoo", AssignmentAffinity: map[string]string{"CustomTag": "foo"}},
@@ -113,7 +113,7 @@ func TestScheduler_AffinityRule(t *testing.T) {
 			}
 
 			Convey("When an affinity rule is given with an LogicalPlanner", func() {
-				_, pp := Schedule(nn, []Plan{
+				_, pp := mustSchedule(nn, []Plan{
 					{Partitioner: partitionerStub{[]Partition{
 						{ID: "p1", AssignmentAffinity: map[string]string{"CustomTag": "hello"}},
 						{ID: "p2", AssignmentAffinity: map[string]string{"CustomTag": "hello"}},
@@ -150,9 +150,209 @@ func (p partitionerStub) DeterminePartition(c Context, r *lrdd.Row, numOutputs i
 	return
 }
 
+// mustSchedule calls Schedule, asserting that it succeeds.
+func mustSchedule(workers []*node.Node, plans []Plan, opt ...ScheduleOption) ([]Partitions, []Assignments) {
+	pp, aa, err := Schedule(workers, plans, opt...)
+	So(err, ShouldBeNil)
+	return pp, aa
+}
+
 func checkPartitionerType(actual, expected Partitioner) {
 	if sp, ok := actual.(SerializablePartitioner); ok {
 		actual = sp.Partitioner
 	}
 	So(actual, ShouldHaveSameTypeAs, expected)
 }
+
+func TestScheduler_ResourceHint(t *testing.T) {
+	Convey("Given nodes with enough executors", t, func() {
+		nn := []*node.Node{
+			{Host: "localhost:1001", Executors: 4},
+			{Host: "localhost:1002", Executors: 4},
+		}
+		heavy := ResourceHint{MemoryBytes: 1 << 30}
+
+		Convey("When two adjacent stages have memory-heavy tasks", func() {
+			_, aa := mustSchedule(nn, []Plan{
+				{Partitioner: NewShuffledPartitioner()},
+				{Partitioner: NewShuffledPartitioner(), DesiredCount: 1, Resources: heavy},
+				{Partitioner: NewShuffledPartitioner(), DesiredCount: 1, Resources: heavy},
+			}, WithoutShufflingNodes())
+
+			Convey("The heavy tasks should land on different nodes", func() {
+				So(aa[1], ShouldHaveLength, 1)
+				So(aa[2], ShouldHaveLength, 1)
+				So(aa[1][0].Host, ShouldNotEqual, aa[2][0].Host)
+			})
+		})
+
+		Convey("When heavy tasks outnumber the nodes", func() {
+			_, aa := mustSchedule(nn, []Plan{
+				{Partitioner: NewShuffledPartitioner()},
+				{Partitioner: NewShuffledPartitioner(), DesiredCount: 4, Resources: heavy},
+			}, WithoutShufflingNodes())
+
+			Convey("They should be spread evenly", func() {
+				So(aa[1].GroupIDsByHost()["localhost:1001"], ShouldHaveLength, 2)
+				So(aa[1].GroupIDsByHost()["localhost:1002"], ShouldHaveLength, 2)
+			})
+		})
+	})
+}
+
+func TestScheduler_PreferredHosts(t *testing.T) {
+	Convey("Given nodes", t, func() {
+		nn := []*node.Node{
+			{Host: "localhost:1001", Executors: 2},
+			{Host: "localhost:1002", Executors: 1},
+			{Host: "localhost:1003", Executors: 1},
+		}
+
+		Convey("When partitions prefer hosts", func() {
+			_, aa := mustSchedule(nn, []Plan{
+				{Partitioner: partitionerStub{[]Partition{
+					{ID: "onSecond", PreferredHosts: []string{"localhost:1002"}},
+					{ID: "onThird", PreferredHosts: []string{"localhost:1004", "localhost:1003"}},
+					{ID: "busySecond", PreferredHosts: []string{"localhost:1002"}},
+				}}},
+				{ /* ignored */ },
+			}, WithoutShufflingNodes())
+			hosts := aa[1].ToMap()
+
+			Convey("They should be placed on the preferred hosts", func() {
+				So(hosts["onSecond"], ShouldEqual, "localhost:1002")
+				So(hosts["onThird"], ShouldEqual, "localhost:1003")
+			})
+
+			Convey("It should fall back to another node if the preferred host is busy", func() {
+				So(hosts["busySecond"], ShouldEqual, "localhost:1001")
+			})
+		})
+	})
+}
+
+// packingPolicy assigns every partition to the candidate with the most tasks, and records the candidates.
+type packingPolicy struct {
+	seen [][]Candidate
+}
+
+func (p *packingPolicy) Assign(_ Plan, partitions []Partition, candidates []Candidate, _ *node.Node) Assignments {
+	p.seen = append(p.seen, candidates)
+	busiest := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.Tasks > busiest.Tasks {
+			busiest = c
+		}
+	}
+	assignments := make(Assignments, len(partitions))
+	for i, pt := range partitions {
+		assignments[i] = Assignment{PartitionID: pt.ID, Host: busiest.Host}
+	}
+	return assignments
+}
+
+func TestScheduler_AssignmentPolicy(t *testing.T) {
+	Convey("Given nodes", t, func() {
+		nn := []*node.Node{
+			{Host: "localhost:1001", Executors: 2},
+			{Host: "localhost:1002", Executors: 2},
+		}
+
+		Convey("When scheduling with a custom assignment policy", func() {
+			policy := &packingPolicy{}
+			_, aa := mustSchedule(nn, []Plan{
+				{Partitioner: NewShuffledPartitioner()},
+				{Partitioner: NewShuffledPartitioner(), DesiredCount: 3},
+				{Partitioner: NewShuffledPartitioner(), DesiredCount: 2},
+			}, WithoutShufflingNodes(), WithAssignmentPolicy(policy))
+
+			Convey("The partitions should be placed by the policy", func() {
+				So(aa[1].GroupIDsByHost(), ShouldHaveLength, 1)
+				So(aa[2].GroupIDsByHost(), ShouldHaveLength, 1)
+				So(aa[2][0].Host, ShouldEqual, aa[1][0].Host)
+			})
+
+			Convey("The policy should be given the tasks assigned in the preceding stages", func() {
+				So(policy.seen, ShouldHaveLength, 3)
+				total := 0
+				for _, c := range policy.seen[2] {
+					total += c.Tasks
+				}
+				// 1 input task and 3 tasks of the first stage
+				So(total, ShouldEqual, 4)
+			})
+		})
+	})
+}
+
+// misbehavingPolicy modifies the assignments of DefaultAssignmentPolicy.
+type misbehavingPolicy struct {
+	modify func(Assignments) Assignments
+}
+
+func (p misbehavingPolicy) Assign(plan Plan, partitions []Partition, candidates []Candidate, master *node.Node) Assignments {
+	return p.modify(DefaultAssignmentPolicy{}.Assign(plan, partitions, candidates, master))
+}
+
+func TestScheduler_InvalidAssignments(t *testing.T) {
+	Convey("Given nodes", t, func() {
+		nn := []*node.Node{
+			{Host: "localhost:1001", Executors: 2},
+			{Host: "localhost:1002", Executors: 2},
+		}
+		plans := []Plan{
+			{Partitioner: NewShuffledPartitioner()},
+			{Partitioner: NewShuffledPartitioner(), DesiredCount: 3},
+		}
+		schedule := func(modify func(Assignments) Assignments) error {
+			_, _, err := Schedule(nn, plans, WithAssignmentPolicy(misbehavingPolicy{modify}))
+			return err
+		}
+
+		Convey("When a policy assigns a partition to an unknown host", func() {
+			err := schedule(func(aa Assignments) Assignments {
+				aa[0].Host = "localhost:9999"
+				return aa
+			})
+
+			Convey("It should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "unknown host localhost:9999")
+			})
+		})
+
+		Convey("When a policy leaves a partition unassigned", func() {
+			err := schedule(func(aa Assignments) Assignments {
+				return aa[:len(aa)-1]
+			})
+
+			Convey("It should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "is not assigned")
+			})
+		})
+
+		Convey("When a policy assigns a partition twice", func() {
+			err := schedule(func(aa Assignments) Assignments {
+				return append(aa, aa[0])
+			})
+
+			Convey("It should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "assigned more than once")
+			})
+		})
+
+		Convey("When a policy assigns an unknown partition", func() {
+			err := schedule(func(aa Assignments) Assignments {
+				aa[len(aa)-1].PartitionID = "unknown"
+				return aa
+			})
+
+			Convey("It should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "unknown partition unknown")
+			})
+		})
+	})
+}
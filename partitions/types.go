@@ -20,6 +20,26 @@ type Plan struct {
 	ExecutorsPerNode int
 
 	DesiredNodeAffinity map[string]string
+
+	// Resources is a hint of the resources used by each task of the stage.
+	Resources ResourceHint
+}
+
+// ResourceHint describes the resources used by a task, which the scheduler uses to spread heavy tasks
+// across the nodes. Tasks are placed on the node with the least memory estimated for its tasks first,
+// and then the least CPU weight, among the nodes having a free executor. Zero values mean no hint,
+// and the tasks without hints are placed only by the number of tasks in the nodes.
+type ResourceHint struct {
+	// CPUWeight is the relative CPU usage of a task, e.g. 1 for a CPU-bound task.
+	CPUWeight float64
+
+	// MemoryBytes is the estimated memory usage of a task.
+	MemoryBytes int64
+}
+
+// IsZero returns true if there's no hint.
+func (h ResourceHint) IsZero() bool {
+	return h.CPUWeight == 0 && h.MemoryBytes == 0
 }
 
 // Equal returns true if the partition is equal with given partition.
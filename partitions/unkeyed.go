@@ -0,0 +1,88 @@
+package partitions
+
+import (
+	"fmt"
+
+	"github.com/ab180/lrmr/lrdd"
+)
+
+// UnkeyedPolicy decides where the rows without keys (i.e. with empty keys) are routed by a partitioner.
+//
+// Without a policy, the partitioners treat an empty key as any other key: the hash partitioners send all
+// unkeyed rows to the same partition, RangePartitioner sends them to the first partition, and
+// FiniteKeyPartitioner drops them silently with ErrNoOutput unless the empty key is in its key set.
+// Use WithUnkeyedPolicy to choose the behavior explicitly.
+type UnkeyedPolicy struct {
+	// Drop drops the unkeyed rows.
+	Drop bool
+
+	// DefaultPartition is the ID of the partition receiving the unkeyed rows if Drop is false.
+	DefaultPartition string
+}
+
+// DropUnkeyed drops the rows without keys.
+func DropUnkeyed() UnkeyedPolicy {
+	return UnkeyedPolicy{Drop: true}
+}
+
+// RouteToDefaultPartition sends the rows without keys to the partition with given ID,
+// which must be one of the partitions planned by the partitioner.
+func RouteToDefaultPartition(id string) UnkeyedPolicy {
+	return UnkeyedPolicy{DefaultPartition: id}
+}
+
+type unkeyedRouter struct {
+	Partitioner SerializablePartitioner
+	Policy      UnkeyedPolicy
+}
+
+// WithUnkeyedPolicy wraps given partitioner to route the rows without keys by given policy.
+// The rows with keys are routed by the wrapped partitioner.
+//
+// Since the wrapper implements MultiPartitioner to support wrapping one, the stage partitioned by it
+// always shuffles its output, even if the input is already partitioned in the same way.
+func WithUnkeyedPolicy(p Partitioner, policy UnkeyedPolicy) Partitioner {
+	return &unkeyedRouter{
+		Partitioner: WrapPartitioner(p),
+		Policy:      policy,
+	}
+}
+
+func (u *unkeyedRouter) PlanNext(numExecutors int) []Partition {
+	planned := u.Partitioner.PlanNext(numExecutors)
+	if u.Policy.Drop {
+		return planned
+	}
+	for _, p := range planned {
+		if p.ID == u.Policy.DefaultPartition {
+			return planned
+		}
+	}
+	log.Warn("Default partition {} for unkeyed rows is not planned by {}.",
+		u.Policy.DefaultPartition, fmt.Sprintf("%T", UnwrapPartitioner(u.Partitioner)))
+	return planned
+}
+
+func (u *unkeyedRouter) DeterminePartition(c Context, r *lrdd.Row, numOutputs int) (id string, err error) {
+	if r.Key == "" {
+		if u.Policy.Drop {
+			return "", ErrNoOutput
+		}
+		return u.Policy.DefaultPartition, nil
+	}
+	return u.Partitioner.DeterminePartition(c, r, numOutputs)
+}
+
+func (u *unkeyedRouter) DeterminePartitions(c Context, r *lrdd.Row, numOutputs int) (ids []string, err error) {
+	if m, ok := u.Partitioner.Partitioner.(MultiPartitioner); ok && r.Key != "" {
+		return m.DeterminePartitions(c, r, numOutputs)
+	}
+	if r.Key == "" && u.Policy.Drop {
+		return nil, nil
+	}
+	id, err := u.DeterminePartition(c, r, numOutputs)
+	if err != nil {
+		return nil, err
+	}
+	return []string{id}, nil
+}
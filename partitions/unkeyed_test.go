@@ -0,0 +1,84 @@
+package partitions
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/lrdd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithUnkeyedPolicy(t *testing.T) {
+	partitioners := map[string]Partitioner{
+		"FiniteKeyPartitioner":      NewFiniteKeyPartitioner([]string{"a", "b"}),
+		"LocalityPartitioner":       NewLocalityPartitioner(map[string][]string{"a": {"host1"}, "b": {"host2"}}),
+		"RangePartitioner":          NewRangePartitioner([]string{"m"}),
+		"hashKeyPartitioner":        NewHashKeyPartitioner(),
+		"ConsistentHashPartitioner": NewConsistentHashPartitioner(8),
+		"replicatingPartitioner":    NewReplicatingPartitioner(2),
+	}
+	unkeyedRows := map[string]*lrdd.Row{
+		"empty-string key": {Key: ""},
+		"missing key":      lrdd.Value(map[string]interface{}{"foo": 1}),
+	}
+	for name, p := range partitioners {
+		defaultID := p.PlanNext(2)[0].ID
+
+		Convey("Given "+name+" dropping unkeyed rows", t, func() {
+			up := WithUnkeyedPolicy(p, DropUnkeyed())
+
+			for desc, row := range unkeyedRows {
+				Convey("A row with "+desc+" should be dropped", func() {
+					_, err := up.DeterminePartition(NewContext("0"), row, 2)
+					So(err, ShouldEqual, ErrNoOutput)
+
+					ids, err := up.(MultiPartitioner).DeterminePartitions(NewContext("0"), row, 2)
+					So(err, ShouldBeNil)
+					So(ids, ShouldBeEmpty)
+				})
+			}
+
+			Convey("A keyed row should be routed by the wrapped partitioner", func() {
+				row := &lrdd.Row{Key: "a"}
+				expected, _ := p.DeterminePartition(NewContext("0"), row, 2)
+				actual, err := up.DeterminePartition(NewContext("0"), row, 2)
+				So(err, ShouldBeNil)
+				So(actual, ShouldEqual, expected)
+			})
+		})
+
+		Convey("Given "+name+" routing unkeyed rows to the default partition", t, func() {
+			up := WithUnkeyedPolicy(p, RouteToDefaultPartition(defaultID))
+
+			for desc, row := range unkeyedRows {
+				Convey("A row with "+desc+" should be sent to the default partition", func() {
+					id, err := up.DeterminePartition(NewContext("0"), row, 2)
+					So(err, ShouldBeNil)
+					So(id, ShouldEqual, defaultID)
+
+					ids, err := up.(MultiPartitioner).DeterminePartitions(NewContext("0"), row, 2)
+					So(err, ShouldBeNil)
+					So(ids, ShouldResemble, []string{defaultID})
+				})
+			}
+		})
+	}
+
+	Convey("Given a MultiPartitioner wrapped with a policy", t, func() {
+		up := WithUnkeyedPolicy(NewReplicatingPartitioner(2), DropUnkeyed())
+
+		Convey("Keyed rows should still be replicated", func() {
+			ids, err := up.(MultiPartitioner).DeterminePartitions(NewContext("0"), &lrdd.Row{Key: "a"}, 3)
+			So(err, ShouldBeNil)
+			So(ids, ShouldHaveLength, 2)
+		})
+	})
+
+	Convey("Given a FiniteKeyPartitioner without a policy", t, func() {
+		p := NewFiniteKeyPartitioner([]string{"a"})
+
+		Convey("Unkeyed rows should be dropped", func() {
+			_, err := p.DeterminePartition(NewContext("0"), &lrdd.Row{}, 1)
+			So(err, ShouldEqual, ErrNoOutput)
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package lrmr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/lrdd"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+var _ = RegisterTypes(&LineDecoder{}, &JSONLinesDecoder{}, &csvDecoder{})
+
+// RecordDecoder decodes a record, such as a line of a file or a message of Kafka, into a row.
+// It separates how the bytes become rows from where the bytes come from, so that any input source
+// accepting a RecordDecoder can read any format. Since it is sent to the workers, its implementation
+// should be registered with RegisterTypes.
+type RecordDecoder interface {
+	// Decode decodes the record into a row. It can return nil to skip the record.
+	// The record is only valid until Decode returns.
+	Decode(record []byte) (*lrdd.Row, error)
+}
+
+// LineDecoder decodes each line into a row whose value is a map having the line in Field.
+type LineDecoder struct {
+	Field string
+}
+
+func (l *LineDecoder) Decode(line []byte) (*lrdd.Row, error) {
+	return lrdd.Value(map[string]interface{}{l.Field: string(line)}), nil
+}
+
+// JSONLineDecoder returns a decoder decoding each line into a row whose value is a map of the JSON object
+// in the line. Empty lines are skipped.
+func JSONLineDecoder() RecordDecoder {
+	return &JSONLinesDecoder{}
+}
+
+// JSONLinesDecoder decodes each line into a row whose value is a map of the JSON object in the line.
+// Empty lines are skipped. If KeyField is set, the field is used as the key of the row,
+// and the lines without the field are skipped.
+type JSONLinesDecoder struct {
+	KeyField string
+}
+
+func (j *JSONLinesDecoder) Decode(line []byte) (*lrdd.Row, error) {
+	if len(line) == 0 {
+		return nil, nil
+	}
+	fields := make(map[string]interface{})
+	if err := jsoniter.Unmarshal(line, &fields); err != nil {
+		return nil, err
+	}
+	var key string
+	if j.KeyField != "" {
+		k, ok := fields[j.KeyField]
+		if !ok {
+			return nil, nil
+		}
+		key = fmt.Sprint(k)
+	}
+	return lrdd.KeyValue(key, fields), nil
+}
+
+// CSVDecoder returns a decoder decoding each line of CSV into a row whose value is a map of the columns
+// keyed by the header. Values are decoded as strings. Empty lines and the lines same as the header
+// (i.e. the header line of the file) are skipped, and a line having a different number of columns
+// from the header is an error.
+func CSVDecoder(header []string) RecordDecoder {
+	return &csvDecoder{Header: header}
+}
+
+type csvDecoder struct {
+	Header []string
+}
+
+func (c *csvDecoder) Decode(line []byte) (*lrdd.Row, error) {
+	if len(line) == 0 {
+		return nil, nil
+	}
+	r := csv.NewReader(bytes.NewReader(line))
+	r.FieldsPerRecord = len(c.Header)
+	columns, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	isHeader := true
+	fields := make(map[string]interface{}, len(columns))
+	for i, col := range columns {
+		fields[c.Header[i]] = col
+		isHeader = isHeader && col == c.Header[i]
+	}
+	if isHeader {
+		return nil, nil
+	}
+	return lrdd.Value(fields), nil
+}
+
+// decodeLines decodes each line read from r until EOF, without the trailing newline ("\n" or "\r\n").
+func decodeLines(r io.Reader, d RecordDecoder, emit func(*lrdd.Row) error) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		row, err := d.Decode(trimNewline(line))
+		if err != nil {
+			return errors.Wrapf(err, "decode line %d", n)
+		}
+		if row == nil {
+			continue
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+}
+
+// serializableDecoder is a RecordDecoder which can be sent to the workers with its type.
+type serializableDecoder struct{ RecordDecoder }
+
+func (s serializableDecoder) MarshalJSON() ([]byte, error) {
+	return serialization.SerializeStruct(s.RecordDecoder)
+}
+
+func (s *serializableDecoder) UnmarshalJSON(d []byte) error {
+	v, err := serialization.DeserializeStruct(d)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		s.RecordDecoder = v.(RecordDecoder)
+	}
+	return nil
+}
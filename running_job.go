@@ -3,6 +3,7 @@ package lrmr
 import (
 	"context"
 	"os"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -14,15 +15,17 @@ import (
 )
 
 var (
-	Aborted = errors.New("job aborted")
+	Aborted  = errors.New("job aborted")
+	Canceled = errors.New("job canceled")
 )
 
 type RunningJob struct {
 	*job.Job
 	Master *master.Master
 
-	finalStatus *job.Status
-	statusMu    sync.RWMutex
+	accumulators []string
+	finalStatus  *job.Status
+	statusMu     sync.RWMutex
 }
 
 func (r *RunningJob) Status() job.RunningState {
@@ -48,6 +51,153 @@ func (r *RunningJob) Metrics() (job.Metrics, error) {
 	return metric, nil
 }
 
+// Accumulators returns the values of the accumulators in the job, summed over its tasks and keyed by
+// their names. Running tasks are counted by the values they reported so far, which are updated periodically.
+func (r *RunningJob) Accumulators() (map[string]int64, error) {
+	statuses, err := r.Master.JobManager.ListTaskStatusesInJob(context.TODO(), r.Job.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list task status")
+	}
+	acc := make(map[string]int64)
+	for _, name := range r.accumulators {
+		acc[name] = 0
+	}
+	for _, status := range statuses {
+		for name, val := range status.Accumulators {
+			acc[name] += val
+		}
+	}
+	return acc, nil
+}
+
+// Progress returns an estimated fraction of work done in the job, averaged over its tasks.
+// Tasks reporting their progress by Context.ReportProgress are estimated by the reported value,
+// and the others are counted only after they finish.
+func (r *RunningJob) Progress() (float64, error) {
+	statuses, total, err := r.taskStatuses()
+	if err != nil || total == 0 {
+		return 0, err
+	}
+	done := 0.0
+	for _, status := range statuses {
+		done += status.EstimateProgress()
+	}
+	return done / float64(total), nil
+}
+
+// TaskProgress returns the number of finished tasks and the total number of tasks in every stage of the job,
+// and the fraction of them. Since the stages run in sequence, the fraction reflects the progress of the whole job
+// rather than the current stage. Failed tasks are also counted as finished. Unlike Progress, running tasks
+// are not counted until they finish, even if they report their progress.
+func (r *RunningJob) TaskProgress() (completed, total int, fraction float64, err error) {
+	statuses, total, err := r.taskStatuses()
+	if err != nil || total == 0 {
+		return 0, 0, 0, err
+	}
+	for _, status := range statuses {
+		if status.CompletedAt != nil {
+			completed++
+		}
+	}
+	return completed, total, float64(completed) / float64(total), nil
+}
+
+// taskStatuses returns the statuses of the tasks in the stages run by the workers, and the total number of the tasks.
+// The failure of the job reported by the master as the input stage is not counted as a task.
+func (r *RunningJob) taskStatuses() (statuses []*job.TaskStatus, total int, err error) {
+	for _, s := range r.Job.Stages[1:] {
+		total += len(r.Job.GetPartitionsOfStage(s.Name))
+	}
+	byID, err := r.Master.JobManager.ListTaskStatusesByID(context.TODO(), r.Job.ID)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "list task status")
+	}
+	for id, status := range byID {
+		if id.StageName == r.Job.Stages[0].Name {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, total, nil
+}
+
+// Events returns the events of the tasks in the job, such as starts, failures and retries, in the order of
+// their time. They are kept after the job completes, so that failed jobs can be investigated without logs.
+func (r *RunningJob) Events() ([]job.Event, error) {
+	events, err := r.Master.JobManager.ListJobEvents(context.TODO(), r.Job.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list events")
+	}
+	return events, nil
+}
+
+// StageStats is a row and byte accounting of a stage, summed over its tasks.
+type StageStats struct {
+	// InputRows is the number of rows that the stage received.
+	InputRows int
+
+	// OutputRows is the number of rows that the stage sent to OutputStage.
+	OutputRows int
+
+	// InputBytes is the size of the rows that the stage received. On the first stage,
+	// it is the size of the rows fed from the input.
+	InputBytes int
+
+	// OutputBytes is the size of the rows that the stage sent to OutputStage.
+	// If OutputStage is empty, the stage is the last one and it's the size of the rows written to the sink.
+	OutputBytes int
+	OutputStage string
+
+	// PartitionRows is the number of rows sent to each partition of OutputStage, keyed by
+	// the partition ID. It is not reported if the stage preserves the partitions, and a partition
+	// receiving much more rows than the others indicates hot keys. Unlike the others, it is counted
+	// only after the tasks finish.
+	PartitionRows map[string]int64
+}
+
+// StageStats returns row and byte accountings of the stages in the job, keyed by the stage name.
+// Running tasks are counted by the numbers they reported so far, which are updated periodically.
+func (r *RunningJob) StageStats() (map[string]StageStats, error) {
+	metrics, err := r.Metrics()
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]StageStats)
+	for _, s := range r.Job.Stages[1:] {
+		stats[s.Name] = StageStats{OutputStage: s.Output.Stage}
+	}
+	for key, val := range metrics {
+		frags := strings.Split(key, "/")
+		if len(frags) < 3 {
+			continue
+		}
+		st, ok := stats[frags[0]]
+		if !ok {
+			continue
+		}
+		if len(frags) == 4 && frags[2] == "PartitionRows" {
+			if st.PartitionRows == nil {
+				st.PartitionRows = make(map[string]int64)
+			}
+			st.PartitionRows[frags[3]] += int64(val)
+			stats[frags[0]] = st
+			continue
+		}
+		switch frags[2] {
+		case "InputRows":
+			st.InputRows += val
+		case "OutputRows":
+			st.OutputRows += val
+		case "InputBytes":
+			st.InputBytes += val
+		case "OutputBytes":
+			st.OutputBytes += val
+		}
+		stats[frags[0]] = st
+	}
+	return stats, nil
+}
+
 func (r *RunningJob) Wait() error {
 	ctx, cancel := util.ContextWithSignal(context.Background(), os.Interrupt, os.Kill, syscall.SIGTERM)
 	defer cancel()
@@ -55,28 +205,23 @@ func (r *RunningJob) Wait() error {
 	return r.WaitWithContext(ctx)
 }
 
+// WaitWithContext blocks until the job completes, and returns the first error of its tasks if it failed.
+// If the context is done first, it aborts the job. Use master.Master.WaitJob to wait without aborting.
 func (r *RunningJob) WaitWithContext(ctx context.Context) error {
-	jobWaitChan := make(chan struct{}, 1)
-	r.Master.JobTracker.OnJobCompletion(r.Job, func(j *job.Job, status *job.Status) {
-		r.statusMu.Lock()
-		r.finalStatus = status
-		r.statusMu.Unlock()
-
-		jobWaitChan <- struct{}{}
-		r.logMetrics()
-	})
-
-	select {
-	case <-jobWaitChan:
-		if r.Status() == job.Failed {
-			return r.finalStatus.Errors[0]
+	status, err := r.Master.JobTracker.WaitForCompletion(ctx, r.Job)
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Info("Canceling jobs")
+			_ = r.AbortWithContext(ctx)
 		}
-	case <-ctx.Done():
-		log.Info("Canceling jobs")
-		_ = r.AbortWithContext(ctx)
-		return ctx.Err()
+		return err
 	}
-	return nil
+	r.statusMu.Lock()
+	r.finalStatus = status
+	r.statusMu.Unlock()
+	r.logMetrics()
+
+	return status.Err()
 }
 
 func (r *RunningJob) Collect() ([]*lrdd.Row, error) {
@@ -94,23 +239,51 @@ func (r *RunningJob) Abort() error {
 }
 
 func (r *RunningJob) AbortWithContext(ctx context.Context) error {
-	ref := job.TaskID{
-		JobID:       r.Job.ID,
-		StageName:   "__input",
-		PartitionID: "__master",
-	}
-	reporter := job.NewTaskReporter(ctx, r.Master.Cluster.States(), r.Job, ref, job.NewTaskStatus())
-	if err := reporter.ReportFailure(Aborted); err != nil {
-		return errors.Wrap(err, "abort")
+	if err := r.abort(ctx, Aborted); err != nil {
+		return err
 	}
+	return Aborted
+}
+
+// Cancel stops the job. The cancellation is propagated to the workers, whose tasks stop
+// pulling inputs and free their resources, and the job fails with Canceled.
+// It blocks until the job stops, and does nothing if the job has already completed.
+func (r *RunningJob) Cancel(ctx context.Context) error {
+	return r.abort(ctx, Canceled)
+}
 
+// abort fails the job with given reason, and waits for the job to stop.
+func (r *RunningJob) abort(ctx context.Context, reason error) error {
 	jobWaitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	r.Master.JobTracker.OnJobCompletion(r.Job, func(*job.Job, *job.Status) {
-		log.Info("Aborted {} successfully.", r.Job.ID)
+		log.Info("Stopped {} ({}).", r.Job.ID, reason)
 		cancel()
 	})
+
+	// checked after the subscription to prevent missing the completion in between
+	js, err := r.Master.JobManager.GetJobStatus(ctx, r.Job.ID)
+	if err != nil {
+		return errors.Wrap(err, "get job status")
+	}
+	if js.Status == job.Succeeded || js.Status == job.Failed {
+		return nil
+	}
+
+	if err := r.Master.FailJob(ctx, r.Job, reason); err != nil {
+		return errors.Wrap(err, "abort")
+	}
 	<-jobWaitCtx.Done()
-	return Aborted
+	return ctx.Err()
+}
+
+// Pause holds the job until Resume is called. See master.Master.PauseJob for details.
+func (r *RunningJob) Pause(ctx context.Context) error {
+	return r.Master.PauseJob(ctx, r.Job.ID)
+}
+
+func (r *RunningJob) Resume(ctx context.Context) error {
+	return r.Master.ResumeJob(ctx, r.Job.ID)
 }
 
 func (r *RunningJob) logMetrics() {
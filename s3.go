@@ -0,0 +1,156 @@
+package lrmr
+
+import (
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+type S3Options struct {
+	// Region of the bucket. Defaults to the region from the AWS SDK configuration (e.g. AWS_REGION).
+	Region string
+
+	// Endpoint overrides the S3 endpoint, e.g. for S3-compatible storages. Requests are sent in path style if set.
+	Endpoint string
+
+	// Decoder decodes each line of the objects into a row. Defaults to LineDecoder with field "line".
+	Decoder RecordDecoder
+}
+
+type S3Option func(o *S3Options)
+
+func WithS3Region(region string) S3Option {
+	return func(o *S3Options) {
+		o.Region = region
+	}
+}
+
+func WithS3Endpoint(endpoint string) S3Option {
+	return func(o *S3Options) {
+		o.Endpoint = endpoint
+	}
+}
+
+// WithS3Decoder sets the decoder of the lines of the objects.
+func WithS3Decoder(d RecordDecoder) S3Option {
+	return func(o *S3Options) {
+		o.Decoder = d
+	}
+}
+
+// S3Input creates new Dataset by reading the objects under given prefix of the S3 bucket.
+// The objects are listed on the master, and distributed across the workers which read each object
+// line by line with the RecordDecoder given by WithS3Decoder. Credentials are resolved by the default
+// credential chain of AWS SDK (e.g. environment variables, shared credentials file and IAM roles) on each node.
+func (s *Session) S3Input(bucket, prefix string, opts ...S3Option) *Dataset {
+	o := S3Options{Decoder: &LineDecoder{Field: defaultTextFileField}}
+	for _, optFn := range opts {
+		optFn(&o)
+	}
+	loc := s3Location{Bucket: bucket, Region: o.Region, Endpoint: o.Endpoint}
+
+	d := newDataset(s, &s3Input{Location: loc, Prefix: prefix})
+	reader := &s3Reader{Location: loc, Decoder: serializableDecoder{o.Decoder}}
+	d.addStage(d.stageName(reader), reader)
+	return d
+}
+
+// s3Location is a bucket and the configuration of the client accessing it.
+type s3Location struct {
+	Bucket   string
+	Region   string
+	Endpoint string
+}
+
+func (l s3Location) client() (*s3.S3, error) {
+	cfg := aws.NewConfig()
+	if l.Region != "" {
+		cfg = cfg.WithRegion(l.Region)
+	}
+	if l.Endpoint != "" {
+		cfg = cfg.WithEndpoint(l.Endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create AWS session")
+	}
+	return s3.New(sess), nil
+}
+
+// s3Input emits the keys of the objects under the prefix.
+type s3Input struct {
+	partitions.ShuffledPartitioner
+	Location s3Location
+	Prefix   string
+}
+
+func (i s3Input) FeedInput(out output.Output) error {
+	cli, err := i.Location.client()
+	if err != nil {
+		return err
+	}
+	req := &s3.ListObjectsV2Input{
+		Bucket: aws.String(i.Location.Bucket),
+		Prefix: aws.String(i.Prefix),
+	}
+	var writeErr error
+	err = cli.ListObjectsV2Pages(req, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		rows := make([]*lrdd.Row, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			if aws.Int64Value(obj.Size) == 0 {
+				// directory markers or empty objects
+				continue
+			}
+			rows = append(rows, lrdd.Value(aws.StringValue(obj.Key)))
+		}
+		writeErr = out.Write(rows...)
+		return writeErr == nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "list s3://%s/%s", i.Location.Bucket, i.Prefix)
+	}
+	return writeErr
+}
+
+// s3Reader decodes the objects of the keys.
+type s3Reader struct {
+	Location s3Location
+	Decoder  serializableDecoder
+}
+
+func (r *s3Reader) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	cli, err := r.Location.client()
+	if err != nil {
+		return err
+	}
+	emit := func(row *lrdd.Row) error {
+		return out.Write(row)
+	}
+	for row := range in {
+		var key string
+		if err := row.DecodeValue(&key); err != nil {
+			return errors.Wrap(err, "decode object key")
+		}
+		obj, err := cli.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(r.Location.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "get s3://%s/%s", r.Location.Bucket, key)
+		}
+		err = decodeLines(obj.Body, r.Decoder, emit)
+		obj.Body.Close()
+		if err != nil {
+			return errors.Wrapf(err, "decode s3://%s/%s", r.Location.Bucket, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package lrmr
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+// sampleKey is the key of the rows and the partition which the partial samples of SampleN are gathered into.
+const sampleKey = "_sample"
+
+// Sample keeps each row with the probability of given fraction, so that the number of the rows
+// is approximately the fraction of the total. It doesn't involve any shuffle.
+//
+// Each task uses a random generator seeded with given seed and its partition ID, so that the result
+// is reproducible across reruns of the same job as long as the rows are partitioned in the same way.
+func (d *Dataset) Sample(fraction float64, seed int64) *Dataset {
+	d.addStage(d.stageName(&sampleTransformation{}), &sampleTransformation{Fraction: fraction, Seed: seed})
+	return d
+}
+
+// SampleN picks at most n rows uniformly at random across all partitions. Unlike Sample which
+// gives an approximate number of rows, it gives exactly n rows if the dataset has more than n rows.
+//
+// Each task picks n rows from its partition, and then the partial samples are gathered into a task
+// which picks n rows from them. Therefore a task holds up to n rows in memory, and the stages after
+// SampleN run in a single partition until they are repartitioned (e.g. with Shuffle).
+// The result is reproducible in the same way as Sample.
+func (d *Dataset) SampleN(n int, seed int64) *Dataset {
+	d.addStage(d.stageName(&reservoirSampleTransformation{}), &reservoirSampleTransformation{N: n, Seed: seed})
+	d.GroupByKnownKeys([]string{sampleKey})
+	d.addStage(d.stageName(&mergeSampleTransformation{}), &mergeSampleTransformation{N: n})
+	return d
+}
+
+// partitionRand returns a random generator for the partition derived from given seed.
+func partitionRand(seed int64, partitionID string) *rand.Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(partitionID))
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+}
+
+type sampleTransformation struct {
+	Fraction float64
+	Seed     int64
+}
+
+func (s *sampleTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	rng := partitionRand(s.Seed, ctx.PartitionID())
+	for row := range in {
+		if rng.Float64() >= s.Fraction {
+			continue
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reservoirSampleTransformation assigns a random priority to each row, and emits the rows with
+// the n highest priorities wrapped with their priority. Picking the n highest priorities of
+// the partial samples again gives a uniform sample of the whole rows.
+type reservoirSampleTransformation struct {
+	N    int
+	Seed int64
+}
+
+func (r *reservoirSampleTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	rng := partitionRand(r.Seed, ctx.PartitionID())
+	reservoir := &sampleHeap{}
+	for row := range in {
+		reservoir.offer(r.N, sampledRow{priority: rng.Float64(), row: row})
+	}
+	rows := make([]*lrdd.Row, len(*reservoir))
+	for i, s := range *reservoir {
+		raw, err := s.row.Marshal()
+		if err != nil {
+			return errors.Wrapf(err, "marshal row %s", s.row.Key)
+		}
+		value := make([]byte, 8, 8+len(raw))
+		binary.BigEndian.PutUint64(value, math.Float64bits(s.priority))
+		rows[i] = &lrdd.Row{Key: sampleKey, Value: append(value, raw...)}
+	}
+	return out.Write(rows...)
+}
+
+// mergeSampleTransformation unwraps the rows with the n highest priorities among the partial samples.
+type mergeSampleTransformation struct {
+	N int
+}
+
+func (m *mergeSampleTransformation) Apply(_ transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	reservoir := &sampleHeap{}
+	for wrapped := range in {
+		if len(wrapped.Value) < 8 {
+			return errors.Errorf("invalid sampled row %s", wrapped.Key)
+		}
+		row := new(lrdd.Row)
+		if err := row.Unmarshal(wrapped.Value[8:]); err != nil {
+			return errors.Wrap(err, "unmarshal row")
+		}
+		priority := math.Float64frombits(binary.BigEndian.Uint64(wrapped.Value[:8]))
+		reservoir.offer(m.N, sampledRow{priority: priority, row: row})
+	}
+	rows := make([]*lrdd.Row, len(*reservoir))
+	for i, s := range *reservoir {
+		rows[i] = s.row
+	}
+	return out.Write(rows...)
+}
+
+type sampledRow struct {
+	priority float64
+	row      *lrdd.Row
+}
+
+// sampleHeap is a min-heap of the sampled rows by their priority.
+type sampleHeap []sampledRow
+
+// offer adds the row if the heap has less than n rows, or replaces the row with the lowest
+// priority if given row has higher one.
+func (h *sampleHeap) offer(n int, s sampledRow) {
+	if n <= 0 {
+		return
+	}
+	if h.Len() < n {
+		heap.Push(h, s)
+		return
+	}
+	if s.priority > (*h)[0].priority {
+		(*h)[0] = s
+		heap.Fix(h, 0)
+	}
+}
+
+func (h sampleHeap) Len() int { return len(h) }
+
+func (h sampleHeap) Less(i, j int) bool { return h[i].priority < h[j].priority }
+
+func (h sampleHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *sampleHeap) Push(x interface{}) {
+	*h = append(*h, x.(sampledRow))
+}
+
+func (h *sampleHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	s := old[n-1]
+	*h = old[:n-1]
+	return s
+}
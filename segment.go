@@ -0,0 +1,267 @@
+package lrmr
+
+import (
+	"time"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+// Aggregator is a function aggregating a field of the rows in a time window.
+type Aggregator string
+
+const (
+	AggregateCount Aggregator = "count"
+	AggregateSum   Aggregator = "sum"
+	AggregateMin   Aggregator = "min"
+	AggregateMax   Aggregator = "max"
+	AggregateAvg   Aggregator = "avg"
+)
+
+// Operator compares an aggregated value with the threshold of a SegmentCondition.
+type Operator string
+
+const (
+	OpGreaterThanOrEqual Operator = ">="
+	OpGreaterThan        Operator = ">"
+	OpLessThanOrEqual    Operator = "<="
+	OpLessThan           Operator = "<"
+	OpEqual              Operator = "=="
+	OpNotEqual           Operator = "!="
+)
+
+// SegmentMatch specifies how multiple conditions in a Segment are combined.
+type SegmentMatch string
+
+const (
+	// MatchAll keeps a key only if it satisfies every condition (AND).
+	MatchAll SegmentMatch = "all"
+
+	// MatchAny keeps a key if it satisfies at least one of the conditions (OR).
+	MatchAny SegmentMatch = "any"
+)
+
+// SegmentCondition is a predicate on an aggregate of the rows with same key in a time window,
+// such as "sum of value >= 1 over 30 days". Row values are expected to be a map of fields.
+type SegmentCondition struct {
+	// Field is a numeric field to be aggregated. It is ignored when the Aggregator is AggregateCount.
+	Field string
+
+	// TimestampField is a field containing the time of the row in unix seconds.
+	// It is required only if the Window is set.
+	TimestampField string
+
+	Aggregator Aggregator
+	Operator   Operator
+	Threshold  float64
+
+	// Window is the length of the time window ending at Segment.Until.
+	// Zero means that all rows are aggregated regardless of their time.
+	Window time.Duration
+}
+
+// Validate checks whether the condition is well-formed.
+func (c SegmentCondition) Validate() error {
+	switch c.Aggregator {
+	case AggregateCount:
+	case AggregateSum, AggregateMin, AggregateMax, AggregateAvg:
+		if c.Field == "" {
+			return errors.Errorf("field is required for %s aggregator", c.Aggregator)
+		}
+	default:
+		return errors.Errorf("unknown aggregator: %s", c.Aggregator)
+	}
+	switch c.Operator {
+	case OpGreaterThanOrEqual, OpGreaterThan, OpLessThanOrEqual, OpLessThan, OpEqual, OpNotEqual:
+	default:
+		return errors.Errorf("unknown operator: %s", c.Operator)
+	}
+	if c.Window < 0 {
+		return errors.Errorf("invalid window: %s", c.Window)
+	}
+	if c.Window > 0 && c.TimestampField == "" {
+		return errors.New("timestamp field is required for windowed condition")
+	}
+	return nil
+}
+
+func (c SegmentCondition) isSatisfiedBy(v float64) bool {
+	switch c.Operator {
+	case OpGreaterThanOrEqual:
+		return v >= c.Threshold
+	case OpGreaterThan:
+		return v > c.Threshold
+	case OpLessThanOrEqual:
+		return v <= c.Threshold
+	case OpLessThan:
+		return v < c.Threshold
+	case OpEqual:
+		return v == c.Threshold
+	case OpNotEqual:
+		return v != c.Threshold
+	}
+	return false
+}
+
+// Segment filters keys by aggregate-over-time conditions. Conditions are combined with
+// AND if Match is MatchAll (default), and with OR if Match is MatchAny. To express nested
+// combinations like (A and B) or C, chain multiple segment stages or union the results.
+type Segment struct {
+	Conditions []SegmentCondition
+	Match      SegmentMatch
+
+	// Until is the end of the time windows of the conditions.
+	// Defaults to the time when the segment is added to the dataset.
+	Until time.Time
+
+	// EmitRows makes the segment emit the original rows of the qualifying keys instead of
+	// their aggregated values. Note that every row needs to be buffered until the input ends.
+	EmitRows bool
+}
+
+// Validate checks whether the segment and its conditions are well-formed.
+func (s Segment) Validate() error {
+	if len(s.Conditions) == 0 {
+		return errors.New("segment must have at least one condition")
+	}
+	if s.Match != "" && s.Match != MatchAll && s.Match != MatchAny {
+		return errors.Errorf("unknown match type: %s", s.Match)
+	}
+	for i, c := range s.Conditions {
+		if err := c.Validate(); err != nil {
+			return errors.Wrapf(err, "condition #%d", i)
+		}
+	}
+	return nil
+}
+
+// isSatisfiedBy checks the aggregated values against the conditions. A condition whose value is undefined
+// (e.g. min of an empty window) is not satisfied.
+func (s Segment) isSatisfiedBy(aggregated []float64, defined []bool) bool {
+	for i, c := range s.Conditions {
+		ok := defined[i] && c.isSatisfiedBy(aggregated[i])
+		if s.Match == MatchAny && ok {
+			return true
+		}
+		if s.Match != MatchAny && !ok {
+			return false
+		}
+	}
+	return s.Match != MatchAny
+}
+
+type segmentTransformation struct {
+	Segment Segment
+}
+
+// Apply aggregates the rows by key and emits a row for each key satisfying the segment.
+// The value of emitted row is a list of aggregated values, in the order of the conditions.
+// Undefined values (e.g. min of an empty window) are emitted as zero.
+// Unless Segment.EmitRows is set, only the aggregation state is kept per key,
+// so the memory usage grows with the number of keys.
+func (s *segmentTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	if err := s.Segment.Validate(); err != nil {
+		return errors.Wrap(err, "invalid segment")
+	}
+	until := s.Segment.Until
+	states := make(map[string][]aggregationState)
+	buffered := make(map[string][]*lrdd.Row)
+	var keys []string
+
+	for row := range in {
+		var fields map[string]interface{}
+		if err := row.DecodeValue(&fields); err != nil {
+			return errors.Wrapf(err, "decode value of row %s", row.Key)
+		}
+
+		state, ok := states[row.Key]
+		if !ok {
+			state = make([]aggregationState, len(s.Segment.Conditions))
+			states[row.Key] = state
+			keys = append(keys, row.Key)
+		}
+		if s.Segment.EmitRows {
+			buffered[row.Key] = append(buffered[row.Key], row)
+		}
+		for i, c := range s.Segment.Conditions {
+			if c.Window > 0 {
+				ts, ok := lrdd.ToFloat64(fields[c.TimestampField])
+				if !ok {
+					return errors.Errorf("field %s of row %s is not a timestamp", c.TimestampField, row.Key)
+				}
+				t := time.Unix(int64(ts), 0)
+				if t.Before(until.Add(-c.Window)) || !t.Before(until) {
+					continue
+				}
+			}
+			if c.Aggregator == AggregateCount {
+				state[i].add(1)
+				continue
+			}
+			v, ok := lrdd.ToFloat64(fields[c.Field])
+			if !ok {
+				continue
+			}
+			state[i].add(v)
+		}
+	}
+
+	var rows []*lrdd.Row
+	for _, key := range keys {
+		aggregated := make([]float64, len(s.Segment.Conditions))
+		defined := make([]bool, len(s.Segment.Conditions))
+		for i, c := range s.Segment.Conditions {
+			aggregated[i], defined[i] = states[key][i].result(c.Aggregator)
+		}
+		if !s.Segment.isSatisfiedBy(aggregated, defined) {
+			continue
+		}
+		if s.Segment.EmitRows {
+			rows = append(rows, buffered[key]...)
+			continue
+		}
+		rows = append(rows, lrdd.KeyValue(key, aggregated))
+	}
+	return out.Write(rows...)
+}
+
+type aggregationState struct {
+	count    int
+	sum      float64
+	min, max float64
+}
+
+func (a *aggregationState) add(v float64) {
+	if a.count == 0 || v < a.min {
+		a.min = v
+	}
+	if a.count == 0 || v > a.max {
+		a.max = v
+	}
+	a.count++
+	a.sum += v
+}
+
+// result returns the aggregated value. Min, max and average of no values are undefined, and reported as not ok.
+func (a aggregationState) result(agg Aggregator) (v float64, ok bool) {
+	switch agg {
+	case AggregateCount:
+		return float64(a.count), true
+	case AggregateSum:
+		return a.sum, true
+	}
+	if a.count == 0 {
+		return 0, false
+	}
+	switch agg {
+	case AggregateMin:
+		return a.min, true
+	case AggregateMax:
+		return a.max, true
+	case AggregateAvg:
+		return a.sum / float64(a.count), true
+	}
+	return 0, false
+}
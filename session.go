@@ -2,29 +2,52 @@ package lrmr
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/internal/tracing"
+	"github.com/ab180/lrmr/job"
 	"github.com/ab180/lrmr/lrdd"
 	"github.com/ab180/lrmr/master"
 	"github.com/goombaio/namegenerator"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// failJobTimeout bounds the time to fail a job which could not be started.
+const failJobTimeout = 10 * time.Second
+
 type Session struct {
 	ctx        context.Context
 	master     *master.Master
 	broadcasts serialization.Broadcast
+	caches     *datasetCache
 	options    SessionOptions
+
+	// accumulators are the names of the accumulators registered in the session.
+	accumulators []string
+
+	// runningJobs are the jobs started by the session which have not completed yet.
+	runningJobs   map[string]*RunningJob
+	runningJobsMu sync.Mutex
 }
 
 func NewSession(ctx context.Context, m *master.Master, opts ...SessionOption) *Session {
-	return &Session{
+	s := &Session{
 		ctx:        ctx,
 		master:     m,
 		broadcasts: make(serialization.Broadcast),
 		options:    buildSessionOptions(opts),
+
+		runningJobs: make(map[string]*RunningJob),
 	}
+	s.caches = newDatasetCache(func(b *storedBlock) {
+		freeBlock(s.master.Cluster.States(), b)
+	})
+	return s
 }
 
 // Parallelize creates new Dataset from given value.
@@ -33,9 +56,14 @@ func (s *Session) Parallelize(val interface{}) *Dataset {
 	return newDataset(s, in)
 }
 
-// FromFile creates new Dataset by reading files under given path.
-func (s *Session) FromFile(path string) *Dataset {
-	in := &localInput{Path: path}
+// FromFile creates new Dataset by reading files under given path. The dataset has a row with the path
+// of each file, which is assigned to a partition by the options (see FileInputOptions).
+func (s *Session) FromFile(path string, opts ...FileInputOption) *Dataset {
+	var o FileInputOptions
+	for _, optFn := range opts {
+		optFn(&o)
+	}
+	in := &localInput{Path: path, Partitions: o.Partitions, Split: o.Split}
 	return newDataset(s, in)
 }
 
@@ -45,8 +73,49 @@ func (s *Session) Broadcast(key string, val interface{}) {
 	s.broadcasts[key] = val
 }
 
+// RegisterAccumulator registers an accumulator of given name, which transformations can add values to
+// by Context.Accumulator. Accumulators are summed over the tasks of a job, and RunningJob.Accumulators
+// reports registered accumulators even if no values are added to them.
+func (s *Session) RegisterAccumulator(name string) {
+	for _, n := range s.accumulators {
+		if n == name {
+			return
+		}
+	}
+	s.accumulators = append(s.accumulators, name)
+}
+
+// ClearCache frees every dataset cached in the session, including their partitions on the workers.
+func (s *Session) ClearCache() {
+	s.caches.clear()
+}
+
 func (s *Session) Run(ds *Dataset) (*RunningJob, error) {
-	timer := log.Timer()
+	return s.run(ds, nil)
+}
+
+// run runs the dataset in a job, which is recorded as a retry of the failed runs if retry is given.
+func (s *Session) run(ds *Dataset, retry *job.Retry) (rj *RunningJob, err error) {
+	if s.options.StagewiseScheduling {
+		if ds, err = s.scheduleStagewise(ds); err != nil {
+			return nil, err
+		}
+	}
+	if m, ok := ds.input.(materializedInput); ok {
+		if err := m.materialize(); err != nil {
+			return nil, errors.WithMessage(err, "materialize input")
+		}
+	}
+	var j *job.Job
+	if r, ok := ds.input.(inputReleaser); ok {
+		defer func() {
+			// released on the completion of the job once it's created
+			if j == nil {
+				r.ReleaseInput()
+			}
+		}()
+	}
+	startedAt := time.Now()
 
 	jobName := s.options.Name
 	if jobName == "" {
@@ -59,14 +128,54 @@ func (s *Session) Run(ds *Dataset) (*RunningJob, error) {
 		defer cancel()
 	}
 
-	var createJobOptions []master.CreateJobOption
-	if s.options.NodeSelector != nil {
-		createJobOptions = append(createJobOptions, master.WithNodeSelector(s.options.NodeSelector))
+	// the span of the job is the parent of the spans of its tasks, and ends when the job completes
+	ctx, span := tracing.Tracer().Start(ctx, "lrmr.Job", trace.WithAttributes(attribute.String("lrmr.job_name", jobName)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.End()
+		}
+	}()
+
+	plans := ds.physicalPlans()
+	if err := ds.validate(plans); err != nil {
+		return nil, err
 	}
-	j, err := s.master.CreateJob(ctx, jobName, ds.plans, ds.stages, createJobOptions...)
+	if _, err := s.adaptPartitions(ds, plans); err != nil {
+		return nil, err
+	}
+	opts := s.createJobOptions()
+	if retry != nil {
+		opts = append(opts, master.WithRetry(retry))
+	}
+	created, err := s.master.CreateJob(ctx, jobName, plans, ds.stages, opts...)
 	if err != nil {
 		return nil, err
 	}
+	j = created
+	if r, ok := ds.input.(inputReleaser); ok {
+		s.master.JobTracker.OnJobCompletion(j, func(*job.Job, *job.Status) {
+			r.ReleaseInput()
+		})
+	}
+	defer func() {
+		if err != nil {
+			// the job never completes otherwise, holding its admission and the tasks already started
+			failCtx, cancel := context.WithTimeout(context.Background(), failJobTimeout)
+			defer cancel()
+			if failErr := s.master.FailJob(failCtx, j, err); failErr != nil {
+				log.Error("Failed to fail job {} after an error on starting: {}", j.ID, failErr)
+			}
+		}
+	}()
+	span.SetAttributes(attribute.String("lrmr.job_id", j.ID))
+	s.master.JobTracker.OnJobCompletion(j, func(j *job.Job, status *job.Status) {
+		if status.Status == job.Failed && len(status.Errors) > 0 {
+			span.SetStatus(codes.Error, status.Errors[0].Message)
+		}
+		span.End()
+	})
 
 	broadcast, err := serialization.SerializeBroadcast(s.broadcasts)
 	if err != nil {
@@ -76,6 +185,18 @@ func (s *Session) Run(ds *Dataset) (*RunningJob, error) {
 		return nil, errors.WithMessage(err, "assign task")
 	}
 
+	if c, ok := ds.input.(inputCommitter); ok {
+		// registered before the job can complete, and before the callbacks of RunningJob.Wait
+		s.master.JobTracker.OnJobCompletion(j, func(j *job.Job, status *job.Status) {
+			if status.Status != job.Succeeded {
+				return
+			}
+			if err := c.CommitInput(); err != nil {
+				log.Error("Failed to commit input of job {}: {}", j.ID, err)
+			}
+		})
+	}
+
 	iw, err := s.master.OpenInputWriter(ctx, j, j.Stages[1].Name, ds.plans[0].Partitioner)
 	if err != nil {
 		return nil, errors.WithMessage(err, "open input")
@@ -86,10 +207,52 @@ func (s *Session) Run(ds *Dataset) (*RunningJob, error) {
 	if err := iw.Close(); err != nil {
 		return nil, errors.Wrap(err, "close input")
 	}
-	timer.End("Job creation completed. Now running...")
+	log.Info("Job creation completed in {}. Now running...", time.Since(startedAt))
+
+	rj = &RunningJob{
+		Master:       s.master,
+		Job:          j,
+		accumulators: append([]string(nil), s.accumulators...),
+	}
+	s.runningJobsMu.Lock()
+	s.runningJobs[j.ID] = rj
+	s.runningJobsMu.Unlock()
+
+	s.master.JobTracker.OnJobCompletion(j, func(j *job.Job, _ *job.Status) {
+		s.runningJobsMu.Lock()
+		delete(s.runningJobs, j.ID)
+		s.runningJobsMu.Unlock()
+	})
+	return rj, nil
+}
+
+func (s *Session) createJobOptions() []master.CreateJobOption {
+	opts := []master.CreateJobOption{
+		master.WithPriority(s.options.Priority),
+		master.WithCompression(s.options.Compression),
+	}
+	if s.options.NodeSelector != nil {
+		opts = append(opts, master.WithNodeSelector(s.options.NodeSelector))
+	}
+	if s.options.AssignmentPolicy != nil {
+		opts = append(opts, master.WithAssignmentPolicy(s.options.AssignmentPolicy))
+	}
+	return opts
+}
+
+// Cancel cancels every running job started by the session. See RunningJob.Cancel for details.
+func (s *Session) Cancel() error {
+	s.runningJobsMu.Lock()
+	jobs := make([]*RunningJob, 0, len(s.runningJobs))
+	for _, j := range s.runningJobs {
+		jobs = append(jobs, j)
+	}
+	s.runningJobsMu.Unlock()
 
-	return &RunningJob{
-		Master: s.master,
-		Job:    j,
-	}, nil
+	for _, j := range jobs {
+		if err := j.Cancel(context.Background()); err != nil {
+			return errors.Wrapf(err, "cancel job %s", j.Job.ID)
+		}
+	}
+	return nil
 }
@@ -1,11 +1,24 @@
 package lrmr
 
-import "time"
+import (
+	"time"
+
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+)
 
 type SessionOptions struct {
 	Name         string
 	Timeout      time.Duration
 	NodeSelector map[string]string
+	Priority     int
+	Compression  output.Compression
+
+	AdaptivePartitions  AdaptivePartitionOptions
+	OrderedCollect      bool
+	JobRetries          int
+	AssignmentPolicy    partitions.AssignmentPolicy
+	StagewiseScheduling bool
 }
 
 type SessionOption func(o *SessionOptions)
@@ -28,9 +41,87 @@ func WithNodeSelector(selector map[string]string) SessionOption {
 	}
 }
 
+// WithPriority sets priority of the jobs in the session. Jobs with higher priority are
+// admitted first when the master limits the number of concurrent jobs. Defaults to 0.
+func WithPriority(p int) SessionOption {
+	return func(o *SessionOptions) {
+		o.Priority = p
+	}
+}
+
+// WithCompression compresses the rows sent between the nodes in the jobs of the session, which trades
+// CPU usage for network bandwidth. See the benchmarks in the output package for the tradeoff.
+// It is also the default compression of the files written by WriteFiles and WriteParquet, which can be
+// overridden by their options. Defaults to output.NoCompression.
+func WithCompression(c output.Compression) SessionOption {
+	return func(o *SessionOptions) {
+		o.Compression = c
+	}
+}
+
+// WithAdaptivePartitions chooses the number of partitions of the stages from the estimated size of the input,
+// so that each partition processes about bytesPerPartition bytes of the input, bounded by min and max
+// (zero max means unbounded). It avoids tiny partitions for a small input and giant ones for a large input.
+// The size is estimated without reading the input, e.g. from the size of the files, and the number of
+// partitions is the executor count of the workers if the input cannot estimate it. The stages whose number
+// of partitions is set by Repartition are not affected. The chosen number is reported by Session.Explain.
+func WithAdaptivePartitions(bytesPerPartition int64, min, max int) SessionOption {
+	return func(o *SessionOptions) {
+		o.AdaptivePartitions = AdaptivePartitionOptions{
+			BytesPerPartition: bytesPerPartition,
+			MinPartitions:     min,
+			MaxPartitions:     max,
+		}
+	}
+}
+
+// WithOrderedCollect makes Collect return the rows sorted by the partition ID of the last stage, and then
+// by the key and the encoded value of the rows. Reruns of a deterministic job give identical results as long
+// as each partition gets the same rows, which is useful for golden-file tests; use a partitioner with a fixed
+// assignment (e.g. partitions.NewSeededShuffledPartitioner) for the stages whose partitions would depend on
+// the arrival of rows. The order of the rows emitted by the tasks is not kept.
+// It costs an extra tagging of the rows and a sort in the master, so Collect is unordered by default.
+func WithOrderedCollect() SessionOption {
+	return func(o *SessionOptions) {
+		o.OrderedCollect = true
+	}
+}
+
+// WithStagewiseScheduling assigns the elastic partitions of the stages following a shuffle (see
+// partitions.Partition.IsElastic) to the workers registered when the stages before the shuffle complete,
+// rather than those registered when the dataset is run, so that workers joining in the middle of a long
+// dataset take its remaining stages. The stages before each such shuffle are run as a separate job which keeps
+// its output in the memory of the workers, as Barrier does, so the shuffle is not pipelined anymore.
+// The returned RunningJob is the job of the last stages. Datasets whose input is committed after the job
+// succeeds (e.g. KafkaInput) are run in a single job. Disabled by default.
+func WithStagewiseScheduling() SessionOption {
+	return func(o *SessionOptions) {
+		o.StagewiseScheduling = true
+	}
+}
+
 func buildSessionOptions(opts []SessionOption) (o SessionOptions) {
 	for _, optFn := range opts {
 		optFn(&o)
 	}
 	return o
 }
+
+// WithJobRetries makes the jobs failed due to a lost worker (e.g. a crashed one) rescheduled on the remaining
+// workers and run again from the beginning, up to n times. It applies to Collect, Count, Foreach and WriteBatches,
+// which don't expose the rows until the job succeeds, and the input of the dataset must be replayable.
+// The whole job is run again, so the side effects of the stages (e.g. the batches written by WriteBatches)
+// can be repeated. See also Dataset.WithRetries retrying the jobs failed with retryable errors.
+func WithJobRetries(n int) SessionOption {
+	return func(o *SessionOptions) {
+		o.JobRetries = n
+	}
+}
+
+// WithAssignmentPolicy places the partitions of the jobs in the session on the workers by given policy,
+// e.g. to bin-pack or spread the tasks. Defaults to partitions.DefaultAssignmentPolicy.
+func WithAssignmentPolicy(p partitions.AssignmentPolicy) SessionOption {
+	return func(o *SessionOptions) {
+		o.AssignmentPolicy = p
+	}
+}
@@ -0,0 +1,285 @@
+package lrmr
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/master"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+var _ = RegisterTypes(&jsonLinesReader{}, &fieldFilter{})
+
+// Spec is a declarative description of a job. It allows non-Go callers to submit jobs
+// as a JSON document, which is built into a pipeline of input table → filter → segment → aggregation.
+//
+//	{
+//	  "table": {"path": "/data/events", "keyField": "userID"},
+//	  "filter": [{"field": "type", "operator": "==", "value": "purchase"}],
+//	  "segmentCondition": {
+//	    "match": "all",
+//	    "conditions": [
+//	      {"field": "amount", "timestampField": "ts", "aggregator": "sum", "operator": ">=", "threshold": 1, "interval": "30d"}
+//	    ]
+//	  },
+//	  "aggregationSpec": {"groupBy": "country", "aggregator": "count"}
+//	}
+type Spec struct {
+	Table            *TableSpec       `json:"table"`
+	Filter           []FilterSpec     `json:"filter,omitempty"`
+	SegmentCondition *SegmentSpec     `json:"segmentCondition,omitempty"`
+	AggregationSpec  *AggregationSpec `json:"aggregationSpec,omitempty"`
+}
+
+// TableSpec describes an input table, which is a set of JSON lines files under the path.
+type TableSpec struct {
+	Path string `json:"path"`
+
+	// KeyField is a field used as a key of the rows.
+	KeyField string `json:"keyField"`
+}
+
+// FilterSpec keeps the rows whose field satisfies given comparison.
+// Multiple filters are combined with AND.
+type FilterSpec struct {
+	Field    string      `json:"field"`
+	Operator Operator    `json:"operator"`
+	Value    interface{} `json:"value"`
+}
+
+type SegmentSpec struct {
+	Match SegmentMatch `json:"match,omitempty"`
+
+	// Until is the end of the time windows in RFC3339. Defaults to the time when the job runs.
+	Until      string                 `json:"until,omitempty"`
+	Conditions []SegmentConditionSpec `json:"conditions"`
+}
+
+type SegmentConditionSpec struct {
+	Field          string     `json:"field,omitempty"`
+	TimestampField string     `json:"timestampField,omitempty"`
+	Aggregator     Aggregator `json:"aggregator"`
+	Operator       Operator   `json:"operator"`
+	Threshold      float64    `json:"threshold"`
+
+	// Interval is the length of the time window (e.g. "30d", "2w", "12h").
+	Interval string `json:"interval,omitempty"`
+}
+
+type AggregationSpec struct {
+	GroupBy    string     `json:"groupBy,omitempty"`
+	Field      string     `json:"field,omitempty"`
+	Aggregator Aggregator `json:"aggregator"`
+}
+
+// FromSpec parses given JSON spec document and builds a Dataset from it.
+func FromSpec(m *master.Master, specJSON []byte, opts ...SessionOption) (*Dataset, error) {
+	var spec Spec
+	if err := jsoniter.Unmarshal(specJSON, &spec); err != nil {
+		return nil, errors.Wrap(err, "parse spec")
+	}
+	return spec.Build(NewSession(context.Background(), m, opts...))
+}
+
+// Build validates the spec and builds a Dataset in given session.
+func (s Spec) Build(sess *Session) (*Dataset, error) {
+	if s.Table == nil || s.Table.Path == "" {
+		return nil, errors.New("table path is required")
+	}
+	if s.Table.KeyField == "" {
+		return nil, errors.New("table key field is required")
+	}
+	for i, f := range s.Filter {
+		if err := f.validate(); err != nil {
+			return nil, errors.Wrapf(err, "filter #%d", i)
+		}
+	}
+	var segment *Segment
+	if s.SegmentCondition != nil {
+		seg, err := s.SegmentCondition.toSegment()
+		if err != nil {
+			return nil, errors.Wrap(err, "segmentCondition")
+		}
+		seg.EmitRows = s.AggregationSpec != nil
+		segment = &seg
+	}
+	var aggregation *Aggregation
+	if s.AggregationSpec != nil {
+		agg := Aggregation(*s.AggregationSpec)
+		if err := agg.Validate(); err != nil {
+			return nil, errors.Wrap(err, "aggregationSpec")
+		}
+		aggregation = &agg
+	}
+
+	ds := sess.FromFile(s.Table.Path).
+		FlatMap(&jsonLinesReader{KeyField: s.Table.KeyField})
+
+	if len(s.Filter) > 0 {
+		ds.Filter(&fieldFilter{Filters: s.Filter})
+	}
+	if segment != nil {
+		ds.GroupByKey().Segment(*segment)
+	}
+	if aggregation != nil {
+		ds.Aggregate(*aggregation)
+	}
+	return ds, nil
+}
+
+func (f FilterSpec) validate() error {
+	if f.Field == "" {
+		return errors.New("field is required")
+	}
+	switch f.Operator {
+	case OpEqual, OpNotEqual:
+		switch f.Value.(type) {
+		case string, float64, bool, nil:
+			return nil
+		}
+		return errors.Errorf("unsupported value %v for operator %s", f.Value, f.Operator)
+
+	case OpGreaterThanOrEqual, OpGreaterThan, OpLessThanOrEqual, OpLessThan:
+		switch f.Value.(type) {
+		case string, float64:
+			return nil
+		}
+		return errors.Errorf("unsupported value %v for operator %s", f.Value, f.Operator)
+	}
+	return errors.Errorf("unknown operator: %q", f.Operator)
+}
+
+func (s SegmentSpec) toSegment() (seg Segment, err error) {
+	seg.Match = s.Match
+	if s.Until != "" {
+		seg.Until, err = time.Parse(time.RFC3339, s.Until)
+		if err != nil {
+			return seg, errors.Errorf("malformed until: %q", s.Until)
+		}
+	}
+	for i, c := range s.Conditions {
+		cond := SegmentCondition{
+			Field:          c.Field,
+			TimestampField: c.TimestampField,
+			Aggregator:     c.Aggregator,
+			Operator:       c.Operator,
+			Threshold:      c.Threshold,
+		}
+		if c.Interval != "" {
+			cond.Window, err = parseInterval(c.Interval)
+			if err != nil {
+				return seg, errors.Wrapf(err, "condition #%d", i)
+			}
+		}
+		seg.Conditions = append(seg.Conditions, cond)
+	}
+	return seg, seg.Validate()
+}
+
+// parseInterval parses an interval which is either a time.Duration string or
+// a number of days or weeks like "30d" and "2w".
+func parseInterval(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit > 0 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n <= 0 {
+			return 0, errors.Errorf("malformed interval: %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, errors.Errorf("malformed interval: %q", s)
+	}
+	return d, nil
+}
+
+// jsonLinesReader reads a JSON lines file in given path, emitting a row per line.
+type jsonLinesReader struct {
+	KeyField string
+}
+
+func (j *jsonLinesReader) FlatMap(ctx Context, in *lrdd.Row) (rows []*lrdd.Row, err error) {
+	var path string
+	in.UnmarshalValue(&path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open file")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		fields := make(map[string]interface{})
+		if err := jsoniter.Unmarshal(line, &fields); err != nil {
+			return nil, errors.Wrapf(err, "decode line of %s", path)
+		}
+		key, ok := fields[j.KeyField]
+		if !ok {
+			continue
+		}
+		rows = append(rows, lrdd.KeyValue(fmt.Sprint(key), fields))
+	}
+	return rows, scanner.Err()
+}
+
+// fieldFilter keeps the rows satisfying every filter.
+type fieldFilter struct {
+	Filters []FilterSpec
+}
+
+func (f *fieldFilter) Filter(row *lrdd.Row) bool {
+	var fields map[string]interface{}
+	row.UnmarshalValue(&fields)
+
+	for _, filter := range f.Filters {
+		if !filter.matches(fields[filter.Field]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f FilterSpec) matches(v interface{}) bool {
+	if expected, ok := f.Value.(float64); ok {
+		actual, ok := lrdd.ToFloat64(v)
+		if !ok {
+			return f.Operator == OpNotEqual
+		}
+		return SegmentCondition{Operator: f.Operator, Threshold: expected}.isSatisfiedBy(actual)
+	}
+	if expected, ok := f.Value.(string); ok {
+		actual, ok := v.(string)
+		if !ok {
+			return f.Operator == OpNotEqual
+		}
+		c := strings.Compare(actual, expected)
+		return SegmentCondition{Operator: f.Operator}.isSatisfiedBy(float64(c))
+	}
+	switch f.Operator {
+	case OpEqual:
+		return v == f.Value
+	case OpNotEqual:
+		return v != f.Value
+	}
+	return false
+}
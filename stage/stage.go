@@ -1,7 +1,11 @@
 package stage
 
 import (
+	"time"
+
 	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
 	"github.com/ab180/lrmr/partitions"
 	"github.com/ab180/lrmr/transformation"
 )
@@ -15,6 +19,19 @@ type Stage struct {
 	// Function is a transformation the stage executes.
 	Function transformation.Serializable `json:"function"`
 
+	// InputSchema is validated against the input rows of the stage if it is set.
+	InputSchema lrdd.Schema `json:"inputSchema,omitempty"`
+
+	// TaskTimeout limits the running time of each task in the stage. Zero means unlimited.
+	TaskTimeout time.Duration `json:"taskTimeout,omitempty"`
+
+	// MaxRetries is the number of times the job is run again after a task in the stage fails with
+	// a retryable error. Zero disables retries.
+	MaxRetries int `json:"maxRetries,omitempty"`
+
+	// NoOutputPolicy decides whether the output rows with no partition to go are dropped or fail the task.
+	NoOutputPolicy output.NoOutputPolicy `json:"noOutputPolicy,omitempty"`
+
 	Output Output
 }
 
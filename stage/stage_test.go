@@ -0,0 +1,56 @@
+package stage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/transformation"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var _ = serialization.Register(&routedScaler{})
+
+// routedScaler has a partitioner in its fields, which is encoded by gob through partitions.SerializablePartitioner.
+type routedScaler struct {
+	Factor int
+	Route  partitions.SerializablePartitioner
+}
+
+func (s *routedScaler) Apply(transformation.Context, chan *lrdd.Row, output.Output) error {
+	return nil
+}
+
+func TestStage_Gob(t *testing.T) {
+	Convey("Given the gob codec", t, func() {
+		So(serialization.SetStructCodec(serialization.GobCodec.Name()), ShouldBeNil)
+		Reset(func() {
+			So(serialization.SetStructCodec(serialization.JSONCodec.Name()), ShouldBeNil)
+		})
+
+		fn := &routedScaler{
+			Factor: 2,
+			Route:  partitions.WrapPartitioner(partitions.NewRangePartitioner([]string{"m"})),
+		}
+		s := New("scale", fn)
+		s.Output.Partitioner = partitions.WrapPartitioner(partitions.NewHashKeyPartitioner())
+
+		Convey("A stage should be same after the serialization", func() {
+			data, err := json.Marshal(s)
+			So(err, ShouldBeNil)
+
+			var actual Stage
+			So(json.Unmarshal(data, &actual), ShouldBeNil)
+			So(actual.Name, ShouldEqual, s.Name)
+			So(partitions.Equal(actual.Output.Partitioner, s.Output.Partitioner), ShouldBeTrue)
+
+			actualFn, ok := actual.Function.Transformation.(*routedScaler)
+			So(ok, ShouldBeTrue)
+			So(actualFn.Factor, ShouldEqual, 2)
+			So(partitions.Equal(actualFn.Route, fn.Route), ShouldBeTrue)
+		})
+	})
+}
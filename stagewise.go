@@ -0,0 +1,77 @@
+package lrmr
+
+import (
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/stage"
+)
+
+// scheduleStagewise splits the dataset before the last stage whose partitions are elastic, so that they are
+// assigned to the workers registered when the stages before it complete (see WithStagewiseScheduling).
+// The stages before the split are run as the job storing the input of the returned dataset, which splits
+// them again when it runs. The dataset is returned as is if it has no such stage.
+func (s *Session) scheduleStagewise(ds *Dataset) (*Dataset, error) {
+	if _, ok := ds.input.(inputCommitter); ok {
+		// the input would be committed after the job of the first stages, before the dataset succeeds
+		return ds, nil
+	}
+	plans := ds.physicalPlans()
+	if _, err := s.adaptPartitions(ds, plans); err != nil {
+		return nil, err
+	}
+	for i := len(ds.stages) - 2; i >= 1; i-- {
+		p := plans[i].Partitioner
+		if p == nil {
+			// see partitions.Schedule for the default partitioner
+			if plans[i].Equal(plans[i+1]) {
+				continue
+			}
+			p = partitions.NewShuffledPartitioner()
+		}
+		if !plansElastic(p) {
+			continue
+		}
+		return splitBefore(ds, plans, i+1, p), nil
+	}
+	return ds, nil
+}
+
+// plansElastic returns true if the partitions planned by given partitioner are elastic and not bound
+// to any node, which can be assigned to any worker.
+func plansElastic(p partitions.Partitioner) bool {
+	if partitions.IsPreserved(p) {
+		return false
+	}
+	if _, ok := p.(partitions.Coalescer); ok {
+		// planned from the assignments of the current stage
+		return false
+	}
+	for _, pt := range p.PlanNext(1) {
+		if !pt.IsElastic || len(pt.AssignmentAffinity) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// splitBefore creates a dataset running the stages of ds from i-th stage, which reads the output of the
+// previous stages stored on the workers as Barrier does, and partitions it by p. The plans are the physical
+// plans of ds, which are kept on both sides of the split.
+func splitBefore(ds *Dataset, plans []partitions.Plan, i int, p partitions.Partitioner) *Dataset {
+	upstream := ds.clone()
+	upstream.stages = upstream.stages[:i]
+	upstream.plans = append([]partitions.Plan(nil), plans[:i]...)
+	upstream.lastPlan().Partitioner = partitions.NewPreservePartitioner()
+
+	downstream := ds.readBlock(&blockInput{source: upstream})
+	downstream.lastPlan().Partitioner = p
+	for ; i < len(ds.stages); i++ {
+		st := ds.stages[i]
+		st.Inputs = []stage.Input{stage.InputFrom(*downstream.lastStage())}
+		st.Output = stage.Output{}
+		downstream.lastStage().SetOutputTo(st)
+
+		downstream.stages = append(downstream.stages, st)
+		downstream.plans = append(downstream.plans, plans[i])
+	}
+	return downstream
+}
@@ -0,0 +1,45 @@
+package test
+
+import (
+	"sync"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/testutils"
+)
+
+var _ = lrmr.RegisterTypes(&ConcurrentOddCounter{})
+
+// ConcurrentOddCounter counts odd numbers in the "odd" accumulator, adding from several goroutines.
+type ConcurrentOddCounter struct{}
+
+func (c *ConcurrentOddCounter) Transform(ctx lrmr.Context, in chan *lrdd.Row, emit func(*lrdd.Row)) error {
+	odd := ctx.Accumulator("odd")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range in {
+				if testutils.IntValue(row)%2 != 0 {
+					odd.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func CountOddByAccumulator(sess *lrmr.Session) *lrmr.Dataset {
+	sess.RegisterAccumulator("odd")
+	sess.RegisterAccumulator("unused")
+
+	data := make([]int, 1000)
+	for i := range data {
+		data[i] = i + 1
+	}
+	return sess.Parallelize(data).
+		Do(&ConcurrentOddCounter{})
+}
@@ -0,0 +1,31 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAccumulator(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When transformations add values to an accumulator", func() {
+			j, err := CountOddByAccumulator(cluster.Session).Run()
+			So(err, ShouldBeNil)
+			So(j.Wait(), ShouldBeNil)
+
+			Convey("It should sum the values over the tasks", func() {
+				acc, err := j.Accumulators()
+				So(err, ShouldBeNil)
+				So(acc["odd"], ShouldEqual, 500)
+			})
+
+			Convey("It should report registered accumulators without values", func() {
+				acc, err := j.Accumulators()
+				So(err, ShouldBeNil)
+				So(acc, ShouldContainKey, "unused")
+				So(acc["unused"], ShouldEqual, 0)
+			})
+		})
+	}))
+}
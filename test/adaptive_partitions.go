@@ -0,0 +1,15 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+)
+
+// MultiplyNumbers multiplies n numbers.
+func MultiplyNumbers(sess *lrmr.Session, n int) *lrmr.Dataset {
+	data := make([]int, n)
+	for i := range data {
+		data[i] = i
+	}
+	return sess.Parallelize(data).
+		Map(&Multiply{})
+}
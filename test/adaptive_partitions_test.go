@@ -0,0 +1,42 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdaptivePartitions(t *testing.T) {
+	Convey("Given running nodes with adaptive partitions", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When planning a small input", func() {
+			dag, err := cluster.Session.Explain(MultiplyNumbers(cluster.Session, 3))
+			So(err, ShouldBeNil)
+
+			Convey("It should choose the minimum number of partitions", func() {
+				So(dag.EstimatedInputBytes, ShouldBeGreaterThan, 0)
+				So(dag.Stages[1].Partitions, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When planning a large input", func() {
+			dag, err := cluster.Session.Explain(MultiplyNumbers(cluster.Session, 10000))
+			So(err, ShouldBeNil)
+
+			Convey("It should choose the maximum number of partitions", func() {
+				So(dag.EstimatedInputBytes, ShouldBeGreaterThan, 100*8)
+				So(dag.Stages[1].Partitions, ShouldEqual, 8)
+			})
+		})
+
+		Convey("When running a job", func() {
+			rows, err := MultiplyNumbers(cluster.Session, 10000).Collect()
+
+			Convey("It should be processed by the chosen partitions", func() {
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 10000)
+			})
+		})
+	}, lrmr.WithAdaptivePartitions(100, 1, 8)))
+}
@@ -0,0 +1,11 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+)
+
+// MissingFiles fails to feed its input, after the job is created.
+func MissingFiles(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.FromFile("/nonexistent/lrmr/input").
+		Map(&Multiply{})
+}
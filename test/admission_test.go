@@ -0,0 +1,29 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/master"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdmission(t *testing.T) {
+	admitOne := integration.ClusterOptions{
+		Master: func(opt *master.Options) {
+			opt.MaxConcurrentJobs = 1
+		},
+	}
+	Convey("Given a cluster running one job at a time", t, integration.WithConfiguredLocalCluster(2, admitOne, func(cluster *integration.LocalCluster) {
+		Convey("When a job fails to start after it is admitted", func() {
+			_, err := MissingFiles(cluster.Session).Run()
+			So(err, ShouldNotBeNil)
+
+			Convey("The next job should be admitted", func() {
+				rows, err := Map(cluster.Session).Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1000)
+			})
+		})
+	}))
+}
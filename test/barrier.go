@@ -0,0 +1,88 @@
+package test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&CountAll{}, &SlowFirstPartition{}, &RecordFirstRow{})
+
+// CountAll emits the number of rows in its partition.
+type CountAll struct{}
+
+func (c *CountAll) Transform(ctx lrmr.Context, in chan *lrdd.Row, emit func(*lrdd.Row)) error {
+	n := 0
+	for range in {
+		n++
+	}
+	emit(lrdd.Value(n))
+	return nil
+}
+
+// BarrierTimeline records the times when the upstream tasks finish and the downstream tasks receive their first rows.
+var BarrierTimeline struct {
+	UpstreamFinished  []time.Time
+	DownstreamStarted []time.Time
+	sync.Mutex
+}
+
+// SlowFirstPartition passes through the rows, but finishes the partition "0" late.
+type SlowFirstPartition struct{}
+
+func (s *SlowFirstPartition) Transform(ctx lrmr.Context, in chan *lrdd.Row, emit func(*lrdd.Row)) error {
+	for row := range in {
+		emit(row)
+	}
+	if ctx.PartitionID() == "0" {
+		time.Sleep(500 * time.Millisecond)
+	}
+	BarrierTimeline.Lock()
+	BarrierTimeline.UpstreamFinished = append(BarrierTimeline.UpstreamFinished, time.Now())
+	BarrierTimeline.Unlock()
+	return nil
+}
+
+// RecordFirstRow passes through the rows, recording the time of the first row.
+type RecordFirstRow struct{}
+
+func (r *RecordFirstRow) Transform(ctx lrmr.Context, in chan *lrdd.Row, emit func(*lrdd.Row)) error {
+	first := true
+	for row := range in {
+		if first {
+			BarrierTimeline.Lock()
+			BarrierTimeline.DownstreamStarted = append(BarrierTimeline.DownstreamStarted, time.Now())
+			BarrierTimeline.Unlock()
+			first = false
+		}
+		emit(row)
+	}
+	return nil
+}
+
+func CountAfterBarrier(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 100)
+	for i := range data {
+		data[i] = i
+	}
+	return sess.Parallelize(data).
+		Map(&Multiply{}).
+		Barrier().
+		Repartition(1).
+		Do(&CountAll{})
+}
+
+// SkewedStagesWithBarrier has an upstream stage with a slow partition, followed by a barrier.
+func SkewedStagesWithBarrier(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 100)
+	for i := range data {
+		data[i] = i
+	}
+	return sess.Parallelize(data).
+		Do(&SlowFirstPartition{}).
+		Barrier().
+		Shuffle().
+		Do(&RecordFirstRow{})
+}
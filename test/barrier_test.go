@@ -0,0 +1,54 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBarrier(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When running stages after a barrier", func() {
+			ds := CountAfterBarrier(cluster.Session)
+
+			Convey("Downstream should see the complete result of the upstream", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1)
+				So(testutils.IntValue(rows[0]), ShouldEqual, 100)
+			})
+
+			Convey("The partitions of the upstream should be freed after the downstream", func() {
+				_, err := ds.Collect()
+				So(err, ShouldBeNil)
+				So(waitForBlocksFreed(cluster), ShouldBeTrue)
+			})
+		})
+
+		Convey("When an upstream task is slower than the others", func() {
+			BarrierTimeline.Lock()
+			BarrierTimeline.UpstreamFinished = nil
+			BarrierTimeline.DownstreamStarted = nil
+			BarrierTimeline.Unlock()
+
+			rows, err := SkewedStagesWithBarrier(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 100)
+
+			Convey("Downstream should start after every upstream task finishes", func() {
+				BarrierTimeline.Lock()
+				defer BarrierTimeline.Unlock()
+
+				So(BarrierTimeline.UpstreamFinished, ShouldNotBeEmpty)
+				So(BarrierTimeline.DownstreamStarted, ShouldNotBeEmpty)
+				for _, started := range BarrierTimeline.DownstreamStarted {
+					for _, finished := range BarrierTimeline.UpstreamFinished {
+						So(started.After(finished), ShouldBeTrue)
+					}
+				}
+			})
+		})
+	}))
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"sync"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+)
+
+var _ = lrmr.RegisterTypes(&DedupSink{})
+
+// DedupSink records the batches by their keys, skipping the batches already recorded.
+// It fails once after writing the batch with the sequence of FailAfter if it's nonzero.
+type DedupSink struct {
+	FailAfter int
+}
+
+var (
+	sinkBatches   sync.Map
+	sinkFailedYet atomic.Bool
+)
+
+func (s *DedupSink) WriteBatch(ctx lrmr.Context, key lrmr.BatchKey, rows []*lrdd.Row) error {
+	sinkBatches.LoadOrStore(key.String(), len(rows))
+	if s.FailAfter != 0 && key.Sequence == s.FailAfter && sinkFailedYet.CAS(false, true) {
+		return errors.New("sink failure")
+	}
+	return nil
+}
+
+// SinkRows returns the number of the rows recorded by DedupSink, and whether DedupSink has failed.
+func SinkRows() (rows int64, failed bool) {
+	sinkBatches.Range(func(_, v interface{}) bool {
+		rows += int64(v.(int))
+		return true
+	})
+	return rows, sinkFailedYet.Load()
+}
+
+func BatchWriteJob(sess *lrmr.Session, failAfter int) error {
+	sinkBatches = sync.Map{}
+	sinkFailedYet.Store(false)
+
+	return sess.Parallelize(limitData(1000)).
+		WriteBatches(&DedupSink{FailAfter: failAfter}, 10, lrmr.WithWriteRetries(1))
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteBatches(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When writing batches", func() {
+			err := BatchWriteJob(cluster.Session, 0)
+
+			Convey("It should write every row", func() {
+				So(err, ShouldBeNil)
+				rows, _ := SinkRows()
+				So(rows, ShouldEqual, 1000)
+			})
+		})
+
+		Convey("When a task fails after writing some batches", func() {
+			err := BatchWriteJob(cluster.Session, 2)
+
+			Convey("The retried batches should have the same keys", func() {
+				So(err, ShouldBeNil)
+				rows, failed := SinkRows()
+				So(failed, ShouldBeTrue)
+				So(rows, ShouldEqual, 1000)
+			})
+		})
+	}))
+}
@@ -2,12 +2,13 @@ package test
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ab180/lrmr"
 	"github.com/ab180/lrmr/lrdd"
 )
 
-var _ = lrmr.RegisterTypes(&BroadcastStage{})
+var _ = lrmr.RegisterTypes(&BroadcastStage{}, &TypedBroadcastStage{})
 
 func BroadcastTester(sess *lrmr.Session) *lrmr.Dataset {
 	return sess.Parallelize("dummy").
@@ -15,6 +16,13 @@ func BroadcastTester(sess *lrmr.Session) *lrmr.Dataset {
 		Map(&BroadcastStage{ThroughStruct: "foo"})
 }
 
+// OversizedBroadcast broadcasts a value larger than the default limit of workers.
+func OversizedBroadcast(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize("dummy").
+		Broadcast("Oversized", strings.Repeat("a", 17<<20)).
+		Map(&BroadcastStage{ThroughStruct: "foo"})
+}
+
 type BroadcastStage struct {
 	ThroughStruct string
 }
@@ -23,3 +31,36 @@ func (b *BroadcastStage) Map(c lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
 	v := c.Broadcast("ThroughContext")
 	return lrdd.Value(fmt.Sprintf("throughStruct=%s, throughContext=%v", b.ThroughStruct, v)), nil
 }
+
+type broadcastConfig struct {
+	Name  string
+	Limit int
+}
+
+func TypedBroadcastTester(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize("dummy").
+		Broadcast("String", "foo").
+		Broadcast("Bytes", []byte{1, 2, 3}).
+		Broadcast("Int", 42).
+		Broadcast("Float", 0.5).
+		Broadcast("Config", broadcastConfig{Name: "bar", Limit: 10}).
+		Map(&TypedBroadcastStage{})
+}
+
+// TypedBroadcastStage emits the broadcasts read with typed accessors.
+type TypedBroadcastStage struct{}
+
+func (t *TypedBroadcastStage) Map(c lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	s, _ := lrmr.BroadcastString(c, "String")
+	b, _ := lrmr.BroadcastBytes(c, "Bytes")
+	n, _ := lrmr.BroadcastInt64(c, "Int")
+	f, _ := lrmr.BroadcastFloat64(c, "Float")
+	_, intAsString := lrmr.BroadcastString(c, "Int")
+	_, missing := lrmr.BroadcastInt64(c, "Missing")
+
+	var conf broadcastConfig
+	if err := lrmr.DecodeBroadcast(c, "Config", &conf); err != nil {
+		return nil, err
+	}
+	return lrdd.Value(fmt.Sprintf("%s %v %d %g %v %v %s/%d", s, b, n, f, intAsString, missing, conf.Name, conf.Limit)), nil
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ab180/lrmr/test/integration"
 	"github.com/ab180/lrmr/test/testutils"
@@ -19,6 +20,41 @@ func TestBroadcast(t *testing.T) {
 				So(rows, ShouldHaveLength, 1)
 				So(testutils.StringValue(rows[0]), ShouldEqual, "throughStruct=foo, throughContext=bar")
 			})
+
+			Convey("It should release broadcasts after the tasks finish", func() {
+				_, err := ds.Collect()
+				So(err, ShouldBeNil)
+
+				broadcastBytes := -1
+				deadline := time.Now().Add(5 * time.Second)
+				for broadcastBytes != 0 && time.Now().Before(deadline) {
+					time.Sleep(10 * time.Millisecond)
+					broadcastBytes = 0
+					for _, s := range cluster.WorkerStatuses() {
+						broadcastBytes += s.BroadcastBytes
+					}
+				}
+				So(broadcastBytes, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When reading broadcasts with typed accessors", func() {
+			rows, err := TypedBroadcastTester(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should convert them into the expected types", func() {
+				So(rows, ShouldHaveLength, 1)
+				So(testutils.StringValue(rows[0]), ShouldEqual, "foo [1 2 3] 42 0.5 false false bar/10")
+			})
+		})
+
+		Convey("When broadcasting a value exceeding the limit", func() {
+			_, err := OversizedBroadcast(cluster.Session).Run()
+
+			Convey("It should fail to run the job", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "exceeds the limit")
+			})
 		})
 	}))
 }
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/testutils"
+	"go.uber.org/atomic"
+)
+
+var _ = lrmr.RegisterTypes(&CountingMultiply{})
+
+// MultiplyCalls is the number of rows processed by CountingMultiply.
+var MultiplyCalls atomic.Int64
+
+// CountingMultiply multiplies input, counting the number of the calls.
+type CountingMultiply struct{}
+
+func (m *CountingMultiply) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	MultiplyCalls.Inc()
+	return lrdd.Value(testutils.IntValue(row) * 2), nil
+}
+
+func ExpensiveUpstream(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize([]int{1, 2, 3, 4, 5}).
+		Map(&CountingMultiply{})
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCache(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		MultiplyCalls.Store(0)
+
+		Convey("When running multiple actions on a cached dataset", func() {
+			rows, err := ExpensiveUpstream(cluster.Session).Cache().Collect()
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 5)
+
+			rows, err = ExpensiveUpstream(cluster.Session).Cache().Map(&Multiply{}).Collect()
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 5)
+
+			Convey("It should compute the upstream only once", func() {
+				So(MultiplyCalls.Load(), ShouldEqual, 5)
+			})
+
+			Convey("It should keep the partitions on the workers", func() {
+				So(blockBytesOf(cluster), ShouldBeGreaterThan, 0)
+			})
+
+			Convey("It should recompute the upstream after the cache is freed", func() {
+				cluster.Session.ClearCache()
+				So(waitForBlocksFreed(cluster), ShouldBeTrue)
+
+				_, err := ExpensiveUpstream(cluster.Session).Cache().Collect()
+				So(err, ShouldBeNil)
+				So(MultiplyCalls.Load(), ShouldEqual, 10)
+			})
+		})
+
+		Convey("When a cached dataset is unpersisted", func() {
+			cached := ExpensiveUpstream(cluster.Session).Cache()
+			_, err := cached.Collect()
+			So(err, ShouldBeNil)
+			cached.Unpersist()
+
+			Convey("Its partitions should be freed on the workers", func() {
+				So(waitForBlocksFreed(cluster), ShouldBeTrue)
+			})
+		})
+	}))
+}
+
+// blockBytesOf returns the total size of the blocks stored in the workers.
+func blockBytesOf(cluster *integration.LocalCluster) (n int) {
+	for _, st := range cluster.WorkerStatuses() {
+		n += st.BlockBytes
+	}
+	return n
+}
+
+// waitForBlocksFreed returns true if the workers free every block in a second.
+func waitForBlocksFreed(cluster *integration.LocalCluster) bool {
+	for i := 0; i < 100; i++ {
+		if blockBytesOf(cluster) == 0 {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
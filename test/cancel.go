@@ -0,0 +1,28 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"go.uber.org/atomic"
+)
+
+var _ = lrmr.RegisterTypes(&NeverEnding{})
+
+// CanceledTasks is the number of NeverEnding tasks which observed the cancellation.
+var CanceledTasks atomic.Int32
+
+// NeverEnding consumes the input and runs until the task is canceled.
+type NeverEnding struct{}
+
+func (n *NeverEnding) Transform(ctx lrmr.Context, in chan *lrdd.Row, emit func(*lrdd.Row)) error {
+	for range in {
+	}
+	<-ctx.Done()
+	CanceledTasks.Inc()
+	return ctx.Err()
+}
+
+func NeverEndingJob(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize([]int{1, 2, 3, 4}).
+		Do(&NeverEnding{})
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCancelJob(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When a running job is canceled", func() {
+			CanceledTasks.Store(0)
+			j, err := NeverEndingJob(cluster.Session).Run()
+			So(err, ShouldBeNil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			So(j.Cancel(ctx), ShouldBeNil)
+
+			Convey("It should stop the job", func() {
+				status, err := j.Master.JobManager.GetJobStatus(ctx, j.ID)
+				So(err, ShouldBeNil)
+				So(status.Status, ShouldEqual, job.Failed)
+				So(status.Errors[0].Message, ShouldEqual, "job canceled")
+			})
+
+			Convey("Its tasks should observe the cancellation", func() {
+				deadline := time.Now().Add(3 * time.Second)
+				for CanceledTasks.Load() == 0 && time.Now().Before(deadline) {
+					time.Sleep(10 * time.Millisecond)
+				}
+				So(CanceledTasks.Load(), ShouldBeGreaterThan, 0)
+			})
+		})
+
+		Convey("When the jobs of a session are canceled", func() {
+			_, err := NeverEndingJob(cluster.Session).Run()
+			So(err, ShouldBeNil)
+
+			Convey("It should cancel all of them", func() {
+				So(cluster.Session.Cancel(), ShouldBeNil)
+			})
+		})
+
+		Convey("When a completed job is canceled", func() {
+			j, err := Map(cluster.Session).Run()
+			So(err, ShouldBeNil)
+			So(j.Wait(), ShouldBeNil)
+
+			Convey("It should do nothing", func() {
+				So(j.Cancel(context.Background()), ShouldBeNil)
+			})
+		})
+	}))
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"path/filepath"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+)
+
+var _ = lrmr.RegisterTypes(&FailUntilRecovered{})
+
+// DownstreamRecovered makes FailUntilRecovered succeed.
+var DownstreamRecovered atomic.Bool
+
+// FailUntilRecovered fails until DownstreamRecovered is set, e.g. as if a worker has crashed.
+type FailUntilRecovered struct{}
+
+func (f *FailUntilRecovered) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	if !DownstreamRecovered.Load() {
+		return nil, errors.New("downstream failure")
+	}
+	return row, nil
+}
+
+func CheckpointedJob(sess *lrmr.Session, id, dir string, opts ...lrmr.CheckpointOption) *lrmr.Dataset {
+	return ExpensiveUpstream(sess).
+		Checkpoint(id, dir, opts...).
+		Map(&FailUntilRecovered{})
+}
+
+// CheckpointedTextFileJob checkpoints the lines of the text files under inputDir, whose content is not
+// a part of the plan, unlike Parallelize.
+func CheckpointedTextFileJob(sess *lrmr.Session, inputDir, id, dir string) *lrmr.Dataset {
+	return sess.TextFileInput(filepath.Join(inputDir, "*.txt"), lrmr.WithLineField("text")).
+		Checkpoint(id, dir).
+		Map(&FailUntilRecovered{})
+}
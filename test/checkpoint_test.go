@@ -0,0 +1,122 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckpoint(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		dir, err := ioutil.TempDir("", "lrmr-checkpoint")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		MultiplyCalls.Store(0)
+		DownstreamRecovered.Store(false)
+
+		Convey("When a stage after the checkpoint fails", func() {
+			_, err := CheckpointedJob(cluster.Session, "run-1", dir).Collect()
+			So(err, ShouldNotBeNil)
+			So(MultiplyCalls.Load(), ShouldEqual, 5)
+
+			Convey("Rerunning the job should resume from the checkpoint", func() {
+				DownstreamRecovered.Store(true)
+
+				rows, err := CheckpointedJob(cluster.Session, "run-1", dir).Collect()
+				So(err, ShouldBeNil)
+				var values []int
+				for _, row := range rows {
+					values = append(values, testutils.IntValue(row))
+				}
+				sort.Ints(values)
+				So(values, ShouldResemble, []int{2, 4, 6, 8, 10})
+				So(MultiplyCalls.Load(), ShouldEqual, 5)
+
+				Convey("The checkpoint should be deleted after the job succeeds", func() {
+					rows, err := CheckpointedJob(cluster.Session, "run-1", dir).Collect()
+					So(err, ShouldBeNil)
+					So(rows, ShouldHaveLength, 5)
+					So(MultiplyCalls.Load(), ShouldEqual, 10)
+				})
+			})
+
+			Convey("Running the job with another checkpoint ID should recompute the upstream", func() {
+				DownstreamRecovered.Store(true)
+
+				rows, err := CheckpointedJob(cluster.Session, "run-2", dir).Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 5)
+				So(MultiplyCalls.Load(), ShouldEqual, 10)
+			})
+		})
+
+		Convey("When a stage after the checkpoint with a TTL fails", func() {
+			_, err := CheckpointedJob(cluster.Session, "run-1", dir, lrmr.WithCheckpointTTL(2*time.Second)).Collect()
+			So(err, ShouldNotBeNil)
+			So(MultiplyCalls.Load(), ShouldEqual, 5)
+
+			Convey("Rerunning the job after the TTL should recompute the upstream", func() {
+				DownstreamRecovered.Store(true)
+				time.Sleep(4 * time.Second)
+
+				rows, err := CheckpointedJob(cluster.Session, "run-1", dir).Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 5)
+				So(MultiplyCalls.Load(), ShouldEqual, 10)
+			})
+		})
+
+		Convey("Given input files changing between the runs", func() {
+			inputDir, err := ioutil.TempDir("", "lrmr-checkpoint-input")
+			So(err, ShouldBeNil)
+			Reset(func() { os.RemoveAll(inputDir) })
+			writeLines := func(content string) {
+				So(ioutil.WriteFile(filepath.Join(inputDir, "lines.txt"), []byte(content), 0644), ShouldBeNil)
+			}
+			writeLines("a\nb\n")
+
+			_, err = CheckpointedTextFileJob(cluster.Session, inputDir, "run-1", dir).Collect()
+			So(err, ShouldNotBeNil)
+			writeLines("c\n")
+			DownstreamRecovered.Store(true)
+
+			Convey("Rerunning the job with the same checkpoint ID should read the checkpointed input", func() {
+				rows, err := CheckpointedTextFileJob(cluster.Session, inputDir, "run-1", dir).Collect()
+				So(err, ShouldBeNil)
+				So(linesOf(rows), ShouldResemble, []string{"a", "b"})
+
+				Convey("And the next run should read the changed input", func() {
+					rows, err := CheckpointedTextFileJob(cluster.Session, inputDir, "run-1", dir).Collect()
+					So(err, ShouldBeNil)
+					So(linesOf(rows), ShouldResemble, []string{"c"})
+				})
+			})
+
+			Convey("Running the job with another checkpoint ID should read the changed input", func() {
+				rows, err := CheckpointedTextFileJob(cluster.Session, inputDir, "run-2", dir).Collect()
+				So(err, ShouldBeNil)
+				So(linesOf(rows), ShouldResemble, []string{"c"})
+			})
+		})
+	}))
+}
+
+func linesOf(rows []*lrdd.Row) (lines []string) {
+	for _, row := range rows {
+		var v map[string]interface{}
+		So(row.DecodeValue(&v), ShouldBeNil)
+		lines = append(lines, v["text"].(string))
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"fmt"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&keyByPartition{}, &keyByMod{})
+
+// keyByPartition keys each row by the ID of the partition having it.
+type keyByPartition struct{}
+
+func (k *keyByPartition) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	return &lrdd.Row{Key: ctx.PartitionID(), Value: row.Value}, nil
+}
+
+// keyByMod keys each number by the remainder of dividing it by Mod.
+type keyByMod struct {
+	Mod int
+}
+
+func (k *keyByMod) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	var n int
+	row.UnmarshalValue(&n)
+	return &lrdd.Row{Key: fmt.Sprint(n % k.Mod), Value: row.Value}, nil
+}
+
+func Coalesce(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 1000)
+	for i := range data {
+		data[i] = i
+	}
+	return sess.Parallelize(data).
+		Repartition(8).
+		Map(&Multiply{}).
+		Coalesce(2).
+		Map(&keyByPartition{})
+}
+
+func RepartitionByKey(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 1000)
+	for i := range data {
+		data[i] = i
+	}
+	return sess.Parallelize(data).
+		Map(&keyByMod{Mod: 10}).
+		RepartitionByKey(3).
+		Map(&keyByPartition{})
+}
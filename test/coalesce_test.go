@@ -0,0 +1,63 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCoalesce(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When coalescing partitions", func() {
+			dag, err := cluster.Session.Explain(Coalesce(cluster.Session))
+			So(err, ShouldBeNil)
+
+			Convey("The stages after it should have the coalesced number of partitions", func() {
+				So(dag.Stages, ShouldHaveLength, 3)
+				So(dag.Stages[1].Partitions, ShouldEqual, 8)
+				So(dag.Stages[2].Partitions, ShouldEqual, 2)
+			})
+
+			Convey("Every row should be emitted", func() {
+				rows, err := Coalesce(cluster.Session).Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1000)
+			})
+		})
+	}))
+
+	Convey("Given a session without a cluster", t, func() {
+		sess := lrmr.NewSession(context.Background(), nil)
+
+		Convey("Coalescing should merge the rows into the given number of partitions", func() {
+			rows, err := Coalesce(sess).RunLocal(8)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 1000)
+			So(testutils.GroupRowsByKey(rows), ShouldHaveLength, 2)
+		})
+
+		Convey("Repartitioning by key should send the rows of a key to the same partition", func() {
+			rows, err := RepartitionByKey(sess).RunLocal(8)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 1000)
+
+			byPartition := testutils.GroupRowsByKey(rows)
+			So(len(byPartition), ShouldBeLessThanOrEqualTo, 3)
+
+			partitionOf := make(map[int]string)
+			for id, partitionRows := range byPartition {
+				for _, row := range partitionRows {
+					mod := testutils.IntValue(row) % 10
+					if prev, ok := partitionOf[mod]; ok {
+						So(prev, ShouldEqual, id)
+					}
+					partitionOf[mod] = id
+				}
+			}
+		})
+	})
+}
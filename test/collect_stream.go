@@ -0,0 +1,14 @@
+package test
+
+import (
+	"github.com/ab180/lrmr/lrdd"
+)
+
+// DrainStream receives every row and the error from the channels returned by CollectStream.
+func DrainStream(rows <-chan *lrdd.Row, errs <-chan error) ([]*lrdd.Row, error) {
+	var collected []*lrdd.Row
+	for row := range rows {
+		collected = append(collected, row)
+	}
+	return collected, <-errs
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCollectStream(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When collecting a dataset with a stream", func() {
+			rows, err := DrainStream(Map(cluster.Session).CollectStream(context.Background()))
+
+			Convey("It should receive every row", func() {
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1000)
+			})
+		})
+
+		Convey("When a task of the streamed job fails", func() {
+			_, err := DrainStream(FailingJob(cluster.Session).CollectStream(context.Background()))
+
+			Convey("It should receive the error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When the stream is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			rows, errs := NeverEndingJob(cluster.Session).CollectStream(ctx)
+			time.Sleep(500 * time.Millisecond)
+			cancel()
+
+			Convey("It should stop the job and close the channels", func() {
+				_, err := DrainStream(rows, errs)
+				So(err, ShouldEqual, context.Canceled)
+			})
+		})
+	}))
+}
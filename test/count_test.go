@@ -0,0 +1,34 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCount(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When counting rows of a dataset", func() {
+			n, err := Map(cluster.Session).Count()
+			So(err, ShouldBeNil)
+
+			Convey("It should match the number of the collected rows", func() {
+				rows, err := Map(cluster.Session).Collect()
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, int64(len(rows)))
+			})
+		})
+
+		Convey("When counting rows of a shuffled dataset", func() {
+			n, err := SimpleCount(cluster.Session).Count()
+			So(err, ShouldBeNil)
+
+			Convey("It should match the number of the collected rows", func() {
+				rows, err := SimpleCount(cluster.Session).Collect()
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, int64(len(rows)))
+			})
+		})
+	}))
+}
@@ -0,0 +1,40 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&FieldKeyer{})
+
+// FieldKeyer keys a row by the value of a field.
+type FieldKeyer struct {
+	Field string
+}
+
+func (f *FieldKeyer) RowKey(row *lrdd.Row) string {
+	v, _ := row.GetString(f.Field)
+	return v
+}
+
+func duplicatedUsers() []map[string]interface{} {
+	var users []map[string]interface{}
+	for i := 0; i < 3; i++ {
+		users = append(users,
+			map[string]interface{}{"name": "alice", "country": "KR"},
+			map[string]interface{}{"name": "bob", "country": "KR"},
+			map[string]interface{}{"name": "carol", "country": "US"},
+		)
+	}
+	return users
+}
+
+func DistinctUsers(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(duplicatedUsers()).
+		Distinct()
+}
+
+func DistinctCountries(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(duplicatedUsers()).
+		DistinctBy(&FieldKeyer{Field: "country"})
+}
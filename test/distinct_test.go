@@ -0,0 +1,47 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDistinct(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When removing duplicated rows", func() {
+			rows, err := DistinctUsers(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should emit each unique row once", func() {
+				So(rows, ShouldHaveLength, 3)
+
+				var names []string
+				for _, row := range rows {
+					name, _ := row.GetString("name")
+					names = append(names, name)
+				}
+				So(names, ShouldContain, "alice")
+				So(names, ShouldContain, "bob")
+				So(names, ShouldContain, "carol")
+			})
+		})
+
+		Convey("When removing rows duplicated in a field", func() {
+			rows, err := DistinctCountries(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should emit a row for each value of the field", func() {
+				So(rows, ShouldHaveLength, 2)
+
+				var countries []string
+				for _, row := range rows {
+					country, _ := row.GetString("country")
+					countries = append(countries, country)
+				}
+				So(countries, ShouldContain, "KR")
+				So(countries, ShouldContain, "US")
+			})
+		})
+	}))
+}
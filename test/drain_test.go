@@ -0,0 +1,38 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDrainWorkers(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When workers are drained while a task runs longer than the grace period", func() {
+			j, err := StuckJob(cluster.Session, 0).Run()
+			So(err, ShouldBeNil)
+			time.Sleep(500 * time.Millisecond)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			So(cluster.DrainWorkers(ctx), ShouldNotBeNil)
+
+			Convey("It should abort the remaining tasks", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				err := j.WaitWithContext(ctx)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "shut down before the task finished")
+			})
+
+			Convey("It should reject new tasks", func() {
+				_, err := StuckJob(cluster.Session, 0).Run()
+				So(err, ShouldNotBeNil)
+			})
+		})
+	}))
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrorKind(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When a transformation panics", func() {
+			_, err := FailingJob(cluster.Session).Collect()
+
+			Convey("It should fail with a UserError", func() {
+				So(err, ShouldNotBeNil)
+
+				var userErr *job.UserError
+				So(errors.As(err, &userErr), ShouldBeTrue)
+
+				var transportErr *job.TransportError
+				So(errors.As(err, &transportErr), ShouldBeFalse)
+			})
+
+			Convey("It should carry the stack of the panic", func() {
+				var jobErr job.Error
+				So(errors.As(err, &jobErr), ShouldBeTrue)
+				So(jobErr.Message, ShouldEqual, "panic: station")
+				So(jobErr.PanicStack, ShouldContainSubstring, "FailingStage")
+				So(fmt.Sprintf("%+v", err), ShouldContainSubstring, jobErr.PanicStack)
+			})
+		})
+
+		Convey("When a task runs longer than its timeout", func() {
+			j, err := StuckJob(cluster.Session, 500*time.Millisecond).Run()
+			So(err, ShouldBeNil)
+
+			Convey("It should fail with a TimeoutError", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				err := j.WaitWithContext(ctx)
+				So(err, ShouldNotBeNil)
+
+				var timeoutErr *job.TimeoutError
+				So(errors.As(err, &timeoutErr), ShouldBeTrue)
+				So(timeoutErr.Error(), ShouldContainSubstring, "task timed out")
+			})
+		})
+	}))
+}
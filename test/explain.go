@@ -0,0 +1,20 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+)
+
+// ShufflingJob multiplies input and counts them by key, which shuffles the rows between the stages.
+func ShufflingJob(sess *lrmr.Session) *lrmr.Dataset {
+	return ExpensiveUpstream(sess).
+		GroupByKey().
+		Reduce(Count())
+}
+
+// ChainedCount counts the rows by key twice, where the second count doesn't need a shuffle
+// since the keys are already partitioned by the first one.
+func ChainedCount(sess *lrmr.Session) *lrmr.Dataset {
+	return SimpleCount(sess).
+		GroupByKey().
+		Reduce(Count())
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExplain(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		MultiplyCalls.Store(0)
+
+		Convey("When explaining a job", func() {
+			ds := ShufflingJob(cluster.Session)
+			dag, err := cluster.Session.Explain(ds)
+			So(err, ShouldBeNil)
+
+			Convey("It should not run the job", func() {
+				So(MultiplyCalls.Load(), ShouldEqual, 0)
+			})
+
+			Convey("It should plan the stages with their partitioners", func() {
+				So(dag.Input, ShouldEqual, "parallelizedInput")
+				So(dag.Stages, ShouldHaveLength, 3)
+				So(dag.Stages[0].Partitions, ShouldEqual, 1)
+				So(dag.Stages[1].Partitions, ShouldBeGreaterThan, 0)
+				So(dag.Stages[1].Nodes, ShouldEqual, 2)
+				So(dag.Stages[1].OutputPartitioner, ShouldEqual, "hashKeyPartitioner")
+				So(dag.Stages[1].Shuffle, ShouldBeTrue)
+				So(dag.Stages[2].OutputPartitioner, ShouldBeEmpty)
+			})
+
+			Convey("It should be encoded into JSON", func() {
+				data, err := json.Marshal(dag)
+				So(err, ShouldBeNil)
+
+				var decoded lrmr.DAG
+				So(json.Unmarshal(data, &decoded), ShouldBeNil)
+				So(&decoded, ShouldResemble, dag)
+			})
+
+			Convey("It should be rendered in DOT", func() {
+				dot, err := cluster.Session.ExplainDAG(ds)
+				So(err, ShouldBeNil)
+				So(dot, ShouldStartWith, "digraph")
+				for _, st := range dag.Stages {
+					So(dot, ShouldContainSubstring, st.Name)
+				}
+				So(dot, ShouldContainSubstring, `label="hashKeyPartitioner", style=bold`)
+			})
+
+			Convey("The dataset should still be runnable", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldNotBeEmpty)
+			})
+		})
+
+		Convey("When explaining consecutive stages partitioned by the same partitioner", func() {
+			ds := ChainedCount(cluster.Session)
+			dag, err := cluster.Session.Explain(ds)
+			So(err, ShouldBeNil)
+
+			Convey("It should not plan a shuffle between the stages", func() {
+				So(dag.Stages, ShouldHaveLength, 3)
+				So(dag.Stages[0].Shuffle, ShouldBeTrue)
+				So(dag.Stages[1].Shuffle, ShouldBeFalse)
+				So(dag.Stages[1].OutputPartitioner, ShouldEqual, "PreservePartitioner")
+			})
+
+			Convey("It should produce the same result as the shuffle", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 2)
+				for _, row := range rows {
+					So(testutils.IntValue(row), ShouldEqual, 1)
+				}
+			})
+		})
+	}))
+}
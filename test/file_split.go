@@ -0,0 +1,22 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&tagPartition{})
+
+// tagPartition keys the path of each file by the ID of the partition reading it.
+type tagPartition struct{}
+
+func (t *tagPartition) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	var path string
+	row.UnmarshalValue(&path)
+	return lrdd.KeyValue(ctx.PartitionID(), path), nil
+}
+
+func FileSplit(sess *lrmr.Session, dir string, split lrmr.FileSplitStrategy) *lrmr.Dataset {
+	return sess.FromFile(dir, lrmr.WithInputPartitions(4), lrmr.WithFileSplit(split)).
+		Map(&tagPartition{})
+}
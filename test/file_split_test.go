@@ -0,0 +1,60 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFileSplit(t *testing.T) {
+	Convey("Given a directory with files", t, func() {
+		dir, err := ioutil.TempDir("", "lrmr-file-split")
+		So(err, ShouldBeNil)
+		Reset(func() { _ = os.RemoveAll(dir) })
+
+		for i := 0; i < 20; i++ {
+			err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%02d.txt", i)), []byte("foo"), 0644)
+			So(err, ShouldBeNil)
+		}
+		sess := lrmr.NewSession(context.Background(), nil)
+
+		assign := func(split lrmr.FileSplitStrategy) map[string]string {
+			rows, err := FileSplit(sess, dir, split).RunLocal(2)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 20)
+
+			partitionOf := make(map[string]string)
+			for _, row := range rows {
+				var path string
+				row.UnmarshalValue(&path)
+				partitionOf[path] = row.Key
+			}
+			return partitionOf
+		}
+
+		for _, split := range []lrmr.FileSplitStrategy{lrmr.SplitRoundRobin, lrmr.SplitByPathHash} {
+			Convey(fmt.Sprintf("With strategy %d, files should be assigned to the same partitions across runs", split), func() {
+				first := assign(split)
+				So(assign(split), ShouldResemble, first)
+
+				for _, id := range first {
+					So(id, ShouldBeIn, "0", "1", "2", "3")
+				}
+			})
+		}
+
+		Convey("With SplitRoundRobin, files should be balanced across partitions", func() {
+			counts := make(map[string]int)
+			for _, id := range assign(lrmr.SplitRoundRobin) {
+				counts[id]++
+			}
+			So(counts, ShouldResemble, map[string]int{"0": 5, "1": 5, "2": 5, "3": 5})
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+// WriteNumberFiles writes numbers from 0 to n-1, spread across the partitions, to files under given directory.
+func WriteNumberFiles(sess *lrmr.Session, dir string, n int, opts ...lrmr.FileOption) ([]string, error) {
+	nums := make([]int, n)
+	for i := range nums {
+		nums[i] = i
+	}
+	return sess.Parallelize(nums).
+		Map(&PassThrough{}).
+		Repartition(4).
+		WriteFiles(dir, opts...)
+}
+
+// ReadRowFiles reads the rows in the files written by WriteFiles.
+func ReadRowFiles(paths []string) (rows []*lrdd.Row, err error) {
+	for _, path := range paths {
+		f, err := lrmr.OpenFile(path)
+		if err != nil {
+			return nil, err
+		}
+		next, decodeErr := lrdd.DecodeRows(f)
+		for row, ok := next(); ok; row, ok = next() {
+			rows = append(rows, row)
+		}
+		f.Close()
+		if err := decodeErr(); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
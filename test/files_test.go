@@ -0,0 +1,104 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteFiles(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		dir, err := ioutil.TempDir("", "lrmr-files")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		Convey("When writing rows to files", func() {
+			paths, err := WriteNumberFiles(cluster.Session, dir, 1000)
+			So(err, ShouldBeNil)
+
+			Convey("It should return the path of the file of each partition", func() {
+				So(paths, ShouldNotBeEmpty)
+				for _, path := range paths {
+					So(filepath.Dir(path), ShouldEqual, dir)
+					So(filepath.Base(path), ShouldStartWith, "part-")
+				}
+			})
+
+			Convey("It should leave no temporary files", func() {
+				written, err := filepath.Glob(filepath.Join(dir, "*"))
+				So(err, ShouldBeNil)
+
+				var expected []string
+				for _, path := range paths {
+					expected = append(expected, path, lrmr.ManifestPathOf(path))
+				}
+				sort.Strings(written)
+				sort.Strings(expected)
+				So(written, ShouldResemble, expected)
+			})
+
+			Convey("The manifests should record no compression by default", func() {
+				rows := 0
+				for _, path := range paths {
+					m, err := lrmr.ReadFileManifest(path)
+					So(err, ShouldBeNil)
+					So(m.Compression, ShouldEqual, output.NoCompression)
+					rows += m.Rows
+				}
+				So(rows, ShouldEqual, 1000)
+			})
+
+			Convey("The files should contain every row", func() {
+				rows, err := ReadRowFiles(paths)
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1000)
+
+				seen := make(map[int]bool)
+				for _, row := range rows {
+					var n int
+					So(row.DecodeValue(&n), ShouldBeNil)
+					seen[n] = true
+				}
+				So(seen, ShouldHaveLength, 1000)
+			})
+		})
+
+		Convey("When writing rows to files with a compression", func() {
+			paths, err := WriteNumberFiles(cluster.Session, dir, 1000, lrmr.WithFileCompression(output.Zstd, 19))
+			So(err, ShouldBeNil)
+
+			Convey("The manifests should record the compression", func() {
+				for _, path := range paths {
+					m, err := lrmr.ReadFileManifest(path)
+					So(err, ShouldBeNil)
+					So(m.Compression, ShouldEqual, output.Zstd)
+					So(m.CompressionLevel, ShouldEqual, 19)
+				}
+			})
+
+			Convey("The files should be read back by their manifests", func() {
+				rows, err := ReadRowFiles(paths)
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1000)
+			})
+		})
+
+		Convey("When writing rows to files with an unsupported compression level", func() {
+			_, err := WriteNumberFiles(cluster.Session, dir, 1000, lrmr.WithFileCompression(output.Snappy, 3))
+
+			Convey("It should fail before running the job", func() {
+				So(err, ShouldBeError)
+				written, err := filepath.Glob(filepath.Join(dir, "*"))
+				So(err, ShouldBeNil)
+				So(written, ShouldBeEmpty)
+			})
+		})
+	}))
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/testutils"
+)
+
+var _ = lrmr.RegisterFuncs(IsMultipleOfThree)
+
+// IsMultipleOfThree keeps the multiples of three.
+func IsMultipleOfThree(row *lrdd.Row) bool {
+	return testutils.IntValue(row)%3 == 0
+}
+
+func FilterFunc(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 100)
+	for i := range data {
+		data[i] = i + 1
+	}
+	return sess.Parallelize(data).
+		MapFunc(DoubleOdd).
+		FilterFunc(IsMultipleOfThree)
+}
+
+// RepartitionedFilterFunc repartitions the mapped rows before filtering them.
+func RepartitionedFilterFunc(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 100)
+	for i := range data {
+		data[i] = i + 1
+	}
+	return sess.Parallelize(data).
+		MapFunc(DoubleOdd).
+		Repartition(8).
+		FilterFunc(IsMultipleOfThree)
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFilterFunc(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When filtering rows with a registered function", func() {
+			rows, err := FilterFunc(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("Only the rows satisfying the function should be kept", func() {
+				So(rows, ShouldHaveLength, 17)
+				for _, row := range rows {
+					So(testutils.IntValue(row)%6, ShouldEqual, 0)
+				}
+			})
+		})
+
+		Convey("When explaining the filter", func() {
+			dag, err := cluster.Session.Explain(FilterFunc(cluster.Session))
+			So(err, ShouldBeNil)
+
+			Convey("It should not plan a shuffle before the filter", func() {
+				So(dag.Stages, ShouldHaveLength, 3)
+				So(dag.Stages[1].Shuffle, ShouldBeFalse)
+			})
+		})
+
+		Convey("When filtering repartitioned rows", func() {
+			dag, err := cluster.Session.Explain(RepartitionedFilterFunc(cluster.Session))
+			So(err, ShouldBeNil)
+
+			Convey("It should shuffle the rows into the partitions of the filter", func() {
+				So(dag.Stages, ShouldHaveLength, 3)
+				So(dag.Stages[1].Shuffle, ShouldBeTrue)
+				So(dag.Stages[2].Partitions, ShouldEqual, 8)
+			})
+
+			Convey("Only the rows satisfying the function should be kept", func() {
+				rows, err := RepartitionedFilterFunc(cluster.Session).Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 17)
+			})
+		})
+	}))
+
+	Convey("Given a session without a cluster", t, func() {
+		sess := lrmr.NewSession(context.Background(), nil)
+
+		Convey("Filtering rows with a registered function should work locally", func() {
+			rows, err := FilterFunc(sess).RunLocal(2)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 17)
+		})
+	})
+}
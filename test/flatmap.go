@@ -6,7 +6,7 @@ import (
 	"github.com/ab180/lrmr/test/testutils"
 )
 
-var _ = lrmr.RegisterTypes(&MultiplyAndDouble{})
+var _ = lrmr.RegisterTypes(&MultiplyAndDouble{}, &DropOdd{})
 
 // MultiplyAndDouble doubles number of inputs each multiplied by 2.
 type MultiplyAndDouble struct{}
@@ -26,3 +26,21 @@ func FlatMap(sess *lrmr.Session) *lrmr.Dataset {
 		FlatMap(&MultiplyAndDouble{}).
 		FlatMap(&MultiplyAndDouble{})
 }
+
+// DropOdd emits only even inputs, dropping odd ones by returning no rows.
+type DropOdd struct{}
+
+func (d *DropOdd) FlatMap(ctx lrmr.Context, row *lrdd.Row) ([]*lrdd.Row, error) {
+	if n := testutils.IntValue(row); n%2 != 0 {
+		return nil, nil
+	}
+	return []*lrdd.Row{row}, nil
+}
+
+func FlatMapDroppingRows(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 1000)
+	for i := 0; i < len(data); i++ {
+		data[i] = i + 1
+	}
+	return sess.Parallelize(data).FlatMap(&DropOdd{})
+}
@@ -28,5 +28,18 @@ func TestFlatMap(t *testing.T) {
 				So(max, ShouldEqual, 8000)
 			})
 		})
+
+		Convey("When FlatMapper returns no rows", func() {
+			ds := FlatMapDroppingRows(cluster.Session)
+
+			Convey("It should drop the input rows", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 500)
+				for _, row := range rows {
+					So(testutils.IntValue(row)%2, ShouldEqual, 0)
+				}
+			})
+		})
 	}))
 }
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"strconv"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&One{}, &Sum{})
+
+// One replaces the value of the row with 1.
+type One struct{}
+
+func (o *One) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	return lrdd.KeyValue(row.Key, 1), nil
+}
+
+// Sum folds integer values by adding them up.
+type Sum struct{}
+
+func (s *Sum) Fold(ctx lrmr.Context, acc, cur *lrdd.Row) (*lrdd.Row, error) {
+	var a, b int
+	acc.UnmarshalValue(&a)
+	cur.UnmarshalValue(&b)
+	return lrdd.KeyValue(acc.Key, a+b), nil
+}
+
+// CountByFold counts 1000 rows evenly distributed over 10 keys.
+func CountByFold(sess *lrmr.Session, combine bool) *lrmr.Dataset {
+	d := make(map[string][]int)
+	for i := 0; i < 1000; i++ {
+		key := "k" + strconv.Itoa(1+i%10)
+		d[key] = append(d[key], i)
+	}
+	ds := sess.Parallelize(d).
+		Map(&One{})
+
+	if combine {
+		return ds.FoldWithCombiner(&Sum{})
+	}
+	return ds.Fold(&Sum{})
+}
@@ -0,0 +1,45 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFold(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When folding rows with a combiner", func() {
+			Convey("It should result the same as folding without the combiner", func() {
+				combined, err := CountByFold(cluster.Session, true).Collect()
+				So(err, ShouldBeNil)
+				rows, err := CountByFold(cluster.Session, false).Collect()
+				So(err, ShouldBeNil)
+
+				expected := testutils.GroupRowsByKey(rows)
+				actual := testutils.GroupRowsByKey(combined)
+				So(actual, ShouldHaveLength, 10)
+				So(actual, ShouldHaveLength, len(expected))
+				for key, rows := range expected {
+					So(actual[key], ShouldHaveLength, 1)
+					So(testutils.IntValue(actual[key][0]), ShouldEqual, testutils.IntValue(rows[0]))
+				}
+				So(testutils.IntValue(actual["k1"][0]), ShouldEqual, 100)
+			})
+
+			Convey("It should shuffle fewer rows than its input", func() {
+				j, err := CountByFold(cluster.Session, true).Run()
+				So(err, ShouldBeNil)
+				So(j.Wait(), ShouldBeNil)
+
+				stats, err := j.StageStats()
+				So(err, ShouldBeNil)
+
+				combiner := stats[j.Stages[2].Name]
+				So(combiner.InputRows, ShouldEqual, 1000)
+				So(combiner.OutputRows, ShouldBeLessThan, combiner.InputRows)
+			})
+		})
+	}))
+}
@@ -0,0 +1,32 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/testutils"
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+)
+
+var _ = lrmr.RegisterTypes(&CountSideEffects{})
+
+// SideEffects is the number of rows visited by CountSideEffects.
+var SideEffects atomic.Int64
+
+// CountSideEffects counts the rows it visited, and fails on the row with FailOn if it's nonzero.
+type CountSideEffects struct {
+	FailOn int
+}
+
+func (c *CountSideEffects) Foreach(ctx lrmr.Context, row *lrdd.Row) error {
+	if n := testutils.IntValue(row); c.FailOn != 0 && n == c.FailOn {
+		return errors.Errorf("side effect failed on %d", n)
+	}
+	SideEffects.Inc()
+	return nil
+}
+
+func ForeachJob(sess *lrmr.Session, failOn int) error {
+	return sess.Parallelize(limitData(1000)).
+		Foreach(&CountSideEffects{FailOn: failOn})
+}
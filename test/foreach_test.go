@@ -0,0 +1,31 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestForeach(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When running Foreach", func() {
+			SideEffects.Store(0)
+			err := ForeachJob(cluster.Session, 0)
+
+			Convey("It should visit every row", func() {
+				So(err, ShouldBeNil)
+				So(SideEffects.Load(), ShouldEqual, 1000)
+			})
+		})
+
+		Convey("When the side effect fails", func() {
+			err := ForeachJob(cluster.Session, 500)
+
+			Convey("It should fail the job with the error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "side effect failed on 500")
+			})
+		})
+	}))
+}
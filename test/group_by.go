@@ -2,9 +2,12 @@ package test
 
 import (
 	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
 	"github.com/ab180/lrmr/test/testdata"
 )
 
+var _ = lrmr.RegisterTypes(&GroupSize{})
+
 func BasicGroupByKey(sess *lrmr.Session) *lrmr.Dataset {
 	return sess.FromFile(testdata.Path()).
 		FlatMap(DecodeJSON()).
@@ -28,3 +31,19 @@ func SimpleCount(sess *lrmr.Session) *lrmr.Dataset {
 		GroupByKey().
 		Reduce(Count())
 }
+
+// GroupSize emits the number of rows in each group.
+type GroupSize struct{}
+
+func (g *GroupSize) MapGroup(ctx lrmr.Context, key string, rows []*lrdd.Row) ([]*lrdd.Row, error) {
+	return []*lrdd.Row{lrdd.KeyValue(key, len(rows))}, nil
+}
+
+func GroupSizes(sess *lrmr.Session) *lrmr.Dataset {
+	d := map[string][]string{
+		"foo": {"goo", "hoo"},
+		"bar": {"baz"},
+	}
+	return sess.Parallelize(d).
+		MapGroups(&GroupSize{})
+}
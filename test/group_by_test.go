@@ -92,3 +92,22 @@ func TestSimpleCount_WithCollect(t *testing.T) {
 		})
 	}))
 }
+
+func TestMapGroups(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When mapping groups of rows", func() {
+			rows, err := GroupSizes(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should call the mapper once with all rows of each key", func() {
+				res := testutils.GroupRowsByKey(rows)
+				So(res, ShouldHaveLength, 2)
+				So(res["foo"], ShouldHaveLength, 1)
+				So(res["bar"], ShouldHaveLength, 1)
+
+				So(testutils.IntValue(res["foo"][0]), ShouldEqual, 2)
+				So(testutils.IntValue(res["bar"][0]), ShouldEqual, 1)
+			})
+		})
+	}))
+}
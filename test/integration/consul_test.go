@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/thoas/go-funk"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestConsul_Counter(t *testing.T) {
+	RunOnConsul(t)
+	Convey("Given a consul cluster", t, WithConsul(func(consul coordinator.Coordinator) {
+		n := 100
+		var counterRecords sync.Map
+
+		Convey("Calling count() with a race condition", func(c C) {
+			wg, wctx := errgroup.WithContext(testutils.ContextWithTimeout())
+			for i := 0; i < n; i++ {
+				wg.Go(func() error {
+					count, err := consul.IncrementCounter(wctx, "counter")
+					if err != nil {
+						return err
+					}
+					if _, duplicated := counterRecords.LoadOrStore(count, true); duplicated {
+						return fmt.Errorf("number %d is duplicated", count)
+					}
+					return nil
+				})
+			}
+			err := wg.Wait()
+			So(err, ShouldBeNil)
+
+			Convey("Should increment counter correctly", func() {
+				counter, err := consul.ReadCounter(testutils.ContextWithTimeout(), "counter")
+				So(err, ShouldBeNil)
+				So(counter, ShouldEqual, n)
+			})
+		})
+	}))
+}
+
+func TestConsul_Transaction(t *testing.T) {
+	RunOnConsul(t)
+	Convey("Given a consul cluster", t, WithConsul(func(consul coordinator.Coordinator) {
+		n := 20
+		m := 10
+		var duplicateCounts sync.Map
+
+		Convey("Calling count() within a transaction with race condition", func(c C) {
+			wg, wctx := errgroup.WithContext(testutils.ContextWithTimeout())
+			for i := 0; i < n; i++ {
+				wg.Go(func() error {
+					for j := 0; j < m; j++ {
+						txnResults, err := consul.Commit(wctx, coordinator.NewTxn().
+							Put("foo", "bar").
+							IncrementCounter("counter1").
+							IncrementCounter("counter2"))
+
+						if err != nil {
+							return err
+						}
+						if _, duplicated := duplicateCounts.LoadOrStore(txnResults[1].Counter, true); duplicated {
+							return fmt.Errorf("counter1: number %d is duplicated", txnResults[1].Counter)
+						}
+					}
+					return nil
+				})
+			}
+			err := wg.Wait()
+			So(err, ShouldBeNil)
+
+			Convey("Should increment counter correctly", func() {
+				counter, err := consul.ReadCounter(testutils.ContextWithTimeout(), "counter1")
+				So(err, ShouldBeNil)
+				So(counter, ShouldEqual, n*m)
+
+				counter, err = consul.ReadCounter(testutils.ContextWithTimeout(), "counter2")
+				So(err, ShouldBeNil)
+				So(counter, ShouldEqual, n*m)
+			})
+		})
+	}))
+}
+
+func TestConsul_Watch(t *testing.T) {
+	RunOnConsul(t)
+	Convey("Given a consul cluster", t, WithConsul(func(consul coordinator.Coordinator) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		Convey("Watching keys with a prefix", func() {
+			events, err := consul.Watch(ctx, "watched/")
+			So(err, ShouldBeNil)
+
+			So(consul.Put(ctx, "watched/foo", "bar"), ShouldBeNil)
+			So(consul.Put(ctx, "unwatched/foo", "bar"), ShouldBeNil)
+			_, err = consul.IncrementCounter(ctx, "watched/counter")
+			So(err, ShouldBeNil)
+
+			Convey("Should receive the events of the keys", func() {
+				var put, counter WatchEventSummary
+				for put.Key == "" || counter.Key == "" {
+					e := <-events
+					switch e.Type {
+					case coordinator.PutEvent:
+						put = WatchEventSummary{Key: e.Item.Key, Value: string(e.Item.Value)}
+					case coordinator.CounterEvent:
+						counter = WatchEventSummary{Key: e.Item.Key, Value: fmt.Sprint(e.Counter)}
+					}
+				}
+				So(put, ShouldResemble, WatchEventSummary{Key: "watched/foo", Value: `"bar"`})
+				So(counter, ShouldResemble, WatchEventSummary{Key: "watched/counter", Value: "1"})
+
+				_, err := consul.Delete(ctx, "watched/foo")
+				So(err, ShouldBeNil)
+
+				e := <-events
+				So(e.Type, ShouldEqual, coordinator.DeleteEvent)
+				So(e.Item.Key, ShouldEqual, "watched/foo")
+			})
+		})
+	}))
+}
+
+type WatchEventSummary struct {
+	Key, Value string
+}
+
+// RunOnConsul skips the test unless LRMR_TEST_CONSUL_ENDPOINT is set.
+func RunOnConsul(t *testing.T) {
+	if _, ok := os.LookupEnv(consulEndpointEnvKey); !ok {
+		t.Skipf("Skipping %s since %s is not set.", t.Name(), consulEndpointEnvKey)
+	}
+}
+
+func WithConsul(fn func(consul coordinator.Coordinator)) func() {
+	return func() {
+		rand.Seed(time.Now().Unix())
+
+		testNs := fmt.Sprintf("lrmr_test_%s/", funk.RandomString(10))
+		consul, err := coordinator.NewConsul([]string{os.Getenv(consulEndpointEnvKey)}, testNs)
+		So(err, ShouldBeNil)
+
+		// clean all items under test namespace
+		Reset(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			_, err := consul.Delete(ctx, "")
+			So(err, ShouldBeNil)
+			So(consul.Close(), ShouldBeNil)
+		})
+
+		fn(consul)
+	}
+}
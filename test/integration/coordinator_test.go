@@ -0,0 +1,253 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/thoas/go-funk"
+	"golang.org/x/sync/errgroup"
+)
+
+// coordinatorBackend provides a Coordinator to run the conformance suite against.
+type coordinatorBackend struct {
+	name string
+	skip func(t *testing.T)
+	open func(ns string) (coordinator.Coordinator, error)
+
+	// leaseExpiry is the time taken to expire keys with a lease of 2 seconds.
+	leaseExpiry time.Duration
+}
+
+var coordinatorBackends = []coordinatorBackend{
+	{
+		name: "Etcd",
+		skip: RunOnIntegrationTest,
+		open: func(ns string) (coordinator.Coordinator, error) {
+			endpoint, ok := os.LookupEnv(etcdEndpointEnvKey)
+			if !ok {
+				endpoint = defaultEtcdEndpoint
+			}
+			return coordinator.NewEtcd([]string{endpoint}, ns)
+		},
+		leaseExpiry: 4 * time.Second,
+	},
+	{
+		name: "Redis",
+		skip: RunOnRedis,
+		open: func(ns string) (coordinator.Coordinator, error) {
+			return coordinator.NewRedis(os.Getenv(redisEndpointEnvKey), ns)
+		},
+		leaseExpiry: 3 * time.Second,
+	},
+	{
+		name: "Consul",
+		skip: RunOnConsul,
+		open: func(ns string) (coordinator.Coordinator, error) {
+			return coordinator.NewConsul([]string{os.Getenv(consulEndpointEnvKey)}, ns)
+		},
+		// sessions have TTL of 10 seconds at least, and are invalidated up to twice the TTL
+		leaseExpiry: 25 * time.Second,
+	},
+}
+
+// TestCoordinator_Conformance runs the same suite against each coordinator backend.
+func TestCoordinator_Conformance(t *testing.T) {
+	for _, backend := range coordinatorBackends {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			backend.skip(t)
+			testCoordinatorConformance(t, backend)
+		})
+	}
+}
+
+func testCoordinatorConformance(t *testing.T, backend coordinatorBackend) {
+	Convey("Given a coordinator on "+backend.name, t, withCoordinator(backend, func(crd coordinator.Coordinator) {
+		ctx := testutils.ContextWithTimeout()
+
+		Convey("Getting a key put", func() {
+			So(crd.Put(ctx, "foo", map[string]string{"bar": "baz"}), ShouldBeNil)
+
+			var value map[string]string
+			So(crd.Get(ctx, "foo", &value), ShouldBeNil)
+			So(value, ShouldResemble, map[string]string{"bar": "baz"})
+
+			Convey("Getting a nonexistent key should return ErrNotFound", func() {
+				So(crd.Get(ctx, "nonexistent", &value), ShouldEqual, coordinator.ErrNotFound)
+			})
+		})
+
+		Convey("Scanning keys with a prefix", func() {
+			So(crd.Put(ctx, "scanned/b", "2"), ShouldBeNil)
+			So(crd.Put(ctx, "scanned/a", "1"), ShouldBeNil)
+			So(crd.Put(ctx, "unscanned/c", "3"), ShouldBeNil)
+
+			items, err := crd.Scan(ctx, "scanned/")
+			So(err, ShouldBeNil)
+
+			var summaries []WatchEventSummary
+			for _, item := range items {
+				summaries = append(summaries, WatchEventSummary{Key: item.Key, Value: string(item.Value)})
+			}
+			So(summaries, ShouldResemble, []WatchEventSummary{
+				{Key: "scanned/a", Value: `"1"`},
+				{Key: "scanned/b", Value: `"2"`},
+			})
+		})
+
+		Convey("Incrementing a counter with a race condition", func() {
+			n := 100
+			var counterRecords sync.Map
+
+			wg, wctx := errgroup.WithContext(ctx)
+			for i := 0; i < n; i++ {
+				wg.Go(func() error {
+					count, err := crd.IncrementCounter(wctx, "counter")
+					if err != nil {
+						return err
+					}
+					if _, duplicated := counterRecords.LoadOrStore(count, true); duplicated {
+						return fmt.Errorf("number %d is duplicated", count)
+					}
+					return nil
+				})
+			}
+			So(wg.Wait(), ShouldBeNil)
+
+			counter, err := crd.ReadCounter(ctx, "counter")
+			So(err, ShouldBeNil)
+			So(counter, ShouldEqual, n)
+
+			Convey("Reading a nonexistent counter should return zero", func() {
+				counter, err := crd.ReadCounter(ctx, "nonexistent")
+				So(err, ShouldBeNil)
+				So(counter, ShouldEqual, 0)
+			})
+		})
+
+		Convey("Committing a transaction", func() {
+			So(crd.Put(ctx, "deleted/a", "1"), ShouldBeNil)
+			So(crd.Put(ctx, "deleted/b", "2"), ShouldBeNil)
+
+			results, err := crd.Commit(ctx, coordinator.NewTxn().
+				Put("foo", "bar").
+				IncrementCounter("counter").
+				Delete("deleted/"))
+			So(err, ShouldBeNil)
+			So(results, ShouldHaveLength, 3)
+			So(results[1].Counter, ShouldEqual, 1)
+			So(results[2].Deleted, ShouldEqual, 2)
+
+			var value string
+			So(crd.Get(ctx, "foo", &value), ShouldBeNil)
+			So(value, ShouldEqual, "bar")
+
+			items, err := crd.Scan(ctx, "deleted/")
+			So(err, ShouldBeNil)
+			So(items, ShouldBeEmpty)
+		})
+
+		Convey("Deleting keys with a prefix", func() {
+			So(crd.Put(ctx, "deleted/a", "1"), ShouldBeNil)
+			So(crd.Put(ctx, "deleted/b", "2"), ShouldBeNil)
+			So(crd.Put(ctx, "kept", "3"), ShouldBeNil)
+
+			deleted, err := crd.Delete(ctx, "deleted/")
+			So(err, ShouldBeNil)
+			So(deleted, ShouldEqual, 2)
+
+			var value string
+			So(crd.Get(ctx, "kept", &value), ShouldBeNil)
+		})
+
+		Convey("Putting a key with a lease", func() {
+			lease, err := crd.GrantLease(ctx, 2*time.Second)
+			So(err, ShouldBeNil)
+			So(crd.Put(ctx, "leased", "foo", coordinator.WithLease(lease)), ShouldBeNil)
+
+			Convey("The key should expire after the TTL", func() {
+				var value string
+				So(crd.Get(ctx, "leased", &value), ShouldBeNil)
+
+				time.Sleep(backend.leaseExpiry)
+				So(crd.Get(context.Background(), "leased", &value), ShouldEqual, coordinator.ErrNotFound)
+			})
+		})
+
+		Convey("Watching keys with a prefix", func() {
+			wctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			events, err := crd.Watch(wctx, "watched/")
+			So(err, ShouldBeNil)
+
+			So(crd.Put(ctx, "watched/foo", "bar"), ShouldBeNil)
+			So(crd.Put(ctx, "unwatched/foo", "bar"), ShouldBeNil)
+			_, err = crd.IncrementCounter(ctx, "watched/counter")
+			So(err, ShouldBeNil)
+
+			var put, counter WatchEventSummary
+			for put.Key == "" || counter.Key == "" {
+				e := <-events
+				switch e.Type {
+				case coordinator.PutEvent:
+					put = WatchEventSummary{Key: e.Item.Key, Value: string(e.Item.Value)}
+				case coordinator.CounterEvent:
+					counter = WatchEventSummary{Key: e.Item.Key, Value: fmt.Sprint(e.Counter)}
+				}
+			}
+			So(put, ShouldResemble, WatchEventSummary{Key: "watched/foo", Value: `"bar"`})
+			So(counter, ShouldResemble, WatchEventSummary{Key: "watched/counter", Value: "1"})
+
+			_, err = crd.Delete(ctx, "watched/foo")
+			So(err, ShouldBeNil)
+
+			e := <-events
+			So(e.Type, ShouldEqual, coordinator.DeleteEvent)
+			So(e.Key(), ShouldEqual, "watched/foo")
+
+			Convey("The channel should be closed when the context is cancelled", func() {
+				cancel()
+				for range events {
+				}
+			})
+		})
+	}))
+}
+
+// RunOnRedis skips the test unless LRMR_TEST_REDIS_ENDPOINT is set.
+func RunOnRedis(t *testing.T) {
+	if _, ok := os.LookupEnv(redisEndpointEnvKey); !ok {
+		t.Skipf("Skipping %s since %s is not set.", t.Name(), redisEndpointEnvKey)
+	}
+}
+
+func withCoordinator(backend coordinatorBackend, fn func(crd coordinator.Coordinator)) func() {
+	return func() {
+		rand.Seed(time.Now().UnixNano())
+
+		testNs := fmt.Sprintf("lrmr_test_%s/", funk.RandomString(10))
+		crd, err := backend.open(testNs)
+		So(err, ShouldBeNil)
+
+		// clean all items under test namespace
+		Reset(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			_, err := crd.Delete(ctx, "")
+			So(err, ShouldBeNil)
+			So(crd.Close(), ShouldBeNil)
+		})
+
+		fn(crd)
+	}
+}
@@ -15,9 +15,16 @@ import (
 const (
 	etcdEndpointEnvKey  = "LRMR_TEST_ETCD_ENDPOINT"
 	defaultEtcdEndpoint = "127.0.0.1:2379"
+
+	// consulEndpointEnvKey makes integration tests run on Consul instead of etcd if it is set.
+	consulEndpointEnvKey = "LRMR_TEST_CONSUL_ENDPOINT"
+
+	// redisEndpointEnvKey makes integration tests run on Redis instead of etcd if it is set.
+	redisEndpointEnvKey = "LRMR_TEST_REDIS_ENDPOINT"
 )
 
-// ProvideEtcd provides coordinator.Etcd on integration tests.
+// ProvideEtcd provides coordinator.Etcd on integration tests, or coordinator.Consul if
+// LRMR_TEST_CONSUL_ENDPOINT is set, or coordinator.Redis if LRMR_TEST_REDIS_ENDPOINT is set.
 // Otherwise, coordinator.LocalMemory is provided.
 func ProvideEtcd() coordinator.Coordinator {
 	if !IsIntegrationTest {
@@ -26,11 +33,21 @@ func ProvideEtcd() coordinator.Coordinator {
 	rand.Seed(time.Now().Unix())
 	testNs := fmt.Sprintf("lrmr_test_%s/", funk.RandomString(10))
 
-	etcdEndpoint, ok := os.LookupEnv(etcdEndpointEnvKey)
-	if !ok {
-		etcdEndpoint = defaultEtcdEndpoint
+	var (
+		etcd coordinator.Coordinator
+		err  error
+	)
+	if consulEndpoint, ok := os.LookupEnv(consulEndpointEnvKey); ok {
+		etcd, err = coordinator.NewConsul([]string{consulEndpoint}, testNs)
+	} else if redisEndpoint, ok := os.LookupEnv(redisEndpointEnvKey); ok {
+		etcd, err = coordinator.NewRedis(redisEndpoint, testNs)
+	} else {
+		etcdEndpoint, ok := os.LookupEnv(etcdEndpointEnvKey)
+		if !ok {
+			etcdEndpoint = defaultEtcdEndpoint
+		}
+		etcd, err = coordinator.NewEtcd([]string{etcdEndpoint}, testNs)
 	}
-	etcd, err := coordinator.NewEtcd([]string{etcdEndpoint}, testNs)
 	if err != nil {
 		So(err, ShouldBeNil)
 	}
@@ -2,6 +2,7 @@ package integration
 
 import (
 	"context"
+	"sort"
 	"strconv"
 	"time"
 
@@ -19,35 +20,50 @@ type LocalCluster struct {
 	master  *master.Master
 	workers []*worker.Worker
 	testCtx C
+
+	workerOpts func(opt *worker.Options)
 }
 
 func WithLocalCluster(numWorkers int, fn func(c *LocalCluster), options ...lrmr.SessionOption) func() {
+	return WithConfiguredLocalCluster(numWorkers, ClusterOptions{}, fn, options...)
+}
+
+// ClusterOptions modifies the options of the nodes in a LocalCluster.
+type ClusterOptions struct {
+	Worker func(opt *worker.Options)
+	Master func(opt *master.Options)
+}
+
+// WithConfiguredLocalCluster is same as WithLocalCluster, except that the options of the nodes
+// are modified by given ClusterOptions.
+func WithConfiguredLocalCluster(
+	numWorkers int,
+	clusterOpts ClusterOptions,
+	fn func(c *LocalCluster),
+	options ...lrmr.SessionOption,
+) func() {
 	return func() {
+		c := &LocalCluster{
+			crd:        ProvideEtcd(),
+			workers:    make([]*worker.Worker, numWorkers),
+			workerOpts: clusterOpts.Worker,
+		}
 		var m *master.Master
-		workers := make([]*worker.Worker, numWorkers)
 		Reset(func() {
-			for _, w := range workers {
+			for _, w := range c.workers {
+				if w == nil {
+					// killed by KillWorker
+					continue
+				}
 				So(w.Close(), ShouldBeNil)
 			}
 			m.Stop()
 		})
 
-		crd := ProvideEtcd()
-
-		for i := 0; i < numWorkers; i++ {
-			opt := worker.DefaultOptions()
-			opt.ListenHost = "127.0.0.1:"
-			opt.AdvertisedHost = "127.0.0.1:"
-			opt.Concurrency = 2
-			opt.NodeTags["No"] = strconv.Itoa(i + 1)
-
-			w, err := worker.New(crd, opt)
+		for i := range c.workers {
+			w, err := c.newWorker(i + 1)
 			So(err, ShouldBeNil)
-			w.SetWorkerLocalOption("No", i+1)
-			w.SetWorkerLocalOption("IsWorker", true)
-
-			go w.Start()
-			workers[i] = w
+			c.workers[i] = w
 		}
 
 		// wait for workers to register themselves
@@ -56,26 +72,58 @@ func WithLocalCluster(numWorkers int, fn func(c *LocalCluster), options ...lrmr.
 		opt := master.DefaultOptions()
 		opt.ListenHost = "127.0.0.1:"
 		opt.AdvertisedHost = "127.0.0.1:"
+		if clusterOpts.Master != nil {
+			clusterOpts.Master(&opt)
+		}
 
 		var err error
-		m, err = master.New(crd, opt)
+		m, err = master.New(c.crd, opt)
 		So(err, ShouldBeNil)
 		m.Start()
+		c.master = m
 
 		options = append(options, lrmr.WithTimeout(30*time.Second))
-		sess := lrmr.NewSession(context.Background(), m, options...)
-
-		c := &LocalCluster{
-			Session: sess,
-			crd:     crd,
-			master:  m,
-			workers: workers,
-		}
+		c.Session = lrmr.NewSession(context.Background(), m, options...)
 
 		fn(c)
 	}
 }
 
+// newWorker starts a worker with given number, which is set to its "No" tag and worker local option.
+func (lc *LocalCluster) newWorker(no int) (*worker.Worker, error) {
+	opt := worker.DefaultOptions()
+	opt.ListenHost = "127.0.0.1:"
+	opt.AdvertisedHost = "127.0.0.1:"
+	opt.Concurrency = 2
+	opt.NodeTags["No"] = strconv.Itoa(no)
+	if lc.workerOpts != nil {
+		lc.workerOpts(&opt)
+	}
+
+	w, err := worker.New(lc.crd, opt)
+	if err != nil {
+		return nil, err
+	}
+	w.SetWorkerLocalOption("No", no)
+	w.SetWorkerLocalOption("IsWorker", true)
+
+	go w.Start()
+	return w, nil
+}
+
+// AddWorker starts a new worker joining the cluster, numbered after the existing workers,
+// and waits for it to register itself.
+func (lc *LocalCluster) AddWorker() error {
+	w, err := lc.newWorker(len(lc.workers) + 1)
+	if err != nil {
+		return err
+	}
+	lc.workers = append(lc.workers, w)
+
+	time.Sleep(200 * time.Millisecond)
+	return nil
+}
+
 func (lc *LocalCluster) EmulateMasterFailure(old *lrmr.RunningJob) (new *lrmr.RunningJob) {
 	lc.master.Stop()
 
@@ -97,3 +145,70 @@ func (lc *LocalCluster) EmulateMasterFailure(old *lrmr.RunningJob) (new *lrmr.Ru
 
 	return newJob
 }
+
+// Jobs returns the jobs created in the cluster, in the order of their submission.
+func (lc *LocalCluster) Jobs(ctx context.Context) ([]*lrmr.RunningJob, error) {
+	jobs, err := lc.master.JobManager.ListJobs(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].SubmittedAt.Before(jobs[j].SubmittedAt)
+	})
+	running := make([]*lrmr.RunningJob, len(jobs))
+	for i, j := range jobs {
+		running[i] = &lrmr.RunningJob{Job: j, Master: lc.master}
+	}
+	return running, nil
+}
+
+// DrainWorkers drains every worker in the cluster.
+func (lc *LocalCluster) DrainWorkers(ctx context.Context) error {
+	for _, w := range lc.workers {
+		if w == nil {
+			continue
+		}
+		if err := w.Drain(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WaitForPause blocks until every worker running the tasks of given job observes the pause of the job.
+func (lc *LocalCluster) WaitForPause(ctx context.Context, jobID string) error {
+	for _, w := range lc.workers {
+		if w == nil {
+			continue
+		}
+		paused, ok := w.JobPaused(jobID)
+		if !ok {
+			continue
+		}
+		select {
+		case <-paused:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// KillWorker stops i-th worker without draining its tasks, as if it has crashed. The registration of the worker
+// expires after the liveness probe interval, as it stops sending heartbeats.
+func (lc *LocalCluster) KillWorker(i int) error {
+	w := lc.workers[i]
+	lc.workers[i] = nil
+	return w.Kill()
+}
+
+// WorkerStatuses returns the status of every worker in the cluster.
+func (lc *LocalCluster) WorkerStatuses() []worker.Status {
+	statuses := make([]worker.Status, len(lc.workers))
+	for i, w := range lc.workers {
+		if w != nil {
+			statuses[i] = w.Status()
+		}
+	}
+	return statuses
+}
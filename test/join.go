@@ -0,0 +1,25 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+)
+
+func usersByID(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(map[string]string{
+		"1": "alice",
+		"2": "bob",
+		"3": "carol",
+	})
+}
+
+func ordersByUserID(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(map[string][]string{
+		"1": {"apple", "banana"},
+		"2": {"cherry"},
+		"4": {"durian"},
+	})
+}
+
+func JoinUsersWithOrders(sess *lrmr.Session, mode lrmr.JoinMode) *lrmr.Dataset {
+	return usersByID(sess).Join(ordersByUserID(sess), mode)
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// joinedPairs returns "<left value>:<right value>" of the joined rows, with empty right value if it's missing.
+func joinedPairs(rows []*lrdd.Row) (pairs []string) {
+	for _, row := range rows {
+		var j lrmr.Joined
+		row.UnmarshalValue(&j)
+
+		var left, right string
+		j.Left.UnmarshalValue(&left)
+		if j.Right != nil {
+			j.Right.UnmarshalValue(&right)
+		}
+		pairs = append(pairs, row.Key+"/"+left+":"+right)
+	}
+	return pairs
+}
+
+func TestJoin(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When joining two datasets", func() {
+			rows, err := JoinUsersWithOrders(cluster.Session, lrmr.InnerJoin).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should emit the pairs of the rows having the same key", func() {
+				pairs := joinedPairs(rows)
+				So(pairs, ShouldHaveLength, 3)
+				So(pairs, ShouldContain, "1/alice:apple")
+				So(pairs, ShouldContain, "1/alice:banana")
+				So(pairs, ShouldContain, "2/bob:cherry")
+			})
+
+			Convey("It should free both datasets stored on the workers", func() {
+				So(waitForBlocksFreed(cluster), ShouldBeTrue)
+			})
+		})
+
+		Convey("When left outer joining two datasets", func() {
+			rows, err := JoinUsersWithOrders(cluster.Session, lrmr.LeftOuterJoin).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should also emit the rows without matching ones", func() {
+				pairs := joinedPairs(rows)
+				So(pairs, ShouldHaveLength, 4)
+				So(pairs, ShouldContain, "1/alice:apple")
+				So(pairs, ShouldContain, "1/alice:banana")
+				So(pairs, ShouldContain, "2/bob:cherry")
+				So(pairs, ShouldContain, "3/carol:")
+			})
+		})
+	}))
+}
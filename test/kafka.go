@@ -0,0 +1,15 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+)
+
+const (
+	KafkaTopic = "events"
+	KafkaGroup = "lrmr-test"
+)
+
+func KafkaInput(sess *lrmr.Session, brokers []string, opts ...lrmr.KafkaOption) *lrmr.Dataset {
+	opts = append([]lrmr.KafkaOption{lrmr.WithKafkaVersion("0.10.2.0")}, opts...)
+	return sess.KafkaInput(brokers, KafkaTopic, KafkaGroup, opts...)
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newMockKafka starts a broker serving a topic of three partitions:
+//
+//	partition 0 has 3 messages, and 1 of them is committed by the group
+//	partition 1 has 2 messages, and nothing is committed
+//	partition 2 has no messages after the committed offset
+//
+// Each request handled by the broker is notified to the channel returned.
+func newMockKafka(t *testing.T) (*sarama.MockBroker, <-chan struct{}) {
+	b := sarama.NewMockBroker(t, 1)
+	handled := make(chan struct{}, 1)
+	b.SetNotifier(func(int, int) {
+		select {
+		case handled <- struct{}{}:
+		default:
+		}
+	})
+	b.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(b.Addr(), b.BrokerID()).
+			SetController(b.BrokerID()).
+			SetLeader(KafkaTopic, 0, b.BrokerID()).
+			SetLeader(KafkaTopic, 1, b.BrokerID()).
+			SetLeader(KafkaTopic, 2, b.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset(KafkaTopic, 0, sarama.OffsetOldest, 0).
+			SetOffset(KafkaTopic, 0, sarama.OffsetNewest, 3).
+			SetOffset(KafkaTopic, 1, sarama.OffsetOldest, 0).
+			SetOffset(KafkaTopic, 1, sarama.OffsetNewest, 2).
+			SetOffset(KafkaTopic, 2, sarama.OffsetOldest, 0).
+			SetOffset(KafkaTopic, 2, sarama.OffsetNewest, 5),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, KafkaGroup, b),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(KafkaGroup, KafkaTopic, 0, 1, "", sarama.ErrNoError).
+			SetOffset(KafkaGroup, KafkaTopic, 1, -1, "", sarama.ErrNoError).
+			SetOffset(KafkaGroup, KafkaTopic, 2, 5, "", sarama.ErrNoError),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 10).
+			SetVersion(3).
+			SetMessage(KafkaTopic, 0, 0, sarama.StringEncoder("a")).
+			SetMessage(KafkaTopic, 0, 1, sarama.StringEncoder("b")).
+			SetMessage(KafkaTopic, 0, 2, sarama.StringEncoder("c")).
+			SetHighWaterMark(KafkaTopic, 0, 3).
+			SetMessage(KafkaTopic, 1, 0, sarama.StringEncoder("d")).
+			SetMessage(KafkaTopic, 1, 1, sarama.StringEncoder("e")).
+			SetHighWaterMark(KafkaTopic, 1, 2),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+	return b, handled
+}
+
+// waitForCommit waits for the group to commit offsets to the broker, which is done after the job completes.
+func waitForCommit(ctx context.Context, b *sarama.MockBroker, handled <-chan struct{}) (map[int32]int64, error) {
+	for {
+		if offsets := committedOffsets(b); len(offsets) > 0 {
+			return offsets, nil
+		}
+		select {
+		case <-handled:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// committedOffsets returns the offsets committed to the broker by the group.
+func committedOffsets(b *sarama.MockBroker) map[int32]int64 {
+	offsets := make(map[int32]int64)
+	for _, rr := range b.History() {
+		req, ok := rr.Request.(*sarama.OffsetCommitRequest)
+		if !ok {
+			continue
+		}
+		for p := int32(0); p < 3; p++ {
+			if offset, _, err := req.Offset(KafkaTopic, p); err == nil {
+				offsets[p] = offset
+			}
+		}
+	}
+	return offsets
+}
+
+func TestKafkaInput(t *testing.T) {
+	Convey("Given running nodes and a kafka broker", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		broker, handled := newMockKafka(t)
+		defer broker.Close()
+
+		Convey("When reading the topic", func() {
+			rows, err := KafkaInput(cluster.Session, []string{broker.Addr()}).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should read the messages after the committed offsets", func() {
+				values := make([]string, 0, len(rows))
+				for _, row := range rows {
+					var msg map[string]interface{}
+					So(row.DecodeValue(&msg), ShouldBeNil)
+					values = append(values, msg["value"].(string))
+				}
+				So(values, ShouldHaveLength, 4)
+				So(values, ShouldContain, "b")
+				So(values, ShouldContain, "c")
+				So(values, ShouldContain, "d")
+				So(values, ShouldContain, "e")
+			})
+
+			Convey("It should commit the end of the ranges read after the job succeeds", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				offsets, err := waitForCommit(ctx, broker, handled)
+				So(err, ShouldBeNil)
+				So(offsets, ShouldResemble, map[int32]int64{0: 3, 1: 2})
+			})
+		})
+
+		Convey("When the job reading the topic fails", func() {
+			j, err := KafkaInput(cluster.Session, []string{broker.Addr()}).Do(FailingStage{}).Run()
+			So(err, ShouldBeNil)
+			So(j.Wait(), ShouldNotBeNil)
+
+			Convey("It should not commit the offsets", func() {
+				So(committedOffsets(broker), ShouldBeEmpty)
+			})
+		})
+	}))
+}
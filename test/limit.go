@@ -0,0 +1,46 @@
+package test
+
+import (
+	"time"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"go.uber.org/atomic"
+)
+
+var _ = lrmr.RegisterTypes(&SlowPassThrough{})
+
+// slowPassThroughRows is the number of rows processed by SlowPassThrough.
+var slowPassThroughRows atomic.Int64
+
+// SlowPassThrough emits rows as is, taking a millisecond for each row.
+type SlowPassThrough struct{}
+
+func (s *SlowPassThrough) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	time.Sleep(time.Millisecond)
+	slowPassThroughRows.Inc()
+	return row, nil
+}
+
+func limitData(n int) []int {
+	data := make([]int, n)
+	for i := range data {
+		data[i] = i + 1
+	}
+	return data
+}
+
+// SlowDataset has 10000 rows processed by SlowPassThrough.
+func SlowDataset(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(limitData(10000)).
+		Map(&SlowPassThrough{})
+}
+
+func Limit(sess *lrmr.Session, n int) *lrmr.Dataset {
+	return SlowDataset(sess).Limit(n)
+}
+
+func LimitMoreThanRows(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(limitData(100)).
+		Limit(1000)
+}
@@ -0,0 +1,49 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLimit(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When limiting rows", func() {
+			slowPassThroughRows.Store(0)
+			rows, err := Limit(cluster.Session, 10).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should emit given number of rows", func() {
+				So(rows, ShouldHaveLength, 10)
+			})
+
+			Convey("It should stop the upstream tasks early", func() {
+				So(slowPassThroughRows.Load(), ShouldBeLessThan, 1000)
+			})
+		})
+
+		Convey("When limiting more rows than the dataset has", func() {
+			rows, err := LimitMoreThanRows(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should emit every row", func() {
+				So(rows, ShouldHaveLength, 100)
+			})
+		})
+
+		Convey("When collecting first N rows", func() {
+			slowPassThroughRows.Store(0)
+			rows, err := SlowDataset(cluster.Session).CollectN(5)
+			So(err, ShouldBeNil)
+
+			Convey("It should return at most N rows", func() {
+				So(rows, ShouldHaveLength, 5)
+			})
+
+			Convey("It should not process the whole dataset", func() {
+				So(slowPassThroughRows.Load(), ShouldBeLessThan, 1000)
+			})
+		})
+	}))
+}
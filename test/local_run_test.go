@@ -0,0 +1,53 @@
+package test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRunLocal(t *testing.T) {
+	Convey("Given a session without a cluster", t, func() {
+		sess := lrmr.NewSession(context.Background(), nil)
+
+		Convey("Running stages with shuffles should produce the same result as a cluster", func() {
+			rows, err := SimpleCount(sess).RunLocal(4)
+			So(err, ShouldBeNil)
+			res := testutils.GroupRowsByKey(rows)
+			So(res, ShouldHaveLength, 2)
+			So(res["foo"], ShouldHaveLength, 1)
+			So(res["bar"], ShouldHaveLength, 1)
+			So(testutils.IntValue(res["foo"][0]), ShouldEqual, 2)
+			So(testutils.IntValue(res["bar"][0]), ShouldEqual, 1)
+		})
+
+		Convey("Running stages with preserved partitions should emit every row", func() {
+			rows, err := Map(sess).RunLocal(4)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 1000)
+
+			values := make([]int, len(rows))
+			for i, row := range rows {
+				values[i] = testutils.IntValue(row)
+			}
+			sort.Ints(values)
+			So(values[0], ShouldEqual, 8)
+			So(values[999], ShouldEqual, 8000)
+		})
+
+		Convey("Running with Limit should stop the inputs early", func() {
+			rows, err := sess.Parallelize(limitData(10000)).Limit(10).RunLocal(4)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 10)
+		})
+
+		Convey("Running a failing stage should return its error", func() {
+			_, err := FailingJob(sess).RunLocal(2)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
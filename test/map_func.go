@@ -0,0 +1,31 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/testutils"
+)
+
+var _ = lrmr.RegisterFuncs(DoubleOdd)
+
+// DoubleOdd doubles the odd numbers and drops the even numbers.
+func DoubleOdd(row *lrdd.Row) (*lrdd.Row, error) {
+	n := testutils.IntValue(row)
+	if n%2 == 0 {
+		return nil, nil
+	}
+	return lrdd.Value(n * 2), nil
+}
+
+// unregisteredDouble is not registered with lrmr.RegisterFuncs.
+func unregisteredDouble(row *lrdd.Row) (*lrdd.Row, error) {
+	return lrdd.Value(testutils.IntValue(row) * 2), nil
+}
+
+func MapFunc(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 100)
+	for i := range data {
+		data[i] = i + 1
+	}
+	return sess.Parallelize(data).MapFunc(DoubleOdd)
+}
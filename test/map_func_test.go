@@ -0,0 +1,43 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMapFunc(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When mapping rows with a registered function", func() {
+			rows, err := MapFunc(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("Rows should be mapped, dropping the rows mapped to nil", func() {
+				So(rows, ShouldHaveLength, 50)
+				for _, row := range rows {
+					So(testutils.IntValue(row)%4, ShouldEqual, 2)
+				}
+			})
+		})
+	}))
+
+	Convey("Given a session without a cluster", t, func() {
+		sess := lrmr.NewSession(context.Background(), nil)
+
+		Convey("Mapping rows with a registered function should work locally", func() {
+			rows, err := MapFunc(sess).RunLocal(2)
+			So(err, ShouldBeNil)
+			So(rows, ShouldHaveLength, 50)
+		})
+
+		Convey("Mapping rows with an unregistered function should fail before running", func() {
+			_, err := sess.Parallelize([]int{1, 2, 3}).MapFunc(unregisteredDouble).RunLocal(2)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unregisteredDouble is not registered")
+		})
+	})
+}
@@ -27,6 +27,24 @@ func TestMap(t *testing.T) {
 				}
 				So(max, ShouldEqual, 8000)
 			})
+
+			Convey("It should account rows and bytes transferred between stages", func() {
+				j, err := ds.Run()
+				So(err, ShouldBeNil)
+				So(j.Wait(), ShouldBeNil)
+
+				stats, err := j.StageStats()
+				So(err, ShouldBeNil)
+				So(stats, ShouldHaveLength, 3)
+				for _, st := range stats {
+					So(st.InputRows, ShouldBeGreaterThan, 0)
+					So(st.InputBytes, ShouldBeGreaterThan, 0)
+					if st.OutputStage != "" {
+						So(st.OutputRows, ShouldEqual, stats[st.OutputStage].InputRows)
+						So(st.OutputBytes, ShouldEqual, stats[st.OutputStage].InputBytes)
+					}
+				}
+			})
 		})
 	}))
 }
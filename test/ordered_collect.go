@@ -0,0 +1,28 @@
+package test
+
+import (
+	"bytes"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/partitions"
+)
+
+// SeededMultiply multiplies numbers distributed to the partitions with a fixed seed, and then gathers them
+// from every partition into one, so that the order of the rows arriving at the last stage varies across runs.
+func SeededMultiply(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(limitData(1000)).
+		PartitionedBy(partitions.NewSeededShuffledPartitioner(42)).
+		Map(&Multiply{}).
+		GroupByKey().
+		Map(&Multiply{})
+}
+
+// EncodeRows encodes the rows into bytes in their order.
+func EncodeRows(rows []*lrdd.Row) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := lrdd.NewRowEncoder(&buf).Encode(rows...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,31 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOrderedCollect(t *testing.T) {
+	Convey("Given running nodes with ordered collect", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When collecting a deterministic job repeatedly", func() {
+			var results [][]byte
+			for i := 0; i < 3; i++ {
+				rows, err := SeededMultiply(cluster.Session).Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1000)
+
+				encoded, err := EncodeRows(rows)
+				So(err, ShouldBeNil)
+				results = append(results, encoded)
+			}
+
+			Convey("It should return byte-identical results", func() {
+				So(results[1], ShouldResemble, results[0])
+				So(results[2], ShouldResemble, results[0])
+			})
+		})
+	}, lrmr.WithOrderedCollect()))
+}
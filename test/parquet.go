@@ -0,0 +1,94 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ab180/lrmr"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// ParquetEvents writes n events to Parquet files under given directory. Every third event has
+// a "country" field, which is not seen in the first rows of the partitions.
+func ParquetEvents(sess *lrmr.Session, dir string, n int, opts ...lrmr.ParquetOption) (*lrmr.RunningJob, error) {
+	events := make([]map[string]interface{}, n)
+	for i := range events {
+		events[i] = map[string]interface{}{
+			"user":   fmt.Sprintf("user-%d", i),
+			"amount": i,
+		}
+		if i%3 == 2 {
+			events[i]["country"] = "KR"
+		}
+	}
+	return sess.Parallelize(events).
+		Map(&PassThrough{}).
+		WriteParquet(dir, append([]lrmr.ParquetOption{lrmr.WithParquetSampleSize(2), lrmr.WithRowGroupSize(1024)}, opts...)...)
+}
+
+// ParquetCodecs returns the compression codecs of the columns in the Parquet files under given directory.
+func ParquetCodecs(dir string) (map[string]bool, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if err != nil {
+		return nil, err
+	}
+	codecs := make(map[string]bool)
+	for _, path := range paths {
+		f, err := local.NewLocalFileReader(path)
+		if err != nil {
+			return nil, err
+		}
+		pr, err := reader.NewParquetReader(f, nil, 1)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		for _, rg := range pr.Footer.RowGroups {
+			for _, col := range rg.Columns {
+				codecs[col.MetaData.Codec.String()] = true
+			}
+		}
+		pr.ReadStop()
+		f.Close()
+	}
+	return codecs, nil
+}
+
+// ReadParquetFiles reads the rows of every Parquet file under given directory.
+// The fields of the rows are keyed by the column names in title case (e.g. "User").
+func ReadParquetFiles(dir string) (rows []map[string]interface{}, err error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		f, err := local.NewLocalFileReader(path)
+		if err != nil {
+			return nil, err
+		}
+		pr, err := reader.NewParquetReader(f, nil, 1)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		res, err := pr.ReadByNumber(int(pr.GetNumRows()))
+		pr.ReadStop()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		// rows are read as structs of pointers, which are flattened through JSON
+		data, err := json.Marshal(res)
+		if err != nil {
+			return nil, err
+		}
+		var read []map[string]interface{}
+		if err := json.Unmarshal(data, &read); err != nil {
+			return nil, err
+		}
+		rows = append(rows, read...)
+	}
+	return rows, nil
+}
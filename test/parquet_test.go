@@ -0,0 +1,84 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteParquet(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		dir, err := ioutil.TempDir("", "lrmr-parquet")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		Convey("When writing rows having fields not seen in the first rows", func() {
+			j, err := ParquetEvents(cluster.Session, dir, 300)
+			So(err, ShouldBeNil)
+			So(j.Wait(), ShouldBeNil)
+
+			Convey("It should write every row with the extra fields to the files", func() {
+				rows, err := ReadParquetFiles(dir)
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 300)
+
+				withCountry := 0
+				for _, row := range rows {
+					So(row["User"], ShouldNotBeNil)
+					So(row["Amount"], ShouldNotBeNil)
+					if row["Country"] == "KR" {
+						withCountry++
+					}
+				}
+				So(withCountry, ShouldEqual, 100)
+			})
+
+			Convey("It should leave no unfinished files", func() {
+				paths, err := filepath.Glob(filepath.Join(dir, "*"))
+				So(err, ShouldBeNil)
+				for _, path := range paths {
+					So(filepath.Ext(path), ShouldEqual, ".parquet")
+				}
+			})
+
+			Convey("It should compress the files with Snappy by default", func() {
+				codecs, err := ParquetCodecs(dir)
+				So(err, ShouldBeNil)
+				So(codecs, ShouldResemble, map[string]bool{"SNAPPY": true})
+			})
+		})
+
+		Convey("When writing rows with a compression", func() {
+			j, err := ParquetEvents(cluster.Session, dir, 300, lrmr.WithParquetCompression(output.Zstd))
+			So(err, ShouldBeNil)
+			So(j.Wait(), ShouldBeNil)
+
+			Convey("It should record the compression in the files", func() {
+				codecs, err := ParquetCodecs(dir)
+				So(err, ShouldBeNil)
+				So(codecs, ShouldResemble, map[string]bool{"ZSTD": true})
+
+				rows, err := ReadParquetFiles(dir)
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 300)
+			})
+		})
+
+		Convey("When writing rows with an unsupported compression", func() {
+			_, err := ParquetEvents(cluster.Session, dir, 300, lrmr.WithParquetCompression("lzma"))
+
+			Convey("It should fail before running the job", func() {
+				So(err, ShouldBeError)
+				files, err := filepath.Glob(filepath.Join(dir, "*"))
+				So(err, ShouldBeNil)
+				So(files, ShouldBeEmpty)
+			})
+		})
+	}))
+}
@@ -0,0 +1,28 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&PassThrough{})
+
+// PassThrough emits the input row as is.
+type PassThrough struct{}
+
+func (p *PassThrough) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	return row, nil
+}
+
+// HotKeyCount counts rows grouped by a skewed set of keys, where most rows have the key "hot".
+func HotKeyCount(sess *lrmr.Session) *lrmr.Dataset {
+	d := map[string][]int{
+		"hot":   make([]int, 90),
+		"cold1": make([]int, 5),
+		"cold2": make([]int, 5),
+	}
+	return sess.Parallelize(d).
+		Map(&PassThrough{}).
+		GroupByKey().
+		Reduce(Count())
+}
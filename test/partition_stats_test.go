@@ -0,0 +1,40 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPartitionStats(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When grouping rows with a hot key", func() {
+			j, err := HotKeyCount(cluster.Session).Run()
+			So(err, ShouldBeNil)
+			So(j.Wait(), ShouldBeNil)
+
+			Convey("It should report the number of rows routed to each partition", func() {
+				stats, err := j.StageStats()
+				So(err, ShouldBeNil)
+
+				// the stage shuffling the rows to GroupByKey
+				var total, max int64
+				for _, st := range stats {
+					var stageTotal, stageMax int64
+					for _, n := range st.PartitionRows {
+						stageTotal += n
+						if n > stageMax {
+							stageMax = n
+						}
+					}
+					if stageMax > max {
+						total, max = stageTotal, stageMax
+					}
+				}
+				So(total, ShouldEqual, 100)
+				So(max, ShouldBeGreaterThanOrEqualTo, 90)
+			})
+		})
+	}))
+}
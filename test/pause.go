@@ -0,0 +1,60 @@
+package test
+
+import (
+	"sync"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/testutils"
+)
+
+var _ = lrmr.RegisterTypes(&HeldMultiply{})
+
+var (
+	heldMultiplyMu      sync.Mutex
+	heldMultiplyRelease = closedChan()
+)
+
+// HoldMultiply makes HeldMultiply hold its rows until the returned function is called.
+func HoldMultiply() (release func()) {
+	c := make(chan struct{})
+	heldMultiplyMu.Lock()
+	heldMultiplyRelease = c
+	heldMultiplyMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(c) })
+	}
+}
+
+// HeldMultiply multiplies input, after it's released by HoldMultiply.
+type HeldMultiply struct{}
+
+func (m *HeldMultiply) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	heldMultiplyMu.Lock()
+	release := heldMultiplyRelease
+	heldMultiplyMu.Unlock()
+	select {
+	case <-release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return lrdd.Value(testutils.IntValue(row) * 2), nil
+}
+
+// HeldMap multiplies enough rows that a task can't finish with the rows buffered before its job is paused.
+func HeldMap(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 10000)
+	for i := 0; i < len(data); i++ {
+		data[i] = i + 1
+	}
+	return sess.Parallelize(data).
+		Map(&HeldMultiply{})
+}
+
+func closedChan() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPauseJob(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When a running job is paused", func() {
+			release := HoldMultiply()
+			defer release()
+
+			j, err := HeldMap(cluster.Session).Run()
+			So(err, ShouldBeNil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			So(j.Pause(ctx), ShouldBeNil)
+			So(cluster.WaitForPause(ctx, j.ID), ShouldBeNil)
+
+			// the tasks can only process the rows pulled before the pause from now on
+			release()
+
+			Convey("It should hold the job until resumed", func() {
+				completed := make(chan struct{}, 1)
+				j.Master.JobTracker.OnJobCompletion(j.Job, func(*job.Job, *job.Status) {
+					select {
+					case completed <- struct{}{}:
+					default:
+					}
+				})
+				completedWhilePaused := false
+				select {
+				case <-completed:
+					completedWhilePaused = true
+				case <-time.After(500 * time.Millisecond):
+				}
+				So(completedWhilePaused, ShouldBeFalse)
+
+				So(j.Resume(ctx), ShouldBeNil)
+				So(j.Wait(), ShouldBeNil)
+			})
+		})
+	}))
+}
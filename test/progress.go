@@ -0,0 +1,79 @@
+package test
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&HalfwayStage{})
+
+var (
+	halfwayMu      sync.Mutex
+	halfwayRelease = closedChan()
+)
+
+// HoldHalfway makes HalfwayStage tasks wait after reporting their progress, until the returned function is called.
+func HoldHalfway() (release func()) {
+	c := make(chan struct{})
+	halfwayMu.Lock()
+	halfwayRelease = c
+	halfwayMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(c) })
+	}
+}
+
+// HalfwayStage reports half of its work done after reading input, and finishes after it's released by HoldHalfway.
+type HalfwayStage struct{}
+
+func (h *HalfwayStage) Transform(ctx lrmr.Context, in chan *lrdd.Row, emit func(*lrdd.Row)) error {
+	for row := range in {
+		emit(row)
+	}
+	ctx.ReportProgress(0.5)
+
+	halfwayMu.Lock()
+	release := halfwayRelease
+	halfwayMu.Unlock()
+	select {
+	case <-release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func ReportProgress(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize([]int{1, 2, 3, 4, 5}).Do(&HalfwayStage{})
+}
+
+// WaitForProgress blocks until the progress of the job reaches given fraction, following the updates of its task statuses.
+func WaitForProgress(ctx context.Context, j *lrmr.RunningJob, fraction float64) error {
+	updates, err := j.Master.JobManager.WatchTaskStatuses(ctx, j.ID)
+	if err != nil {
+		return err
+	}
+	for {
+		// checked after the subscription to prevent missing the updates in between
+		progress, err := j.Progress()
+		if err != nil {
+			return err
+		}
+		if progress >= fraction {
+			return nil
+		}
+		select {
+		case _, ok := <-updates:
+			if !ok {
+				return ctx.Err()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
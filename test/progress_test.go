@@ -0,0 +1,61 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReportProgress(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When a stage reports its progress", func() {
+			release := HoldHalfway()
+			defer release()
+
+			j, err := ReportProgress(cluster.Session).Run()
+			So(err, ShouldBeNil)
+
+			Convey("It should be reflected to the progress of the job", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				So(WaitForProgress(ctx, j, 0.5), ShouldBeNil)
+
+				progress, err := j.Progress()
+				So(err, ShouldBeNil)
+				So(progress, ShouldAlmostEqual, 0.5)
+
+				release()
+				So(j.Wait(), ShouldBeNil)
+				progress, err = j.Progress()
+				So(err, ShouldBeNil)
+				So(progress, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When polling the task progress of a running job", func() {
+			release := HoldHalfway()
+			defer release()
+
+			j, err := ReportProgress(cluster.Session).Run()
+			So(err, ShouldBeNil)
+
+			Convey("It should count the finished tasks over the stages", func() {
+				completed, total, fraction, err := j.TaskProgress()
+				So(err, ShouldBeNil)
+				So(total, ShouldBeGreaterThan, 0)
+				So(completed, ShouldBeLessThan, total)
+				So(fraction, ShouldBeLessThan, 1)
+
+				release()
+				So(j.Wait(), ShouldBeNil)
+				completed, total, fraction, err = j.TaskProgress()
+				So(err, ShouldBeNil)
+				So(completed, ShouldEqual, total)
+				So(fraction, ShouldEqual, 1)
+			})
+		})
+	}))
+}
@@ -0,0 +1,32 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&ProjectedEvents{})
+
+// ProjectedEvents emits events as is, but declares that the downstream only needs their type.
+type ProjectedEvents struct{}
+
+func (p *ProjectedEvents) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	return row, nil
+}
+
+func (p *ProjectedEvents) Projection() []string {
+	return []string{"type", "nonexistent"}
+}
+
+func Projection(sess *lrmr.Session) *lrmr.Dataset {
+	events := make(map[string][]map[string]interface{})
+	for _, user := range []string{"alice", "bob"} {
+		events[user] = append(events[user], map[string]interface{}{
+			"type":    "purchase",
+			"amount":  100,
+			"comment": "a long field which the downstream doesn't need",
+		})
+	}
+	return sess.Parallelize(events).
+		Map(&ProjectedEvents{})
+}
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProjection(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When running a transformation declaring a projection", func() {
+			rows, err := Projection(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("Its output should only have the projected fields", func() {
+				So(rows, ShouldHaveLength, 2)
+				for _, row := range rows {
+					var fields map[string]interface{}
+					row.UnmarshalValue(&fields)
+					So(fields, ShouldResemble, map[string]interface{}{"type": "purchase"})
+				}
+			})
+		})
+	}))
+}
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/pkg/errors"
+)
+
+var _ = lrmr.RegisterTypes(&UnavailableService{})
+
+var errServiceUnavailable = errors.New("503 service unavailable")
+
+// UnavailableService fails with an error which is transient.
+type UnavailableService struct{}
+
+func (u *UnavailableService) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	return nil, errors.Wrap(errServiceUnavailable, "call service")
+}
+
+func (u *UnavailableService) IsRetryable(err error) bool {
+	return errors.Cause(err) == errServiceUnavailable
+}
+
+func CallUnavailableService(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize([]int{1, 2, 3}).Map(&UnavailableService{})
+}
@@ -0,0 +1,35 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryableError(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When a stage fails with an error classified as retryable", func() {
+			j, err := CallUnavailableService(cluster.Session).Run()
+			So(err, ShouldBeNil)
+
+			Convey("The job error should be marked as retryable", func() {
+				err := j.Wait()
+				So(err, ShouldHaveSameTypeAs, job.Error{})
+				So(err.(job.Error).Retryable, ShouldBeTrue)
+			})
+		})
+
+		Convey("When a stage without the classifier fails", func() {
+			j, err := FailingJob(cluster.Session).Run()
+			So(err, ShouldBeNil)
+
+			Convey("The job error should not be retryable", func() {
+				err := j.Wait()
+				So(err, ShouldHaveSameTypeAs, job.Error{})
+				So(err.(job.Error).Retryable, ShouldBeFalse)
+			})
+		})
+	}))
+}
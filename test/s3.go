@@ -0,0 +1,103 @@
+package test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ab180/lrmr"
+)
+
+const fakeS3Bucket = "lrmr-test"
+
+// FakeS3 serves ListObjectsV2 and GetObject of the objects in a bucket, to test S3Input without S3.
+type FakeS3 struct {
+	*httptest.Server
+
+	objects  map[string]string
+	pageSize int
+
+	// ListCalls is the number of ListObjectsV2 requests.
+	ListCalls int32
+}
+
+func NewFakeS3(objects map[string]string, pageSize int) *FakeS3 {
+	f := &FakeS3{objects: objects, pageSize: pageSize}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name          `xml:"ListBucketResult"`
+	Name                  string            `xml:"Name"`
+	Prefix                string            `xml:"Prefix"`
+	KeyCount              int               `xml:"KeyCount"`
+	MaxKeys               int               `xml:"MaxKeys"`
+	IsTruncated           bool              `xml:"IsTruncated"`
+	NextContinuationToken string            `xml:"NextContinuationToken,omitempty"`
+	Contents              []listBucketEntry `xml:"Contents"`
+}
+
+type listBucketEntry struct {
+	Key  string `xml:"Key"`
+	Size int    `xml:"Size"`
+}
+
+func (f *FakeS3) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/"+fakeS3Bucket)
+	if path == "" || path == "/" {
+		f.list(w, r)
+		return
+	}
+	content, ok := f.objects[strings.TrimPrefix(path, "/")]
+	if !ok {
+		http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	_, _ = w.Write([]byte(content))
+}
+
+func (f *FakeS3) list(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&f.ListCalls, 1)
+
+	prefix := r.URL.Query().Get("prefix")
+	after := r.URL.Query().Get("continuation-token")
+
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) && k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	res := listBucketResult{Name: fakeS3Bucket, Prefix: prefix, MaxKeys: f.pageSize}
+	if len(keys) > f.pageSize {
+		keys = keys[:f.pageSize]
+		res.IsTruncated = true
+		res.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, k := range keys {
+		res.Contents = append(res.Contents, listBucketEntry{Key: k, Size: len(f.objects[k])})
+	}
+	res.KeyCount = len(res.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(res)
+}
+
+func S3Lines(sess *lrmr.Session, s3 *FakeS3, prefix string) *lrmr.Dataset {
+	return sess.S3Input(fakeS3Bucket, prefix, lrmr.WithS3Region("us-east-1"), lrmr.WithS3Endpoint(s3.URL))
+}
+
+func S3JSONLines(sess *lrmr.Session, s3 *FakeS3, prefix string) *lrmr.Dataset {
+	return sess.S3Input(fakeS3Bucket, prefix,
+		lrmr.WithS3Region("us-east-1"),
+		lrmr.WithS3Endpoint(s3.URL),
+		lrmr.WithS3Decoder(&lrmr.JSONLinesDecoder{KeyField: "user"}))
+}
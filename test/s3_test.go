@@ -0,0 +1,72 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestS3Input(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		// credentials are resolved by the default chain
+		So(os.Setenv("AWS_ACCESS_KEY_ID", "test"), ShouldBeNil)
+		So(os.Setenv("AWS_SECRET_ACCESS_KEY", "test"), ShouldBeNil)
+		Reset(func() {
+			os.Unsetenv("AWS_ACCESS_KEY_ID")
+			os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+		})
+
+		Convey("When reading objects listed in multiple pages", func() {
+			objects := make(map[string]string)
+			for i := 0; i < 2500; i++ {
+				objects[fmt.Sprintf("logs/%04d.txt", i)] = fmt.Sprintf("%d-a\n%d-b\n", i, i)
+			}
+			objects["others/0000.txt"] = "unlisted\n"
+
+			s3 := NewFakeS3(objects, 1000)
+			Reset(s3.Close)
+
+			ds := S3Lines(cluster.Session, s3, "logs/")
+
+			Convey("It should read every line of the objects under the prefix", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 5000)
+				So(s3.ListCalls, ShouldEqual, 3)
+
+				lines := make(map[string]bool)
+				for _, row := range rows {
+					line, _ := row.GetString("line")
+					lines[line] = true
+				}
+				So(lines, ShouldHaveLength, 5000)
+				So(lines["2499-b"], ShouldBeTrue)
+				So(lines["unlisted"], ShouldBeFalse)
+			})
+		})
+
+		Convey("When reading objects with a JSON lines decoder", func() {
+			s3 := NewFakeS3(map[string]string{
+				"events/1.jsonl": `{"user": "alice", "amount": 1}` + "\n" + `{"amount": 2}` + "\n",
+				"events/2.jsonl": `{"user": "bob", "amount": 3}` + "\n\n",
+			}, 1000)
+			Reset(s3.Close)
+
+			ds := S3JSONLines(cluster.Session, s3, "events/")
+
+			Convey("It should emit rows keyed by the key field", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+
+				res := testutils.GroupRowsByKey(rows)
+				So(res, ShouldHaveLength, 2)
+				So(res["alice"], ShouldHaveLength, 1)
+				So(res["bob"], ShouldHaveLength, 1)
+			})
+		})
+	}))
+}
@@ -0,0 +1,23 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+)
+
+func sampleData() []int {
+	data := make([]int, 10000)
+	for i := range data {
+		data[i] = i + 1
+	}
+	return data
+}
+
+func Sample(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(sampleData()).
+		Sample(0.1, 42)
+}
+
+func SampleN(sess *lrmr.Session, n int) *lrmr.Dataset {
+	return sess.Parallelize(sampleData()).
+		SampleN(n, 42)
+}
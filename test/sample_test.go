@@ -0,0 +1,69 @@
+package test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func sortedInts(rows []*lrdd.Row) []int {
+	ints := make([]int, len(rows))
+	for i, row := range rows {
+		ints[i] = testutils.IntValue(row)
+	}
+	sort.Ints(ints)
+	return ints
+}
+
+func TestSample(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When sampling 10% of rows", func() {
+			rows, err := Sample(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should emit about 10% of rows", func() {
+				// stddev is sqrt(10000 * 0.1 * 0.9) = 30
+				So(len(rows), ShouldBeBetween, 850, 1150)
+			})
+
+			Convey("It should emit same rows on rerun with same seed", func() {
+				rerun, err := Sample(cluster.Session).Collect()
+				So(err, ShouldBeNil)
+				So(sortedInts(rerun), ShouldResemble, sortedInts(rows))
+			})
+		})
+
+		Convey("When sampling N rows", func() {
+			rows, err := SampleN(cluster.Session, 100).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should emit exactly N distinct rows", func() {
+				So(rows, ShouldHaveLength, 100)
+
+				ints := sortedInts(rows)
+				for i := 1; i < len(ints); i++ {
+					So(ints[i], ShouldNotEqual, ints[i-1])
+				}
+			})
+
+			Convey("It should emit same rows on rerun with same seed", func() {
+				rerun, err := SampleN(cluster.Session, 100).Collect()
+				So(err, ShouldBeNil)
+				So(sortedInts(rerun), ShouldResemble, sortedInts(rows))
+			})
+		})
+
+		Convey("When sampling more rows than the dataset has", func() {
+			rows, err := SampleN(cluster.Session, 20000).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("It should emit every row", func() {
+				So(rows, ShouldHaveLength, 10000)
+			})
+		})
+	}))
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&RenameField{})
+
+// RenameField moves a field of the input rows to another key.
+type RenameField struct {
+	From, To string
+}
+
+func (r *RenameField) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	var fields map[string]interface{}
+	row.UnmarshalValue(&fields)
+
+	v := fields[r.From]
+	delete(fields, r.From)
+	fields[r.To] = v
+	return lrdd.KeyValue(row.Key, fields), nil
+}
+
+// RenameWithSchema renames the "amount" field of the events to given name,
+// and validates that the next stage receives the "amount" field.
+func RenameWithSchema(sess *lrmr.Session, to string) *lrmr.Dataset {
+	events := map[string][]map[string]interface{}{
+		"alice": {{"amount": 100}},
+		"bob":   {{"amount": 200}},
+	}
+	return sess.Parallelize(events).
+		Map(&RenameField{From: "amount", To: to}).
+		Map(&PassThrough{}).
+		WithInputSchema(lrdd.Schema{"amount": lrdd.Int})
+}
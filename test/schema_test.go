@@ -0,0 +1,30 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInputSchema(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When the input rows match the schema", func() {
+			rows, err := RenameWithSchema(cluster.Session, "amount").Collect()
+
+			Convey("It should run without error", func() {
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When the input rows don't match the schema", func() {
+			_, err := RenameWithSchema(cluster.Session, "amuont").Collect()
+
+			Convey("It should fail with the mismatch", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "missing field amount")
+			})
+		})
+	}))
+}
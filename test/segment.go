@@ -0,0 +1,99 @@
+package test
+
+import (
+	"time"
+
+	"github.com/ab180/lrmr"
+)
+
+// segmentUntil is the reference time of the segment; events are placed in days before it.
+var segmentUntil = time.Date(2020, 6, 30, 0, 0, 0, 0, time.UTC)
+
+func daysBefore(days int) int64 {
+	return segmentUntil.Add(-time.Duration(days) * 24 * time.Hour).Unix()
+}
+
+func purchaseEvents() map[string][]map[string]interface{} {
+	return map[string][]map[string]interface{}{
+		"alice": {
+			{"amount": 10, "ts": daysBefore(1)},
+			{"amount": 20, "ts": daysBefore(3)},
+		},
+		"bob": {
+			{"amount": 100, "ts": daysBefore(40)},
+		},
+		"carol": {
+			{"amount": 1, "ts": daysBefore(2)},
+			{"amount": 1, "ts": daysBefore(5)},
+			{"amount": 1, "ts": daysBefore(10)},
+		},
+		"dave": {
+			{"amount": 5, "ts": daysBefore(1)},
+			{"amount": 5, "ts": daysBefore(2)},
+			{"amount": 5, "ts": daysBefore(29)},
+		},
+	}
+}
+
+func SegmentByPurchase(sess *lrmr.Session, match lrmr.SegmentMatch) *lrmr.Dataset {
+	return sess.Parallelize(purchaseEvents()).
+		GroupByKey().
+		Segment(lrmr.Segment{
+			Conditions: []lrmr.SegmentCondition{
+				{
+					Field:          "amount",
+					TimestampField: "ts",
+					Aggregator:     lrmr.AggregateSum,
+					Operator:       lrmr.OpGreaterThanOrEqual,
+					Threshold:      10,
+					Window:         30 * 24 * time.Hour,
+				},
+				{
+					TimestampField: "ts",
+					Aggregator:     lrmr.AggregateCount,
+					Operator:       lrmr.OpGreaterThanOrEqual,
+					Threshold:      3,
+					Window:         30 * 24 * time.Hour,
+				},
+			},
+			Match: match,
+			Until: segmentUntil,
+		})
+}
+
+// SegmentByMaxPurchase keeps the keys whose largest purchase in 30 days is at most 50.
+// bob has no purchases in the window, hence no largest purchase.
+func SegmentByMaxPurchase(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(purchaseEvents()).
+		GroupByKey().
+		Segment(lrmr.Segment{
+			Conditions: []lrmr.SegmentCondition{
+				{
+					Field:          "amount",
+					TimestampField: "ts",
+					Aggregator:     lrmr.AggregateMax,
+					Operator:       lrmr.OpLessThanOrEqual,
+					Threshold:      50,
+					Window:         30 * 24 * time.Hour,
+				},
+			},
+			Until: segmentUntil,
+		})
+}
+
+// SegmentOfNonMapValues runs Segment on the rows whose values are not maps, which can't have the fields.
+func SegmentOfNonMapValues(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(map[string][]int{"alice": {1, 2}}).
+		GroupByKey().
+		Segment(lrmr.Segment{
+			Conditions: []lrmr.SegmentCondition{
+				{
+					Field:      "amount",
+					Aggregator: lrmr.AggregateSum,
+					Operator:   lrmr.OpGreaterThanOrEqual,
+					Threshold:  1,
+				},
+			},
+			Until: segmentUntil,
+		})
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSegment(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When running Segment with all conditions", func() {
+			ds := SegmentByPurchase(cluster.Session, lrmr.MatchAll)
+
+			Convey("It should keep only keys satisfying every condition", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1)
+				So(rows[0].Key, ShouldEqual, "dave")
+			})
+		})
+
+		Convey("When running Segment with any condition", func() {
+			ds := SegmentByPurchase(cluster.Session, lrmr.MatchAny)
+
+			Convey("It should keep keys satisfying one of the conditions", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+
+				res := testutils.GroupRowsByKey(rows)
+				So(res, ShouldHaveLength, 3)
+				So(res, ShouldContainKey, "alice")
+				So(res, ShouldContainKey, "carol")
+				So(res, ShouldContainKey, "dave")
+
+				var aggregated []float64
+				res["alice"][0].UnmarshalValue(&aggregated)
+				So(aggregated, ShouldResemble, []float64{30, 2})
+			})
+		})
+
+		Convey("When running Segment with a condition on the max of an empty window", func() {
+			ds := SegmentByMaxPurchase(cluster.Session)
+
+			Convey("It should not keep keys without rows in the window", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+
+				res := testutils.GroupRowsByKey(rows)
+				So(res, ShouldHaveLength, 3)
+				So(res, ShouldNotContainKey, "bob")
+			})
+		})
+	}))
+
+	Convey("Given a session without a cluster", t, func() {
+		sess := lrmr.NewSession(context.Background(), nil)
+
+		Convey("Running Segment on rows whose values are not maps should fail", func() {
+			_, err := SegmentOfNonMapValues(sess).RunLocal(2)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "decode value of row alice")
+		})
+	})
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ab180/lrmr"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const purchaseSpec = `{
+	"table": {"path": "%s", "keyField": "user"},
+	"filter": [{"field": "type", "operator": "==", "value": "purchase"}],
+	"segmentCondition": {
+		"match": "all",
+		"until": "2020-06-30T00:00:00Z",
+		"conditions": [
+			{"field": "amount", "timestampField": "ts", "aggregator": "sum", "operator": ">=", "threshold": 10, "interval": "30d"}
+		]
+	},
+	"aggregationSpec": {"groupBy": "country", "aggregator": "count"}
+}`
+
+// WritePurchaseEvents writes purchase events in JSON lines format under given directory.
+func WritePurchaseEvents(dir string) error {
+	files := map[string][]map[string]interface{}{
+		"events-1.jsonl": {
+			{"user": "alice", "country": "KR", "type": "purchase", "amount": 10, "ts": daysBefore(1)},
+			{"user": "alice", "country": "KR", "type": "view", "amount": 0, "ts": daysBefore(1)},
+			{"user": "bob", "country": "US", "type": "purchase", "amount": 100, "ts": daysBefore(40)},
+		},
+		"events-2.jsonl": {
+			{"user": "carol", "country": "KR", "type": "purchase", "amount": 20, "ts": daysBefore(3)},
+			{"user": "dave", "country": "US", "type": "purchase", "amount": 5, "ts": daysBefore(2)},
+			{"user": "dave", "country": "US", "type": "purchase", "amount": 5, "ts": daysBefore(20)},
+		},
+	}
+	for name, events := range files {
+		var lines []string
+		for _, e := range events {
+			line, err := jsoniter.MarshalToString(e)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\n")), os.ModePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CountPurchasersBySpec(sess *lrmr.Session, dir string) (*lrmr.Dataset, error) {
+	var spec lrmr.Spec
+	if err := jsoniter.UnmarshalFromString(strings.Replace(purchaseSpec, "%s", dir, 1), &spec); err != nil {
+		return nil, err
+	}
+	return spec.Build(sess)
+}
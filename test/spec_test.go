@@ -0,0 +1,39 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSpec(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		dir, err := ioutil.TempDir("", "lrmr-spec")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+		So(WritePurchaseEvents(dir), ShouldBeNil)
+
+		Convey("When running a job built from a spec", func() {
+			ds, err := CountPurchasersBySpec(cluster.Session, dir)
+			So(err, ShouldBeNil)
+
+			Convey("It should count rows of qualifying keys by group", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+
+				res := testutils.GroupRowsByKey(rows)
+				So(res, ShouldHaveLength, 2)
+
+				var count float64
+				res["KR"][0].UnmarshalValue(&count)
+				So(count, ShouldEqual, 2)
+				res["US"][0].UnmarshalValue(&count)
+				So(count, ShouldEqual, 2)
+			})
+		})
+	}))
+}
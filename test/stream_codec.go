@@ -0,0 +1,17 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+)
+
+// ShuffledMap sends enough rows between the nodes to make batches larger than a chunk
+// of the rows decoded from the streams.
+func ShuffledMap(sess *lrmr.Session) *lrmr.Dataset {
+	data := make([]int, 10000)
+	for i := range data {
+		data[i] = i + 1
+	}
+	return sess.Parallelize(data).
+		Map(&Multiply{}).
+		Shuffle()
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/master"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	"github.com/ab180/lrmr/worker"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStreamCodec(t *testing.T) {
+	opts := integration.ClusterOptions{
+		Worker: func(opt *worker.Options) {
+			opt.Output.Codec = output.StreamCodec
+		},
+		Master: func(opt *master.Options) {
+			opt.Output.Codec = output.StreamCodec
+		},
+	}
+	Convey("Given nodes sending the rows by the stream codec", t, integration.WithConfiguredLocalCluster(2, opts, func(cluster *integration.LocalCluster) {
+		Convey("When running a job shuffling the rows between the nodes", func() {
+			rows, err := ShuffledMap(cluster.Session).Collect()
+			So(err, ShouldBeNil)
+
+			Convey("Every row should be delivered once", func() {
+				So(rows, ShouldHaveLength, 10000)
+
+				seen := make(map[int]bool, len(rows))
+				for _, row := range rows {
+					seen[testutils.IntValue(row)] = true
+				}
+				So(seen, ShouldHaveLength, 10000)
+				So(seen[2], ShouldBeTrue)
+				So(seen[20000], ShouldBeTrue)
+			})
+		})
+	}))
+}
@@ -0,0 +1,38 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTaskMetrics(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When a job is running", func() {
+			j, err := NeverEndingJob(cluster.Session).Run()
+			So(err, ShouldBeNil)
+			defer j.Cancel(context.Background())
+
+			Convey("It should report the rows read by the running tasks", func() {
+				inputRows := 0
+				deadline := time.Now().Add(5 * time.Second)
+				for inputRows < 4 && time.Now().Before(deadline) {
+					time.Sleep(100 * time.Millisecond)
+
+					stats, err := j.StageStats()
+					So(err, ShouldBeNil)
+					inputRows = 0
+					for _, st := range stats {
+						inputRows += st.InputRows
+					}
+				}
+				So(inputRows, ShouldEqual, 4)
+				So(j.Status(), ShouldEqual, job.Running)
+			})
+		})
+	}))
+}
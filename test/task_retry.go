@@ -0,0 +1,61 @@
+package test
+
+import (
+	"sync/atomic"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/pkg/errors"
+)
+
+var _ = lrmr.RegisterTypes(&Flaky{}, &Validating{})
+
+// flakyFailures counts the failures made by Flaky in the process.
+var flakyFailures int32
+
+var errFlaky = errors.New("flaky failure")
+
+// Flaky fails the first given number of rows in the process, and passes through the others.
+type Flaky struct {
+	Failures int32
+}
+
+func (f *Flaky) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	if atomic.AddInt32(&flakyFailures, 1) <= f.Failures {
+		return nil, errFlaky
+	}
+	return row, nil
+}
+
+func (f *Flaky) IsRetryable(err error) bool {
+	return errors.Cause(err) == errFlaky
+}
+
+func FlakyJob(sess *lrmr.Session, failures int32, maxRetries int) *lrmr.Dataset {
+	atomic.StoreInt32(&flakyFailures, 0)
+	return sess.Parallelize([]int{1, 2, 3, 4}).
+		Map(&Flaky{Failures: failures}).
+		WithRetries(maxRetries)
+}
+
+// validationAttempts counts the rows mapped by Validating in the process.
+var validationAttempts int32
+
+// Validating fails on every row with a validation error, which is not retryable.
+type Validating struct{}
+
+func (v *Validating) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	atomic.AddInt32(&validationAttempts, 1)
+	return nil, errors.New("invalid row")
+}
+
+func (v *Validating) IsRetryable(err error) bool {
+	return false
+}
+
+func ValidatingJob(sess *lrmr.Session, maxRetries int) *lrmr.Dataset {
+	atomic.StoreInt32(&validationAttempts, 0)
+	return sess.Parallelize([]int{1}).
+		Map(&Validating{}).
+		WithRetries(maxRetries)
+}
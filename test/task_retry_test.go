@@ -0,0 +1,105 @@
+package test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTaskRetry(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When a task fails fewer times than its retries", func() {
+			rows, err := FlakyJob(cluster.Session, 2, 3).Collect()
+
+			Convey("It should succeed with the output of the successful run", func() {
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 4)
+			})
+
+			Convey("The failed runs should be recorded in the job run again", func() {
+				jobs, err := cluster.Jobs(context.TODO())
+				So(err, ShouldBeNil)
+				So(len(jobs), ShouldBeBetweenOrEqual, 2, 3)
+
+				last := jobs[len(jobs)-1]
+				So(last.Retry, ShouldNotBeNil)
+				So(last.Retry.Count, ShouldEqual, len(jobs)-1)
+				So(last.Retry.LastError, ShouldContainSubstring, "flaky failure")
+			})
+
+			Convey("The retries should be recorded in the events of the failed jobs", func() {
+				jobs, err := cluster.Jobs(context.TODO())
+				So(err, ShouldBeNil)
+
+				for i, j := range jobs[:len(jobs)-1] {
+					events, err := j.Events()
+					So(err, ShouldBeNil)
+
+					var retry *job.Event
+					for i, ev := range events {
+						if ev.Type == job.TaskRetryScheduled {
+							retry = &events[i]
+						}
+					}
+					So(retry, ShouldNotBeNil)
+					So(retry.Error, ShouldContainSubstring, "flaky failure")
+					So(retry.Retries, ShouldEqual, i+1)
+				}
+			})
+		})
+
+		Convey("When a task fails more times than its retries", func() {
+			_, err := FlakyJob(cluster.Session, 100, 1).Collect()
+
+			Convey("It should fail the job after the retry", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "flaky failure")
+
+				jobs, err := cluster.Jobs(context.TODO())
+				So(err, ShouldBeNil)
+				So(jobs, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When a task fails with an error which is not retryable", func() {
+			_, err := ValidatingJob(cluster.Session, 3).Collect()
+			So(err, ShouldNotBeNil)
+
+			Convey("It should fail without retries", func() {
+				So(atomic.LoadInt32(&validationAttempts), ShouldEqual, 1)
+
+				jobs, err := cluster.Jobs(context.TODO())
+				So(err, ShouldBeNil)
+				So(jobs, ShouldHaveLength, 1)
+				So(jobs[0].Retry, ShouldBeNil)
+			})
+		})
+
+		Convey("When a task fails and the job finishes", func() {
+			j, err := FlakyJob(cluster.Session, 100, 1).Run()
+			So(err, ShouldBeNil)
+			So(j.Wait(), ShouldNotBeNil)
+
+			Convey("The failure should be recorded in the events of the job", func() {
+				events, err := j.Events()
+				So(err, ShouldBeNil)
+
+				// other tasks canceled by the failure are also recorded as failed, without the error
+				var failure *job.Event
+				for i, ev := range events {
+					So(ev.Task.JobID, ShouldEqual, j.ID)
+					So(ev.Time.IsZero(), ShouldBeFalse)
+					if ev.Type == job.TaskFailed && ev.Error != "" {
+						failure = &events[i]
+					}
+				}
+				So(failure, ShouldNotBeNil)
+				So(failure.Error, ShouldContainSubstring, "flaky failure")
+			})
+		})
+	}))
+}
@@ -0,0 +1,29 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/worker"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTaskSlot(t *testing.T) {
+	limitSlots := integration.ClusterOptions{
+		Worker: func(opt *worker.Options) {
+			opt.MaxConcurrentTasks = 1
+			opt.Input.QueueLength = 1
+			opt.Input.MaxQueuedRows = 10
+		},
+	}
+	Convey("Given a node running one task at a time", t, integration.WithConfiguredLocalCluster(1, limitSlots, func(cluster *integration.LocalCluster) {
+		Convey("When pipelined stages pass more rows than the input queue of a task", func() {
+			rows, err := Map(cluster.Session).Collect()
+
+			Convey("It should not be blocked by the upstream holding the slot", func() {
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1000)
+			})
+		})
+	}))
+}
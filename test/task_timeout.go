@@ -0,0 +1,24 @@
+package test
+
+import (
+	"time"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&Stuck{})
+
+// Stuck blocks on the first row, ignoring the cancellation of the task.
+type Stuck struct{}
+
+func (s *Stuck) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	time.Sleep(time.Hour)
+	return row, nil
+}
+
+func StuckJob(sess *lrmr.Session, timeout time.Duration) *lrmr.Dataset {
+	return sess.Parallelize([]int{1, 2, 3, 4}).
+		Map(&Stuck{}).
+		WithTaskTimeout(timeout)
+}
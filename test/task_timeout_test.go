@@ -0,0 +1,28 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTaskTimeout(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		Convey("When a task runs longer than its timeout", func() {
+			j, err := StuckJob(cluster.Session, 500*time.Millisecond).Run()
+			So(err, ShouldBeNil)
+
+			Convey("It should fail the job with a timeout error", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				err := j.WaitWithContext(ctx)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "task timed out after 500ms")
+			})
+		})
+	}))
+}
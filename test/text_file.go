@@ -0,0 +1,48 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ab180/lrmr"
+)
+
+// WriteTextFiles writes two text files under given directory, and returns the lines in them.
+// The lines have various lengths, so that they span the splits of TextFileLines.
+func WriteTextFiles(dir string) (lines []string, err error) {
+	for i, newline := range []string{"\n", "\r\n"} {
+		var b strings.Builder
+		for j := 0; j < 500; j++ {
+			line := fmt.Sprintf("file%d-line%d-%s", i, j, strings.Repeat("x", j%37))
+			if j%50 == 0 {
+				line = ""
+			} else if j%50 == 7 {
+				// longer than a split
+				line += strings.Repeat("y", 200)
+			}
+			lines = append(lines, line)
+			b.WriteString(line + newline)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("lines-%d.txt", i))
+		if err := ioutil.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+func TextFileLines(sess *lrmr.Session, dir string) *lrmr.Dataset {
+	return sess.TextFileInput(filepath.Join(dir, "*.txt"), lrmr.WithLineField("text"), lrmr.WithSplitSize(64))
+}
+
+// WriteCSVFile writes a CSV file of users with the header under given directory.
+func WriteCSVFile(dir string) error {
+	content := "name,age\nalice,20\n\n\"bob, jr.\",30\n"
+	return ioutil.WriteFile(filepath.Join(dir, "users.csv"), []byte(content), 0644)
+}
+
+func CSVFileUsers(sess *lrmr.Session, dir string) *lrmr.Dataset {
+	return sess.TextFileInput(filepath.Join(dir, "*.csv"), lrmr.WithTextFileDecoder(lrmr.CSVDecoder([]string{"name", "age"})))
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/ab180/lrmr/test/integration"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTextFileInput(t *testing.T) {
+	Convey("Given running nodes", t, integration.WithLocalCluster(2, func(cluster *integration.LocalCluster) {
+		dir, err := ioutil.TempDir("", "lrmr-text-file")
+		So(err, ShouldBeNil)
+		Reset(func() { os.RemoveAll(dir) })
+
+		lines, err := WriteTextFiles(dir)
+		So(err, ShouldBeNil)
+
+		Convey("When reading text files split into small ranges", func() {
+			ds := TextFileLines(cluster.Session, dir)
+
+			Convey("It should emit every line exactly once", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+
+				var read []string
+				for _, row := range rows {
+					text, ok := row.GetString("text")
+					So(ok, ShouldBeTrue)
+					read = append(read, text)
+				}
+				sort.Strings(read)
+				sort.Strings(lines)
+				So(read, ShouldResemble, lines)
+			})
+		})
+
+		Convey("When reading a CSV file with CSVDecoder", func() {
+			So(WriteCSVFile(dir), ShouldBeNil)
+			ds := CSVFileUsers(cluster.Session, dir)
+
+			Convey("It should emit the columns of each line except the header, keyed by the header", func() {
+				rows, err := ds.Collect()
+				So(err, ShouldBeNil)
+
+				users := make(map[string]string)
+				for _, row := range rows {
+					var fields map[string]string
+					So(row.DecodeValue(&fields), ShouldBeNil)
+					users[fields["name"]] = fields["age"]
+				}
+				So(users, ShouldResemble, map[string]string{
+					"alice":    "20",
+					"bob, jr.": "30",
+				})
+			})
+		})
+
+		Convey("When no files match the pattern", func() {
+			ds := cluster.Session.TextFileInput(dir + "/*.csv")
+
+			Convey("It should fail", func() {
+				_, err := ds.Collect()
+				So(err, ShouldNotBeNil)
+			})
+		})
+	}))
+}
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&WorkerNo{})
+
+// WorkerNo emits the number of the worker running its partition, once per partition.
+type WorkerNo struct{}
+
+func (w *WorkerNo) Transform(ctx lrmr.Context, in chan *lrdd.Row, emit func(*lrdd.Row)) error {
+	for range in {
+	}
+	emit(lrdd.Value(ctx.WorkerLocalOption("No")))
+	return nil
+}
+
+// WorkersAfterShuffle reports the workers running the partitions shuffled from the stage held by HoldMultiply.
+func WorkersAfterShuffle(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(limitData(1000)).
+		Map(&HeldMultiply{}).
+		Shuffle().
+		Do(&WorkerNo{})
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/test/testutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// collectWhileJoining collects the dataset, adding a worker to the cluster while its first job is held.
+func collectWhileJoining(cluster *integration.LocalCluster, ds *lrmr.Dataset) ([]*lrdd.Row, error) {
+	release := HoldMultiply()
+	defer release()
+
+	joined := make(chan error, 1)
+	go func() {
+		defer release()
+		for {
+			jobs, err := cluster.Jobs(context.Background())
+			if err != nil {
+				joined <- err
+				return
+			}
+			if len(jobs) > 0 {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		joined <- cluster.AddWorker()
+	}()
+
+	rows, err := ds.Collect()
+	if joinErr := <-joined; joinErr != nil {
+		return nil, joinErr
+	}
+	return rows, err
+}
+
+func workerNos(rows []*lrdd.Row) map[int]bool {
+	nos := make(map[int]bool)
+	for _, row := range rows {
+		nos[testutils.IntValue(row)] = true
+	}
+	return nos
+}
+
+func TestWorkerJoin(t *testing.T) {
+	Convey("Given running nodes with stagewise scheduling", t, integration.WithLocalCluster(1, func(cluster *integration.LocalCluster) {
+		Convey("When a worker joins in the middle of a job", func() {
+			rows, err := collectWhileJoining(cluster, WorkersAfterShuffle(cluster.Session))
+			So(err, ShouldBeNil)
+
+			Convey("The stages after the shuffle should be run on the new worker too", func() {
+				So(workerNos(rows), ShouldResemble, map[int]bool{1: true, 2: true})
+
+				jobs, err := cluster.Jobs(context.Background())
+				So(err, ShouldBeNil)
+				So(jobs, ShouldHaveLength, 2)
+			})
+		})
+	}, lrmr.WithStagewiseScheduling()))
+
+	Convey("Given running nodes", t, integration.WithLocalCluster(1, func(cluster *integration.LocalCluster) {
+		Convey("When a worker joins in the middle of a job", func() {
+			rows, err := collectWhileJoining(cluster, WorkersAfterShuffle(cluster.Session))
+			So(err, ShouldBeNil)
+
+			Convey("The job should be run only on the workers registered when it's submitted", func() {
+				So(workerNos(rows), ShouldResemble, map[int]bool{1: true})
+			})
+		})
+	}))
+}
@@ -0,0 +1,32 @@
+package test
+
+import (
+	"time"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/lrdd"
+)
+
+var _ = lrmr.RegisterTypes(&StallOnWorker{})
+
+// StallOnWorker passes through the rows, but stalls on the worker with given number until the task aborts.
+type StallOnWorker struct {
+	No int
+}
+
+func (s *StallOnWorker) Map(ctx lrmr.Context, row *lrdd.Row) (*lrdd.Row, error) {
+	if no, ok := ctx.WorkerLocalOption("No").(int); ok && no == s.No {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Minute):
+		}
+	}
+	return row, nil
+}
+
+// WorkerLossJob stalls on the second worker, which is expected to be killed during the job.
+func WorkerLossJob(sess *lrmr.Session) *lrmr.Dataset {
+	return sess.Parallelize(limitData(1000)).
+		Map(&StallOnWorker{No: 2})
+}
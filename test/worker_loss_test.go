@@ -0,0 +1,43 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr"
+	"github.com/ab180/lrmr/master"
+	"github.com/ab180/lrmr/test/integration"
+	"github.com/ab180/lrmr/worker"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkerLoss(t *testing.T) {
+	opts := integration.ClusterOptions{
+		Worker: func(opt *worker.Options) {
+			// the registration of a killed worker expires after the interval
+			opt.RPC.LivenessProbeInterval = 2 * time.Second
+		},
+		Master: func(opt *master.Options) {
+			opt.WorkerCheckInterval = 500 * time.Millisecond
+		},
+	}
+	Convey("Given running nodes", t, integration.WithConfiguredLocalCluster(2, opts, func(cluster *integration.LocalCluster) {
+		Convey("When a worker dies in the middle of a job", func() {
+			go func() {
+				time.Sleep(time.Second)
+				_ = cluster.KillWorker(1)
+			}()
+			rows, err := WorkerLossJob(cluster.Session).Collect()
+
+			Convey("The job should be rescheduled on the remaining worker and complete", func() {
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 1000)
+
+				jobs, err := cluster.Jobs(context.Background())
+				So(err, ShouldBeNil)
+				So(jobs, ShouldHaveLength, 2)
+			})
+		})
+	}, lrmr.WithJobRetries(1)))
+}
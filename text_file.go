@@ -0,0 +1,209 @@
+package lrmr
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/transformation"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTextFileField     = "line"
+	defaultTextFileSplitSize = 64 * 1024 * 1024
+)
+
+type TextFileOptions struct {
+	// Decoder decodes each line into a row. Defaults to LineDecoder with field "line".
+	Decoder RecordDecoder
+
+	// SplitSize is the size of byte ranges which the files are split into. Each range is read by a task.
+	// Defaults to 64MiB.
+	SplitSize int64
+
+	// Locations are the hosts holding the files, keyed by the path. See WithFileLocations.
+	Locations map[string][]string
+}
+
+type TextFileOption func(o *TextFileOptions)
+
+// WithLineField decodes the lines into rows having the line in given field, by LineDecoder.
+func WithLineField(field string) TextFileOption {
+	return func(o *TextFileOptions) {
+		o.Decoder = &LineDecoder{Field: field}
+	}
+}
+
+// WithTextFileDecoder sets the decoder of the lines.
+func WithTextFileDecoder(d RecordDecoder) TextFileOption {
+	return func(o *TextFileOptions) {
+		o.Decoder = d
+	}
+}
+
+// WithSplitSize sets the size of byte ranges which the files are split into.
+func WithSplitSize(size int64) TextFileOption {
+	return func(o *TextFileOptions) {
+		o.SplitSize = size
+	}
+}
+
+// WithFileLocations makes the splits of a file read by a task preferring to run on the hosts holding the file,
+// given by its path matched by the pattern (e.g. the nodes holding the file on their local disks, or its blocks
+// in HDFS-like sources). Each file with locations is read by a task, and the stages following the reader run on
+// the same node unless they are repartitioned. The preference is a soft constraint; a task runs on another worker
+// if the preferred ones are busy or absent. The files without locations are distributed to the tasks by their paths.
+func WithFileLocations(locations map[string][]string) TextFileOption {
+	return func(o *TextFileOptions) {
+		o.Locations = locations
+	}
+}
+
+// TextFileInput creates new Dataset by reading lines of the files matching given glob pattern.
+// Each line, without the trailing newline ("\n" or "\r\n"), is decoded into a row by the RecordDecoder given
+// by WithTextFileDecoder. By default, the row is a map having the line in the field given by WithLineField.
+//
+// The files are split into byte ranges of the size given by WithSplitSize, which are distributed
+// across the workers. A range reads the lines starting in it, so a line spanning ranges is read once.
+// The files should be accessible in the same path from every worker (e.g. on a shared filesystem).
+func (s *Session) TextFileInput(pattern string, opts ...TextFileOption) *Dataset {
+	o := TextFileOptions{Decoder: &LineDecoder{Field: defaultTextFileField}, SplitSize: defaultTextFileSplitSize}
+	for _, optFn := range opts {
+		optFn(&o)
+	}
+	d := newDataset(s, &textFileInput{Pattern: pattern, SplitSize: o.SplitSize})
+	if len(o.Locations) > 0 {
+		d.plans[0].Partitioner = partitions.NewLocalityPartitioner(o.Locations)
+	}
+	reader := &textFileReader{Decoder: serializableDecoder{o.Decoder}}
+	d.addStage(d.stageName(reader), reader)
+	return d
+}
+
+// textFileSplit is a byte range of a file, from Start (inclusive) to End (exclusive).
+type textFileSplit struct {
+	Path  string
+	Start int64
+	End   int64
+}
+
+// textFileInput emits the splits of the files matching the pattern.
+type textFileInput struct {
+	partitions.ShuffledPartitioner
+	Pattern   string
+	SplitSize int64
+}
+
+func (t textFileInput) FeedInput(out output.Output) error {
+	if t.SplitSize <= 0 {
+		return errors.Errorf("invalid split size %d", t.SplitSize)
+	}
+	paths, err := filepath.Glob(t.Pattern)
+	if err != nil {
+		return errors.Wrapf(err, "match %s", t.Pattern)
+	}
+	if len(paths) == 0 {
+		return errors.Errorf("no files match %s", t.Pattern)
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return errors.Wrapf(err, "stat %s", path)
+		}
+		if info.IsDir() {
+			continue
+		}
+		for start := int64(0); start < info.Size(); start += t.SplitSize {
+			end := start + t.SplitSize
+			if end > info.Size() {
+				end = info.Size()
+			}
+			// keyed by the path for the locality of the file
+			if err := out.Write(lrdd.KeyValue(path, textFileSplit{Path: path, Start: start, End: end})); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// textFileReader reads the lines starting in each split.
+type textFileReader struct {
+	Decoder serializableDecoder
+}
+
+func (t *textFileReader) Apply(_ transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	for row := range in {
+		var split textFileSplit
+		if err := row.DecodeValue(&split); err != nil {
+			return errors.Wrap(err, "decode split")
+		}
+		if err := t.readSplit(split, out); err != nil {
+			return errors.Wrapf(err, "read %s from %d to %d", split.Path, split.Start, split.End)
+		}
+	}
+	return nil
+}
+
+func (t *textFileReader) readSplit(split textFileSplit, out output.Output) error {
+	file, err := os.Open(split.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	pos := split.Start
+	if pos > 0 {
+		// the line containing the start belongs to the previous split, unless it starts right at the start
+		pos--
+	}
+	if _, err := file.Seek(pos, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(file)
+	if split.Start > 0 {
+		skipped, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		pos += int64(len(skipped))
+	}
+	for pos < split.End {
+		line, err := r.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		pos += int64(len(line))
+
+		row, err := t.Decoder.Decode(trimNewline(line))
+		if err != nil {
+			return errors.Wrapf(err, "decode line at %d", pos-int64(len(line)))
+		}
+		if row == nil {
+			continue
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func trimNewline(line []byte) []byte {
+	n := len(line)
+	if n > 0 && line[n-1] == '\n' {
+		n--
+		if n > 0 && line[n-1] == '\r' {
+			n--
+		}
+	}
+	return line[:n]
+}
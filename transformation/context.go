@@ -2,6 +2,10 @@ package transformation
 
 import "context"
 
+// Context is passed to the transformations running in a task. As a context.Context, it is canceled when
+// the task aborts (e.g. the job fails or is canceled) or exceeds its timeout, so that long-running
+// transformations can stop early by observing Done. It also carries the values of the context which
+// created the task, such as tracing spans, and can be passed to the functions taking a context.Context.
 type Context interface {
 	context.Context
 
@@ -12,4 +16,26 @@ type Context interface {
 
 	AddMetric(name string, delta int)
 	SetMetric(name string, val int)
+
+	// ReportProgress reports an estimated fraction of the task's work done, between 0 and 1.
+	// It's optional, but gives more accurate progress of the job than counting finished tasks
+	// if the cost of the rows is uneven.
+	ReportProgress(fraction float64)
+
+	// IncrementCounter atomically increments the counter of given name shared by the tasks of the job
+	// through the coordinator, and returns the incremented value.
+	IncrementCounter(name string) (int64, error)
+
+	// Accumulator returns the accumulator of given name. Values added to the accumulator are reported
+	// with the task status, and summed over the tasks of the job at the master.
+	Accumulator(name string) Accumulator
+
+	// StopInputs makes the tasks of the current stage and its preceding stages stop pulling inputs
+	// as if their inputs have ended. The stopped tasks finish successfully with the inputs pulled so far.
+	StopInputs() error
+}
+
+// Accumulator is a named counter summed over the tasks of the job. It is safe for concurrent use.
+type Accumulator interface {
+	Add(delta int64)
 }
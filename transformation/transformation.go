@@ -29,9 +29,72 @@ func (s *Serializable) UnmarshalJSON(d []byte) error {
 	return nil
 }
 
+// GobEncode encodes the transformation as MarshalJSON does, so that it can be a field of the structs
+// encoded by serialization.GobCodec without registering the transformation to gob.
+func (s Serializable) GobEncode() ([]byte, error) {
+	return s.MarshalJSON()
+}
+
+func (s *Serializable) GobDecode(d []byte) error {
+	return s.UnmarshalJSON(d)
+}
+
 func NameOf(tf Transformation) string {
 	if s, ok := tf.(Serializable); ok {
 		return NameOf(s.Transformation)
 	}
 	return reflect.TypeOf(tf).Name()
 }
+
+// RetryClassifier can be implemented by transformations which know whether their errors are transient,
+// e.g. a 503 from an external service is retryable while a validation error is not.
+type RetryClassifier interface {
+	IsRetryable(err error) bool
+}
+
+// IsRetryable returns true if given error returned by the transformation is retryable.
+// Errors are considered non-retryable if the transformation does not implement RetryClassifier.
+func IsRetryable(tf Transformation, err error) bool {
+	if s, ok := tf.(Serializable); ok {
+		return IsRetryable(s.Transformation, err)
+	}
+	c, ok := tf.(RetryClassifier)
+	return ok && c.IsRetryable(err)
+}
+
+// Projector can be implemented by transformations whose output rows only need some of the fields
+// in the downstream. The output rows are projected to the fields before they are sent, which
+// reduces the size of the rows over the wire.
+type Projector interface {
+	// Projection returns the keys of the fields to keep. Nil means all fields.
+	Projection() []string
+}
+
+// ProjectionOf returns the projection declared by the transformation. It returns nil
+// if the transformation does not implement Projector.
+func ProjectionOf(tf Transformation) []string {
+	if s, ok := tf.(Serializable); ok {
+		return ProjectionOf(s.Transformation)
+	}
+	if p, ok := tf.(Projector); ok {
+		return p.Projection()
+	}
+	return nil
+}
+
+// KeyPreserver can be implemented by transformations which emit rows only with the keys of their input rows,
+// e.g. reducing rows by key. If such a stage is partitioned by a key, its output is already partitioned
+// by the same key, so that the shuffle to the next stage partitioned in the same way can be skipped.
+type KeyPreserver interface {
+	PreservesKeys() bool
+}
+
+// PreservesKeys returns true if the transformation emits rows only with the keys of its input rows.
+// It returns false if the transformation does not implement KeyPreserver.
+func PreservesKeys(tf Transformation) bool {
+	if s, ok := tf.(Serializable); ok {
+		return PreservesKeys(s.Transformation)
+	}
+	k, ok := tf.(KeyPreserver)
+	return ok && k.PreservesKeys()
+}
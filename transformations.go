@@ -13,9 +13,15 @@ import (
 	"github.com/pkg/errors"
 )
 
+// RegisterTypes registers the types of given values, e.g. the transformations and partitioners,
+// so that they can be deserialized on the workers. It should be called in the package declaring the types:
+//
+//	var _ = lrmr.RegisterTypes(&MyMapper{})
+//
+// Running a dataset with unregistered types fails with an error naming the type.
 func RegisterTypes(tfs ...interface{}) interface{} {
 	for _, tf := range tfs {
-		serialization.TypeOf(tf)
+		serialization.Register(tf)
 	}
 	return nil
 }
@@ -67,6 +73,8 @@ type filterTransformation struct {
 	filter Filter
 }
 
+func (f filterTransformation) PreservesKeys() bool { return true }
+
 func (f filterTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
 	for row := range in {
 		if !f.filter.Filter(row) {
@@ -79,6 +87,19 @@ func (f filterTransformation) Apply(ctx transformation.Context, in chan *lrdd.Ro
 	return nil
 }
 
+func (f *filterTransformation) MarshalJSON() ([]byte, error) {
+	return serialization.SerializeStruct(f.filter)
+}
+
+func (f *filterTransformation) UnmarshalJSON(data []byte) error {
+	v, err := serialization.DeserializeStruct(data)
+	if err != nil {
+		return err
+	}
+	f.filter = v.(Filter)
+	return nil
+}
+
 type Mapper interface {
 	Map(Context, *lrdd.Row) (*lrdd.Row, error)
 }
@@ -113,6 +134,7 @@ func (m *mapTransformation) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// FlatMapper emits zero or more rows for each input row. Returning no rows drops the input row.
 type FlatMapper interface {
 	FlatMap(Context, *lrdd.Row) ([]*lrdd.Row, error)
 }
@@ -127,6 +149,9 @@ func (f *flatMapTransformation) Apply(ctx transformation.Context, in chan *lrdd.
 		if err != nil {
 			return err
 		}
+		if len(outRows) == 0 {
+			continue
+		}
 		if err := out.Write(outRows...); err != nil {
 			return err
 		}
@@ -156,6 +181,8 @@ type sortTransformation struct {
 	rows   []*lrdd.Row
 }
 
+func (s *sortTransformation) PreservesKeys() bool { return true }
+
 func (s *sortTransformation) Apply(ctx transformation.Context, in chan *lrdd.Row, out output.Output) error {
 	for row := range in {
 		s.rows = append(s.rows, row)
@@ -200,6 +227,8 @@ type combinerTransformation struct {
 	combinerPrototype Combiner
 }
 
+func (f *combinerTransformation) PreservesKeys() bool { return true }
+
 func (f *combinerTransformation) Apply(c transformation.Context, in chan *lrdd.Row, out output.Output) error {
 	combiners := make(map[string]Combiner)
 	state := make(map[string]interface{})
@@ -263,6 +292,8 @@ type reduceTransformation struct {
 	reducerPrototype Reducer
 }
 
+func (f *reduceTransformation) PreservesKeys() bool { return true }
+
 func (f *reduceTransformation) Apply(c transformation.Context, in chan *lrdd.Row, out output.Output) error {
 	reducers := make(map[string]Reducer)
 	state := make(map[string]interface{})
@@ -312,6 +343,200 @@ func (f *reduceTransformation) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// GroupMapper processes all rows with the same key at once.
+type GroupMapper interface {
+	MapGroup(ctx Context, key string, rows []*lrdd.Row) ([]*lrdd.Row, error)
+}
+
+type groupMapTransformation struct {
+	groupMapper GroupMapper
+}
+
+// Apply collects the rows by key, and calls MapGroup for each key in the order of their first appearance.
+func (g *groupMapTransformation) Apply(c transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	groups := make(map[string][]*lrdd.Row)
+	var keys []string
+	for row := range in {
+		if _, ok := groups[row.Key]; !ok {
+			keys = append(keys, row.Key)
+		}
+		groups[row.Key] = append(groups[row.Key], row)
+	}
+	for _, key := range keys {
+		rows, err := g.groupMapper.MapGroup(replacePartitionKey(c, key), key, groups[key])
+		if err != nil {
+			return err
+		}
+		// release the group as early as possible
+		delete(groups, key)
+
+		if err := out.Write(rows...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *groupMapTransformation) MarshalJSON() ([]byte, error) {
+	return serialization.SerializeStruct(g.groupMapper)
+}
+
+func (g *groupMapTransformation) UnmarshalJSON(data []byte) error {
+	groupMapper, err := serialization.DeserializeStruct(data)
+	if err != nil {
+		return err
+	}
+	g.groupMapper = groupMapper.(GroupMapper)
+	return nil
+}
+
+// Folder folds the rows with the same key into a row.
+type Folder interface {
+	Fold(ctx Context, acc, cur *lrdd.Row) (next *lrdd.Row, err error)
+}
+
+type foldTransformation struct {
+	folder Folder
+}
+
+func (f *foldTransformation) PreservesKeys() bool { return true }
+
+// Apply folds the rows by key, starting from the first row of each key.
+// Folded rows are emitted in the order of the first appearance of their keys.
+func (f *foldTransformation) Apply(c transformation.Context, in chan *lrdd.Row, out output.Output) error {
+	accs := make(map[string]*lrdd.Row)
+	var keys []string
+
+	for row := range in {
+		acc, ok := accs[row.Key]
+		if !ok {
+			accs[row.Key] = row
+			keys = append(keys, row.Key)
+			continue
+		}
+		next, err := f.folder.Fold(replacePartitionKey(c, row.Key), acc, row)
+		if err != nil {
+			return err
+		}
+		// the key is kept so that the folded rows can be folded again
+		accs[row.Key] = &lrdd.Row{Key: row.Key, Value: next.Value}
+	}
+
+	rows := make([]*lrdd.Row, len(keys))
+	for i, key := range keys {
+		rows[i] = accs[key]
+	}
+	return out.Write(rows...)
+}
+
+func (f *foldTransformation) MarshalJSON() ([]byte, error) {
+	return serialization.SerializeStruct(f.folder)
+}
+
+func (f *foldTransformation) UnmarshalJSON(data []byte) error {
+	folder, err := serialization.DeserializeStruct(data)
+	if err != nil {
+		return err
+	}
+	f.folder = folder.(Folder)
+	return nil
+}
+
+// RetryClassifier can be implemented by user-defined functions (e.g. a Mapper) to tell
+// whether an error returned by them is transient. Errors are non-retryable by default.
+type RetryClassifier = transformation.RetryClassifier
+
+// isRetryable consults the user-defined function if it implements RetryClassifier.
+func isRetryable(fn interface{}, err error) bool {
+	c, ok := fn.(RetryClassifier)
+	return ok && c.IsRetryable(err)
+}
+
+func (t *transformerTransformation) IsRetryable(err error) bool {
+	return isRetryable(t.transformer, err)
+}
+
+func (f *filterTransformation) IsRetryable(err error) bool {
+	return isRetryable(f.filter, err)
+}
+
+func (m *mapTransformation) IsRetryable(err error) bool {
+	return isRetryable(m.mapper, err)
+}
+
+func (f *flatMapTransformation) IsRetryable(err error) bool {
+	return isRetryable(f.flatMapper, err)
+}
+
+func (s *sortTransformation) IsRetryable(err error) bool {
+	return isRetryable(s.sorter, err)
+}
+
+func (f *combinerTransformation) IsRetryable(err error) bool {
+	return isRetryable(f.combinerPrototype, err)
+}
+
+func (f *reduceTransformation) IsRetryable(err error) bool {
+	return isRetryable(f.reducerPrototype, err)
+}
+
+func (f *foldTransformation) IsRetryable(err error) bool {
+	return isRetryable(f.folder, err)
+}
+
+func (g *groupMapTransformation) IsRetryable(err error) bool {
+	return isRetryable(g.groupMapper, err)
+}
+
+// Projector can be implemented by user-defined functions (e.g. a Mapper) to declare the fields of
+// their output rows which the downstream needs. Other fields are dropped before the rows are sent.
+// The rows are expected to have a map value.
+type Projector = transformation.Projector
+
+// projectionOf consults the user-defined function if it implements Projector.
+func projectionOf(fn interface{}) []string {
+	if p, ok := fn.(Projector); ok {
+		return p.Projection()
+	}
+	return nil
+}
+
+func (t *transformerTransformation) Projection() []string {
+	return projectionOf(t.transformer)
+}
+
+func (f *filterTransformation) Projection() []string {
+	return projectionOf(f.filter)
+}
+
+func (m *mapTransformation) Projection() []string {
+	return projectionOf(m.mapper)
+}
+
+func (f *flatMapTransformation) Projection() []string {
+	return projectionOf(f.flatMapper)
+}
+
+func (s *sortTransformation) Projection() []string {
+	return projectionOf(s.sorter)
+}
+
+func (f *combinerTransformation) Projection() []string {
+	return projectionOf(f.combinerPrototype)
+}
+
+func (f *reduceTransformation) Projection() []string {
+	return projectionOf(f.reducerPrototype)
+}
+
+func (f *foldTransformation) Projection() []string {
+	return projectionOf(f.folder)
+}
+
+func (g *groupMapTransformation) Projection() []string {
+	return projectionOf(g.groupMapper)
+}
+
 type partitionKeyContext struct {
 	Context
 	partitionKey string
@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ab180/lrmr/cluster"
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/airbloc/logger"
+)
+
+// blockNs is the namespace of the blocks in the cluster state.
+const blockNs = "blocks/"
+
+// BlockKey returns the key of the block in the cluster state. Deleting the key frees the block on every worker.
+func BlockKey(blockID string) string {
+	return path.Join(blockNs, blockID)
+}
+
+// BlockProvider is implemented by the contexts of the tasks running on a worker, which can store
+// their partitions in the worker.
+type BlockProvider interface {
+	Blocks() *BlockStore
+}
+
+// BlockStore holds the partitions of the datasets materialized on the worker (e.g. by lrmr.Dataset.Cache),
+// so that the jobs reading them run on the worker instead of computing them again. A block is the set of
+// the partitions of a dataset, which are spread over the workers having run their tasks. The partitions are
+// held in memory, encoded by lrdd.RowEncoder, until the key of their block is deleted from the cluster state.
+type BlockStore struct {
+	// partitions are the encoded rows of the partitions, keyed by block ID and partition ID.
+	partitions map[string]map[string][]byte
+	mu         sync.RWMutex
+}
+
+func newBlockStore() *BlockStore {
+	return &BlockStore{partitions: make(map[string]map[string][]byte)}
+}
+
+// Put stores the encoded rows of a partition of the block, replacing the previous one.
+func (s *BlockStore) Put(blockID, partitionID string, rows []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.partitions[blockID]
+	if !ok {
+		b = make(map[string][]byte)
+		s.partitions[blockID] = b
+	}
+	b[partitionID] = rows
+}
+
+// Get returns the encoded rows of a partition of the block. It returns false if the partition is not on the worker.
+func (s *BlockStore) Get(blockID, partitionID string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, ok := s.partitions[blockID][partitionID]
+	return rows, ok
+}
+
+// Free releases the partitions of the block.
+func (s *BlockStore) Free(blockID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.partitions, blockID)
+}
+
+// Size returns the total size of the partitions held in encoded form.
+func (s *BlockStore) Size() (size int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, b := range s.partitions {
+		for _, rows := range b {
+			size += len(rows)
+		}
+	}
+	return size
+}
+
+// watch frees the blocks whose keys are deleted from the cluster state until the context is done.
+func (s *BlockStore) watch(ctx context.Context, cs cluster.State) error {
+	events, err := cs.Watch(ctx, blockNs)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer func() {
+			if err := logger.WrapRecover(recover()); err != nil {
+				log.Error("Panic occurred during watching blocks: {}", err.Pretty())
+			}
+		}()
+		for e := range events {
+			if e.Type != coordinator.DeleteEvent {
+				continue
+			}
+			s.Free(strings.TrimPrefix(e.Key(), blockNs))
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/coordinator"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBlockStore(t *testing.T) {
+	Convey("Given a block store", t, func() {
+		s := newBlockStore()
+		s.Put("B1", "0", []byte("foo"))
+		s.Put("B1", "1", []byte("bar"))
+		s.Put("B2", "0", []byte("baz"))
+
+		Convey("It should return the partitions stored", func() {
+			rows, ok := s.Get("B1", "1")
+			So(ok, ShouldBeTrue)
+			So(string(rows), ShouldEqual, "bar")
+			So(s.Size(), ShouldEqual, 9)
+
+			_, ok = s.Get("B2", "1")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("When a block is freed", func() {
+			s.Free("B1")
+
+			Convey("Only the partitions of the block should be released", func() {
+				_, ok := s.Get("B1", "0")
+				So(ok, ShouldBeFalse)
+				_, ok = s.Get("B2", "0")
+				So(ok, ShouldBeTrue)
+				So(s.Size(), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When the key of a block is deleted from the cluster state", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			crd := coordinator.NewLocalMemory()
+			So(crd.Put(ctx, BlockKey("B1"), time.Now()), ShouldBeNil)
+			So(s.watch(ctx, crd), ShouldBeNil)
+
+			_, err := crd.Delete(ctx, BlockKey("B1"))
+			So(err, ShouldBeNil)
+
+			Convey("The block should be freed", func() {
+				So(func() bool {
+					for i := 0; i < 100; i++ {
+						if _, ok := s.Get("B1", "0"); !ok {
+							return true
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+					return false
+				}(), ShouldBeTrue)
+				_, ok := s.Get("B2", "0")
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"sync"
+
+	"github.com/ab180/lrmr/internal/serialization"
+)
+
+// broadcastStore shares the broadcasts of a job among its tasks in the worker, so that they are
+// deserialized once per job. The broadcasts are released after every task of the job in the worker finishes.
+type broadcastStore struct {
+	entries map[string]*broadcastEntry
+	mu      sync.Mutex
+}
+
+type broadcastEntry struct {
+	broadcast serialization.Broadcast
+	size      int
+	refs      int
+}
+
+func newBroadcastStore() *broadcastStore {
+	return &broadcastStore{entries: make(map[string]*broadcastEntry)}
+}
+
+// Acquire returns the broadcasts of the job, deserializing them from given data if they are not held.
+// Release must be called after the task using the broadcasts finishes.
+func (s *broadcastStore) Acquire(jobID string, data map[string][]byte) (serialization.Broadcast, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[jobID]; ok {
+		e.refs++
+		return e.broadcast, nil
+	}
+	b, err := serialization.DeserializeBroadcast(data)
+	if err != nil {
+		return nil, err
+	}
+	e := &broadcastEntry{broadcast: b, refs: 1}
+	for _, raw := range data {
+		e.size += len(raw)
+	}
+	s.entries[jobID] = e
+	return b, nil
+}
+
+// Release drops a reference to the broadcasts of the job, and releases them if no tasks use them.
+func (s *broadcastStore) Release(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[jobID]
+	if !ok {
+		return
+	}
+	e.refs--
+	if e.refs <= 0 {
+		delete(s.entries, jobID)
+	}
+}
+
+// Size returns the total size of the broadcasts held in serialized form.
+func (s *broadcastStore) Size() (size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.entries {
+		size += e.size
+	}
+	return size
+}
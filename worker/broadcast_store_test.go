@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBroadcastStore(t *testing.T) {
+	Convey("Given a broadcast store", t, func() {
+		s := newBroadcastStore()
+		data := map[string][]byte{"foo": []byte(`"bar"`)}
+
+		Convey("When tasks of a job acquire broadcasts", func() {
+			b1, err := s.Acquire("J1", data)
+			So(err, ShouldBeNil)
+			b2, err := s.Acquire("J1", data)
+			So(err, ShouldBeNil)
+
+			Convey("It should deserialize them once", func() {
+				So(b1["foo"], ShouldEqual, "bar")
+				So(b2, ShouldResemble, b1)
+				So(s.Size(), ShouldEqual, len(`"bar"`))
+			})
+
+			Convey("It should hold them until every task releases", func() {
+				s.Release("J1")
+				So(s.Size(), ShouldEqual, len(`"bar"`))
+
+				s.Release("J1")
+				So(s.Size(), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When acquiring invalid broadcasts", func() {
+			_, err := s.Acquire("J1", map[string][]byte{"foo": []byte("{")})
+
+			Convey("It should return an error", func() {
+				So(err, ShouldNotBeNil)
+				So(s.Size(), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// heartbeat publishes the status of a worker to the coordinator periodically, and remembers the time of
+// the last successful publish.
+type heartbeat struct {
+	interval time.Duration
+	publish  func(ctx context.Context) error
+	last     atomic.Int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newHeartbeat(interval time.Duration, publish func(ctx context.Context) error) *heartbeat {
+	return &heartbeat{
+		interval: interval,
+		publish:  publish,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start publishes the status periodically until Close is called. It does nothing if the interval is not set.
+func (h *heartbeat) Start() {
+	if h.interval == 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(h.interval)
+		defer t.Stop()
+		for {
+			h.beat()
+			select {
+			case <-t.C:
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (h *heartbeat) beat() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.interval)
+	defer cancel()
+
+	if err := h.publish(ctx); err != nil {
+		log.Warn("Failed to send heartbeat: {}", err)
+		return
+	}
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Last returns the time of the last successful heartbeat. It returns zero time if there was none.
+func (h *heartbeat) Last() time.Time {
+	last := h.last.Load()
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+func (h *heartbeat) Close() {
+	h.stopOnce.Do(func() {
+		close(h.stop)
+	})
+}
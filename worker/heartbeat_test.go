@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.uber.org/atomic"
+)
+
+func TestHeartbeat(t *testing.T) {
+	Convey("Given a heartbeat", t, func() {
+		var beats atomic.Int32
+		var failing atomic.Bool
+		h := newHeartbeat(10*time.Millisecond, func(ctx context.Context) error {
+			if failing.Load() {
+				return errors.New("coordinator unavailable")
+			}
+			beats.Inc()
+			return nil
+		})
+		Reset(h.Close)
+
+		Convey("It should have no heartbeat before starting", func() {
+			So(h.Last().IsZero(), ShouldBeTrue)
+		})
+
+		Convey("When it's started", func() {
+			startedAt := time.Now()
+			h.Start()
+			time.Sleep(50 * time.Millisecond)
+
+			Convey("It should publish periodically", func() {
+				So(beats.Load(), ShouldBeGreaterThan, 1)
+				So(h.Last(), ShouldHappenAfter, startedAt)
+			})
+
+			Convey("It should keep the last successful heartbeat on failures", func() {
+				failing.Store(true)
+				time.Sleep(20 * time.Millisecond)
+				last := h.Last()
+				time.Sleep(30 * time.Millisecond)
+				So(h.Last(), ShouldEqual, last)
+			})
+		})
+	})
+}
+
+func TestWorker_StatusHandler(t *testing.T) {
+	Convey("Given a worker running tasks", t, func() {
+		w := &Worker{
+			taskQueue:  newTaskQueue(0),
+			broadcasts: newBroadcastStore(),
+			blocks:     newBlockStore(),
+			startedAt:  time.Now().Add(-time.Minute),
+		}
+		w.runningTasks.Store("job1/Map0/1", &TaskExecutor{})
+		w.runningTasks.Store("job1/Map0/0", &TaskExecutor{})
+
+		Convey("Its status should be served in JSON", func() {
+			rec := httptest.NewRecorder()
+			w.StatusHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+
+			So(rec.Header().Get("Content-Type"), ShouldEqual, "application/json")
+			body := rec.Body.String()
+			So(body, ShouldContainSubstring, `"RunningTaskIDs":["job1/Map0/0","job1/Map0/1"]`)
+			So(body, ShouldContainSubstring, `"LastHeartbeat":"0001-01-01T00:00:00Z"`)
+		})
+
+		Convey("Its uptime should be measured from the start", func() {
+			So(w.Status().Uptime, ShouldBeGreaterThanOrEqualTo, time.Minute)
+		})
+	})
+}
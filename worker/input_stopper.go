@@ -0,0 +1,53 @@
+package worker
+
+import "sync"
+
+// inputStopper notifies the tasks of a job that their inputs are stopped by job.Manager.StopInputs,
+// which stops the inputs of the tasks in the stage and its preceding stages.
+type inputStopper struct {
+	stoppedAt int
+	stopped   map[int]chan struct{}
+	mu        sync.Mutex
+}
+
+func newInputStopper() *inputStopper {
+	return &inputStopper{
+		stoppedAt: -1,
+		stopped:   make(map[int]chan struct{}),
+	}
+}
+
+// Stop stops the inputs of the tasks in the stages up to given index.
+func (s *inputStopper) Stop(stageIndex int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stageIndex <= s.stoppedAt {
+		return
+	}
+	s.stoppedAt = stageIndex
+	for i, ch := range s.stopped {
+		if i <= stageIndex {
+			close(ch)
+			delete(s.stopped, i)
+		}
+	}
+}
+
+// Stopped returns a channel closed when the inputs of the tasks in the stage of given index are stopped.
+func (s *inputStopper) Stopped(stageIndex int) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stageIndex <= s.stoppedAt {
+		ch := make(chan struct{})
+		close(ch)
+		return ch
+	}
+	ch, ok := s.stopped[stageIndex]
+	if !ok {
+		ch = make(chan struct{})
+		s.stopped[stageIndex] = ch
+	}
+	return ch
+}
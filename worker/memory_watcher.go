@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// memoryWatcher sheds the load of a worker under memory pressure, rather than letting it crash by OOM.
+// When the heap grows above the high watermark, the tasks stop processing new inputs and spill them from
+// their input queues to disk, until the heap shrinks below the low watermark. The spilled inputs are
+// processed after the pressure is relieved.
+type memoryWatcher struct {
+	gate      *pauseGate
+	high, low uint64
+	interval  time.Duration
+	pressured atomic.Bool
+
+	// readHeap returns the current heap size in bytes. It can be replaced in tests.
+	readHeap func() uint64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newMemoryWatcher(opt MemoryOptions) *memoryWatcher {
+	low := opt.LowWatermark
+	if low == 0 || low > opt.HighWatermark {
+		low = opt.HighWatermark / 10 * 8
+	}
+	return &memoryWatcher{
+		gate:     &pauseGate{},
+		high:     opt.HighWatermark,
+		low:      low,
+		interval: opt.CheckInterval,
+		readHeap: readHeapAlloc,
+		stop:     make(chan struct{}),
+	}
+}
+
+func readHeapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// Start checks the heap periodically until Close is called. It does nothing if the high watermark is not set.
+func (m *memoryWatcher) Start() {
+	if m.high == 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(m.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				m.check()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (m *memoryWatcher) check() {
+	heap := m.readHeap()
+	if !m.pressured.Load() && heap >= m.high {
+		log.Warn("Heap size {} exceeded high watermark {}. Spilling inputs of the tasks to disk.", heap, m.high)
+		m.pressured.Store(true)
+		m.gate.Pause()
+
+		// inputs are paused, so the garbage can be collected without new allocations piling up
+		runtime.GC()
+	} else if m.pressured.Load() && heap < m.low {
+		log.Info("Heap size {} is below low watermark {}. Resuming inputs of the tasks.", heap, m.low)
+		m.pressured.Store(false)
+		m.gate.Resume()
+	}
+}
+
+// Pressured returns true if the heap has exceeded the high watermark and has not shrunk below the low watermark.
+func (m *memoryWatcher) Pressured() bool {
+	return m.pressured.Load()
+}
+
+func (m *memoryWatcher) Close() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+		m.gate.Resume()
+	})
+}
@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemoryWatcher(t *testing.T) {
+	Convey("Given a memory watcher", t, func() {
+		m := newMemoryWatcher(MemoryOptions{HighWatermark: 1000, CheckInterval: time.Second})
+		heap := uint64(0)
+		m.readHeap = func() uint64 { return heap }
+
+		Convey("When the heap exceeds the high watermark", func() {
+			heap = 1000
+			m.check()
+
+			Convey("It should pause the inputs", func() {
+				So(m.Pressured(), ShouldBeTrue)
+
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				So(m.gate.Wait(ctx), ShouldNotBeNil)
+			})
+
+			Convey("It should keep pausing until the heap shrinks below the low watermark", func() {
+				heap = 900
+				m.check()
+				So(m.Pressured(), ShouldBeTrue)
+
+				heap = 700
+				m.check()
+				So(m.Pressured(), ShouldBeFalse)
+				So(m.gate.Wait(context.Background()), ShouldBeNil)
+			})
+		})
+	})
+}
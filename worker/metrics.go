@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// workerMetrics is the Prometheus metrics of the tasks run by a worker, labeled by their stage.
+// Each worker has its own registry, so that multiple workers can run in a process.
+type workerMetrics struct {
+	registry *prometheus.Registry
+
+	tasksRunning   *prometheus.GaugeVec
+	tasksCompleted *prometheus.CounterVec
+	tasksFailed    *prometheus.CounterVec
+	rowsProcessed  *prometheus.CounterVec
+	shuffleBytes   *prometheus.CounterVec
+	taskDuration   *prometheus.HistogramVec
+}
+
+func newWorkerMetrics() *workerMetrics {
+	labels := []string{"stage"}
+	m := &workerMetrics{
+		registry: prometheus.NewRegistry(),
+		tasksRunning: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "lrmr",
+			Name:      "tasks_running",
+			Help:      "Number of the tasks running in the worker.",
+		}, labels),
+		tasksCompleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "lrmr",
+			Name:      "tasks_completed_total",
+			Help:      "Number of the tasks succeeded in the worker.",
+		}, labels),
+		tasksFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "lrmr",
+			Name:      "tasks_failed_total",
+			Help:      "Number of the tasks failed or aborted in the worker.",
+		}, labels),
+		rowsProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "lrmr",
+			Name:      "rows_processed_total",
+			Help:      "Number of the input rows processed by the finished tasks.",
+		}, labels),
+		shuffleBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "lrmr",
+			Name:      "shuffle_bytes_total",
+			Help:      "Size of the rows sent to the next stage by the finished tasks, in bytes.",
+		}, labels),
+		taskDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "lrmr",
+			Name:      "task_duration_seconds",
+			Help:      "Running time of the finished tasks.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, labels),
+	}
+	m.registry.MustRegister(
+		m.tasksRunning,
+		m.tasksCompleted,
+		m.tasksFailed,
+		m.rowsProcessed,
+		m.shuffleBytes,
+		m.taskDuration,
+		prometheus.NewGoCollector(),
+	)
+	return m
+}
+
+// taskMetrics records the metrics of a task. A task is counted as running from start until finish,
+// which can be called multiple times (e.g. aborted after success) but counts only once.
+type taskMetrics struct {
+	m         *workerMetrics
+	stage     string
+	startedAt time.Time
+	finished  bool
+	mu        sync.Mutex
+}
+
+func (m *workerMetrics) newTaskMetrics(stage string) *taskMetrics {
+	return &taskMetrics{m: m, stage: stage}
+}
+
+func (t *taskMetrics) start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.startedAt.IsZero() || t.finished {
+		return
+	}
+	t.startedAt = time.Now()
+	t.m.tasksRunning.WithLabelValues(t.stage).Inc()
+}
+
+func (t *taskMetrics) finish(failed bool, rows, bytes int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished {
+		return
+	}
+	t.finished = true
+	if t.startedAt.IsZero() {
+		// aborted before start
+		return
+	}
+	t.m.tasksRunning.WithLabelValues(t.stage).Dec()
+	if failed {
+		t.m.tasksFailed.WithLabelValues(t.stage).Inc()
+	} else {
+		t.m.tasksCompleted.WithLabelValues(t.stage).Inc()
+	}
+	t.m.rowsProcessed.WithLabelValues(t.stage).Add(float64(rows))
+	t.m.shuffleBytes.WithLabelValues(t.stage).Add(float64(bytes))
+	t.m.taskDuration.WithLabelValues(t.stage).Observe(time.Since(t.startedAt).Seconds())
+}
+
+// MetricsHandler returns an HTTP handler serving the metrics of the worker in Prometheus format.
+func (w *Worker) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(w.metrics.registry, promhttp.HandlerOpts{})
+}
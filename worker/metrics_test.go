@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkerMetrics(t *testing.T) {
+	Convey("Given metrics of a worker", t, func() {
+		m := newWorkerMetrics()
+
+		Convey("When tasks run and finish", func() {
+			succeeded := m.newTaskMetrics("Map0")
+			succeeded.start()
+			failed := m.newTaskMetrics("Map0")
+			failed.start()
+			running := m.newTaskMetrics("Reduce1")
+			running.start()
+
+			succeeded.finish(false, 10, 100)
+			failed.finish(true, 5, 50)
+
+			// aborting after the success should not be counted again
+			succeeded.finish(true, 10, 100)
+
+			Convey("They should be counted by stage", func() {
+				So(testutil.ToFloat64(m.tasksRunning.WithLabelValues("Map0")), ShouldEqual, 0)
+				So(testutil.ToFloat64(m.tasksRunning.WithLabelValues("Reduce1")), ShouldEqual, 1)
+				So(testutil.ToFloat64(m.tasksCompleted.WithLabelValues("Map0")), ShouldEqual, 1)
+				So(testutil.ToFloat64(m.tasksFailed.WithLabelValues("Map0")), ShouldEqual, 1)
+				So(testutil.ToFloat64(m.rowsProcessed.WithLabelValues("Map0")), ShouldEqual, 15)
+				So(testutil.ToFloat64(m.shuffleBytes.WithLabelValues("Map0")), ShouldEqual, 150)
+			})
+
+			Convey("They should be served in Prometheus format", func() {
+				w := &Worker{metrics: m}
+				rec := httptest.NewRecorder()
+				w.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+				body := rec.Body.String()
+				So(body, ShouldContainSubstring, `lrmr_tasks_completed_total{stage="Map0"} 1`)
+				So(body, ShouldContainSubstring, `lrmr_tasks_running{stage="Reduce1"} 1`)
+				So(strings.Contains(body, "lrmr_task_duration_seconds_bucket"), ShouldBeTrue)
+			})
+		})
+
+		Convey("When a task is aborted before it starts", func() {
+			tm := m.newTaskMetrics("Map0")
+			tm.finish(true, 0, 0)
+			tm.start()
+
+			Convey("It should not be counted", func() {
+				So(testutil.ToFloat64(m.tasksRunning.WithLabelValues("Map0")), ShouldEqual, 0)
+				So(testutil.ToFloat64(m.tasksFailed.WithLabelValues("Map0")), ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -2,7 +2,9 @@ package worker
 
 import (
 	"runtime"
+	"time"
 
+	"github.com/ab180/lrmr/cluster"
 	"github.com/ab180/lrmr/cluster/node"
 	"github.com/ab180/lrmr/output"
 	"github.com/creasty/defaults"
@@ -16,15 +18,71 @@ type Options struct {
 	// By default, it will be number of CPUs in the machine.
 	Concurrency int `default:"-"`
 
+	// MaxConcurrentTasks limits the number of tasks running simultaneously in a worker.
+	// Tasks beyond the limit wait for running ones to finish. Zero means unlimited.
+	MaxConcurrentTasks int `default:"0"`
+
 	// NodeTags is used for partitioner.
 	NodeTags map[string]string `default:"{}"`
 	NodeType node.Type         `default:"worker"`
 
 	Input struct {
+		// QueueLength is the number of input batches queued for a task. The inputs are received ahead of
+		// the processing of the task up to the limits of the queue, overlapping the network transfer with it.
 		QueueLength int `default:"1000"`
+
+		// MaxQueuedRows is the number of input rows queued for a task. When a task falls behind,
+		// its upstream is blocked until the queued rows are consumed. Zero means unlimited.
+		MaxQueuedRows int `default:"100000"`
+
+		// MaxQueuedBytes bounds the size of the input rows queued for a task like MaxQueuedRows,
+		// which limits the memory used by the queue regardless of the size of the rows. Zero means unlimited.
+		MaxQueuedBytes int64 `default:"67108864"`
+
 		MaxRecvSize int `default:"67108864"`
 	}
 	Output output.Options
+
+	// MaxBroadcastSize is the maximum size of a broadcast in serialized form. Jobs having a larger
+	// broadcast are rejected, as broadcasts are held in the memory of every worker. Zero means unlimited.
+	MaxBroadcastSize int `default:"16777216"`
+
+	// ReportInterval is the interval of reporting metrics of the running tasks.
+	ReportInterval time.Duration `default:"1s"`
+
+	// HeartbeatInterval is the interval of publishing the status of the worker to the coordinator.
+	// See Worker.Status for the details. Zero disables it.
+	HeartbeatInterval time.Duration `default:"5s"`
+
+	// DrainTimeout is the grace period for the running tasks to finish on shutdown.
+	DrainTimeout time.Duration `default:"30s"`
+
+	Memory MemoryOptions
+
+	// RPC configures the connections to the other nodes and the registration of the worker.
+	RPC cluster.Options
+
+	// MetricsListenHost is the address which lrmr.RunWorker serves the Prometheus metrics of the worker on,
+	// at /metrics, and the status of the worker at /status. Empty disables it. See Worker.MetricsHandler
+	// and Worker.StatusHandler for serving them by yourself.
+	MetricsListenHost string
+}
+
+// MemoryOptions sheds the load of a worker under memory pressure, rather than letting it crash by OOM.
+type MemoryOptions struct {
+	// HighWatermark is the heap size in bytes above which the tasks stop processing new inputs,
+	// and spill them to disk instead of keeping them in their input queues. Zero disables it.
+	HighWatermark uint64 `default:"0"`
+
+	// LowWatermark is the heap size in bytes below which the tasks resume processing inputs.
+	// Defaults to 80% of HighWatermark.
+	LowWatermark uint64 `default:"0"`
+
+	// CheckInterval is the interval of checking the heap size.
+	CheckInterval time.Duration `default:"500ms"`
+
+	// SpillDir is the directory of the spilled rows. Defaults to the temporary directory of the OS.
+	SpillDir string
 }
 
 func DefaultOptions() (o Options) {
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// closedChan is returned by pauseGate.Resumed if the gate is not paused.
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// pauseGate blocks tasks of a paused job from pulling new inputs until the job is resumed.
+type pauseGate struct {
+	paused  chan struct{}
+	resumed chan struct{}
+	mu      sync.Mutex
+}
+
+func (g *pauseGate) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+		if g.paused == nil {
+			g.paused = make(chan struct{})
+		}
+		close(g.paused)
+	}
+}
+
+func (g *pauseGate) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+		g.paused = nil
+	}
+}
+
+// Paused returns a channel which is closed when the gate is paused.
+func (g *pauseGate) Paused() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.paused == nil {
+		g.paused = make(chan struct{})
+		if g.resumed != nil {
+			close(g.paused)
+		}
+	}
+	return g.paused
+}
+
+// Resumed returns a channel which is closed when the gate is not paused.
+func (g *pauseGate) Resumed() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.resumed == nil {
+		return closedChan
+	}
+	return g.resumed
+}
+
+// Wait blocks while the gate is paused. It returns an error if the context is done before resuming.
+func (g *pauseGate) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
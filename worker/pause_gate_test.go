@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestPauseGate(t *testing.T) {
+	Convey("Given a pause gate", t, func() {
+		g := &pauseGate{}
+		paused := g.Paused()
+
+		Convey("It should not be paused at first", func() {
+			So(isClosed(paused), ShouldBeFalse)
+			So(isClosed(g.Resumed()), ShouldBeTrue)
+			So(g.Wait(context.TODO()), ShouldBeNil)
+		})
+
+		Convey("When it's paused", func() {
+			g.Pause()
+
+			Convey("The channel taken before the pause should be closed", func() {
+				So(isClosed(paused), ShouldBeTrue)
+				So(isClosed(g.Paused()), ShouldBeTrue)
+			})
+
+			Convey("The channel of the resume should be closed on resume", func() {
+				resumed := g.Resumed()
+				So(isClosed(resumed), ShouldBeFalse)
+
+				g.Resume()
+				So(isClosed(resumed), ShouldBeTrue)
+			})
+
+			Convey("Pausing again should not panic", func() {
+				So(g.Pause, ShouldNotPanic)
+			})
+
+			Convey("When it's resumed", func() {
+				g.Resume()
+
+				Convey("A new channel should wait for the next pause", func() {
+					next := g.Paused()
+					So(isClosed(next), ShouldBeFalse)
+					So(g.Wait(context.TODO()), ShouldBeNil)
+
+					g.Pause()
+					So(isClosed(next), ShouldBeTrue)
+				})
+			})
+		})
+	})
+}
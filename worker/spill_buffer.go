@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/pkg/errors"
+)
+
+// spillBuffer buffers rows in memory, and spills them into a temporary file while shouldSpill returns true.
+// The rows are replayed in the order of writes. Remove should be called to remove the file after use.
+type spillBuffer struct {
+	dir         string
+	shouldSpill func() bool
+
+	rows    []*lrdd.Row
+	file    *os.File
+	w       *bufio.Writer
+	enc     *lrdd.RowEncoder
+	spilled int
+}
+
+func newSpillBuffer(dir string, shouldSpill func() bool) *spillBuffer {
+	return &spillBuffer{dir: dir, shouldSpill: shouldSpill}
+}
+
+func (b *spillBuffer) Write(rows ...*lrdd.Row) error {
+	// once spilled, the rest are also spilled to keep the order
+	if b.file == nil && (b.shouldSpill == nil || !b.shouldSpill()) {
+		b.rows = append(b.rows, rows...)
+		return nil
+	}
+	if b.file == nil {
+		f, err := ioutil.TempFile(b.dir, "lrmr-spill-")
+		if err != nil {
+			return errors.Wrap(err, "create spill file")
+		}
+		b.file = f
+		b.w = bufio.NewWriter(f)
+		b.enc = lrdd.NewRowEncoder(b.w)
+	}
+	if err := b.enc.Encode(rows...); err != nil {
+		return job.MarkSerializationError(errors.Wrap(err, "spill rows"))
+	}
+	b.spilled += len(rows)
+	return nil
+}
+
+func (b *spillBuffer) Close() error {
+	return nil
+}
+
+// Len returns the number of the rows in the buffer, including the spilled ones.
+func (b *spillBuffer) Len() int {
+	return len(b.rows) + b.spilled
+}
+
+// Replay calls fn with every row in the buffer, in the order of writes. It can be called multiple times.
+func (b *spillBuffer) Replay(fn func(*lrdd.Row) error) error {
+	for _, r := range b.rows {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if b.file == nil {
+		return nil
+	}
+	if err := b.w.Flush(); err != nil {
+		return errors.Wrap(err, "flush spill file")
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "rewind spill file")
+	}
+	next, decodeErr := lrdd.DecodeRows(b.file)
+	for row, ok := next(); ok; row, ok = next() {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := decodeErr(); err != nil {
+		return job.MarkSerializationError(errors.Wrap(err, "read spill file"))
+	}
+	// rewound to the end, so that further writes are appended
+	_, err := b.file.Seek(0, io.SeekEnd)
+	return err
+}
+
+// Remove frees the rows and removes the spilled file.
+func (b *spillBuffer) Remove() {
+	b.rows = nil
+	if b.file != nil {
+		_ = b.file.Close()
+		_ = os.Remove(b.file.Name())
+		b.file = nil
+	}
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ab180/lrmr/lrdd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	Convey("Given a spill buffer", t, func() {
+		dir, err := ioutil.TempDir("", "lrmr-spill-test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		pressured := false
+		b := newSpillBuffer(dir, func() bool { return pressured })
+		defer b.Remove()
+
+		Convey("When rows are written under memory pressure", func() {
+			for i := 0; i < 10; i++ {
+				pressured = i >= 5
+				So(b.Write(&lrdd.Row{Key: strconv.Itoa(i)}), ShouldBeNil)
+			}
+			pressured = false
+			So(b.Write(&lrdd.Row{Key: "10"}), ShouldBeNil)
+
+			Convey("It should spill the rows to a file", func() {
+				files, _ := filepath.Glob(filepath.Join(dir, "*"))
+				So(files, ShouldHaveLength, 1)
+				So(b.Len(), ShouldEqual, 11)
+			})
+
+			Convey("It should replay every row in the order of writes, multiple times", func() {
+				for n := 0; n < 2; n++ {
+					var keys []string
+					So(b.Replay(func(r *lrdd.Row) error {
+						keys = append(keys, r.Key)
+						return nil
+					}), ShouldBeNil)
+					So(keys, ShouldResemble, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10"})
+				}
+			})
+
+			Convey("It should remove the file", func() {
+				b.Remove()
+				files, _ := filepath.Glob(filepath.Join(dir, "*"))
+				So(files, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"time"
 
 	"github.com/ab180/lrmr/job"
 	"github.com/ab180/lrmr/transformation"
@@ -35,6 +36,10 @@ func (c taskContext) WorkerLocalOption(key string) interface{} {
 	return c.executor.localOptions[key]
 }
 
+func (c taskContext) Blocks() *BlockStore {
+	return c.executor.blocks
+}
+
 func (c *taskContext) AddMetric(name string, delta int) {
 	c.executor.taskReporter.UpdateMetric(func(metrics job.Metrics) {
 		metrics[name] += int(delta)
@@ -47,6 +52,29 @@ func (c *taskContext) SetMetric(name string, val int) {
 	})
 }
 
+func (c *taskContext) ReportProgress(fraction float64) {
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	c.executor.taskReporter.UpdateStatus(func(ts *job.TaskStatus) {
+		ts.Progress = &fraction
+	})
+}
+
+func (c *taskContext) IncrementCounter(name string) (int64, error) {
+	return c.executor.jobManager.IncrementJobCounter(c, c.executor.task.JobID, name)
+}
+
+func (c *taskContext) Accumulator(name string) transformation.Accumulator {
+	return taskAccumulator{name: name, reporter: c.executor.taskReporter}
+}
+
+func (c *taskContext) StopInputs() error {
+	return c.executor.jobManager.StopInputs(c, c.executor.task.JobID, c.executor.task.StageName)
+}
+
 func (c *taskContext) SetGauge(name string, val float64) {
 	panic("implement me")
 }
@@ -59,5 +87,44 @@ func (c *taskContext) ObserveSummary(name string, val float64) {
 	panic("implement me")
 }
 
+// detachedContext carries the values of its parent, such as tracing spans, without its deadline and cancellation.
+// It is used for deriving the context of a task from the request creating it, which ends before the task.
+type detachedContext struct {
+	parent context.Context
+}
+
+func detachContext(parent context.Context) context.Context {
+	return detachedContext{parent: parent}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// taskAccumulator adds values to the partial value of an accumulator in the task.
+type taskAccumulator struct {
+	name     string
+	reporter *job.TaskReporter
+}
+
+func (a taskAccumulator) Add(delta int64) {
+	a.reporter.AddAccumulator(a.name, delta)
+}
+
 // taskContext implements transformation.Context.
 var _ transformation.Context = (*taskContext)(nil)
+
+// taskContext provides the blocks of its worker.
+var _ BlockProvider = (*taskContext)(nil)
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testContextKey struct{}
+
+func TestDetachContext(t *testing.T) {
+	Convey("Given a context detached from a canceled context", t, func() {
+		parent, cancel := context.WithCancel(context.WithValue(context.Background(), testContextKey{}, "span"))
+		ctx := detachContext(parent)
+		cancel()
+
+		Convey("It should carry the values of the parent", func() {
+			So(ctx.Value(testContextKey{}), ShouldEqual, "span")
+		})
+
+		Convey("It should not be canceled with the parent", func() {
+			So(ctx.Err(), ShouldBeNil)
+
+			_, hasDeadline := ctx.Deadline()
+			So(hasDeadline, ShouldBeFalse)
+		})
+
+		Convey("A context derived from it should be canceled on its own", func() {
+			taskCtx, cancelTask := context.WithCancel(ctx)
+			So(taskCtx.Err(), ShouldBeNil)
+
+			cancelTask()
+			So(taskCtx.Err(), ShouldNotBeNil)
+			So(taskCtx.Value(testContextKey{}), ShouldEqual, "span")
+		})
+	})
+}
@@ -4,16 +4,23 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/ab180/lrmr/cluster"
 	"github.com/ab180/lrmr/input"
 	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/internal/tracing"
 	"github.com/ab180/lrmr/job"
 	"github.com/ab180/lrmr/lrdd"
 	"github.com/ab180/lrmr/output"
 	"github.com/ab180/lrmr/transformation"
 	"github.com/airbloc/logger"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/atomic"
 )
 
 type TaskExecutor struct {
@@ -28,9 +35,33 @@ type TaskExecutor struct {
 	broadcast    serialization.Broadcast
 	localOptions map[string]interface{}
 
+	jobContext   context.Context
 	finishChan   chan struct{}
-	taskReporter *job.TaskReporter
-	jobManager   *job.Manager
+	pause        *pauseGate
+	inputStopped <-chan struct{}
+	queue        *taskQueue
+	priority     int
+	inputSchema  lrdd.Schema
+	timeout      time.Duration
+	memory       *memoryWatcher
+	spillDir     string
+	blocks       *BlockStore
+	metrics      *taskMetrics
+
+	inputRows      atomic.Int64
+	inputBytes     atomic.Int64
+	reportInterval time.Duration
+	taskReporter   *job.TaskReporter
+	jobManager     *job.Manager
+
+	// span traces the task. Abort can be called from other goroutines, hence the mutex.
+	span   trace.Span
+	spanMu sync.Mutex
+
+	// finished is set when the result of the task is reported. The task can be aborted from several goroutines
+	// (e.g. by the job failure while the transformation fails), but the result is reported only once.
+	finished bool
+	mu       sync.Mutex
 }
 
 func NewTaskExecutor(
@@ -46,6 +77,9 @@ func NewTaskExecutor(
 	localOptions map[string]interface{},
 ) *TaskExecutor {
 	ctx, cancel := context.WithCancel(parentCtx)
+	// the span is started before the executor is shared with other goroutines, since its context is
+	// the one of the transformation. It includes the time waiting for the inputs and a slot.
+	ctx, span := startSpan(ctx, task)
 	exec := &TaskExecutor{
 		task:         task,
 		Input:        in,
@@ -53,9 +87,12 @@ func NewTaskExecutor(
 		Output:       out,
 		broadcast:    broadcast,
 		localOptions: localOptions,
-		finishChan:   make(chan struct{}, 1),
+		jobContext:   parentCtx,
+		finishChan:   make(chan struct{}),
 		taskReporter: job.NewTaskReporter(parentCtx, cs, j, task.ID(), status),
 		jobManager:   job.NewManager(cs),
+		timeout:      j.GetStage(task.StageName).TaskTimeout,
+		span:         span,
 	}
 	exec.context = newTaskContext(ctx, exec)
 	exec.cancel = cancel
@@ -63,69 +100,423 @@ func NewTaskExecutor(
 }
 
 func (e *TaskExecutor) Run() {
+	defer close(e.finishChan)
+	defer e.endSpan()
 	defer e.guardPanic()
-	totalRows := 0
+	if e.metrics != nil {
+		e.metrics.start()
+	}
+	log.Verbose("Task {} started", e.task.ID())
+	e.taskReporter.Start(e.reportInterval)
+	e.taskReporter.ReportStart()
+	go e.reportMetricsPeriodically()
 
 	// pipe input.Reader.C to function input channel
 	inputChan := make(chan *lrdd.Row, 100)
+	inputArrived := make(chan struct{})
 	go func() {
 		defer e.guardPanic()
 		defer close(inputChan)
-		for rows := range e.Input.C {
-			for _, r := range rows {
+
+		var arrivalOnce sync.Once
+		defer arrivalOnce.Do(func() { close(inputArrived) })
+
+		// while the worker is under memory pressure, the inputs are spilled to disk instead of waiting,
+		// so that the upstream tasks are not blocked by the full input queue of the paused task
+		var spilled *spillBuffer
+		defer func() {
+			if spilled != nil {
+				spilled.Remove()
+			}
+		}()
+		// replaySpilled sends the spilled inputs ahead of the new ones.
+		// It returns false if the task stops pulling inputs.
+		replaySpilled := func() bool {
+			b := spilled
+			spilled = nil
+			defer b.Remove()
+
+			err := b.Replay(func(r *lrdd.Row) error {
+				size := r.Size()
+				select {
+				case inputChan <- r:
+				case <-e.context.Done():
+					return e.context.Err()
+				case <-e.inputStopped:
+					return errInputStopped
+				}
+				e.inputBytes.Add(int64(size))
+				return nil
+			})
+			if err == errInputStopped {
+				go e.discardInput()
+			} else if err != nil && e.context.Err() == nil {
+				e.Abort(err)
+			}
+			return err == nil
+		}
+		for {
+			if spilled != nil && !e.memoryPaused() {
+				if !replaySpilled() {
+					return
+				}
+			}
+			var rows []*lrdd.Row
+			select {
+			case rs, ok := <-e.Input.C:
+				arrivalOnce.Do(func() { close(inputArrived) })
+				if !ok {
+					if spilled != nil && e.memory.gate.Wait(e.context) == nil {
+						replaySpilled()
+					}
+					return
+				}
+				rows = rs
+			case <-e.memoryResumed(spilled):
+				continue
+			case <-e.context.Done():
+				// canceled while waiting for inputs
+				return
+			case <-e.inputStopped:
+				go e.discardInput()
+				return
+			}
+			for i, r := range rows {
 				if e.context.Err() != nil {
 					return
 				}
-				inputChan <- r
+				if e.pause != nil {
+					// a paused task holds its state, and stops pulling new inputs until resumed
+					if err := e.pause.Wait(e.context); err != nil {
+						return
+					}
+				}
+				if e.inputSchema != nil {
+					if err := r.Validate(e.inputSchema); err != nil {
+						e.Abort(job.MarkUserError(errors.Wrap(err, "invalid input")))
+						return
+					}
+				}
+				if spilled != nil || e.memoryPaused() {
+					// once spilled, the rest of the batch is also spilled to keep the order
+					if spilled == nil {
+						spilled = e.newSpillBuffer()
+					}
+					if err := spilled.Write(r); err != nil {
+						e.Abort(err)
+						return
+					}
+					continue
+				}
+				// measured before sending, since the row belongs to the transformation afterwards
+				size := r.Size()
+				select {
+				case inputChan <- r:
+				case <-e.context.Done():
+					return
+				case <-e.inputStopped:
+					e.inputRows.Add(int64(i))
+					e.Input.Consumed(rows)
+					go e.discardInput()
+					return
+				}
+				e.inputBytes.Add(int64(size))
+			}
+			e.inputRows.Add(int64(len(rows)))
+			e.Input.Consumed(rows)
+		}
+	}()
+
+	if e.queue != nil {
+		if e.queue.maxRunning > 0 {
+			// a task takes a slot after its inputs arrive, so that tasks waiting for the upstream
+			// do not occupy the slots of the upstream tasks
+			select {
+			case <-inputArrived:
+			case <-e.context.Done():
+				return
 			}
-			totalRows += len(rows)
 		}
+		// a task whose input queue is full runs without a slot, since its upstream tasks may be holding
+		// the slots while they are blocked on writing to it
+		release, err := e.queue.Acquire(e.context, e.priority, e.Input.Full())
+		if err != nil {
+			// canceled while waiting for a slot
+			return
+		}
+		defer release()
+	}
+	var timedOut <-chan struct{}
+	if e.timeout > 0 {
+		// the timeout counts from here, excluding the time waiting for the inputs and a slot
+		timeoutCtx, timeout := context.WithCancel(context.Background())
+		defer timeout()
+		go e.watchTimeout(timeout)
+		timedOut = timeoutCtx.Done()
+	}
+
+	fn := e.function
+	applied := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := logger.WrapRecover(recover()); err != nil {
+				applied <- job.MarkUserError(err)
+			}
+		}()
+		applied <- fn.Apply(e.context, inputChan, transportErrorOutput{e.Output})
 	}()
 
-	if err := e.function.Apply(e.context, inputChan, e.Output); err != nil {
+	var err error
+	select {
+	case err = <-applied:
+	case <-timedOut:
+		// not waiting for the transformation, which may not observe the cancellation
+		err = job.MarkTimeout(errors.Errorf("task timed out after %s", e.timeout))
+	}
+	if err != nil {
 		if errors.Cause(err) == context.Canceled || (e.context.Err() != nil && errors.Cause(err) == io.EOF) {
 			// ignore errors caused by task cancellation
 			return
 		}
+		if job.KindOf(err) == job.UnknownErrorKind {
+			err = job.MarkUserError(err)
+		}
+		if transformation.IsRetryable(fn, err) {
+			err = job.MarkRetryable(err)
+		}
 		e.Abort(err)
 		return
 	} else if e.context.Err() != nil {
 		return
 	}
 	e.close()
-	e.context.AddMetric(fmt.Sprintf("%s/%s/InputRows", e.task.StageName, e.task.PartitionID), totalRows)
 
 	if err := e.Output.Close(); err != nil {
-		e.Abort(errors.Wrap(err, "close output"))
+		e.Abort(job.MarkTransportError(errors.Wrap(err, "close output")))
 		return
 	}
 	e.close()
-	e.context.AddMetric(fmt.Sprintf("%s/%s/InputRows", e.task.StageName, e.task.PartitionID), totalRows)
+	e.reportMetrics()
+	for id, n := range e.Output.PartitionCounts() {
+		e.context.SetMetric(fmt.Sprintf("%s/%s/PartitionRows/%s", e.task.StageName, e.task.PartitionID, id), int(n))
+	}
 
+	if !e.markFinished() {
+		// aborted meanwhile
+		return
+	}
+	e.finishMetrics(false)
 	if err := e.taskReporter.ReportSuccess(); err != nil {
 		log.Error("Task {} have been successfully done, but failed to report: {}", e.task.ID(), err)
+		return
+	}
+	log.Verbose("Task {} succeeded", e.task.ID())
+}
+
+// watchTimeout calls timeout if the task runs longer than the timeout. The time while the job is paused
+// is not counted.
+func (e *TaskExecutor) watchTimeout(timeout context.CancelFunc) {
+	remaining := e.timeout
+	for {
+		var paused <-chan struct{}
+		if e.pause != nil {
+			paused = e.pause.Paused()
+		}
+		started := time.Now()
+		t := time.NewTimer(remaining)
+		select {
+		case <-t.C:
+			timeout()
+			return
+		case <-paused:
+			t.Stop()
+			remaining -= time.Since(started)
+			if err := e.pause.Wait(e.context); err != nil {
+				return
+			}
+		case <-e.context.Done():
+			t.Stop()
+			return
+		}
+	}
+}
+
+// discardInput consumes the rest of the inputs without processing them after the inputs are stopped,
+// so that the upstream tasks are not blocked on writing to the task.
+func (e *TaskExecutor) discardInput() {
+	for {
+		select {
+		case rows, ok := <-e.Input.C:
+			if !ok {
+				return
+			}
+			e.Input.Consumed(rows)
+		case <-e.jobContext.Done():
+			return
+		}
+	}
+}
+
+// errInputStopped is returned while replaying the spilled inputs if the inputs of the task are stopped.
+var errInputStopped = errors.New("input stopped")
+
+// memoryPaused returns true if the worker is under memory pressure.
+func (e *TaskExecutor) memoryPaused() bool {
+	if e.memory == nil {
+		return false
+	}
+	select {
+	case <-e.memory.gate.Resumed():
+		return false
+	default:
+		return true
+	}
+}
+
+// memoryResumed returns a channel which is closed when the memory pressure is relieved,
+// or nil if no inputs are spilled.
+func (e *TaskExecutor) memoryResumed(spilled *spillBuffer) <-chan struct{} {
+	if spilled == nil {
+		return nil
 	}
+	return e.memory.gate.Resumed()
+}
+
+// newSpillBuffer creates a buffer of rows, which is spilled to disk while the worker is under memory pressure.
+func (e *TaskExecutor) newSpillBuffer() *spillBuffer {
+	var shouldSpill func() bool
+	if e.memory != nil {
+		shouldSpill = e.memory.Pressured
+	}
+	return newSpillBuffer(e.spillDir, shouldSpill)
+}
+
+// reportMetricsPeriodically reports the metrics of the task until it finishes.
+func (e *TaskExecutor) reportMetricsPeriodically() {
+	if e.reportInterval <= 0 {
+		return
+	}
+	t := time.NewTicker(e.reportInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			e.reportMetrics()
+		case <-e.context.Done():
+			return
+		}
+	}
+}
+
+// reportMetrics updates the numbers of rows and bytes read and written by the task so far.
+func (e *TaskExecutor) reportMetrics() {
+	prefix := fmt.Sprintf("%s/%s/", e.task.StageName, e.task.PartitionID)
+	e.taskReporter.UpdateMetric(func(metrics job.Metrics) {
+		metrics[prefix+"InputRows"] = int(e.inputRows.Load())
+		metrics[prefix+"InputBytes"] = int(e.inputBytes.Load())
+		metrics[prefix+"OutputRows"] = e.Output.RowsWritten()
+		metrics[prefix+"OutputBytes"] = e.Output.BytesWritten()
+		metrics[prefix+"OutputDroppedRows"] = e.Output.RowsDropped()
+	})
 }
 
+// Abort cancels the task and reports the failure, unless the result of the task is already reported.
 func (e *TaskExecutor) Abort(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.finished {
+		return
+	}
+	e.finished = true
+
 	e.close()
+	e.reportMetrics()
+	e.recordSpanError(err)
+	e.finishMetrics(true)
+	log.Verbose("Task {} failed: {}", e.task.ID(), err)
 	reportErr := e.taskReporter.ReportFailure(err)
 	if reportErr != nil {
-		log.Error("While reporting the error, another error occurred", reportErr)
+		log.Error("While reporting the error of task {}, another error occurred: {}", e.task.ID(), reportErr)
 	}
 	_ = e.Output.Close()
+	// unblocks the upstream writing to the task
+	e.Input.Close()
+}
+
+// markFinished marks the result of the task to be reported, and returns false if it's already reported.
+func (e *TaskExecutor) markFinished() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.finished {
+		return false
+	}
+	e.finished = true
+	return true
+}
+
+func (e *TaskExecutor) finishMetrics(failed bool) {
+	if e.metrics != nil {
+		e.metrics.finish(failed, int(e.inputRows.Load()), e.Output.BytesWritten())
+	}
+}
+
+// startSpan starts the span of the task, whose parent is the span of the job propagated from the master.
+// The context of the transformation carries the span, so that it can create child spans.
+func startSpan(ctx context.Context, task *job.Task) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "lrmr.Task",
+		trace.WithAttributes(
+			attribute.String("lrmr.job_id", task.JobID),
+			attribute.String("lrmr.stage", task.StageName),
+			attribute.String("lrmr.partition_id", task.PartitionID),
+		),
+	)
+}
+
+func (e *TaskExecutor) recordSpanError(err error) {
+	e.spanMu.Lock()
+	defer e.spanMu.Unlock()
+	if e.span == nil || err == nil {
+		return
+	}
+	e.span.RecordError(err)
+	e.span.SetStatus(codes.Error, err.Error())
+}
+
+func (e *TaskExecutor) endSpan() {
+	e.spanMu.Lock()
+	defer e.spanMu.Unlock()
+	if e.span == nil {
+		return
+	}
+	e.span.SetAttributes(
+		attribute.Int64("lrmr.input_rows", e.inputRows.Load()),
+		attribute.Int("lrmr.output_rows", e.Output.RowsWritten()),
+	)
+	e.span.End()
 }
 
 func (e *TaskExecutor) guardPanic() {
 	if err := logger.WrapRecover(recover()); err != nil {
-		e.Abort(err)
+		e.Abort(job.MarkUserError(err))
+	}
+}
+
+// transportErrorOutput marks the errors on writing to the output as job.TransportError.
+type transportErrorOutput struct {
+	output.Output
+}
+
+func (t transportErrorOutput) Write(rows ...*lrdd.Row) error {
+	if err := t.Output.Write(rows...); err != nil {
+		return job.MarkTransportError(err)
 	}
+	return nil
 }
 
-// close frees occupied resources and memories.
+// close cancels the task. The transformation and the broadcasts are freed along with the executor,
+// which is dropped by the worker after Run returns.
 func (e *TaskExecutor) close() {
 	e.cancel()
-	e.function = nil
 }
 
 func (e *TaskExecutor) WaitForFinish() {
@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ab180/lrmr/coordinator"
+	"github.com/ab180/lrmr/input"
+	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/lrdd"
+	"github.com/ab180/lrmr/output"
+	"github.com/ab180/lrmr/partitions"
+	"github.com/ab180/lrmr/stage"
+	"github.com/ab180/lrmr/transformation"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// collectTransformation sends the values of its input rows to the channel.
+type collectTransformation struct {
+	values chan int
+}
+
+func (c *collectTransformation) Apply(_ transformation.Context, in chan *lrdd.Row, _ output.Output) error {
+	for row := range in {
+		var v int
+		if err := row.DecodeValue(&v); err != nil {
+			return err
+		}
+		c.values <- v
+	}
+	return nil
+}
+
+func TestTaskExecutor_MemoryPressure(t *testing.T) {
+	Convey("Given a task running on a worker under memory pressure", t, func() {
+		m := newMemoryWatcher(MemoryOptions{HighWatermark: 1000, CheckInterval: time.Second})
+		heap := uint64(1000)
+		m.readHeap = func() uint64 { return heap }
+		m.check()
+
+		st := stage.New("Collect0", nil)
+		j := &job.Job{ID: "job1", Stages: []stage.Stage{st}}
+		task := &job.Task{JobID: j.ID, StageName: st.Name, PartitionID: "0"}
+		fn := &collectTransformation{values: make(chan int, 100)}
+
+		// holds up to 2 rows, so that the writer is blocked unless the task takes the inputs
+		in := input.NewReader(1, 2, 0)
+		out := output.NewWriter("0", partitions.NewPreservePartitioner(), map[string]output.Output{})
+
+		exec := NewTaskExecutor(context.Background(), coordinator.NewLocalMemory(), j, task, job.NewTaskStatus(), fn, in, out, nil, nil)
+		exec.memory = m
+		exec.spillDir = t.TempDir()
+		go exec.Run()
+
+		Convey("It should spill its inputs rather than blocking the upstream", func() {
+			written := make(chan struct{})
+			go func() {
+				defer close(written)
+				for i := 0; i < 10; i++ {
+					in.Write([]*lrdd.Row{lrdd.Value(i)})
+				}
+				in.Close()
+			}()
+			So(func() bool {
+				select {
+				case <-written:
+					return true
+				case <-time.After(time.Second):
+					return false
+				}
+			}(), ShouldBeTrue)
+			So(fn.values, ShouldBeEmpty)
+
+			Convey("The spilled inputs should be processed in order after the pressure is relieved", func() {
+				heap = 0
+				m.check()
+
+				var values []int
+				for i := 0; i < 10; i++ {
+					select {
+					case v := <-fn.values:
+						values = append(values, v)
+					case <-time.After(time.Second):
+					}
+				}
+				So(values, ShouldResemble, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+			})
+		})
+	})
+}
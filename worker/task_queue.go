@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// taskQueue limits the number of concurrently running tasks in a worker. When the limit is reached,
+// tasks wait for a slot in the order of their priority, and then in the order of arrival.
+type taskQueue struct {
+	maxRunning int
+	running    int
+	waiting    slotHeap
+	seq        uint64
+	mu         sync.Mutex
+}
+
+func newTaskQueue(maxRunning int) *taskQueue {
+	return &taskQueue{maxRunning: maxRunning}
+}
+
+// Acquire blocks until a task with given priority gets a slot. The returned release function
+// must be called after the task finishes. Slots are unlimited if the maximum is not positive.
+// If bypass is closed while waiting, the task runs without a slot and the release function is a no-op.
+func (q *taskQueue) Acquire(ctx context.Context, priority int, bypass <-chan struct{}) (release func(), err error) {
+	q.mu.Lock()
+	if q.maxRunning <= 0 || (q.running < q.maxRunning && q.waiting.Len() == 0) {
+		q.running++
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	s := &slot{
+		priority: priority,
+		seq:      q.seq,
+		acquired: make(chan struct{}),
+	}
+	q.seq++
+	heap.Push(&q.waiting, s)
+	q.mu.Unlock()
+
+	select {
+	case <-s.acquired:
+		return q.releaseFunc(), nil
+
+	case <-bypass:
+		if q.cancel(s) {
+			return q.releaseFunc(), nil
+		}
+		return func() {}, nil
+
+	case <-ctx.Done():
+		if q.cancel(s) {
+			// acquired right before the cancellation. give the slot to the next one
+			q.releaseFunc()()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// cancel removes the slot from the waiting ones. It returns true if the slot is already acquired.
+func (q *taskQueue) cancel(s *slot) (acquired bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case <-s.acquired:
+		return true
+	default:
+		heap.Remove(&q.waiting, s.index)
+		return false
+	}
+}
+
+// Counts returns the number of running and waiting tasks.
+func (q *taskQueue) Counts() (running, waiting int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running, q.waiting.Len()
+}
+
+func (q *taskQueue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+
+			q.running--
+			q.dispatch()
+		})
+	}
+}
+
+// dispatch gives slots to waiting tasks while slots are available. q.mu must be held.
+func (q *taskQueue) dispatch() {
+	for q.running < q.maxRunning && q.waiting.Len() > 0 {
+		s := heap.Pop(&q.waiting).(*slot)
+		q.running++
+		close(s.acquired)
+	}
+}
+
+type slot struct {
+	priority int
+	seq      uint64
+	acquired chan struct{}
+	index    int
+}
+
+type slotHeap []*slot
+
+func (h slotHeap) Len() int { return len(h) }
+
+func (h slotHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h slotHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *slotHeap) Push(x interface{}) {
+	s := x.(*slot)
+	s.index = len(*h)
+	*h = append(*h, s)
+}
+
+func (h *slotHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	s := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return s
+}
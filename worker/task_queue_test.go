@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTaskQueue(t *testing.T) {
+	Convey("Given a task queue allowing one running task", t, func() {
+		q := newTaskQueue(1)
+		release, err := q.Acquire(context.TODO(), 0, nil)
+		So(err, ShouldBeNil)
+
+		Convey("When tasks are waiting for a slot", func() {
+			acquired := make(chan int, 3)
+			for i, priority := range []int{-2, 0, -1} {
+				n, p := i, priority
+				go func() {
+					r, err := q.Acquire(context.TODO(), p, nil)
+					if err != nil {
+						return
+					}
+					acquired <- n
+					r()
+				}()
+				// ensure the arrival order
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			Convey("It should count them as waiting", func() {
+				running, waiting := q.Counts()
+				So(running, ShouldEqual, 1)
+				So(waiting, ShouldEqual, 3)
+				release()
+			})
+
+			Convey("It should give slots in the order of their priority", func() {
+				release()
+				So(<-acquired, ShouldEqual, 1)
+				So(<-acquired, ShouldEqual, 2)
+				So(<-acquired, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When a waiting task is canceled", func() {
+			ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+			defer cancel()
+			_, err := q.Acquire(ctx, 0, nil)
+
+			Convey("It should return the context error", func() {
+				So(err, ShouldBeError, context.DeadlineExceeded)
+				_, waiting := q.Counts()
+				So(waiting, ShouldEqual, 0)
+			})
+		})
+
+		Convey("When a waiting task is bypassed", func() {
+			bypass := make(chan struct{})
+			close(bypass)
+			r, err := q.Acquire(context.TODO(), 0, bypass)
+
+			Convey("It should run without taking a slot", func() {
+				So(err, ShouldBeNil)
+				running, waiting := q.Counts()
+				So(running, ShouldEqual, 1)
+				So(waiting, ShouldEqual, 0)
+
+				r()
+				running, _ = q.Counts()
+				So(running, ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given a task queue without limit", t, func() {
+		q := newTaskQueue(0)
+		for i := 0; i < 3; i++ {
+			_, err := q.Acquire(context.TODO(), 0, nil)
+			So(err, ShouldBeNil)
+		}
+
+		Convey("It should count every task as running", func() {
+			running, waiting := q.Counts()
+			So(running, ShouldEqual, 3)
+			So(waiting, ShouldEqual, 0)
+		})
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"io"
 	"net"
+	"net/http"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,15 +15,17 @@ import (
 	"github.com/ab180/lrmr/cluster/node"
 	"github.com/ab180/lrmr/coordinator"
 	"github.com/ab180/lrmr/input"
-	"github.com/ab180/lrmr/internal/serialization"
+	"github.com/ab180/lrmr/internal/tracing"
 	"github.com/ab180/lrmr/job"
+	"github.com/ab180/lrmr/logging"
 	"github.com/ab180/lrmr/lrmrpb"
 	"github.com/ab180/lrmr/output"
 	"github.com/ab180/lrmr/partitions"
-	"github.com/airbloc/logger"
+	"github.com/ab180/lrmr/transformation"
 	"github.com/airbloc/logger/module/loggergrpc"
 	"github.com/golang/protobuf/ptypes/empty"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
@@ -29,7 +33,7 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-var log = logger.New("lrmr")
+var log = logging.New("lrmr")
 
 type Worker struct {
 	Cluster   cluster.Cluster
@@ -40,13 +44,29 @@ type Worker struct {
 	jobManager      *job.Manager
 	jobTracker      *job.Tracker
 	runningTasks    sync.Map
+	unregisterOnce  sync.Once
+	draining        bool
+	drainMu         sync.RWMutex
+	pauseGates      sync.Map
+	inputStoppers   sync.Map
+	broadcasts      *broadcastStore
+	blocks          *BlockStore
+	stopWatchBlocks context.CancelFunc
+	taskQueue       *taskQueue
+	memory          *memoryWatcher
+	heartbeat       *heartbeat
+	metrics         *workerMetrics
+	startedAt       time.Time
 	workerLocalOpts map[string]interface{}
 
 	opt Options
 }
 
 func New(crd coordinator.Coordinator, opt Options) (*Worker, error) {
-	c, err := cluster.OpenRemote(crd, cluster.DefaultOptions())
+	if err := opt.Output.Codec.Validate(); err != nil {
+		return nil, errors.Wrap(err, "output codec")
+	}
+	c, err := cluster.OpenRemote(crd, opt.RPC)
 	if err != nil {
 		return nil, err
 	}
@@ -59,17 +79,36 @@ func New(crd coordinator.Coordinator, opt Options) (*Worker, error) {
 		)),
 	)
 	jm := job.NewManager(c.States())
+	jt, err := job.NewJobTracker(c.States(), jm)
+	if err != nil {
+		return nil, errors.Wrap(err, "init job tracker")
+	}
 	w := &Worker{
 		Cluster:         c,
 		jobManager:      jm,
-		jobTracker:      job.NewJobTracker(c.States(), jm),
+		jobTracker:      jt,
 		RPCServer:       srv,
+		taskQueue:       newTaskQueue(opt.MaxConcurrentTasks),
+		memory:          newMemoryWatcher(opt.Memory),
+		metrics:         newWorkerMetrics(),
+		broadcasts:      newBroadcastStore(),
+		blocks:          newBlockStore(),
 		workerLocalOpts: make(map[string]interface{}),
+		startedAt:       time.Now(),
 		opt:             opt,
 	}
 	if err := w.register(); err != nil {
 		return nil, errors.WithMessage(err, "register worker")
 	}
+	wctx, cancel := context.WithCancel(context.Background())
+	if err := w.blocks.watch(wctx, c.States()); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "watch blocks")
+	}
+	w.stopWatchBlocks = cancel
+	w.heartbeat = newHeartbeat(opt.HeartbeatInterval, w.publishStatus)
+	w.memory.Start()
+	w.heartbeat.Start()
 	return w, nil
 }
 
@@ -117,16 +156,94 @@ func (w *Worker) State() node.State {
 	return w.Node.States()
 }
 
+// StatusNs is the namespace of the worker statuses in the coordinator, keyed by the host of the worker.
+// The statuses are published on each heartbeat, and expire with the registration of the worker.
+const StatusNs = "workerStatus"
+
+// Status is a snapshot of the tasks in a worker.
+type Status struct {
+	// RunningTasks is the number of tasks running transformations.
+	RunningTasks int
+
+	// QueuedTasks is the number of tasks waiting for a slot due to MaxConcurrentTasks.
+	QueuedTasks int
+
+	// RunningTaskIDs is the references of the tasks created in the worker and not finished yet,
+	// including the queued ones.
+	RunningTaskIDs []string
+
+	// BroadcastBytes is the total size of the broadcasts held for the tasks, in serialized form.
+	BroadcastBytes int
+
+	// BlockBytes is the total size of the partitions of the blocks stored in the worker, in encoded form.
+	BlockBytes int
+
+	// Uptime is the time elapsed since the worker started.
+	Uptime time.Duration
+
+	// LastHeartbeat is the time of the last heartbeat sent to the coordinator.
+	// It's zero if the worker has not sent any heartbeat yet.
+	LastHeartbeat time.Time
+}
+
+// Status returns the status of the worker. It does not block the running tasks.
+func (w *Worker) Status() Status {
+	running, queued := w.taskQueue.Counts()
+	var taskIDs []string
+	for taskID := range w.unfinishedTasks() {
+		taskIDs = append(taskIDs, taskID)
+	}
+	sort.Strings(taskIDs)
+
+	s := Status{
+		RunningTasks:   running,
+		QueuedTasks:    queued,
+		RunningTaskIDs: taskIDs,
+		BroadcastBytes: w.broadcasts.Size(),
+		BlockBytes:     w.blocks.Size(),
+		Uptime:         time.Since(w.startedAt),
+	}
+	if w.heartbeat != nil {
+		s.LastHeartbeat = w.heartbeat.Last()
+	}
+	return s
+}
+
+// StatusHandler returns an HTTP handler serving the status of the worker in JSON.
+func (w *Worker) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		if err := jsoniter.NewEncoder(rw).Encode(w.Status()); err != nil {
+			log.Warn("Failed to write status: {}", err)
+		}
+	})
+}
+
+// publishStatus writes the status of the worker to the coordinator, leased by the registration of the node.
+func (w *Worker) publishStatus(ctx context.Context) error {
+	return w.Node.States().Put(ctx, path.Join(StatusNs, w.Node.Info().Host), w.Status())
+}
+
 func (w *Worker) CreateTasks(ctx context.Context, req *lrmrpb.CreateTasksRequest) (*empty.Empty, error) {
-	broadcasts, err := serialization.DeserializeBroadcast(req.Broadcasts)
-	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	w.drainMu.RLock()
+	defer w.drainMu.RUnlock()
+	if w.draining {
+		return nil, status.Error(codes.Unavailable, "worker is shutting down")
+	}
+
+	if max := w.opt.MaxBroadcastSize; max > 0 {
+		for key, raw := range req.Broadcasts {
+			if len(raw) > max {
+				return nil, status.Errorf(codes.InvalidArgument,
+					"broadcast %s is %d bytes, which exceeds the limit of %d bytes", key, len(raw), max)
+			}
+		}
 	}
 
-	wg, wctx := errgroup.WithContext(ctx)
+	wg, wctx := errgroup.WithContext(tracing.ExtractMetadata(ctx))
 	for _, p := range req.PartitionIDs {
 		partitionID := p
-		wg.Go(func() error { return w.createTask(wctx, req, partitionID, broadcasts) })
+		wg.Go(func() error { return w.createTask(wctx, req, partitionID) })
 	}
 	if err := wg.Wait(); err != nil {
 		return nil, err
@@ -134,33 +251,52 @@ func (w *Worker) CreateTasks(ctx context.Context, req *lrmrpb.CreateTasksRequest
 	return &empty.Empty{}, nil
 }
 
-func (w *Worker) createTask(ctx context.Context, req *lrmrpb.CreateTasksRequest, partitionID string, broadcasts serialization.Broadcast) error {
+func (w *Worker) createTask(ctx context.Context, req *lrmrpb.CreateTasksRequest, partitionID string) error {
 	j := new(job.Job)
 	if err := req.Job.UnmarshalJSON(j); err != nil {
 		return status.Errorf(codes.InvalidArgument, "invalid JSON in Job: %v", err)
 	}
 	s := j.GetStage(req.Stage)
 
-	// jobCtx will be disposed after the job completes
-	jobCtx, cancelJobCtx := context.WithCancel(context.Background())
+	// jobCtx will be disposed after the job completes. it keeps the values of the request
+	// (e.g. tracing spans), which are propagated to the transformation through its Context
+	jobCtx, cancelJobCtx := context.WithCancel(detachContext(ctx))
 
 	task := job.NewTask(partitionID, w.Node.Info(), j.ID, s)
 	ts, err := w.jobManager.CreateTask(ctx, task)
 	if err != nil {
 		return status.Errorf(codes.Internal, "create task failed: %v", err)
 	}
-	in := input.NewReader(w.opt.Input.QueueLength)
+	in := input.NewReader(w.opt.Input.QueueLength, w.opt.Input.MaxQueuedRows, w.opt.Input.MaxQueuedBytes)
 
 	// after job finishes, remaining connections should be closed
 	out, err := w.newOutputWriter(jobCtx, j, s.Name, partitionID, req.Output)
 	if err != nil {
 		return status.Errorf(codes.Internal, "unable to create output: %v", err)
 	}
+	out.SetProjection(transformation.ProjectionOf(s.Function))
+	out.SetNoOutputPolicy(s.NoOutputPolicy)
 
+	broadcasts, err := w.broadcasts.Acquire(j.ID, req.Broadcasts)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
 	exec := NewTaskExecutor(jobCtx, w.Cluster.States(), j, task, ts, s.Function, in, out, broadcasts, w.workerLocalOpts)
+	exec.pause = w.pauseGateOf(jobCtx, j)
+	exec.inputStopped = w.inputStopperOf(jobCtx, j).Stopped(stageIndexOf(j, s.Name))
+	exec.queue = w.taskQueue
+	exec.reportInterval = w.opt.ReportInterval
+	exec.priority = -stageIndexOf(j, s.Name)
+	exec.inputSchema = s.InputSchema
+	exec.memory = w.memory
+	exec.metrics = w.metrics.newTaskMetrics(s.Name)
+	exec.spillDir = w.opt.Memory.SpillDir
+	exec.blocks = w.blocks
 	w.runningTasks.Store(task.ID().String(), exec)
 
 	w.jobTracker.OnJobCompletion(j, func(j *job.Job, stat *job.Status) {
+		w.pauseGates.Delete(j.ID)
+		w.inputStoppers.Delete(j.ID)
 		if len(stat.Errors) > 0 {
 			err := stat.Errors[0]
 			log.Verbose("Task {} aborted with error caused by task {}.", task.ID(), err.Task)
@@ -168,10 +304,94 @@ func (w *Worker) createTask(ctx context.Context, req *lrmrpb.CreateTasksRequest,
 		}
 		cancelJobCtx()
 	})
-	go exec.Run()
+	go func() {
+		defer w.broadcasts.Release(j.ID)
+		exec.Run()
+	}()
 	return nil
 }
 
+// stageIndexOf returns the order of the stage in the job.
+func stageIndexOf(j *job.Job, stageName string) int {
+	for i, s := range j.Stages {
+		if s.Name == stageName {
+			return i
+		}
+	}
+	return 0
+}
+
+// pauseGateOf returns a pause gate shared by the tasks of the job, which follows
+// pause and resume of the job until the jobCtx is done.
+func (w *Worker) pauseGateOf(jobCtx context.Context, j *job.Job) *pauseGate {
+	entry, loaded := w.pauseGates.LoadOrStore(j.ID, &pauseGate{})
+	gate := entry.(*pauseGate)
+	if loaded {
+		return gate
+	}
+	pauseChan, err := w.jobManager.WatchJobPause(jobCtx, j.ID)
+	if err != nil {
+		log.Warn("Failed to watch pause of job {}: {}", j.ID, err)
+		return gate
+	}
+	if paused, err := w.jobManager.IsJobPaused(jobCtx, j.ID); err != nil {
+		log.Warn("Failed to check whether job {} is paused: {}", j.ID, err)
+	} else if paused {
+		gate.Pause()
+	}
+	go func() {
+		for paused := range pauseChan {
+			if paused {
+				log.Verbose("Pausing tasks of job {}", j.ID)
+				gate.Pause()
+			} else {
+				log.Verbose("Resuming tasks of job {}", j.ID)
+				gate.Resume()
+			}
+		}
+	}()
+	return gate
+}
+
+// JobPaused returns a channel which is closed when the tasks of given job in the worker are paused.
+// It returns false if the worker has no tasks of the job.
+func (w *Worker) JobPaused(jobID string) (<-chan struct{}, bool) {
+	entry, ok := w.pauseGates.Load(jobID)
+	if !ok {
+		return nil, false
+	}
+	return entry.(*pauseGate).Paused(), true
+}
+
+// inputStopperOf returns an input stopper shared by the tasks of the job, which follows
+// job.Manager.StopInputs of the job until the jobCtx is done.
+func (w *Worker) inputStopperOf(jobCtx context.Context, j *job.Job) *inputStopper {
+	entry, loaded := w.inputStoppers.LoadOrStore(j.ID, newInputStopper())
+	stopper := entry.(*inputStopper)
+	if loaded {
+		return stopper
+	}
+	stopChan, err := w.jobManager.WatchStoppedStages(jobCtx, j.ID)
+	if err != nil {
+		log.Warn("Failed to watch stopped inputs of job {}: {}", j.ID, err)
+		return stopper
+	}
+	if stageNames, err := w.jobManager.ListStoppedStages(jobCtx, j.ID); err != nil {
+		log.Warn("Failed to check whether inputs of job {} are stopped: {}", j.ID, err)
+	} else {
+		for _, name := range stageNames {
+			stopper.Stop(stageIndexOf(j, name))
+		}
+	}
+	go func() {
+		for name := range stopChan {
+			log.Verbose("Stopping inputs of job {} up to stage {}", j.ID, name)
+			stopper.Stop(stageIndexOf(j, name))
+		}
+	}()
+	return stopper
+}
+
 func (w *Worker) newOutputWriter(ctx context.Context, j *job.Job, stageName, curPartitionID string, o *lrmrpb.Output) (*output.Writer, error) {
 	idToOutput := make(map[string]output.Output)
 	cur := j.GetStage(stageName)
@@ -203,12 +423,14 @@ func (w *Worker) newOutputWriter(ctx context.Context, j *job.Job, stageName, cur
 			}
 		}
 		wg.Go(func() error {
-			out, err := output.OpenPushStream(ctx, w.Cluster, w.Node.Info(), host, taskID)
+			out, err := output.OpenPushStream(ctx, w.Cluster, w.Node.Info(), host, taskID, w.opt.Output.Codec, j.Compression)
 			if err != nil {
 				return err
 			}
 			mu.Lock()
-			idToOutput[id] = output.NewBufferedOutput(out, w.opt.Output.BufferLength)
+			idToOutput[id] = output.NewBufferedOutput(out, w.opt.Output.BufferLength,
+				output.WithMaxBufferBytes(w.opt.Output.BufferBytes),
+				output.WithIdleFlush(w.opt.Output.IdleFlushTimeout))
 			mu.Unlock()
 			return nil
 		})
@@ -275,10 +497,78 @@ func (w *Worker) PollData(stream lrmrpb.Node_PollDataServer) error {
 	panic("implement me")
 }
 
+// Drain stops accepting new tasks and waits for the running tasks to finish.
+// If the context is done before they finish, the remaining tasks are aborted.
+func (w *Worker) Drain(ctx context.Context) error {
+	w.drainMu.Lock()
+	w.draining = true
+	w.drainMu.Unlock()
+
+	// unregistered node is no longer assigned to new jobs
+	w.unregister()
+
+	execs := w.unfinishedTasks()
+	log.Info("Draining {} running tasks", len(execs))
+
+	for _, exec := range execs {
+		select {
+		case <-exec.finishChan:
+		case <-ctx.Done():
+			remaining := w.unfinishedTasks()
+			log.Warn("Aborting {} tasks not finished in time", len(remaining))
+			for _, exec := range remaining {
+				exec.Abort(errors.Errorf("worker %s shut down before the task finished", w.Node.Info().Host))
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// unfinishedTasks returns the executors of the tasks running on the worker, keyed by task ID.
+// The executors are kept in runningTasks until their input streams end, which can be after they finish.
+func (w *Worker) unfinishedTasks() map[string]*TaskExecutor {
+	execs := make(map[string]*TaskExecutor)
+	w.runningTasks.Range(func(k, v interface{}) bool {
+		exec := v.(*TaskExecutor)
+		select {
+		case <-exec.finishChan:
+		default:
+			execs[k.(string)] = exec
+		}
+		return true
+	})
+	return execs
+}
+
+// unregister unregisters the node once, as both Drain and Close do.
+func (w *Worker) unregister() {
+	w.unregisterOnce.Do(w.Node.Unregister)
+}
+
 func (w *Worker) Close() error {
+	w.heartbeat.Close()
+	w.memory.Close()
 	w.RPCServer.Stop()
-	w.Node.Unregister()
+	w.unregister()
+	w.jobTracker.Close()
+	w.stopWatchBlocks()
+	return w.Cluster.Close()
+}
+
+// Kill stops the worker abruptly as if it has crashed, which is useful for testing failures. Unlike Close,
+// the running tasks are canceled without reporting their results, and the worker is not unregistered.
+// Its registration expires after the liveness probe interval, as the lease is no longer kept alive.
+func (w *Worker) Kill() error {
+	w.heartbeat.Close()
+	w.memory.Close()
+	w.RPCServer.Stop()
+	w.runningTasks.Range(func(_, v interface{}) bool {
+		v.(*TaskExecutor).close()
+		return true
+	})
 	w.jobTracker.Close()
+	w.stopWatchBlocks()
 	return w.Cluster.Close()
 }
 